@@ -9,25 +9,46 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
+	"github.com/orders-service/internal/admission"
+	"github.com/orders-service/internal/auth"
 	"github.com/orders-service/internal/events"
+	"github.com/orders-service/internal/export"
 	grpcserver "github.com/orders-service/internal/grpc"
+	"github.com/orders-service/internal/health"
 	handler "github.com/orders-service/internal/http"
+	"github.com/orders-service/internal/idempotency"
 	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/metrics"
+	"github.com/orders-service/internal/ordercache"
+	"github.com/orders-service/internal/outbox"
+	"github.com/orders-service/internal/policy"
+	"github.com/orders-service/internal/projection"
+	"github.com/orders-service/internal/ratelimit"
 	"github.com/orders-service/internal/repo"
 	"github.com/orders-service/internal/service"
 	pb "github.com/orders-service/proto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 )
 
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
-	log, err := logger.New()
+	log, logLevel, err := logger.New(os.Getenv("LOG_LEVEL"))
 	if err != nil {
 		panic(err)
 	}
@@ -69,33 +90,29 @@ func main() {
 		log.Fatal("REDIS_URL is required")
 	}
 
-	db, err := sql.Open("postgres", dbURL)
-	if err != nil {
-		log.Fatal("failed to open database", zap.Error(err))
-	}
-	defer db.Close()
-
-	maxOpenConns := 25
-	maxIdleConns := 5
-	db.SetMaxOpenConns(maxOpenConns)
-	db.SetMaxIdleConns(maxIdleConns)
-	db.SetConnMaxLifetime(5 * time.Minute)
-	db.SetConnMaxIdleTime(10 * time.Minute)
-
-	log.Info("database pool configured",
-		zap.Int("max_open_conns", maxOpenConns),
-		zap.Int("max_idle_conns", maxIdleConns),
-	)
-
-	if err := db.Ping(); err != nil {
-		log.Fatal("failed to ping database", zap.Error(err))
-	}
-	log.Info("connected to database")
-
-	if err := repo.RunMigrations(db, "migrations"); err != nil {
-		log.Fatal("failed to run migrations", zap.Error(err))
+	// db is the Postgres database. It stays nil for the in-memory and SQLite
+	// stores, which turns off the features only Postgres backs.
+	var db *sql.DB
+	var sqliteDB *sql.DB
+	var orderRepo orderStore
+	var tagRepo repo.TagRepository
+	driver, dsn := repo.ParseDatabaseURL(dbURL)
+	switch {
+	case dbURL == repo.InMemoryURL:
+		log.Warn("using in-memory order repository, data is lost on restart")
+		memRepo := repo.NewInMemoryOrderRepository()
+		orderRepo, tagRepo = memRepo, memRepo
+	case driver == repo.DriverSQLite:
+		sqliteDB = openSQLite(log, dsn)
+		defer sqliteDB.Close()
+		orderRepo = repo.NewSQLiteOrderRepository(sqliteDB)
+		tagRepo = repo.NewSQLiteTagRepository(sqliteDB)
+	default:
+		db = openDB(log, dsn)
+		defer db.Close()
+		orderRepo = repo.NewPostgresOrderRepository(db, repo.WithQueryTimeout(getEnvDuration(log, "QUERY_TIMEOUT", repo.DefaultQueryTimeout)))
+		tagRepo = repo.NewPostgresTagRepository(db)
 	}
-	log.Info("migrations applied")
 
 	opt, err := redis.ParseURL(redisURL)
 	if err != nil {
@@ -107,48 +124,408 @@ func main() {
 	}
 	log.Info("connected to redis")
 
-	publisher := events.NewRedisPublisher(redisClient)
+	eventFormat := os.Getenv("EVENT_FORMAT")
+	if eventFormat == "" {
+		eventFormat = "json"
+	}
+	serializer, err := events.ParseFormat(eventFormat)
+	if err != nil {
+		log.Fatal("invalid EVENT_FORMAT", zap.Error(err))
+	}
+	streamMaxLen := int64(getEnvInt(log, "STREAM_MAXLEN", events.DefaultStreamMaxLen))
+	publishAttempts := getEnvInt(log, "PUBLISH_RETRY_ATTEMPTS", events.DefaultPublishAttempts)
+	publishRetryDelay := getEnvDuration(log, "PUBLISH_RETRY_DELAY", events.DefaultPublishRetryDelay)
+
+	// The relay publishes one event at a time and waits for each, so it
+	// keeps using the unbatched publisher.
+	var servicePublisher events.Publisher
+	var relayPublisher events.RawPublisher
+	var batchPublisher *events.BatchPublisher
+	// kafkaClient is set when events go through Kafka rather than Redis
+	// Streams, which turns off the features built on the stream.
+	var kafkaClient *events.KafkaClient
+	kafkaTopic := os.Getenv("KAFKA_TOPIC")
+	if kafkaTopic == "" {
+		kafkaTopic = events.DefaultKafkaTopic
+	}
+	switch backend := os.Getenv("EVENT_BACKEND"); backend {
+	case "", "redis":
+		publisherOpts := []events.PublisherOption{
+			events.WithSerializer(serializer),
+			events.WithMaxLen(streamMaxLen),
+			events.WithRetry(publishAttempts, publishRetryDelay),
+		}
+		if threshold := getEnvInt(log, "PUBLISH_BREAKER_THRESHOLD", events.DefaultBreakerThreshold); threshold > 0 {
+			breaker := events.NewCircuitBreaker(threshold, getEnvDuration(log, "PUBLISH_BREAKER_COOLDOWN", events.DefaultBreakerCooldown))
+			publisherOpts = append(publisherOpts, events.WithCircuitBreaker(breaker))
+		}
+		publisher := events.NewRedisPublisher(redisClient, publisherOpts...)
+		servicePublisher, relayPublisher = publisher, publisher
+		if size := getEnvInt(log, "PUBLISH_BATCH_SIZE", 0); size > 0 {
+			batchPublisher = events.NewBatchPublisher(publisher, size, getEnvDuration(log, "PUBLISH_BATCH_INTERVAL", events.DefaultBatchInterval))
+			servicePublisher = batchPublisher
+		}
+	case "kafka":
+		brokers := splitList(os.Getenv("KAFKA_BROKERS"))
+		if len(brokers) == 0 {
+			log.Fatal("KAFKA_BROKERS is required with EVENT_BACKEND=kafka")
+		}
+		if getEnvInt(log, "PUBLISH_BATCH_SIZE", 0) > 0 {
+			log.Fatal("PUBLISH_BATCH_SIZE is only supported with EVENT_BACKEND=redis")
+		}
+		kafkaClient = events.NewKafkaClient(brokers, "orders-service")
+		publisher := events.NewKafkaPublisher(kafkaClient, kafkaTopic,
+			events.WithKafkaSerializer(serializer),
+			events.WithKafkaRetry(publishAttempts, publishRetryDelay),
+		)
+		servicePublisher, relayPublisher = publisher, publisher
+		log.Info("publishing events to kafka", zap.Strings("brokers", brokers), zap.String("topic", kafkaTopic))
+	default:
+		log.Fatal("unsupported EVENT_BACKEND, use redis or kafka", zap.String("backend", backend))
+	}
 
-	orderRepo := repo.NewPostgresOrderRepository(db)
-	orderService := service.NewOrderService(orderRepo, publisher)
+	var softChecks []service.SoftCheck
+	if threshold := getEnvInt(log, "WARN_QUANTITY_ABOVE", 0); threshold > 0 {
+		softChecks = append(softChecks, service.LargeQuantityCheck(threshold))
+	}
+	if catalog := os.Getenv("PRODUCT_CATALOG"); catalog != "" {
+		softChecks = append(softChecks, service.CatalogCheck(strings.Split(catalog, ",")))
+	}
+
+	serviceOpts := []service.Option{
+		service.WithIdempotencyStore(idempotency.NewRedisStore(redisClient, getEnvDuration(log, "IDEMPOTENCY_TTL", 24*time.Hour))),
+		service.WithSoftChecks(softChecks...),
+		service.WithTags(tagRepo, getEnvInt(log, "ORDER_MAX_TAGS", service.DefaultMaxTags)),
+		service.WithStats(orderRepo, getEnvDuration(log, "STATS_SOFT_TIMEOUT", service.DefaultStatsSoftTimeout)),
+		service.WithBatchCreate(orderRepo),
+		service.WithOrderStream(orderRepo),
+		service.WithProductSearch(orderRepo),
+		service.WithOrderFinder(orderRepo),
+	}
+	var projectionRepo *repo.PostgresProjectionRepository
+	var relay *outbox.Relay
+	var txm *repo.TxManager
+	if db != nil {
+		txm = repo.NewTxManager(db)
+	}
+	if db != nil && getEnvBool(log, "OUTBOX_ENABLED", true) {
+		outboxRepo := repo.NewPostgresOutboxRepository(db)
+		serviceOpts = append(serviceOpts, service.WithOutbox(txm, outboxRepo, serializer))
+		relay = outbox.NewRelay(txm, outboxRepo, relayPublisher, log,
+			outbox.WithBatchSize(getEnvInt(log, "OUTBOX_RELAY_BATCH_SIZE", outbox.DefaultBatchSize)),
+			outbox.WithMinAge(getEnvDuration(log, "OUTBOX_MIN_AGE", outbox.DefaultMinAge)),
+			outbox.WithRetention(getEnvDuration(log, "OUTBOX_RETENTION", outbox.DefaultRetention)),
+		)
+	}
+	if db != nil && getEnvBool(log, "CHANGE_FEED_ENABLED", true) {
+		serviceOpts = append(serviceOpts, service.WithChangeFeed(txm, repo.NewPostgresChangeFeedRepository(db)))
+	}
+	switch strategy := os.Getenv("ORDER_ID_STRATEGY"); strategy {
+	case "", "uuid":
+	case "sequence":
+		if db == nil {
+			log.Fatal("ORDER_ID_STRATEGY=sequence requires Postgres")
+		}
+		prefix := repo.DefaultOrderNumberPrefix
+		if v, ok := os.LookupEnv("ORDER_ID_PREFIX"); ok {
+			prefix = v
+		}
+		serviceOpts = append(serviceOpts, service.WithIDGenerator(repo.NewPostgresOrderNumberGenerator(db, prefix)))
+		log.Info("sequential order ids enabled", zap.String("prefix", prefix))
+	default:
+		log.Fatal("unsupported ORDER_ID_STRATEGY, use uuid or sequence", zap.String("strategy", strategy))
+	}
+	if db != nil {
+		metrics.RegisterDBStats(db)
+		projectionRepo = repo.NewPostgresProjectionRepository(db)
+		serviceOpts = append(serviceOpts,
+			service.WithAuditLog(repo.NewPostgresAuditRepository(db)),
+			service.WithProjection(projectionRepo),
+		)
+	}
+	if code := os.Getenv("DEFAULT_CURRENCY"); code != "" {
+		currency, err := service.NormalizeCurrency(code)
+		if err != nil {
+			log.Fatal("invalid DEFAULT_CURRENCY", zap.Error(err))
+		}
+		serviceOpts = append(serviceOpts, service.WithDefaultCurrency(currency))
+	}
+	if v := os.Getenv("STATUS_CHANGE_EVENTS"); v != "" {
+		mode, err := service.ParseStatusEventMode(v)
+		if err != nil {
+			log.Fatal("invalid STATUS_CHANGE_EVENTS", zap.Error(err))
+		}
+		serviceOpts = append(serviceOpts, service.WithStatusEventMode(mode))
+	}
+	if spec := os.Getenv("ORDER_AUTO_TRANSITIONS"); spec != "" {
+		rules, err := service.ParseAutoTransitions(spec)
+		if err != nil {
+			log.Fatal("invalid ORDER_AUTO_TRANSITIONS", zap.Error(err))
+		}
+		serviceOpts = append(serviceOpts, service.WithAutoTransitions(orderRepo, rules))
+	}
+	if limit := getEnvInt(log, "PRODUCT_CREATE_LIMIT", 0); limit > 0 {
+		window := getEnvDuration(log, "PRODUCT_CREATE_WINDOW", time.Minute)
+		serviceOpts = append(serviceOpts, service.WithProductLimiter(
+			ratelimit.NewRedisTokenBucket(redisClient, "ratelimit:product:", limit, window),
+		))
+		log.Info("per-product create rate limit enabled", zap.Int("limit", limit), zap.Duration("window", window))
+	}
+
+	if ttl := getEnvDuration(log, "ORDER_CACHE_TTL", 0); ttl > 0 {
+		serviceOpts = append(serviceOpts, service.WithOrderCache(ordercache.NewRedisCache(redisClient, ttl)))
+		log.Info("order cache enabled", zap.Duration("ttl", ttl))
+	}
+
+	if url := os.Getenv("POLICY_URL"); url != "" {
+		evaluator := policy.NewHTTPEvaluator(url,
+			policy.WithHTTPClient(&http.Client{Timeout: getEnvDuration(log, "POLICY_TIMEOUT", policy.DefaultTimeout)}),
+			policy.WithCacheTTL(getEnvDuration(log, "POLICY_CACHE_TTL", policy.DefaultCacheTTL)),
+		)
+		failOpen := getEnvBool(log, "POLICY_FAIL_OPEN", false)
+		serviceOpts = append(serviceOpts, service.WithPolicy(evaluator, failOpen))
+		log.Info("order policy evaluation enabled", zap.String("url", url), zap.Bool("fail_open", failOpen))
+	}
+
+	if threshold := getEnvFloat(log, "ADMISSION_LOW_PRIORITY_THRESHOLD", 0.8); threshold > 0 && db != nil {
+		serviceOpts = append(serviceOpts, service.WithAdmission(admission.NewController(db.Stats, map[admission.Priority]float64{
+			admission.PriorityLow: threshold,
+		})))
+	}
+
+	if limit := getEnvInt(log, "READ_CONCURRENCY_LIMIT", 0); limit > 0 {
+		wait := getEnvDuration(log, "READ_QUEUE_TIMEOUT", 500*time.Millisecond)
+		serviceOpts = append(serviceOpts, service.WithReadLimiter(admission.NewLimiter(limit, wait)))
+		log.Info("read concurrency limit enabled", zap.Int("limit", limit), zap.Duration("queue_timeout", wait))
+	}
+
+	orderService := service.NewOrderService(repo.NewInstrumentedOrderRepository(orderRepo), servicePublisher, serviceOpts...)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	consumer := events.NewConsumer(redisClient, orderService, log)
-	go consumer.Subscribe(ctx, service.OrderCreatedChannel)
+	consumer := events.NewConsumer(redisClient, orderService, log, events.WithRetryPolicy(events.RetryPolicy{
+		MaxRetries:    getEnvInt(log, "CONSUMER_MAX_RETRIES", events.DefaultRetryPolicy.MaxRetries),
+		BaseDelay:     getEnvDuration(log, "CONSUMER_RETRY_BASE_DELAY", events.DefaultRetryPolicy.BaseDelay),
+		MaxDelay:      getEnvDuration(log, "CONSUMER_RETRY_MAX_DELAY", events.DefaultRetryPolicy.MaxDelay),
+		PollInterval:  events.DefaultRetryPolicy.PollInterval,
+		InlineRetries: getEnvInt(log, "CONSUMER_INLINE_RETRIES", events.DefaultRetryPolicy.InlineRetries),
+		InlineDelay:   getEnvDuration(log, "CONSUMER_INLINE_RETRY_DELAY", events.DefaultRetryPolicy.InlineDelay),
+	}), events.WithClaimPolicy(events.ClaimPolicy{
+		MinIdle:  getEnvDuration(log, "CONSUMER_CLAIM_MIN_IDLE", events.DefaultClaimPolicy.MinIdle),
+		Interval: getEnvDuration(log, "CONSUMER_CLAIM_INTERVAL", events.DefaultClaimPolicy.Interval),
+	}), events.WithAckBatch(
+		getEnvInt(log, "CONSUMER_ACK_BATCH_SIZE", 1),
+		getEnvDuration(log, "CONSUMER_ACK_FLUSH_INTERVAL", 100*time.Millisecond),
+	), events.WithPrefetch(getEnvInt(log, "CONSUMER_PREFETCH", events.DefaultPrefetch)),
+		events.WithMaxInFlight(getEnvInt(log, "CONSUMER_MAX_IN_FLIGHT", 0)),
+		events.WithDedup(getEnvDuration(log, "CONSUMER_DEDUP_TTL", events.DefaultDedupTTL)),
+		events.WithStreamMaxLen(streamMaxLen),
+		events.WithStaleOrderErrors(service.ErrOrderNotFound, service.ErrInvalidTransition))
+	consumer.ConfirmationDelay = getEnvDuration(log, "CONSUMER_CONFIRMATION_DELAY", 0)
+	restartDelay := getEnvDuration(log, "CONSUMER_RESTART_DELAY", events.DefaultRestartDelay)
+	maxRestartDelay := getEnvDuration(log, "CONSUMER_MAX_RESTART_DELAY", events.DefaultMaxRestartDelay)
+	var broadcaster *events.Broadcaster
+	if kafkaClient != nil {
+		group := os.Getenv("KAFKA_GROUP")
+		if group == "" {
+			group = events.ConsumerGroup
+		}
+		var kafkaOpts []events.KafkaConsumerOption
+		if topic := os.Getenv("KAFKA_DLQ_TOPIC"); topic != "" {
+			kafkaOpts = append(kafkaOpts, events.WithKafkaDeadLetterTopic(topic))
+		}
+		kafkaConsumer := events.NewKafkaConsumer(kafkaClient, kafkaTopic, group, consumer, log, kafkaOpts...)
+		go events.Supervise(ctx, log, "kafka", restartDelay, maxRestartDelay, kafkaConsumer.Run)
+	} else {
+		go events.Supervise(ctx, log, "subscribe", restartDelay, maxRestartDelay, func(ctx context.Context) {
+			consumer.Subscribe(ctx, service.OrderCreatedChannel)
+		})
+		go events.Supervise(ctx, log, "retry", restartDelay, maxRestartDelay, consumer.RunRetryLoop)
+		go events.Supervise(ctx, log, "claim", restartDelay, maxRestartDelay, consumer.RunClaimLoop)
+		go consumer.RunStatsLoop(ctx, getEnvDuration(log, "CONSUMER_STATS_INTERVAL", events.DefaultStatsInterval))
+
+		subscriberBuffer := getEnvInt(log, "LIVE_SUBSCRIBER_BUFFER", events.DefaultSubscriberBuffer)
+		if subscriberBuffer == 0 {
+			log.Fatal("LIVE_SUBSCRIBER_BUFFER must be at least 1")
+		}
+		broadcaster = events.NewBroadcaster(redisClient, log, subscriberBuffer)
+		go broadcaster.Run(ctx)
+	}
+	go orderService.RunAutoTransitions(logger.WithContext(ctx, log), getEnvDuration(log, "ORDER_AUTO_TRANSITION_INTERVAL", time.Minute))
+	if relay != nil {
+		go relay.Run(ctx, getEnvDuration(log, "OUTBOX_RELAY_INTERVAL", time.Second))
+	}
+
+	bucket := os.Getenv("EXPORT_S3_BUCKET")
+	var exporterDone chan struct{}
+	if bucket != "" && db == nil {
+		log.Fatal("EXPORT_S3_BUCKET requires Postgres, it is not supported with the in-memory or SQLite repository")
+	}
+	if bucket != "" {
+		awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			log.Fatal("failed to load AWS config", zap.Error(err))
+		}
+
+		prefix := os.Getenv("EXPORT_S3_PREFIX")
+		if prefix == "" {
+			prefix = "orders"
+		}
+
+		exporter := export.NewExporter(
+			orderRepo,
+			export.NewS3ObjectStore(s3.NewFromConfig(awsCfg), bucket),
+			repo.NewPostgresExportCursorRepository(db),
+			getEnvDuration(log, "EXPORT_INTERVAL", time.Hour),
+			prefix,
+			log,
+			export.WithOverlap(getEnvDuration(log, "EXPORT_OVERLAP", export.DefaultOverlap)),
+		)
+		exporterDone = make(chan struct{})
+		go func() {
+			defer close(exporterDone)
+			exporter.Run(ctx)
+		}()
+	}
+
 	h := handler.NewHandler(orderService)
 
 	gin.SetMode(gin.ReleaseMode)
-	r := gin.New()
+	routing := handler.LenientRouting
+	if getEnvBool(log, "HTTP_STRICT_ROUTING", false) {
+		routing = handler.StrictRouting
+	}
+	r := handler.NewRouter(routing)
+	var trustedProxies []string
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		trustedProxies = strings.Split(v, ",")
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal("invalid TRUSTED_PROXIES", zap.Error(err))
+	}
 	r.Use(gin.Recovery())
-	r.Use(logger.Middleware(log))
-
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
-	})
-
-	r.GET("/metrics/db", func(c *gin.Context) {
-		stats := db.Stats()
-		c.JSON(http.StatusOK, gin.H{
-			"max_open_connections": stats.MaxOpenConnections,
-			"open_connections":     stats.OpenConnections,
-			"in_use":               stats.InUse,
-			"idle":                 stats.Idle,
-			"wait_count":           stats.WaitCount,
-			"wait_duration":        stats.WaitDuration.String(),
-			"max_idle_closed":      stats.MaxIdleClosed,
-			"max_idle_time_closed": stats.MaxIdleTimeClosed,
-			"max_lifetime_closed":  stats.MaxLifetimeClosed,
+	logRequestBody := getEnvBool(log, "LOG_REQUEST_BODY", false)
+	debugBodies := getEnvBool(log, "LOG_BODIES", false)
+	bodyLimit := getEnvInt(log, "LOG_REQUEST_BODY_LIMIT", logger.DefaultBodyLogLimit)
+	redacted := logger.DefaultRedactedFields
+	if v := os.Getenv("LOG_REDACT_FIELDS"); v != "" {
+		redacted = splitList(v)
+	}
+	logOpts := []logger.MiddlewareOption{logger.WithRedactedFields(redacted...)}
+	if logRequestBody {
+		logOpts = append(logOpts, logger.WithRequestBody())
+	}
+	if debugBodies {
+		logOpts = append(logOpts, logger.WithDebugBodies(bodyLimit))
+		log.Warn("logging request and response bodies at debug level", zap.Int("limit", bodyLimit))
+	}
+	r.Use(logger.Middleware(log, logOpts...))
+	// The size limit must wrap the body before BufferBody reads it.
+	r.Use(handler.MaxBodyBytes(int64(getEnvInt(log, "MAX_BODY_BYTES", handler.DefaultMaxBodyBytes))))
+	if logRequestBody || debugBodies {
+		r.Use(logger.BufferBody(bodyLimit))
+	}
+	r.Use(metrics.GinMiddleware())
+	// CORS runs before rate limiting and authentication so that preflights,
+	// which carry no credentials, are answered and rejections stay readable.
+	if origins := os.Getenv("CORS_ALLOWED_ORIGINS"); origins != "" {
+		cors := handler.DefaultCORSConfig()
+		cors.AllowedOrigins = splitList(origins)
+		if v := os.Getenv("CORS_ALLOWED_METHODS"); v != "" {
+			cors.AllowedMethods = splitList(v)
+		}
+		if v := os.Getenv("CORS_ALLOWED_HEADERS"); v != "" {
+			cors.AllowedHeaders = splitList(v)
+		}
+		cors.AllowCredentials = getEnvBool(log, "CORS_ALLOW_CREDENTIALS", false)
+		cors.MaxAge = getEnvDuration(log, "CORS_MAX_AGE", cors.MaxAge)
+		r.Use(handler.CORS(cors))
+		log.Info("CORS enabled", zap.Strings("origins", cors.AllowedOrigins), zap.Bool("credentials", cors.AllowCredentials))
+	}
+	if limit := getEnvInt(log, "IP_RATE_LIMIT", 0); limit > 0 {
+		burst := getEnvInt(log, "IP_RATE_LIMIT_BURST", limit)
+		r.Use(handler.RateLimitByIP(ratelimit.NewRedisTokenBucket(redisClient, "ratelimit:ip:", limit, time.Second, ratelimit.WithBurst(burst))))
+		log.Info("per-IP rate limit enabled", zap.Int("rate", limit), zap.Int("burst", burst))
+	}
+	authenticator := newAuthenticator(log)
+	if authenticator != nil {
+		// Probes and scrapes must work without a token. The projection
+		// admin routes check ADMIN_TOKEN instead.
+		r.Use(handler.Authenticate(authenticator, handler.PublicPaths{
+			Exact:    []string{"/health", "/livez", "/readyz", "/metrics"},
+			Prefixes: []string{"/admin/projection"},
+		}))
+	}
+
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	r.GET("/admin/log-level", handler.RequireScope(auth.ScopeRead), gin.WrapH(logLevel))
+	r.PUT("/admin/log-level", handler.RequireScope(auth.ScopeWrite), gin.WrapH(logLevel))
+
+	if db != nil {
+		r.GET("/metrics/db", func(c *gin.Context) {
+			stats := db.Stats()
+			c.JSON(http.StatusOK, gin.H{
+				"max_open_connections": stats.MaxOpenConnections,
+				"open_connections":     stats.OpenConnections,
+				"in_use":               stats.InUse,
+				"idle":                 stats.Idle,
+				"wait_count":           stats.WaitCount,
+				"wait_duration":        stats.WaitDuration.String(),
+				"max_idle_closed":      stats.MaxIdleClosed,
+				"max_idle_time_closed": stats.MaxIdleTimeClosed,
+				"max_lifetime_closed":  stats.MaxLifetimeClosed,
+			})
 		})
-	})
+	}
+
+	checks := map[string]health.Checker{"redis": health.NewRedisChecker(redisClient)}
+	healthOpts := []health.Option{
+		health.WithVersion(version),
+		health.WithCheckTimeout(getEnvDuration(log, "READINESS_TIMEOUT", health.DefaultCheckTimeout)),
+	}
+	if db != nil {
+		checks["postgres"] = health.NewDBChecker(db, os.Getenv("READINESS_QUERY"))
+		healthOpts = append(healthOpts, health.WithSchemaVersion(func(ctx context.Context) (string, error) {
+			return repo.LatestMigration(ctx, db)
+		}))
+	}
+	if sqliteDB != nil {
+		checks["sqlite"] = health.NewDBChecker(sqliteDB, "")
+		healthOpts = append(healthOpts, health.WithSchemaVersion(func(ctx context.Context) (string, error) {
+			return repo.LatestMigration(ctx, sqliteDB)
+		}))
+	}
+	health.NewHandler(checks, healthOpts...).RegisterRoutes(r)
 
 	h.RegisterRoutes(r)
+	// These read the Redis stream, which is empty when events go to Kafka.
+	if kafkaClient == nil {
+		handler.NewStreamHandler(consumer).RegisterRoutes(r)
+		handler.NewDeadLetterHandler(consumer).RegisterRoutes(r)
+		handler.NewConsumerStatsHandler(consumer).RegisterRoutes(r)
+		handler.NewLiveHandler(broadcaster).RegisterRoutes(r)
+	}
+	if projectionRepo != nil {
+		if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+			rebuilder := projection.NewRebuilder(orderRepo, projectionRepo, getEnvInt(log, "PROJECTION_REBUILD_BATCH_SIZE", projection.DefaultBatchSize), log)
+			handler.NewProjectionHandler(rebuilder, token).RegisterRoutes(r)
+		} else {
+			log.Warn("ADMIN_TOKEN not set, projection rebuild endpoint disabled")
+		}
+	}
 
+	// Streaming endpoints lift the read and write timeouts for themselves.
 	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: r,
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadHeaderTimeout: getEnvDuration(log, "HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       getEnvDuration(log, "HTTP_READ_TIMEOUT", 10*time.Second),
+		WriteTimeout:      getEnvDuration(log, "HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       getEnvDuration(log, "HTTP_IDLE_TIMEOUT", 120*time.Second),
 	}
+	srv.RegisterOnShutdown(h.StopStreams)
 
 	go func() {
 		log.Info("starting HTTP server", zap.String("port", port))
@@ -157,8 +534,39 @@ func main() {
 		}
 	}()
 
-	grpcSrv := grpc.NewServer()
-	pb.RegisterOrderServiceServer(grpcSrv, grpcserver.NewServer(orderService, log))
+	streamLimiter := grpcserver.NewStreamLimiter(getEnvInt(log, "GRPC_MAX_STREAMS_PER_CLIENT", 16))
+	// Logging runs first so it sees the final status, and recovery runs inside
+	// metrics so panics are counted as Internal.
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		grpcserver.LoggingUnaryInterceptor(log),
+		metrics.UnaryServerInterceptor(),
+		grpcserver.RecoveryUnaryInterceptor(log),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		grpcserver.LoggingStreamInterceptor(log),
+		metrics.StreamServerInterceptor(),
+		grpcserver.RecoveryStreamInterceptor(log),
+	}
+	if authenticator != nil {
+		grpcAuth := grpcserver.NewAuthenticator(authenticator, log)
+		unaryInterceptors = append(unaryInterceptors, grpcAuth.UnaryInterceptor())
+		streamInterceptors = append(streamInterceptors, grpcAuth.StreamInterceptor())
+	}
+	streamInterceptors = append(streamInterceptors, streamLimiter.StreamInterceptor())
+	grpcSrv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
+	pb.RegisterOrderServiceServer(grpcSrv, grpcserver.NewServer(orderService, log,
+		grpcserver.WithMaxListResponseBytes(getEnvInt(log, "GRPC_MAX_LIST_RESPONSE_BYTES", grpcserver.DefaultMaxListResponseBytes)),
+	))
+	// Reflection lets grpcurl and similar tools discover OrderService without
+	// the generated stubs. It exposes the full API schema, so keep it off in
+	// production.
+	if getEnvBool(log, "ENABLE_GRPC_REFLECTION", false) {
+		reflection.Register(grpcSrv)
+		log.Info("gRPC reflection enabled")
+	}
 
 	grpcLis, err := net.Listen("tcp", ":"+grpcPort)
 	if err != nil {
@@ -179,14 +587,65 @@ func main() {
 
 	cancel()
 
-	grpcSrv.GracefulStop()
-	log.Info("gRPC server stopped")
-
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), getEnvDuration(log, "SHUTDOWN_TIMEOUT", 30*time.Second))
 	defer shutdownCancel()
 
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Fatal("HTTP server forced to shutdown", zap.Error(err))
+	var servers sync.WaitGroup
+	servers.Add(2)
+	go func() {
+		defer servers.Done()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Error("HTTP server did not drain before shutdown timeout",
+				zap.Int64("in_flight", metrics.HTTPInFlight()), zap.Error(err))
+			srv.Close()
+			return
+		}
+		log.Info("HTTP server stopped")
+	}()
+	go func() {
+		defer servers.Done()
+		stopped := make(chan struct{})
+		go func() {
+			grpcSrv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+			log.Info("gRPC server stopped")
+		case <-shutdownCtx.Done():
+			log.Error("gRPC server did not drain before shutdown timeout",
+				zap.Int64("in_flight", metrics.GRPCInFlight()))
+			grpcSrv.Stop()
+		}
+	}()
+	servers.Wait()
+
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), getEnvDuration(log, "CONSUMER_DRAIN_TIMEOUT", 10*time.Second))
+	defer drainCancel()
+	if err := consumer.Wait(drainCtx); err != nil {
+		log.Error("consumer did not finish in-flight messages before shutdown", zap.Error(err))
+	} else {
+		log.Info("consumer stopped")
+	}
+
+	if exporterDone != nil {
+		select {
+		case <-exporterDone:
+		case <-drainCtx.Done():
+			log.Error("order exporter did not stop before shutdown")
+		}
+	}
+
+	if batchPublisher != nil {
+		if err := batchPublisher.Close(drainCtx); err != nil {
+			log.Error("event batch was not flushed before shutdown", zap.Error(err))
+		}
+	}
+
+	if kafkaClient != nil {
+		if err := kafkaClient.Close(); err != nil {
+			log.Error("error closing kafka connections", zap.Error(err))
+		}
 	}
 
 	if err := redisClient.Close(); err != nil {
@@ -195,3 +654,183 @@ func main() {
 
 	log.Info("servers exited")
 }
+
+// orderStore is the order repository surface main wires into the service,
+// the auto-transition sweeper, the exporter and the projection rebuilder.
+type orderStore interface {
+	repo.OrderRepository
+	repo.BatchOrderRepository
+	repo.StatsRepository
+	repo.AutoTransitionRepository
+	repo.OrderStreamer
+	repo.ProductSearchRepository
+	repo.OrderFinder
+}
+
+// newAuthenticator accepts bearer JWTs when newVerifier is configured and
+// API keys when API_KEYS is set. It returns nil, leaving the API open, when
+// neither is.
+func newAuthenticator(log *zap.Logger) *auth.Authenticator {
+	verifier := newVerifier(log)
+	var keys *auth.APIKeys
+	if spec := os.Getenv("API_KEYS"); spec != "" {
+		var err error
+		if keys, err = auth.ParseAPIKeys(spec); err != nil {
+			log.Fatal("invalid API_KEYS", zap.Error(err))
+		}
+		log.Info("API key authentication enabled")
+	}
+	if verifier == nil && keys == nil {
+		log.Warn("JWT_SIGNING_KEY, JWT_JWKS_URL and API_KEYS not set, authentication disabled")
+		return nil
+	}
+	return auth.NewAuthenticator(verifier, keys)
+}
+
+// newVerifier configures bearer JWT authentication from JWT_SIGNING_KEY (an
+// HMAC secret) and/or JWT_JWKS_URL, or returns nil when neither is set.
+func newVerifier(log *zap.Logger) *auth.Verifier {
+	var opts []auth.Option
+	if key := os.Getenv("JWT_SIGNING_KEY"); key != "" {
+		opts = append(opts, auth.WithSigningKey([]byte(key)))
+	}
+	if url := os.Getenv("JWT_JWKS_URL"); url != "" {
+		opts = append(opts, auth.WithJWKS(auth.NewJWKS(url, nil)))
+	}
+	if len(opts) == 0 {
+		return nil
+	}
+	opts = append(opts,
+		auth.WithIssuer(os.Getenv("JWT_ISSUER")),
+		auth.WithAudience(os.Getenv("JWT_AUDIENCE")),
+		auth.WithLeeway(getEnvDuration(log, "JWT_LEEWAY", auth.DefaultLeeway)),
+	)
+	verifier, err := auth.NewVerifier(opts...)
+	if err != nil {
+		log.Fatal("invalid JWT configuration", zap.Error(err))
+	}
+	log.Info("JWT authentication enabled")
+	return verifier
+}
+
+// openDB connects to Postgres, configures the pool and applies migrations.
+func openDB(log *zap.Logger, dbURL string) *sql.DB {
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatal("failed to open database", zap.Error(err))
+	}
+
+	// An unlimited pool (0) would defeat admission control, which sheds load
+	// by the share of MaxOpenConns in use, and database/sql silently lowers
+	// an idle limit above the open limit, so both are rejected.
+	maxOpenConns := getEnvInt(log, "DB_MAX_OPEN_CONNS", 25)
+	if maxOpenConns == 0 {
+		log.Fatal("DB_MAX_OPEN_CONNS must be at least 1")
+	}
+	maxIdleConns := getEnvInt(log, "DB_MAX_IDLE_CONNS", 5)
+	if maxIdleConns > maxOpenConns {
+		log.Fatal("DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS",
+			zap.Int("max_idle_conns", maxIdleConns),
+			zap.Int("max_open_conns", maxOpenConns),
+		)
+	}
+	connMaxLifetime := getEnvDuration(log, "DB_CONN_MAX_LIFETIME", 5*time.Minute)
+	connMaxIdleTime := getEnvDuration(log, "DB_CONN_MAX_IDLE_TIME", 10*time.Minute)
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(connMaxLifetime)
+	db.SetConnMaxIdleTime(connMaxIdleTime)
+
+	log.Info("database pool configured",
+		zap.Int("max_open_conns", maxOpenConns),
+		zap.Int("max_idle_conns", maxIdleConns),
+		zap.Duration("conn_max_lifetime", connMaxLifetime),
+		zap.Duration("conn_max_idle_time", connMaxIdleTime),
+	)
+
+	if err := db.Ping(); err != nil {
+		log.Fatal("failed to ping database", zap.Error(err))
+	}
+	log.Info("connected to database")
+
+	if err := repo.RunMigrations(db, "migrations"); err != nil {
+		log.Fatal("failed to run migrations", zap.Error(err))
+	}
+	log.Info("migrations applied")
+	return db
+}
+
+// openSQLite opens the SQLite database at path and applies the migrations in
+// migrations/sqlite.
+func openSQLite(log *zap.Logger, path string) *sql.DB {
+	db, err := repo.OpenSQLite(path)
+	if err != nil {
+		log.Fatal("failed to open SQLite database", zap.String("path", path), zap.Error(err))
+	}
+	log.Warn("using SQLite, Postgres-only features such as the outbox, audit log and read model are off", zap.String("path", path))
+
+	if err := repo.RunMigrations(db, "migrations/sqlite"); err != nil {
+		log.Fatal("failed to run migrations", zap.Error(err))
+	}
+	log.Info("migrations applied")
+	return db
+}
+
+// splitList splits a comma-separated list, dropping blank entries.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvInt(log *zap.Logger, key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Fatal("invalid integer", zap.String("env", key), zap.String("value", v))
+	}
+	return n
+}
+
+func getEnvBool(log *zap.Logger, key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatal("invalid boolean", zap.String("env", key), zap.String("value", v))
+	}
+	return b
+}
+
+func getEnvFloat(log *zap.Logger, key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		log.Fatal("invalid number", zap.String("env", key), zap.String("value", v))
+	}
+	return f
+}
+
+func getEnvDuration(log *zap.Logger, key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Fatal("invalid duration", zap.String("env", key), zap.String("value", v))
+	}
+	return d
+}
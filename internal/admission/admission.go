@@ -0,0 +1,44 @@
+package admission
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var ErrOverloaded = errors.New("server is overloaded, retry later")
+
+type Priority int
+
+const (
+	// PriorityLow covers expensive reads such as list, search and export
+	// that are shed first when the connection pool saturates.
+	PriorityLow Priority = iota
+	PriorityHigh
+)
+
+// Controller admits work based on database pool utilization. Each priority
+// has a threshold in (0, 1]; work is shed once InUse/MaxOpenConnections
+// reaches it. Priorities without a threshold are always admitted.
+type Controller struct {
+	stats      func() sql.DBStats
+	thresholds map[Priority]float64
+}
+
+func NewController(stats func() sql.DBStats, thresholds map[Priority]float64) *Controller {
+	return &Controller{stats: stats, thresholds: thresholds}
+}
+
+func (c *Controller) Admit(p Priority) error {
+	threshold, ok := c.thresholds[p]
+	if !ok || threshold <= 0 {
+		return nil
+	}
+	stats := c.stats()
+	if stats.MaxOpenConnections <= 0 {
+		return nil
+	}
+	if float64(stats.InUse)/float64(stats.MaxOpenConnections) >= threshold {
+		return ErrOverloaded
+	}
+	return nil
+}
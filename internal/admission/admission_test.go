@@ -0,0 +1,83 @@
+package admission
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestControllerShedsLowPriorityFirst(t *testing.T) {
+	stats := sql.DBStats{MaxOpenConnections: 10}
+	ctrl := NewController(func() sql.DBStats { return stats }, map[Priority]float64{PriorityLow: 0.8})
+
+	tests := []struct {
+		inUse   int
+		low     error
+		high    error
+		comment string
+	}{
+		{5, nil, nil, "below both thresholds"},
+		{8, ErrOverloaded, nil, "low priority shed at its threshold"},
+		{10, ErrOverloaded, nil, "pool fully saturated"},
+	}
+
+	for _, tt := range tests {
+		stats.InUse = tt.inUse
+		if err := ctrl.Admit(PriorityLow); !errors.Is(err, tt.low) {
+			t.Errorf("%s: expected low priority %v, got %v", tt.comment, tt.low, err)
+		}
+		if err := ctrl.Admit(PriorityHigh); !errors.Is(err, tt.high) {
+			t.Errorf("%s: expected high priority %v, got %v", tt.comment, tt.high, err)
+		}
+	}
+}
+
+func TestControllerUnlimitedPool(t *testing.T) {
+	ctrl := NewController(func() sql.DBStats { return sql.DBStats{InUse: 100} }, map[Priority]float64{PriorityLow: 0.5})
+	if err := ctrl.Admit(PriorityLow); err != nil {
+		t.Errorf("expected unlimited pool to admit, got %v", err)
+	}
+}
+
+func TestLimiterQueuesThenSheds(t *testing.T) {
+	l := NewLimiter(1, 50*time.Millisecond)
+
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := l.Acquire(context.Background()); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("expected ErrOverloaded after waiting, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release()
+	}()
+	queued, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("expected queued call to get the released slot, got %v", err)
+	}
+	queued()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	held, _ := l.Acquire(context.Background())
+	defer held()
+	if _, err := l.Acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestLimiterWithoutWaitShedsImmediately(t *testing.T) {
+	l := NewLimiter(1, 0)
+	if _, err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := l.Acquire(context.Background()); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("expected ErrOverloaded, got %v", err)
+	}
+}
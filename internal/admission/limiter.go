@@ -0,0 +1,50 @@
+package admission
+
+import (
+	"context"
+	"time"
+)
+
+// Limiter caps how many expensive reads (list, search, export) run at once,
+// independently of pool utilization, so a burst of them cannot take every
+// connection and starve gets and writes. Calls beyond the limit queue for up
+// to maxWait and are then shed with ErrOverloaded.
+type Limiter struct {
+	slots   chan struct{}
+	maxWait time.Duration
+}
+
+// NewLimiter allows limit concurrent calls. With a zero maxWait excess calls
+// are shed immediately instead of queueing.
+func NewLimiter(limit int, maxWait time.Duration) *Limiter {
+	return &Limiter{slots: make(chan struct{}, limit), maxWait: maxWait}
+}
+
+// Acquire takes a slot, returning a function that releases it. It fails with
+// ErrOverloaded if no slot frees up within maxWait, or with ctx.Err() if ctx
+// is done first.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	select {
+	case l.slots <- struct{}{}:
+		return l.release, nil
+	default:
+	}
+	if l.maxWait <= 0 {
+		return nil, ErrOverloaded
+	}
+
+	timer := time.NewTimer(l.maxWait)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return l.release, nil
+	case <-timer.C:
+		return nil, ErrOverloaded
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *Limiter) release() {
+	<-l.slots
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Scopes granted to callers.
+const (
+	ScopeRead  = "orders:read"
+	ScopeWrite = "orders:write"
+)
+
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+type apiKey struct {
+	name   string
+	hash   [sha256.Size]byte
+	scopes []string
+}
+
+// APIKeys authenticates service-to-service callers by static key. Only the
+// SHA-256 of each key is held.
+type APIKeys struct {
+	keys []apiKey
+}
+
+// ParseAPIKeys parses comma-separated "name:sha256hex:scopes" entries, where
+// scopes is a "|"-separated list such as "orders:read|orders:write". Every
+// key needs at least one scope.
+func ParseAPIKeys(spec string) (*APIKeys, error) {
+	var keys []apiKey
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("API key %q: expected name:sha256hex:scopes", entry)
+		}
+		name := parts[0]
+		raw, err := hex.DecodeString(parts[1])
+		if err != nil || len(raw) != sha256.Size {
+			return nil, fmt.Errorf("API key %q: hash must be 64 hex characters", name)
+		}
+		k := apiKey{name: name}
+		copy(k.hash[:], raw)
+		for _, scope := range strings.Split(parts[2], "|") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				k.scopes = append(k.scopes, scope)
+			}
+		}
+		if len(k.scopes) == 0 {
+			return nil, fmt.Errorf("API key %q: no scopes", name)
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no API keys configured")
+	}
+	return &APIKeys{keys: keys}, nil
+}
+
+// Verify returns the identity of the caller holding key. The key's hash is
+// compared against every configured hash in constant time, so timing does not
+// reveal which, if any, matched.
+func (a *APIKeys) Verify(key string) (*Identity, error) {
+	hash := sha256.Sum256([]byte(key))
+	var match *apiKey
+	for i := range a.keys {
+		if subtle.ConstantTimeCompare(hash[:], a.keys[i].hash[:]) == 1 {
+			match = &a.keys[i]
+		}
+	}
+	if match == nil {
+		return nil, ErrInvalidAPIKey
+	}
+	return &Identity{Subject: "apikey:" + match.name, Scopes: match.scopes}, nil
+}
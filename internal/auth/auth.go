@@ -0,0 +1,238 @@
+// Package auth verifies the bearer JWTs and API keys that authenticate API
+// callers, carries the caller's identity through request contexts and checks
+// the scopes it was granted.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultLeeway is the clock skew tolerated when checking exp and nbf.
+const DefaultLeeway = 30 * time.Second
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+	ErrForbidden    = errors.New("forbidden")
+)
+
+var (
+	hmacMethods = []string{"HS256", "HS384", "HS512"}
+	jwksMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+)
+
+// scopeClaims are the JWT claims scopes are read from: the space-separated
+// OAuth2 "scope", Azure AD style "scp" and role lists in "roles".
+var scopeClaims = []string{"scope", "scp", "roles"}
+
+// Identity is the authenticated caller and the scopes it was granted.
+type Identity struct {
+	Subject string
+	Claims  jwt.MapClaims
+	Scopes  []string
+}
+
+// HasScope reports whether the identity was granted scope.
+func (id *Identity) HasScope(scope string) bool {
+	for _, s := range id.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type identityKey struct{}
+
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFromContext returns the identity stored by WithIdentity, if any.
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
+
+// Authorize checks that the identity in ctx was granted scope, returning an
+// error wrapping ErrForbidden if not. A context without an identity, as when
+// authentication is off or the caller is on a public path, is allowed.
+func Authorize(ctx context.Context, scope string) error {
+	id, ok := IdentityFromContext(ctx)
+	if !ok || id.HasScope(scope) {
+		return nil
+	}
+	return fmt.Errorf("%w: requires scope %s", ErrForbidden, scope)
+}
+
+// BearerToken extracts the token from an Authorization header value.
+func BearerToken(header string) (string, error) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", ErrMissingToken
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// Verifier checks JWTs signed with a shared HMAC key, with keys published
+// at a JWKS URL, or both. Tokens must carry exp and sub.
+type Verifier struct {
+	hmacKey  []byte
+	jwks     *JWKS
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+
+	parser *jwt.Parser
+}
+
+type Option func(*Verifier)
+
+// WithSigningKey accepts tokens signed with key using HS256, HS384 or HS512.
+func WithSigningKey(key []byte) Option {
+	return func(v *Verifier) {
+		v.hmacKey = key
+	}
+}
+
+// WithJWKS accepts RSA and ECDSA signed tokens whose kid names a key in jwks.
+func WithJWKS(jwks *JWKS) Option {
+	return func(v *Verifier) {
+		v.jwks = jwks
+	}
+}
+
+// WithIssuer requires the iss claim to equal issuer.
+func WithIssuer(issuer string) Option {
+	return func(v *Verifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithAudience requires the aud claim to contain audience.
+func WithAudience(audience string) Option {
+	return func(v *Verifier) {
+		v.audience = audience
+	}
+}
+
+func WithLeeway(leeway time.Duration) Option {
+	return func(v *Verifier) {
+		v.leeway = leeway
+	}
+}
+
+// NewVerifier returns a Verifier for the configured keys. At least one of
+// WithSigningKey and WithJWKS is required.
+func NewVerifier(opts ...Option) (*Verifier, error) {
+	v := &Verifier{leeway: DefaultLeeway, now: time.Now}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	var methods []string
+	if len(v.hmacKey) > 0 {
+		methods = append(methods, hmacMethods...)
+	}
+	if v.jwks != nil {
+		methods = append(methods, jwksMethods...)
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("auth: no signing key or JWKS configured")
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(v.leeway),
+		jwt.WithTimeFunc(func() time.Time { return v.now() }),
+	}
+	if v.issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(v.audience))
+	}
+	v.parser = jwt.NewParser(parserOpts...)
+	return v, nil
+}
+
+// Verify checks token's signature and claims and returns the identity it
+// asserts. Failures wrap ErrInvalidToken.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+			return v.hmacKey, nil
+		}
+		kid, _ := t.Header["kid"].(string)
+		return v.jwks.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	sub, err := claims.GetSubject()
+	if err != nil || sub == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+	return &Identity{Subject: sub, Claims: claims, Scopes: claimScopes(claims)}, nil
+}
+
+// claimScopes collects the scopes granted by claims. Each claim may be a
+// space-separated string or a list of strings.
+func claimScopes(claims jwt.MapClaims) []string {
+	var scopes []string
+	for _, name := range scopeClaims {
+		switch v := claims[name].(type) {
+		case string:
+			scopes = append(scopes, strings.Fields(v)...)
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := item.(string); ok && s != "" {
+					scopes = append(scopes, s)
+				}
+			}
+		}
+	}
+	return scopes
+}
+
+// Authenticator accepts an API key or a bearer JWT, whichever the caller
+// presents.
+type Authenticator struct {
+	tokens *Verifier
+	keys   *APIKeys
+}
+
+// NewAuthenticator returns an Authenticator that checks tokens with tokens
+// and API keys with keys. Either may be nil to refuse that credential type.
+func NewAuthenticator(tokens *Verifier, keys *APIKeys) *Authenticator {
+	return &Authenticator{tokens: tokens, keys: keys}
+}
+
+// Authenticate returns the caller's identity. A non-empty apiKey is checked
+// as an API key; otherwise authorization must be a bearer token header.
+func (a *Authenticator) Authenticate(ctx context.Context, authorization, apiKey string) (*Identity, error) {
+	if apiKey != "" {
+		if a.keys == nil {
+			return nil, ErrInvalidAPIKey
+		}
+		return a.keys.Verify(apiKey)
+	}
+	token, err := BearerToken(authorization)
+	if err != nil {
+		return nil, err
+	}
+	if a.tokens == nil {
+		return nil, fmt.Errorf("%w: bearer tokens are not accepted", ErrInvalidToken)
+	}
+	return a.tokens.Verify(ctx, token)
+}
@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testKey = []byte("test-signing-key")
+
+func signHMAC(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifierHMAC(t *testing.T) {
+	v, err := NewVerifier(WithSigningKey(testKey), WithIssuer("orders-idp"), WithAudience("orders-api"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	id, err := v.Verify(context.Background(), signHMAC(t, jwt.MapClaims{"sub": "alice", "iss": "orders-idp", "aud": "orders-api", "exp": exp}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id.Subject != "alice" {
+		t.Errorf("expected subject alice, got %q", id.Subject)
+	}
+	if id.HasScope(ScopeRead) || id.HasScope(ScopeWrite) {
+		t.Errorf("expected a token without scope claims to grant nothing, got %v", id.Scopes)
+	}
+
+	wrongKey, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "alice", "iss": "orders-idp", "aud": "orders-api", "exp": exp}).SignedString([]byte("other-key"))
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"sub": "alice", "iss": "orders-idp", "aud": "orders-api", "exp": exp}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	for name, token := range map[string]string{
+		"expired":        signHMAC(t, jwt.MapClaims{"sub": "alice", "iss": "orders-idp", "aud": "orders-api", "exp": time.Now().Add(-time.Hour).Unix()}),
+		"no exp":         signHMAC(t, jwt.MapClaims{"sub": "alice", "iss": "orders-idp", "aud": "orders-api"}),
+		"no sub":         signHMAC(t, jwt.MapClaims{"iss": "orders-idp", "aud": "orders-api", "exp": exp}),
+		"wrong issuer":   signHMAC(t, jwt.MapClaims{"sub": "alice", "iss": "someone-else", "aud": "orders-api", "exp": exp}),
+		"wrong audience": signHMAC(t, jwt.MapClaims{"sub": "alice", "iss": "orders-idp", "aud": "billing-api", "exp": exp}),
+		"wrong key":      wrongKey,
+		"alg none":       unsigned,
+		"garbage":        "not.a.token",
+	} {
+		if _, err := v.Verify(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+}
+
+func TestVerifierJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "key-1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer srv.Close()
+
+	v, err := NewVerifier(WithJWKS(NewJWKS(srv.URL, srv.Client())))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sign := func(kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "bob", "exp": time.Now().Add(time.Hour).Unix()})
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	for i := 0; i < 2; i++ {
+		id, err := v.Verify(context.Background(), sign("key-1"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if id.Subject != "bob" {
+			t.Errorf("expected subject bob, got %q", id.Subject)
+		}
+	}
+	// An unknown kid right after a fetch must not refetch the set.
+	if _, err := v.Verify(context.Background(), sign("key-2")); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for an unknown kid, got %v", err)
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("expected the key set to be fetched once, got %d", n)
+	}
+
+	// Without a signing key HMAC tokens are rejected outright.
+	if _, err := v.Verify(context.Background(), signHMAC(t, jwt.MapClaims{"sub": "bob", "exp": time.Now().Add(time.Hour).Unix()})); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for an HMAC token, got %v", err)
+	}
+}
+
+func TestVerifierScopes(t *testing.T) {
+	v, err := NewVerifier(WithSigningKey(testKey))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	for name, tt := range map[string]struct {
+		claims      jwt.MapClaims
+		read, write bool
+	}{
+		"scope string": {jwt.MapClaims{"scope": "openid orders:read"}, true, false},
+		"scp list":     {jwt.MapClaims{"scp": []string{"orders:write"}}, false, true},
+		"roles":        {jwt.MapClaims{"roles": []string{"orders:read", "orders:write"}}, true, true},
+		"other scopes": {jwt.MapClaims{"scope": "billing:read"}, false, false},
+	} {
+		tt.claims["sub"], tt.claims["exp"] = "alice", exp
+		id, err := v.Verify(context.Background(), signHMAC(t, tt.claims))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if id.HasScope(ScopeRead) != tt.read || id.HasScope(ScopeWrite) != tt.write {
+			t.Errorf("%s: expected read=%v write=%v, got scopes %v", name, tt.read, tt.write, id.Scopes)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	if err := Authorize(context.Background(), ScopeWrite); err != nil {
+		t.Errorf("expected a context without an identity to pass, got %v", err)
+	}
+	ctx := WithIdentity(context.Background(), &Identity{Subject: "alice", Scopes: []string{ScopeRead}})
+	if err := Authorize(ctx, ScopeRead); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := Authorize(ctx, ScopeWrite); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	for header, want := range map[string]string{
+		"Bearer abc":  "abc",
+		"bearer abc ": "abc",
+		"Basic abc":   "",
+		"Bearer ":     "",
+		"":            "",
+	} {
+		got, err := BearerToken(header)
+		if got != want || (want == "") != errors.Is(err, ErrMissingToken) {
+			t.Errorf("%q: expected %q, got %q (%v)", header, want, got, err)
+		}
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	hash := func(key string) string {
+		sum := sha256.Sum256([]byte(key))
+		return hex.EncodeToString(sum[:])
+	}
+	keys, err := ParseAPIKeys("export:" + hash("export-secret") + ":orders:read, billing:" + hash("billing-secret") + ":orders:read|orders:write")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a := NewAuthenticator(nil, keys)
+	ctx := context.Background()
+
+	id, err := a.Authenticate(ctx, "", "export-secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id.Subject != "apikey:export" || !id.HasScope(ScopeRead) || id.HasScope(ScopeWrite) {
+		t.Errorf("expected a read-only export identity, got %+v", id)
+	}
+	if id, err := a.Authenticate(ctx, "", "billing-secret"); err != nil || !id.HasScope(ScopeWrite) {
+		t.Errorf("expected a read-write billing identity, got %+v (%v)", id, err)
+	}
+	if _, err := a.Authenticate(ctx, "", "wrong-secret"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expected ErrInvalidAPIKey, got %v", err)
+	}
+	if _, err := a.Authenticate(ctx, "", ""); !errors.Is(err, ErrMissingToken) {
+		t.Errorf("expected ErrMissingToken without credentials, got %v", err)
+	}
+	if _, err := a.Authenticate(ctx, "Bearer some.jwt.token", ""); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected bearer tokens to be refused without a verifier, got %v", err)
+	}
+
+	for _, spec := range []string{"", "export:nothex:orders:read", "export:" + hash("x"), "export:" + hash("x") + ":", ":" + hash("x") + ":orders:read"} {
+		if _, err := ParseAPIKeys(spec); err == nil {
+			t.Errorf("%q: expected a parse error", spec)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultJWKSTimeout = 5 * time.Second
+	// DefaultJWKSMaxAge is how long fetched keys are used before the set is
+	// fetched again, so revoked keys stop being accepted.
+	DefaultJWKSMaxAge = time.Hour
+	// jwksMinRefresh limits refetches triggered by tokens with an unknown
+	// kid, so a stream of bogus tokens cannot hammer the JWKS endpoint.
+	jwksMinRefresh = time.Minute
+)
+
+var errUnknownKey = errors.New("unknown signing key")
+
+// JWKS fetches and caches the public keys published at a JSON Web Key Set
+// URL. The set is fetched on first use, when it is older than
+// DefaultJWKSMaxAge, and when a token names a key it does not contain.
+type JWKS struct {
+	url    string
+	client *http.Client
+	now    func() time.Time
+
+	mu      sync.Mutex
+	keys    map[string]interface{}
+	fetched time.Time
+}
+
+// NewJWKS returns a key set for url. A nil client uses one with
+// DefaultJWKSTimeout.
+func NewJWKS(url string, client *http.Client) *JWKS {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultJWKSTimeout}
+	}
+	return &JWKS{url: url, client: client, now: time.Now}
+}
+
+// Key returns the public key with ID kid.
+func (j *JWKS) Key(ctx context.Context, kid string) (interface{}, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.now()
+	key, ok := j.keys[kid]
+	stale := now.Sub(j.fetched) >= DefaultJWKSMaxAge
+	if ok && !stale {
+		return key, nil
+	}
+	if !stale && now.Sub(j.fetched) < jwksMinRefresh {
+		return nil, fmt.Errorf("%w %q", errUnknownKey, kid)
+	}
+
+	keys, err := j.fetch(ctx)
+	if err != nil {
+		// Keep using a known key while the endpoint is unreachable.
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+	j.keys, j.fetched = keys, now
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w %q", errUnknownKey, kid)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (j *JWKS) fetch(ctx context.Context) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode JWKS: %w", err)
+	}
+
+	// Keys that cannot be parsed, or are not for signatures, are skipped
+	// rather than failing the whole set.
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA exponent too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,96 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const ackFlushTimeout = 5 * time.Second
+
+// WithAckBatch acknowledges processed messages in batches of up to size IDs,
+// flushing whenever the batch fills or interval elapses, and once more when
+// Subscribe returns. Delivery stays at-least-once: if the process crashes
+// before a flush, the processed-but-unacked messages are delivered again.
+func WithAckBatch(size int, interval time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		if size > 1 {
+			c.acks = &ackBatcher{client: c.client, log: c.log, size: size, interval: interval}
+		}
+	}
+}
+
+type ackBatcher struct {
+	client   *redis.Client
+	log      *zap.Logger
+	size     int
+	interval time.Duration
+
+	mu  sync.Mutex
+	ids []string
+}
+
+func (b *ackBatcher) add(ctx context.Context, id string) {
+	b.mu.Lock()
+	b.ids = append(b.ids, id)
+	if len(b.ids) < b.size {
+		b.mu.Unlock()
+		return
+	}
+	ids := b.ids
+	b.ids = nil
+	b.mu.Unlock()
+
+	b.ack(ctx, ids)
+}
+
+func (b *ackBatcher) flush(ctx context.Context) {
+	b.mu.Lock()
+	ids := b.ids
+	b.ids = nil
+	b.mu.Unlock()
+
+	if len(ids) > 0 {
+		b.ack(ctx, ids)
+	}
+}
+
+func (b *ackBatcher) ack(ctx context.Context, ids []string) {
+	if err := b.client.XAck(ctx, StreamName, ConsumerGroup, ids...).Err(); err != nil {
+		b.log.Error("redis: failed to ack message batch", zap.Int("count", len(ids)), zap.Error(err))
+	}
+}
+
+// start flushes on the interval until the returned stop function is called;
+// stop performs a final flush so nothing processed is left unacked.
+func (b *ackBatcher) start() (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), ackFlushTimeout)
+				b.flush(ctx)
+				cancel()
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+		ctx, cancel := context.WithTimeout(context.Background(), ackFlushTimeout)
+		defer cancel()
+		b.flush(ctx)
+	}
+}
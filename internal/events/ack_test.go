@@ -0,0 +1,113 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+func pendingCount(t *testing.T, client *redis.Client) int64 {
+	t.Helper()
+	pending, err := client.XPending(context.Background(), StreamName, ConsumerGroup).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pending.Count
+}
+
+func publishN(t *testing.T, client *redis.Client, n int) {
+	t.Helper()
+	pub := NewRedisPublisher(client)
+	for i := 0; i < n; i++ {
+		if err := pub.Publish(context.Background(), "order.updated", map[string]int{"n": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAckBatchSizeTrigger(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	consumer := NewConsumer(client, nil, zap.NewNop(), WithAckBatch(3, time.Hour))
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	publishN(t, client, 4)
+	messages := readMessages(t, client)
+
+	for _, m := range messages[:2] {
+		consumer.processMessage(ctx, m)
+	}
+	if n := pendingCount(t, client); n != 4 {
+		t.Fatalf("expected acks to be buffered below the batch size, %d pending", n)
+	}
+
+	consumer.processMessage(ctx, messages[2])
+	if n := pendingCount(t, client); n != 1 {
+		t.Fatalf("expected a full batch to be flushed, %d pending", n)
+	}
+}
+
+func TestAckBatchTimeTrigger(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	consumer := NewConsumer(client, nil, zap.NewNop(), WithAckBatch(100, 20*time.Millisecond))
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	publishN(t, client, 2)
+
+	stop := consumer.acks.start()
+	defer stop()
+
+	for _, m := range readMessages(t, client) {
+		consumer.processMessage(ctx, m)
+	}
+	waitFor(t, func() bool { return pendingCount(t, client) == 0 })
+}
+
+func TestAckBatchFlushedOnShutdown(t *testing.T) {
+	client := newTestClient(t)
+	consumer := NewConsumer(client, nil, zap.NewNop(), WithAckBatch(100, time.Hour))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		consumer.Subscribe(ctx, "order.updated")
+		close(done)
+	}()
+
+	waitFor(t, func() bool { return client.Exists(context.Background(), StreamName).Val() == 1 })
+	publishN(t, client, 2)
+	waitFor(t, func() bool {
+		pos, err := consumer.Position(context.Background())
+		return err == nil && pos.Pending == 2
+	})
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumer did not shut down")
+	}
+
+	if n := pendingCount(t, client); n != 0 {
+		t.Errorf("expected buffered acks to be flushed on shutdown, %d pending", n)
+	}
+}
@@ -0,0 +1,199 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/orders-service/internal/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+const DefaultBatchInterval = 5 * time.Millisecond
+
+var ErrPublisherClosed = errors.New("event publisher closed")
+
+// BatchPublisher collects events from concurrent publishers and appends them
+// to the stream in a single pipelined round trip once size events are
+// waiting or interval has passed since the first of them. Publish calls wait
+// for their batch, so they still return the stream position or error of
+// their own event. It uses the serializer, retry policy, circuit breaker and
+// stream length limit of the RedisPublisher it wraps.
+type BatchPublisher struct {
+	pub      *RedisPublisher
+	size     int
+	interval time.Duration
+	entries  chan *batchEntry
+	stopped  chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+type batchEntry struct {
+	channel string
+	args    *redis.XAddArgs
+	id      string
+	err     error
+	done    chan struct{}
+}
+
+func NewBatchPublisher(pub *RedisPublisher, size int, interval time.Duration) *BatchPublisher {
+	b := &BatchPublisher{
+		pub:      pub,
+		size:     size,
+		interval: interval,
+		entries:  make(chan *batchEntry, size),
+		stopped:  make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+func (b *BatchPublisher) Publish(ctx context.Context, channel string, message interface{}) error {
+	_, err := b.PublishWithPosition(ctx, channel, message)
+	return err
+}
+
+func (b *BatchPublisher) PublishWithPosition(ctx context.Context, channel string, message interface{}) (string, error) {
+	data, err := b.pub.serializer.Marshal(message)
+	if err != nil {
+		metrics.EventsPublished.WithLabelValues(channel, metrics.OutcomeError).Inc()
+		return "", err
+	}
+	return b.PublishRaw(ctx, channel, b.pub.serializer.ContentType(), data)
+}
+
+// PublishRaw queues the event and waits for its batch to be flushed. If ctx
+// is cancelled first it returns ctx.Err(), but the event is still published.
+func (b *BatchPublisher) PublishRaw(ctx context.Context, channel, contentType string, payload []byte) (string, error) {
+	entry := &batchEntry{
+		channel: channel,
+		args:    b.pub.xaddArgs(newEnvelope(ctx, channel, contentType, payload, b.pub.now())),
+		done:    make(chan struct{}),
+	}
+
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return "", ErrPublisherClosed
+	}
+	b.entries <- entry
+	b.mu.RUnlock()
+
+	select {
+	case <-entry.done:
+		return entry.id, entry.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Close stops accepting events, flushes the ones already queued and waits
+// for them to be published or ctx to be done.
+func (b *BatchPublisher) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.entries)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *BatchPublisher) run() {
+	defer close(b.stopped)
+
+	timer := time.NewTimer(b.interval)
+	timer.Stop()
+
+	var batch []*batchEntry
+	for {
+		select {
+		case entry, ok := <-b.entries:
+			if !ok {
+				b.flush(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) == 1 {
+				timer.Reset(b.interval)
+			}
+			if len(batch) >= b.size {
+				timer.Stop()
+				b.flush(batch)
+				batch = nil
+			}
+		case <-timer.C:
+			b.flush(batch)
+			batch = nil
+		}
+	}
+}
+
+// flush publishes batch, retrying the entries that failed with the wrapped
+// publisher's retry policy, and wakes their callers.
+func (b *BatchPublisher) flush(batch []*batchEntry) {
+	if len(batch) == 0 {
+		return
+	}
+	metrics.EventPublishBatchSize.Observe(float64(len(batch)))
+
+	delay := b.pub.retryDelay
+	pending := b.exec(batch)
+	for i := 1; len(pending) > 0 && i < b.pub.attempts && !errors.Is(pending[0].err, ErrCircuitOpen); i++ {
+		time.Sleep(delay)
+		delay *= 2
+
+		for _, entry := range pending {
+			metrics.EventPublishRetries.WithLabelValues(entry.channel).Inc()
+		}
+		pending = b.exec(pending)
+	}
+
+	for _, entry := range batch {
+		metrics.EventsPublished.WithLabelValues(entry.channel, metrics.Outcome(entry.err)).Inc()
+		close(entry.done)
+	}
+}
+
+// exec appends entries in one pipeline and returns the ones that failed.
+func (b *BatchPublisher) exec(entries []*batchEntry) []*batchEntry {
+	// Callers may have stopped waiting, but their events are still published.
+	ctx := context.Background()
+
+	if breaker := b.pub.breaker; breaker != nil {
+		if err := breaker.Allow(); err != nil {
+			for _, entry := range entries {
+				entry.err = err
+			}
+			return entries
+		}
+	}
+
+	pipe := b.pub.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(entries))
+	for i, entry := range entries {
+		cmds[i] = pipe.XAdd(ctx, entry.args)
+	}
+	_, err := pipe.Exec(ctx)
+	if b.pub.breaker != nil {
+		b.pub.breaker.Record(err)
+	}
+
+	var failed []*batchEntry
+	for i, entry := range entries {
+		entry.id, entry.err = cmds[i].Result()
+		if entry.err != nil {
+			failed = append(failed, entry)
+		}
+	}
+	return failed
+}
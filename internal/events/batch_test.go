@@ -0,0 +1,76 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchPublisherFlushesOnSize(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	pub := NewBatchPublisher(NewRedisPublisher(client), 3, time.Hour)
+	defer pub.Close(ctx)
+
+	var wg sync.WaitGroup
+	ids := make([]string, 3)
+	errs := make([]error, 3)
+	for i := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids[i], errs[i] = pub.PublishWithPosition(ctx, "order.created", map[string]int{"n": i})
+		}()
+	}
+	wg.Wait()
+
+	for i := range ids {
+		if errs[i] != nil || ids[i] == "" {
+			t.Errorf("publish %d: expected a stream ID, got %q (%v)", i, ids[i], errs[i])
+		}
+	}
+	if n := client.XLen(ctx, StreamName).Val(); n != 3 {
+		t.Errorf("expected 3 events on the stream, got %d", n)
+	}
+}
+
+func TestBatchPublisherFlushesOnInterval(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	pub := NewBatchPublisher(NewRedisPublisher(client), 100, 10*time.Millisecond)
+	defer pub.Close(ctx)
+
+	if err := pub.Publish(ctx, "order.created", map[string]string{"id": "1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := client.XLen(ctx, StreamName).Val(); n != 1 {
+		t.Errorf("expected 1 event on the stream, got %d", n)
+	}
+}
+
+func TestBatchPublisherFlushesOnClose(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	pub := NewBatchPublisher(NewRedisPublisher(client), 100, time.Hour)
+
+	// The callers give up waiting, but their events are still published on
+	// Close.
+	for i := 0; i < 2; i++ {
+		waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		if err := pub.Publish(waitCtx, "order.created", map[string]int{"n": i}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the batch to still be buffered, got %v", err)
+		}
+		cancel()
+	}
+	if err := pub.Close(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := client.XLen(ctx, StreamName).Val(); n != 2 {
+		t.Errorf("expected 2 events flushed on close, got %d", n)
+	}
+	if err := pub.Publish(ctx, "order.created", map[string]int{"n": 3}); !errors.Is(err, ErrPublisherClosed) {
+		t.Errorf("expected ErrPublisherClosed, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package events
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/orders-service/internal/metrics"
+)
+
+var ErrCircuitOpen = errors.New("event publisher circuit open")
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerHalfOpen
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerHalfOpen:
+		return "half_open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker stops calls to a failing dependency. After threshold
+// consecutive failures it opens and rejects every call with ErrCircuitOpen
+// for cooldown; then it lets a single trial call through, closing again if
+// it succeeds and reopening if it fails.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	b := &CircuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+	metrics.PublisherCircuitState.Set(float64(BreakerClosed))
+	return b
+}
+
+// Allow returns ErrCircuitOpen if the call must not be made. Every allowed
+// call must be followed by Record.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		b.setState(BreakerHalfOpen)
+	}
+	switch b.state {
+	case BreakerOpen:
+		return ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.trial {
+			return ErrCircuitOpen
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+// Record reports the outcome of a call allowed by Allow.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if err == nil {
+		b.failures = 0
+		b.setState(BreakerClosed)
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.setState(BreakerOpen)
+	}
+}
+
+// release gives up a call allowed by Allow without recording an outcome.
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *CircuitBreaker) setState(state BreakerState) {
+	b.state = state
+	metrics.PublisherCircuitState.Set(float64(state))
+}
@@ -0,0 +1,132 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// failXAdd fails the next n XADD commands without sending them to Redis.
+type failXAdd struct {
+	n     int
+	calls int
+}
+
+func (h *failXAdd) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h *failXAdd) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() != "xadd" {
+			return next(ctx, cmd)
+		}
+		h.calls++
+		if h.n > 0 {
+			h.n--
+			err := errors.New("connection refused")
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *failXAdd) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := NewCircuitBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+	fail := errors.New("down")
+
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b.Record(fail)
+	}
+	if b.State() != BreakerOpen {
+		t.Fatalf("expected open after 2 failures, got %s", b.State())
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("expected a trial call after the cooldown, got %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected a single trial call, got %v", err)
+	}
+	b.Record(fail)
+	if b.State() != BreakerOpen {
+		t.Fatalf("expected a failed trial to reopen, got %s", b.State())
+	}
+
+	now = now.Add(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b.Record(nil)
+	if b.State() != BreakerClosed {
+		t.Errorf("expected a successful trial to close, got %s", b.State())
+	}
+}
+
+func TestPublishRetries(t *testing.T) {
+	client := newTestClient(t)
+	hook := &failXAdd{n: 2}
+	client.AddHook(hook)
+	ctx := context.Background()
+
+	pub := NewRedisPublisher(client, WithRetry(3, time.Millisecond))
+	if err := pub.Publish(ctx, "order.created", map[string]string{"id": "1"}); err != nil {
+		t.Fatalf("expected the third try to succeed, got %v", err)
+	}
+	if hook.calls != 3 {
+		t.Errorf("expected 3 tries, got %d", hook.calls)
+	}
+
+	hook.n, hook.calls = 3, 0
+	if err := pub.Publish(ctx, "order.created", map[string]string{"id": "2"}); err == nil {
+		t.Fatal("expected an error once the tries run out")
+	}
+	if hook.calls != 3 {
+		t.Errorf("expected 3 tries, got %d", hook.calls)
+	}
+}
+
+func TestPublishCircuitBreaker(t *testing.T) {
+	client := newTestClient(t)
+	hook := &failXAdd{n: 100}
+	client.AddHook(hook)
+	ctx := context.Background()
+
+	breaker := NewCircuitBreaker(3, time.Minute)
+	pub := NewRedisPublisher(client, WithRetry(2, time.Millisecond), WithCircuitBreaker(breaker))
+
+	for i := 0; i < 2; i++ {
+		if err := pub.Publish(ctx, "order.created", map[string]string{"id": "1"}); err == nil {
+			t.Fatal("expected an error while Redis is down")
+		}
+	}
+	if hook.calls != 3 {
+		t.Errorf("expected the breaker to open after 3 tries, got %d", hook.calls)
+	}
+	if err := pub.Publish(ctx, "order.created", map[string]string{"id": "1"}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+	if hook.calls != 3 {
+		t.Errorf("expected no tries while open, got %d", hook.calls)
+	}
+}
@@ -0,0 +1,273 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/orders-service/internal/model"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const DefaultSubscriberBuffer = 64
+
+// OrderUpdate is an order event as sent to live subscribers. From is set
+// for order.status_changed.
+type OrderUpdate struct {
+	StreamID   string       `json:"-"`
+	Event      string       `json:"event"`
+	EventID    string       `json:"event_id,omitempty"`
+	OccurredAt time.Time    `json:"occurred_at,omitzero"`
+	From       string       `json:"from,omitempty"`
+	Order      *model.Order `json:"order"`
+}
+
+// Broadcaster tails the stream and fans order.created, order.updated,
+// order.status_changed and order.deleted events out to subscribers. It reads independently of
+// the consumer group, so it neither acks nor competes for messages, and only
+// sees events appended after Run started.
+type Broadcaster struct {
+	client *redis.Client
+	log    *zap.Logger
+	buffer int
+
+	mu      sync.Mutex
+	subs    map[*subscriber]struct{}
+	stopped bool
+}
+
+type subscriber struct {
+	ch         chan OrderUpdate
+	customerID string
+	types      map[string]bool
+}
+
+func (s *subscriber) wants(update OrderUpdate) bool {
+	if s.customerID != "" && s.customerID != update.Order.CustomerID {
+		return false
+	}
+	return len(s.types) == 0 || s.types[update.Event]
+}
+
+func NewBroadcaster(client *redis.Client, log *zap.Logger, buffer int) *Broadcaster {
+	return &Broadcaster{client: client, log: log, buffer: buffer, subs: make(map[*subscriber]struct{})}
+}
+
+// Subscribe returns a channel of every order update, or only of those for
+// customerID's orders if it is not empty and of the given event types if
+// there are any, and a function that unsubscribes. The channel is closed on
+// unsubscribe, when the broadcaster stops, or when the subscriber falls more
+// than the buffer size behind and is dropped.
+func (b *Broadcaster) Subscribe(customerID string, types ...string) (<-chan OrderUpdate, func()) {
+	sub := &subscriber{ch: make(chan OrderUpdate, b.buffer), customerID: customerID}
+	if len(types) > 0 {
+		sub.types = make(map[string]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.stopped {
+		close(sub.ch)
+		return sub.ch, func() {}
+	}
+	b.subs[sub] = struct{}{}
+	return sub.ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(sub)
+	}
+}
+
+// remove closes s's channel unless it was removed already. b.mu must be held.
+func (b *Broadcaster) remove(s *subscriber) {
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+// Run tails the stream until ctx is cancelled, then closes every
+// subscription.
+func (b *Broadcaster) Run(ctx context.Context) {
+	defer b.stop()
+
+	// Start after the newest entry by ID rather than with "$", which would
+	// miss entries appended between two reads.
+	for {
+		newest, err := b.client.XRevRangeN(ctx, StreamName, "+", "-", 1).Result()
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			after := "0-0"
+			if len(newest) > 0 {
+				after = newest[0].ID
+			}
+			b.tail(ctx, after)
+			return
+		}
+		b.log.Error("redis: failed to read stream for live updates", zap.Error(err))
+		time.Sleep(time.Second)
+	}
+}
+
+// tail broadcasts the entries after stream ID after until ctx is cancelled.
+func (b *Broadcaster) tail(ctx context.Context, after string) {
+	for {
+		streams, err := b.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{StreamName, after},
+			Count:   100,
+			Block:   time.Second,
+		}).Result()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			if !errors.Is(err, redis.Nil) {
+				b.log.Error("redis: failed to read stream for live updates", zap.Error(err))
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				after = message.ID
+				if update, ok := b.decode(message); ok {
+					b.broadcast(update)
+				}
+			}
+		}
+	}
+}
+
+func (b *Broadcaster) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stopped = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+func (b *Broadcaster) decode(message redis.XMessage) (OrderUpdate, bool) {
+	event, err := parseEnvelope(message.Values)
+	if err != nil {
+		return OrderUpdate{}, false
+	}
+	update := OrderUpdate{StreamID: message.ID, Event: event.Type, EventID: event.ID, OccurredAt: event.OccurredAt}
+	switch event.Type {
+	case "order.created", "order.updated", "order.deleted":
+		var order model.Order
+		err = event.Decode(&order)
+		update.Order = &order
+	case "order.status_changed":
+		var change model.StatusChange
+		err = event.Decode(&change)
+		update.From, update.Order = change.From, change.Order
+	default:
+		return OrderUpdate{}, false
+	}
+	if err != nil || update.Order == nil {
+		b.log.Warn("skipping undecodable event for live updates", zap.String("message_id", message.ID), zap.String("event", event.Type), zap.Error(err))
+		return OrderUpdate{}, false
+	}
+	return update, true
+}
+
+// broadcast hands update to every matching subscriber without blocking.
+// Subscribers whose buffer is full are dropped so that one slow client
+// cannot hold up the others.
+func (b *Broadcaster) broadcast(update OrderUpdate) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		if !sub.wants(update) {
+			continue
+		}
+		select {
+		case sub.ch <- update:
+		default:
+			b.log.Warn("dropping slow live update subscriber", zap.String("customer_id", sub.customerID))
+			b.remove(sub)
+		}
+	}
+}
+
+// Resume is Subscribe for every event type, except that it first delivers
+// the updates appended after stream ID after that the stream still holds.
+// It returns an error if after is not a valid stream ID. The channel is
+// also closed if the updates cannot be read from the stream; like a dropped
+// subscriber, the client should resume again from the last update it got.
+func (b *Broadcaster) Resume(ctx context.Context, customerID, after string) (<-chan OrderUpdate, func(), error) {
+	if _, _, err := parseStreamID(after); err != nil {
+		return nil, nil, err
+	}
+
+	// Subscribe before reading the backlog so that nothing appended in
+	// between is missed; updates that were in both are skipped below.
+	live, unsubscribe := b.Subscribe(customerID)
+	ctx, cancel := context.WithCancel(ctx)
+	out := make(chan OrderUpdate)
+	go func() {
+		defer close(out)
+		send := func(update OrderUpdate) bool {
+			select {
+			case out <- update:
+				after = update.StreamID
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if !b.replay(ctx, customerID, after, send) {
+			return
+		}
+		for update := range live {
+			if newer, _ := compareStreamIDs(update.StreamID, after); newer <= 0 {
+				continue
+			}
+			if !send(update) {
+				return
+			}
+		}
+	}()
+
+	return out, func() {
+		cancel()
+		unsubscribe()
+	}, nil
+}
+
+// replay calls send with the matching updates after stream ID after, oldest
+// first, and reports whether all of them were sent.
+func (b *Broadcaster) replay(ctx context.Context, customerID, after string, send func(OrderUpdate) bool) bool {
+	const count = 100
+	for {
+		messages, err := b.client.XRangeN(ctx, StreamName, "("+after, "+", count).Result()
+		if err != nil {
+			if ctx.Err() == nil {
+				b.log.Error("redis: failed to replay stream for live updates", zap.String("after", after), zap.Error(err))
+			}
+			return false
+		}
+		for _, message := range messages {
+			after = message.ID
+			update, ok := b.decode(message)
+			if !ok || (customerID != "" && customerID != update.Order.CustomerID) {
+				continue
+			}
+			if !send(update) {
+				return false
+			}
+		}
+		if len(messages) < count {
+			return true
+		}
+	}
+}
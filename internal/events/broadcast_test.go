@@ -0,0 +1,155 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/model"
+	"go.uber.org/zap"
+)
+
+func receive(t *testing.T, updates <-chan OrderUpdate) (OrderUpdate, bool) {
+	t.Helper()
+	select {
+	case update, ok := <-updates:
+		return update, ok
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for an update")
+		return OrderUpdate{}, false
+	}
+}
+
+func TestBroadcasterFansOutOrderUpdates(t *testing.T) {
+	client := newTestClient(t)
+	pub := NewRedisPublisher(client)
+	b := NewBroadcaster(client, zap.NewNop(), 10)
+	all, _ := b.Subscribe("")
+	alice, _ := b.Subscribe("alice")
+	statuses, _ := b.Subscribe("", "order.status_changed")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.tail(ctx, "0-0")
+		b.stop()
+		close(done)
+	}()
+
+	order := &model.Order{ID: "order-1", CustomerID: "bob", Status: model.StatusPending}
+	if err := pub.Publish(ctx, "order.created", order); err != nil {
+		t.Fatal(err)
+	}
+	if err := pub.Publish(ctx, "order.deleted", order); err != nil {
+		t.Fatal(err)
+	}
+	confirmed := &model.Order{ID: "order-2", CustomerID: "alice", Status: model.StatusConfirmed}
+	if err := pub.Publish(ctx, "order.status_changed", &model.StatusChange{From: model.StatusPending, To: model.StatusConfirmed, Order: confirmed}); err != nil {
+		t.Fatal(err)
+	}
+
+	update, _ := receive(t, all)
+	if update.Event != "order.created" || update.Order.ID != "order-1" {
+		t.Errorf("expected order-1 to be created, got %+v", update)
+	}
+	update, _ = receive(t, all)
+	if update.Event != "order.deleted" || update.Order.ID != "order-1" || update.StreamID == "" {
+		t.Errorf("expected order-1 to be deleted, got %+v", update)
+	}
+	update, _ = receive(t, all)
+	if update.Event != "order.status_changed" || update.From != model.StatusPending || update.Order.Status != model.StatusConfirmed {
+		t.Errorf("expected order-2's status change, got %+v", update)
+	}
+	update, _ = receive(t, alice)
+	if update.Order.ID != "order-2" {
+		t.Errorf("expected only alice's order, got %+v", update)
+	}
+	update, _ = receive(t, statuses)
+	if update.Event != "order.status_changed" {
+		t.Errorf("expected only status changes, got %+v", update)
+	}
+
+	cancel()
+	<-done
+	if _, ok := receive(t, all); ok {
+		t.Error("expected subscriptions to be closed when the broadcaster stops")
+	}
+	late, _ := b.Subscribe("")
+	if _, ok := receive(t, late); ok {
+		t.Error("expected subscribing after stop to return a closed subscription")
+	}
+}
+
+func TestBroadcasterDropsSlowSubscribers(t *testing.T) {
+	b := NewBroadcaster(nil, zap.NewNop(), 1)
+	slow, unsubscribeSlow := b.Subscribe("")
+	fast, unsubscribeFast := b.Subscribe("")
+
+	update := OrderUpdate{Event: "order.created", Order: &model.Order{ID: "order-1"}}
+	b.broadcast(update)
+	<-fast
+	b.broadcast(update)
+
+	if _, ok := <-slow; !ok {
+		t.Fatal("expected the buffered update to be delivered before the close")
+	}
+	if _, ok := <-slow; ok {
+		t.Error("expected the slow subscriber to be dropped")
+	}
+	if _, ok := <-fast; !ok {
+		t.Error("expected the subscriber that kept up to stay subscribed")
+	}
+	unsubscribeSlow()
+	unsubscribeFast()
+	if _, ok := <-fast; ok {
+		t.Error("expected unsubscribing to close the channel")
+	}
+}
+
+func TestBroadcasterResume(t *testing.T) {
+	client := newTestClient(t)
+	pub := NewRedisPublisher(client)
+	b := NewBroadcaster(client, zap.NewNop(), 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, _, err := b.Resume(ctx, "", "bogus"); err == nil {
+		t.Error("expected an invalid stream id to be rejected")
+	}
+
+	alice := &model.Order{ID: "order-1", CustomerID: "alice"}
+	seen, err := pub.PublishWithPosition(ctx, "order.created", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pub.Publish(ctx, "order.created", &model.Order{ID: "order-2", CustomerID: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pub.Publish(ctx, "order.updated", alice); err != nil {
+		t.Fatal(err)
+	}
+
+	updates, unsubscribe, err := b.Resume(ctx, "alice", seen)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Tail from the start, so that the replayed events are broadcast again.
+	go b.tail(ctx, "0-0")
+
+	update, _ := receive(t, updates)
+	if update.Event != "order.updated" || update.Order.ID != "order-1" {
+		t.Errorf("expected the missed update of order-1, got %+v", update)
+	}
+	if err := pub.Publish(ctx, "order.deleted", alice); err != nil {
+		t.Fatal(err)
+	}
+	update, _ = receive(t, updates)
+	if update.Event != "order.deleted" {
+		t.Errorf("expected the live delete of order-1 without duplicates, got %+v", update)
+	}
+
+	unsubscribe()
+	if _, ok := receive(t, updates); ok {
+		t.Error("expected unsubscribing to close the channel")
+	}
+}
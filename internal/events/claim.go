@@ -0,0 +1,84 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const claimBatchSize = 100
+
+type ClaimPolicy struct {
+	// MinIdle is how long a message must have been delivered but not acked
+	// before another consumer takes it over.
+	MinIdle  time.Duration
+	Interval time.Duration
+}
+
+var DefaultClaimPolicy = ClaimPolicy{
+	MinIdle:  time.Minute,
+	Interval: 30 * time.Second,
+}
+
+func WithClaimPolicy(policy ClaimPolicy) ConsumerOption {
+	return func(c *Consumer) {
+		c.claim = policy
+	}
+}
+
+// RunClaimLoop periodically takes over messages that were read by a consumer
+// but never acked, e.g. because it crashed mid-processing, and processes them
+// again. Together with acking only after processing this gives at-least-once
+// delivery. It runs until ctx is cancelled.
+func (c *Consumer) RunClaimLoop(ctx context.Context) {
+	c.running.Add(1)
+	defer c.running.Done()
+
+	ticker := time.NewTicker(c.claim.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.claimStale(ctx); err != nil && ctx.Err() == nil {
+				c.log.Error("redis: failed to claim stale messages", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (c *Consumer) claimStale(ctx context.Context) (int, error) {
+	work, stop := c.detach(ctx)
+	defer stop()
+
+	claimed := 0
+	start := "0-0"
+	for {
+		messages, next, err := c.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   StreamName,
+			Group:    ConsumerGroup,
+			Consumer: ConsumerName,
+			MinIdle:  c.claim.MinIdle,
+			Start:    start,
+			Count:    claimBatchSize,
+		}).Result()
+		if err != nil {
+			return claimed, err
+		}
+
+		for _, message := range messages {
+			c.log.Warn("reprocessing stale message", zap.String("message_id", message.ID))
+			c.processMessage(work, message)
+			claimed++
+		}
+
+		if next == "0-0" || ctx.Err() != nil {
+			return claimed, nil
+		}
+		start = next
+	}
+}
@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestClaimStaleReprocessesUnackedMessages(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	var handled []string
+	consumer := NewConsumer(client, nil, zap.NewNop(),
+		WithClaimPolicy(ClaimPolicy{MinIdle: 20 * time.Millisecond, Interval: time.Second}),
+		WithEventHandler("order.updated", func(ctx context.Context, event EventEnvelope) error {
+			handled = append(handled, string(event.Data))
+			return nil
+		}),
+	)
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	publishN(t, client, 2)
+
+	// Simulate a consumer that read the messages and crashed before acking.
+	if messages := readMessages(t, client); len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+
+	claimed, err := consumer.claimStale(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claimed != 0 {
+		t.Fatalf("expected recently delivered messages not to be claimed, got %d", claimed)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	claimed, err = consumer.claimStale(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claimed != 2 || len(handled) != 2 {
+		t.Errorf("expected 2 messages to be reprocessed, claimed %d handled %d", claimed, len(handled))
+	}
+	if pending := pendingCount(t, client); pending != 0 {
+		t.Errorf("expected reclaimed messages to be acked, %d pending", pending)
+	}
+}
@@ -2,10 +2,13 @@ package events
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/metrics"
 	"github.com/orders-service/internal/model"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
@@ -20,17 +23,125 @@ type OrderStatusUpdater interface {
 	UpdateOrderStatus(ctx context.Context, id string, status string) error
 }
 
+// EventHandler processes a single stream event. A returned error schedules
+// the message for retry.
+type EventHandler func(ctx context.Context, event EventEnvelope) error
+
 type Consumer struct {
-	client  *redis.Client
-	updater OrderStatusUpdater
-	log     *zap.Logger
+	// ConfirmationDelay holds back confirming a created order. It is zero by
+	// default; set it only where downstream systems need time to catch up.
+	ConfirmationDelay time.Duration
+
+	client   *redis.Client
+	updater  OrderStatusUpdater
+	log      *zap.Logger
+	retry    RetryPolicy
+	now      func() time.Time
+	handlers map[string]EventHandler
+	acks     *ackBatcher
+	claim    ClaimPolicy
+	dedupTTL time.Duration
+	maxLen   int64
+	stale    []error
+	running  sync.WaitGroup
+
+	// work is the parent of every handler context. Wait cancels it when the
+	// drain deadline passes.
+	work     context.Context
+	stopWork context.CancelFunc
+
+	prefetch    int
+	maxInFlight int
+}
+
+type ConsumerOption func(*Consumer)
+
+// WithEventHandler registers handler for event, replacing the built-in
+// handler if there is one. Use it to hook downstream work such as cleanup
+// after order.deleted.
+func WithEventHandler(event string, handler EventHandler) ConsumerOption {
+	return func(c *Consumer) {
+		c.handlers[event] = handler
+	}
 }
 
-func NewConsumer(client *redis.Client, updater OrderStatusUpdater, log *zap.Logger) *Consumer {
-	return &Consumer{client: client, updater: updater, log: log}
+// DefaultPrefetch is how many messages Subscribe reads from the stream at a
+// time by default.
+const DefaultPrefetch = 10
+
+// WithPrefetch sets how many messages Subscribe reads from the stream at a
+// time.
+func WithPrefetch(n int) ConsumerOption {
+	return func(c *Consumer) {
+		if n > 0 {
+			c.prefetch = n
+		}
+	}
+}
+
+// WithStreamMaxLen trims the stream like the publisher's WithMaxLen when
+// retries and replayed dead letters are appended to it again.
+func WithStreamMaxLen(n int64) ConsumerOption {
+	return func(c *Consumer) {
+		c.maxLen = n
+	}
 }
 
+// WithStaleOrderErrors names the errors UpdateOrderStatus returns when an
+// order can no longer be confirmed, because it was deleted or has moved past
+// pending. An order.created event that fails with one of them arrived too
+// late to matter: it is logged and acked instead of being retried.
+func WithStaleOrderErrors(errs ...error) ConsumerOption {
+	return func(c *Consumer) {
+		c.stale = append(c.stale, errs...)
+	}
+}
+
+// WithMaxInFlight processes messages concurrently, at most n at a time.
+// Subscribe reads only as many messages as there are free slots and stops
+// reading while all n are busy, so unread messages stay in Redis. Messages
+// may then be handled out of stream order. By default messages are handled
+// one at a time in order.
+func WithMaxInFlight(n int) ConsumerOption {
+	return func(c *Consumer) {
+		c.maxInFlight = n
+	}
+}
+
+func NewConsumer(client *redis.Client, updater OrderStatusUpdater, log *zap.Logger, opts ...ConsumerOption) *Consumer {
+	c := &Consumer{
+		client:  client,
+		updater: updater,
+		log:     log,
+		retry:   DefaultRetryPolicy,
+		claim:   DefaultClaimPolicy,
+		now:     time.Now,
+
+		prefetch: DefaultPrefetch,
+	}
+	c.work, c.stopWork = context.WithCancel(context.Background())
+	c.handlers = map[string]EventHandler{
+		"order.created": c.handleOrderCreated,
+		"order.updated": c.handleOrderUpdated,
+		"order.deleted": c.handleOrderDeleted,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Subscribe reads and processes messages until ctx is cancelled. A batch
+// that was already read is processed and acked to completion after
+// cancellation, so shutdown does not abandon half-handled messages, unless
+// Wait gives up on draining first.
 func (c *Consumer) Subscribe(ctx context.Context, channel string) {
+	c.running.Add(1)
+	defer c.running.Done()
+
+	work, stop := c.detach(ctx)
+	defer stop()
+
 	err := c.client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err()
 	if err != nil && err.Error() != "BUSYGROUP Consumer Group name already exists" {
 		c.log.Error("redis: failed to create consumer group", zap.Error(err))
@@ -38,6 +149,18 @@ func (c *Consumer) Subscribe(ctx context.Context, channel string) {
 
 	c.log.Info("subscribed to stream", zap.String("stream", StreamName), zap.String("group", ConsumerGroup))
 
+	if c.acks != nil {
+		stop := c.acks.start()
+		defer stop()
+	}
+
+	var slots chan struct{}
+	var workers sync.WaitGroup
+	if c.maxInFlight > 0 {
+		slots = make(chan struct{}, c.maxInFlight)
+	}
+	defer workers.Wait()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -46,14 +169,31 @@ func (c *Consumer) Subscribe(ctx context.Context, channel string) {
 		default:
 		}
 
+		count := c.prefetch
+		if slots != nil {
+			var ok bool
+			if count, ok = c.reserve(ctx, slots); !ok {
+				c.log.Info("consumer shutting down")
+				return
+			}
+		}
+
 		streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
 			Group:    ConsumerGroup,
 			Consumer: ConsumerName,
 			Streams:  []string{StreamName, ">"},
-			Count:    10,
+			Count:    int64(count),
 			Block:    time.Second,
 		}).Result()
 
+		if slots != nil {
+			read := 0
+			for _, stream := range streams {
+				read += len(stream.Messages)
+			}
+			release(slots, count-read)
+		}
+
 		if err != nil {
 			if err == redis.Nil {
 				continue
@@ -68,60 +208,243 @@ func (c *Consumer) Subscribe(ctx context.Context, channel string) {
 
 		for _, stream := range streams {
 			for _, message := range stream.Messages {
-				c.processMessage(ctx, message)
+				if slots == nil {
+					c.processMessage(work, message)
+					continue
+				}
+				workers.Add(1)
+				go func(message redis.XMessage) {
+					defer workers.Done()
+					defer release(slots, 1)
+					c.processMessage(work, message)
+				}(message)
 			}
 		}
 	}
 }
 
+// reserve blocks until an in-flight slot is free, then takes up to prefetch
+// free slots and returns how many it took. It returns false if ctx is done
+// first.
+func (c *Consumer) reserve(ctx context.Context, slots chan struct{}) (int, bool) {
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return 0, false
+	}
+	n := 1
+	for n < c.prefetch {
+		select {
+		case slots <- struct{}{}:
+			n++
+		default:
+			return n, true
+		}
+	}
+	return n, true
+}
+
+func release(slots chan struct{}, n int) {
+	for i := 0; i < n; i++ {
+		<-slots
+	}
+}
+
+// detach returns the context messages are processed with. It keeps ctx's
+// values but not its cancellation, and is cancelled instead once Wait gives
+// up on draining.
+func (c *Consumer) detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	work, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(c.work, cancel)
+	return work, func() {
+		stop()
+		cancel()
+	}
+}
+
+// Wait blocks until Subscribe, the retry and claim loops and any
+// KafkaConsumer.Run using c have returned after their context was
+// cancelled, including any messages they were still processing, or until
+// ctx is done. In the latter case it cancels the handlers still running, so
+// a message that is backing off is left pending and redelivered rather than
+// retried past the deadline. Call it after those loops were started.
+func (c *Consumer) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		c.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		c.stopWork()
+		return ctx.Err()
+	}
+}
+
 func (c *Consumer) processMessage(ctx context.Context, message redis.XMessage) {
-	event, ok := message.Values["event"].(string)
-	if !ok {
-		c.log.Warn("invalid event type in message", zap.String("message_id", message.ID))
-		c.ackMessage(ctx, message.ID)
+	evt, err := parseEnvelope(message.Values)
+	if err != nil {
+		c.log.Warn("malformed message", zap.String("message_id", message.ID), zap.Error(err))
+		if c.handleFailure(ctx, message, evt, malformed(err)) {
+			c.ackMessage(ctx, message.ID)
+		}
 		return
 	}
+	event := evt.Type
+
+	c.log.Info("event received", zap.String("event", event), zap.String("message_id", message.ID), zap.String("event_id", evt.ID), zap.Int("version", evt.Version))
 
-	payload, ok := message.Values["payload"].(string)
-	if !ok {
-		c.log.Warn("invalid payload in message", zap.String("message_id", message.ID))
+	if c.alreadyProcessed(ctx, evt) {
+		c.log.Info("skipping duplicate event", zap.String("event", event), zap.String("message_id", message.ID), zap.String("event_id", evt.ID))
+		metrics.EventsConsumed.WithLabelValues(event, metrics.OutcomeDuplicate).Inc()
 		c.ackMessage(ctx, message.ID)
 		return
 	}
 
-	c.log.Info("event received", zap.String("event", event), zap.String("message_id", message.ID))
-
 	msgCtx := logger.WithContext(ctx, c.log.With(zap.String("event", event), zap.String("message_id", message.ID)))
 
-	if event == "order.created" {
-		c.handleOrderCreated(msgCtx, payload)
+	if handler, ok := c.handlers[event]; ok {
+		err = c.runWithRetry(msgCtx, handler, evt)
+	} else {
+		c.log.Debug("no handler for event", zap.String("event", event), zap.String("message_id", message.ID))
 	}
+	if err != nil && ctx.Err() != nil {
+		// Shutting down mid-retry: leave the message pending so it is
+		// redelivered rather than counted against its retry budget.
+		c.log.Warn("leaving message pending on shutdown", zap.String("message_id", message.ID), zap.Error(err))
+		return
+	}
+	metrics.EventsConsumed.WithLabelValues(event, metrics.Outcome(err)).Inc()
 
+	if err != nil && !c.handleFailure(ctx, message, evt, err) {
+		return
+	}
+	if err == nil {
+		c.markProcessed(ctx, evt)
+	}
 	c.ackMessage(ctx, message.ID)
 }
 
 func (c *Consumer) ackMessage(ctx context.Context, messageID string) {
+	if c.acks != nil {
+		c.acks.add(ctx, messageID)
+		return
+	}
 	if err := c.client.XAck(ctx, StreamName, ConsumerGroup, messageID).Err(); err != nil {
 		c.log.Error("redis: failed to ack message", zap.String("message_id", messageID), zap.Error(err))
 	}
 }
 
-func (c *Consumer) handleOrderCreated(ctx context.Context, payload string) {
-	log := logger.FromContext(ctx)
+// ErrMalformedEvent marks events that can never be handled, such as a payload
+// that does not decode. They are dead-lettered without being retried.
+var ErrMalformedEvent = errors.New("malformed event")
+
+func malformed(err error) error {
+	return fmt.Errorf("%w: %v", ErrMalformedEvent, err)
+}
+
+// ErrHandlerPanic marks events whose handler panicked. The panic is
+// recovered so that the consumer keeps running, and the event is
+// dead-lettered without being retried.
+var ErrHandlerPanic = errors.New("event handler panicked")
+
+// permanent reports whether err can never be resolved by retrying.
+func permanent(err error) bool {
+	return errors.Is(err, ErrMalformedEvent) || errors.Is(err, ErrHandlerPanic)
+}
 
+// callHandler runs handler, turning a panic into an ErrHandlerPanic error.
+func callHandler(ctx context.Context, handler EventHandler, event EventEnvelope) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.FromContext(ctx).Error("panic in event handler", zap.Any("panic", r), zap.Stack("stack"))
+			err = fmt.Errorf("%w: %v", ErrHandlerPanic, r)
+		}
+	}()
+	return handler(ctx, event)
+}
+
+// decodeOrder decodes the order in an order event, which must have an ID.
+func decodeOrder(event EventEnvelope) (*model.Order, error) {
 	var order model.Order
-	if err := json.Unmarshal([]byte(payload), &order); err != nil {
-		log.Error("failed to unmarshal order", zap.Error(err))
-		return
+	if err := event.Decode(&order); err != nil {
+		return nil, malformed(err)
+	}
+	if order.ID == "" {
+		return nil, malformed(errors.New("order has no id"))
+	}
+	return &order, nil
+}
+
+func (c *Consumer) handleOrderCreated(ctx context.Context, event EventEnvelope) error {
+	log := logger.FromContext(ctx)
+
+	order, err := decodeOrder(event)
+	if err != nil {
+		log.Error("failed to decode order", zap.Error(err))
+		return err
 	}
 
-	time.Sleep(2 * time.Second)
+	if c.ConfirmationDelay > 0 {
+		timer := time.NewTimer(c.ConfirmationDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
 
 	if c.updater != nil {
 		if err := c.updater.UpdateOrderStatus(ctx, order.ID, "confirmed"); err != nil {
+			if c.isStale(err) {
+				log.Info("skipping confirmation of stale order", zap.String("order_id", order.ID), zap.Error(err))
+				return nil
+			}
 			log.Error("failed to update order status", zap.String("order_id", order.ID), zap.Error(err))
-			return
+			return err
 		}
 		log.Info("order confirmed", zap.String("order_id", order.ID))
 	}
+	return nil
+}
+
+// isStale reports whether err is one of the errors set with
+// WithStaleOrderErrors.
+func (c *Consumer) isStale(err error) bool {
+	for _, target := range c.stale {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Consumer) handleOrderUpdated(ctx context.Context, event EventEnvelope) error {
+	log := logger.FromContext(ctx)
+
+	order, err := decodeOrder(event)
+	if err != nil {
+		log.Error("failed to decode order", zap.Error(err))
+		return err
+	}
+
+	log.Info("order updated", zap.String("order_id", order.ID), zap.String("status", order.Status))
+	return nil
+}
+
+func (c *Consumer) handleOrderDeleted(ctx context.Context, event EventEnvelope) error {
+	log := logger.FromContext(ctx)
+
+	order, err := decodeOrder(event)
+	if err != nil {
+		log.Error("failed to decode order", zap.Error(err))
+		return err
+	}
+
+	log.Info("order deleted", zap.String("order_id", order.ID))
+	return nil
 }
@@ -0,0 +1,444 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+func TestProcessMessageDispatchesByEvent(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	var deleted []string
+	consumer := NewConsumer(client, nil, zap.NewNop(), WithEventHandler("order.deleted", func(ctx context.Context, event EventEnvelope) error {
+		deleted = append(deleted, string(event.Data))
+		return nil
+	}))
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	pub := NewRedisPublisher(client)
+	for _, event := range []string{"order.created", "order.updated", "order.deleted", "order.unknown"} {
+		if err := pub.Publish(ctx, event, map[string]string{"id": "order-1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, message := range readMessages(t, client) {
+		consumer.processMessage(ctx, message)
+	}
+
+	if len(deleted) != 1 || deleted[0] != `{"id":"order-1"}` {
+		t.Errorf("expected registered handler to receive the deleted event, got %v", deleted)
+	}
+	pending, err := client.XPending(ctx, StreamName, ConsumerGroup).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Count != 0 {
+		t.Errorf("expected all events to be acked, %d pending", pending.Count)
+	}
+	if n, _ := client.ZCard(ctx, RetryQueueKey).Result(); n != 0 {
+		t.Errorf("expected no retries, got %d", n)
+	}
+}
+
+func TestHandleOrderUpdatedRejectsMalformedPayload(t *testing.T) {
+	consumer := NewConsumer(newTestClient(t), nil, zap.NewNop())
+
+	if err := consumer.handleOrderUpdated(context.Background(), EventEnvelope{Data: []byte("not json")}); err == nil {
+		t.Error("expected error for malformed payload")
+	}
+	if err := consumer.handleOrderDeleted(context.Background(), EventEnvelope{Data: []byte(`{"id":"order-1"}`)}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMalformedEventsAreDeadLettered(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	updater := &recordingUpdater{statuses: make(map[string]string)}
+	consumer := NewConsumer(client, updater, zap.NewNop())
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, values := range []map[string]interface{}{
+		{"event": "order.created", "payload": "not json"},
+		{"event": "order.created", "payload": `{"product":"Widget"}`},
+		{"event": "order.created"},
+	} {
+		if err := client.XAdd(ctx, &redis.XAddArgs{Stream: StreamName, Values: values}).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, message := range readMessages(t, client) {
+		consumer.processMessage(ctx, message)
+	}
+
+	if len(updater.statuses) != 0 {
+		t.Errorf("expected no status updates, got %v", updater.statuses)
+	}
+	letters, err := consumer.DeadLetters(ctx, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(letters) != 3 {
+		t.Fatalf("expected 3 dead letters, got %d", len(letters))
+	}
+	if letters[0].Payload != "not json" || letters[0].RetryCount != 0 || !strings.Contains(letters[0].Error, ErrMalformedEvent.Error()) {
+		t.Errorf("unexpected dead letter %+v", letters[0])
+	}
+	if !strings.Contains(letters[1].Error, "order has no id") {
+		t.Errorf("expected the missing id to be reported, got %q", letters[1].Error)
+	}
+	if n, _ := client.ZCard(ctx, RetryQueueKey).Result(); n != 0 {
+		t.Errorf("expected no retries, got %d", n)
+	}
+	pending, err := client.XPending(ctx, StreamName, ConsumerGroup).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Count != 0 {
+		t.Errorf("expected all messages to be acked, %d pending", pending.Count)
+	}
+}
+
+// panickingUpdater panics for one order, as a buggy handler dependency would,
+// and confirms the others.
+type panickingUpdater struct {
+	recordingUpdater
+	panicFor string
+}
+
+func (p *panickingUpdater) UpdateOrderStatus(ctx context.Context, id string, status string) error {
+	if id == p.panicFor {
+		var order *struct{ ID string }
+		_ = order.ID
+	}
+	return p.recordingUpdater.UpdateOrderStatus(ctx, id, status)
+}
+
+func TestConsumerRecoversFromHandlerPanics(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updater := &panickingUpdater{recordingUpdater: recordingUpdater{statuses: make(map[string]string)}, panicFor: "order-1"}
+	consumer := NewConsumer(client, updater, zap.NewNop())
+
+	pub := NewRedisPublisher(client)
+	for _, id := range []string{"order-1", "order-2"} {
+		if err := pub.Publish(ctx, "order.created", map[string]string{"id": id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		consumer.Subscribe(ctx, "order.created")
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		updater.mu.Lock()
+		status := updater.statuses["order-2"]
+		updater.mu.Unlock()
+		if status == "confirmed" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the consumer to keep processing after a handler panic")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	letters, err := consumer.DeadLetters(ctx, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(letters) != 1 || letters[0].Payload != `{"id":"order-1"}` || !strings.Contains(letters[0].Error, ErrHandlerPanic.Error()) {
+		t.Errorf("expected order-1 to be dead-lettered for the panic, got %+v", letters)
+	}
+	if n, _ := client.ZCard(ctx, RetryQueueKey).Result(); n != 0 {
+		t.Errorf("expected no retries, got %d", n)
+	}
+
+	cancel()
+	<-done
+	if pending := pendingCount(t, client); pending != 0 {
+		t.Errorf("expected both messages to be acked, %d pending", pending)
+	}
+}
+
+type recordingUpdater struct {
+	mu       sync.Mutex
+	statuses map[string]string
+}
+
+func (r *recordingUpdater) UpdateOrderStatus(ctx context.Context, id string, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses[id] = status
+	return nil
+}
+
+var (
+	errTestOrderNotFound     = errors.New("order not found")
+	errTestInvalidTransition = errors.New("invalid status transition")
+)
+
+// cancellingUpdater rejects confirming the orders in cancelled, as the order
+// service does for an order that was cancelled before its order.created event
+// was handled.
+type cancellingUpdater struct {
+	recordingUpdater
+	cancelled map[string]bool
+}
+
+func (c *cancellingUpdater) UpdateOrderStatus(ctx context.Context, id string, status string) error {
+	if c.cancelled[id] {
+		return fmt.Errorf("%w: cannot move order from cancelled to %s", errTestInvalidTransition, status)
+	}
+	return c.recordingUpdater.UpdateOrderStatus(ctx, id, status)
+}
+
+func TestCreatedEventAfterCancelIsAcked(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	updater := &cancellingUpdater{recordingUpdater: recordingUpdater{statuses: make(map[string]string)}, cancelled: map[string]bool{"order-1": true}}
+	consumer := NewConsumer(client, updater, zap.NewNop(), WithStaleOrderErrors(errTestOrderNotFound, errTestInvalidTransition))
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	pub := NewRedisPublisher(client)
+	for _, id := range []string{"order-1", "order-2"} {
+		if err := pub.Publish(ctx, "order.created", map[string]string{"id": id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, message := range readMessages(t, client) {
+		consumer.processMessage(ctx, message)
+	}
+
+	if updater.statuses["order-2"] != "confirmed" {
+		t.Errorf("expected order-2 to be confirmed, got %q", updater.statuses["order-2"])
+	}
+	if n, _ := client.ZCard(ctx, RetryQueueKey).Result(); n != 0 {
+		t.Errorf("expected no retries, got %d", n)
+	}
+	letters, err := consumer.DeadLetters(ctx, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(letters) != 0 {
+		t.Errorf("expected no dead letters, got %+v", letters)
+	}
+	if pending := pendingCount(t, client); pending != 0 {
+		t.Errorf("expected both messages to be acked, %d pending", pending)
+	}
+}
+
+func TestCreatedEventAfterCancelIsRetriedWithoutStaleErrors(t *testing.T) {
+	consumer := NewConsumer(newTestClient(t), &cancellingUpdater{cancelled: map[string]bool{"order-1": true}}, zap.NewNop())
+
+	err := consumer.handleOrderCreated(context.Background(), EventEnvelope{Data: []byte(`{"id":"order-1"}`)})
+	if !errors.Is(err, errTestInvalidTransition) {
+		t.Errorf("expected the transition error to be returned, got %v", err)
+	}
+}
+
+func TestHandleOrderCreatedConfirmsWithoutDelay(t *testing.T) {
+	updater := &recordingUpdater{statuses: make(map[string]string)}
+	consumer := NewConsumer(newTestClient(t), updater, zap.NewNop())
+
+	start := time.Now()
+	if err := consumer.handleOrderCreated(context.Background(), EventEnvelope{Data: []byte(`{"id":"order-1"}`)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected confirmation without a fixed delay, took %v", elapsed)
+	}
+	if updater.statuses["order-1"] != "confirmed" {
+		t.Errorf("expected order-1 to be confirmed, got %q", updater.statuses["order-1"])
+	}
+}
+
+func TestHandleOrderCreatedConfirmationDelayHonoursCancellation(t *testing.T) {
+	updater := &recordingUpdater{statuses: make(map[string]string)}
+	consumer := NewConsumer(newTestClient(t), updater, zap.NewNop())
+	consumer.ConfirmationDelay = time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := consumer.handleOrderCreated(ctx, EventEnvelope{Data: []byte(`{"id":"order-1"}`)}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if _, ok := updater.statuses["order-1"]; ok {
+		t.Error("expected order not to be confirmed after cancellation")
+	}
+}
+
+func TestShutdownDrainsInFlightMessages(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var handlerErr error
+	consumer := NewConsumer(client, nil, zap.NewNop(), WithEventHandler("order.updated", func(ctx context.Context, event EventEnvelope) error {
+		close(entered)
+		<-release
+		handlerErr = ctx.Err()
+		return handlerErr
+	}))
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	publishN(t, client, 1)
+
+	go consumer.Subscribe(ctx, StreamName)
+	<-entered
+	cancel()
+
+	waited := make(chan error, 1)
+	go func() { waited <- consumer.Wait(context.Background()) }()
+	select {
+	case err := <-waited:
+		t.Fatalf("expected Wait to block while a message is in flight, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-waited; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if handlerErr != nil {
+		t.Errorf("expected in-flight handler to run with a live context, got %v", handlerErr)
+	}
+	if pending := pendingCount(t, client); pending != 0 {
+		t.Errorf("expected drained message to be acked, %d pending", pending)
+	}
+}
+
+func TestDrainDeadlineLeavesBackingOffMessagePending(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	entered := make(chan struct{})
+	var once sync.Once
+	calls := 0
+	consumer := NewConsumer(client, nil, zap.NewNop(),
+		WithRetryPolicy(RetryPolicy{MaxRetries: 1, BaseDelay: time.Second, MaxDelay: time.Minute, InlineRetries: 2, InlineDelay: time.Minute}),
+		WithEventHandler("order.updated", func(context.Context, EventEnvelope) error {
+			calls++
+			once.Do(func() { close(entered) })
+			return errors.New("database unavailable")
+		}),
+	)
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	publishN(t, client, 1)
+
+	go consumer.Subscribe(ctx, StreamName)
+	<-entered
+	cancel()
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer waitCancel()
+	if err := consumer.Wait(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Wait to give up while the message backs off, got %v", err)
+	}
+	if err := consumer.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("expected no retry after the drain deadline, got %d calls", calls)
+	}
+	if pending := pendingCount(t, client); pending != 1 {
+		t.Errorf("expected message to stay pending, %d pending", pending)
+	}
+	if n, _ := client.ZCard(context.Background(), RetryQueueKey).Result(); n != 0 {
+		t.Errorf("expected no delayed retries, got %d", n)
+	}
+}
+
+func TestMaxInFlightStopsReadingUntilWorkersDrain(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	started, active, peak := 0, 0, 0
+	unblock := make(chan struct{})
+	consumer := NewConsumer(client, nil, zap.NewNop(), WithPrefetch(10), WithMaxInFlight(2),
+		WithEventHandler("order.updated", func(context.Context, EventEnvelope) error {
+			mu.Lock()
+			started++
+			active++
+			if active > peak {
+				peak = active
+			}
+			mu.Unlock()
+			<-unblock
+			mu.Lock()
+			active--
+			mu.Unlock()
+			return nil
+		}))
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	publishN(t, client, 5)
+
+	done := make(chan struct{})
+	go func() {
+		consumer.Subscribe(ctx, "order.updated")
+		close(done)
+	}()
+
+	startedCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return started
+	}
+
+	waitFor(t, func() bool { return startedCount() == 2 })
+	// Give the consumer time to (wrongly) read more while both workers block.
+	time.Sleep(50 * time.Millisecond)
+	if n := pendingCount(t, client); n != 2 {
+		t.Fatalf("expected only 2 messages to be read while workers are busy, %d delivered", n)
+	}
+
+	close(unblock)
+	waitFor(t, func() bool { return startedCount() == 5 && pendingCount(t, client) == 0 })
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumer did not shut down")
+	}
+	if peak > 2 {
+		t.Errorf("expected at most 2 messages in flight, got %d", peak)
+	}
+}
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ProcessedKeyPrefix prefixes the Redis keys recording handled event IDs.
+const ProcessedKeyPrefix = "orders:processed:"
+
+// DefaultDedupTTL is how long handled event IDs are remembered by default.
+const DefaultDedupTTL = 24 * time.Hour
+
+// WithDedup skips events whose ID was already handled successfully within
+// ttl, so redeliveries do not repeat their side effects. Events without an ID
+// are always handled. Duplicates delivered concurrently may both be handled,
+// and a failed Redis lookup lets the event through: delivery stays
+// at-least-once, just with far fewer repeats.
+func WithDedup(ttl time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		if ttl > 0 {
+			c.dedupTTL = ttl
+		}
+	}
+}
+
+// alreadyProcessed reports whether event was handled before.
+func (c *Consumer) alreadyProcessed(ctx context.Context, event EventEnvelope) bool {
+	if c.dedupTTL == 0 || event.ID == "" {
+		return false
+	}
+	n, err := c.client.Exists(ctx, ProcessedKeyPrefix+event.ID).Result()
+	if err != nil {
+		c.log.Error("redis: failed to check processed event", zap.String("event_id", event.ID), zap.Error(err))
+		return false
+	}
+	return n > 0
+}
+
+// markProcessed records that event was handled.
+func (c *Consumer) markProcessed(ctx context.Context, event EventEnvelope) {
+	if c.dedupTTL == 0 || event.ID == "" {
+		return
+	}
+	if err := c.client.Set(ctx, ProcessedKeyPrefix+event.ID, 1, c.dedupTTL).Err(); err != nil {
+		c.log.Error("redis: failed to record processed event", zap.String("event_id", event.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/model"
+	"go.uber.org/zap"
+)
+
+type countingUpdater struct {
+	mu    sync.Mutex
+	calls int
+	err   error
+}
+
+func (u *countingUpdater) UpdateOrderStatus(ctx context.Context, id string, status string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.calls++
+	return u.err
+}
+
+func TestConsumerSkipsProcessedEvents(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	updater := &countingUpdater{}
+	consumer := NewConsumer(client, updater, zap.NewNop(), WithDedup(time.Hour))
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	pub := NewRedisPublisher(client)
+	order := &model.Order{ID: "order-1"}
+	eventCtx := WithEventID(ctx, EventID("order.created", order.ID, 1))
+	for i := 0; i < 2; i++ {
+		if err := pub.Publish(eventCtx, "order.created", order); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, m := range readMessages(t, client) {
+		consumer.processMessage(ctx, m)
+	}
+
+	if updater.calls != 1 {
+		t.Errorf("expected UpdateOrderStatus to be called once, got %d", updater.calls)
+	}
+	if ttl := client.TTL(ctx, ProcessedKeyPrefix+EventID("order.created", order.ID, 1)).Val(); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("expected the processed marker to expire within an hour, got %v", ttl)
+	}
+	pending, err := client.XPending(ctx, StreamName, ConsumerGroup).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Count != 0 {
+		t.Errorf("expected the duplicate to be acked, %d pending", pending.Count)
+	}
+}
+
+func TestConsumerDedupIgnoresFailedEvents(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	updater := &countingUpdater{err: errors.New("database unavailable")}
+	consumer := NewConsumer(client, updater, zap.NewNop(), WithDedup(time.Hour), WithRetryPolicy(RetryPolicy{MaxRetries: 5, BaseDelay: time.Minute, MaxDelay: time.Minute}))
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRedisPublisher(client).Publish(WithEventID(ctx, "event-1"), "order.created", &model.Order{ID: "order-1"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range readMessages(t, client) {
+		consumer.processMessage(ctx, m)
+	}
+
+	if client.Exists(ctx, ProcessedKeyPrefix+"event-1").Val() != 0 {
+		t.Error("expected a failed event not to be recorded as processed")
+	}
+}
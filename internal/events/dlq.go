@@ -0,0 +1,106 @@
+package events
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const DeadLetterStream = "orders.dlq"
+
+type DeadLetter struct {
+	ID          string    `json:"id"`
+	MessageID   string    `json:"message_id"`
+	EventID     string    `json:"event_id,omitempty"`
+	Event       string    `json:"event"`
+	Version     int       `json:"version,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at,omitzero"`
+	ContentType string    `json:"content_type,omitempty"`
+	Payload     string    `json:"payload"`
+	Error       string    `json:"error"`
+	RetryCount  int       `json:"retry_count"`
+	FailedAt    time.Time `json:"failed_at"`
+}
+
+func (c *Consumer) deadLetter(ctx context.Context, messageID string, event EventEnvelope, retries int, cause error) error {
+	values := event.values()
+	values["error"] = cause.Error()
+	values["retry_count"] = retries
+	values["message_id"] = messageID
+	values["failed_at"] = c.now().UTC().Format(time.RFC3339Nano)
+	return c.client.XAdd(ctx, &redis.XAddArgs{Stream: DeadLetterStream, Values: values}).Err()
+}
+
+// DeadLetters returns up to count of the oldest dead-lettered messages.
+func (c *Consumer) DeadLetters(ctx context.Context, count int64) ([]DeadLetter, error) {
+	messages, err := c.client.XRangeN(ctx, DeadLetterStream, "-", "+", count).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]DeadLetter, 0, len(messages))
+	for _, m := range messages {
+		letters = append(letters, parseDeadLetter(m))
+	}
+	return letters, nil
+}
+
+// ReplayDeadLetters moves up to count of the oldest dead-lettered messages
+// back onto the main stream with a fresh retry budget.
+func (c *Consumer) ReplayDeadLetters(ctx context.Context, count int64) (int, error) {
+	letters, err := c.DeadLetters(ctx, count)
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for _, l := range letters {
+		err := c.client.XAdd(ctx, streamArgs(l.envelope().values(), c.maxLen)).Err()
+		if err != nil {
+			return replayed, err
+		}
+		if err := c.client.XDel(ctx, DeadLetterStream, l.ID).Err(); err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+func (l DeadLetter) envelope() EventEnvelope {
+	return EventEnvelope{
+		ID:          l.EventID,
+		Type:        l.Event,
+		Version:     l.Version,
+		OccurredAt:  l.OccurredAt,
+		ContentType: l.ContentType,
+		Data:        []byte(l.Payload),
+	}
+}
+
+func parseDeadLetter(m redis.XMessage) DeadLetter {
+	str := func(key string) string {
+		v, _ := m.Values[key].(string)
+		return v
+	}
+	retries, _ := strconv.Atoi(str("retry_count"))
+	failedAt, _ := time.Parse(time.RFC3339Nano, str("failed_at"))
+	// Malformed messages are dead-lettered with whatever envelope fields they
+	// had; missing ones stay empty.
+	envelope, _ := parseEnvelope(m.Values)
+	return DeadLetter{
+		ID:          m.ID,
+		MessageID:   str("message_id"),
+		EventID:     envelope.ID,
+		Event:       envelope.Type,
+		Version:     envelope.Version,
+		OccurredAt:  envelope.OccurredAt,
+		ContentType: envelope.ContentType,
+		Payload:     string(envelope.Data),
+		Error:       str("error"),
+		RetryCount:  retries,
+		FailedAt:    failedAt,
+	}
+}
@@ -0,0 +1,65 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestReplayDeadLetters(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	failedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	consumer := NewConsumer(client, nil, zap.NewNop())
+	consumer.now = func() time.Time { return failedAt }
+
+	for _, id := range []string{"order-1", "order-2"} {
+		if err := consumer.deadLetter(ctx, "1-0", EventEnvelope{Type: "order.created", Data: []byte(`{"id":"` + id + `"}`)}, 5, errors.New("database unavailable")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	letters, err := consumer.DeadLetters(ctx, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("expected 2 dead letters, got %d", len(letters))
+	}
+	first := letters[0]
+	if first.Event != "order.created" || first.Payload != `{"id":"order-1"}` || first.RetryCount != 5 ||
+		first.Error != "database unavailable" || !first.FailedAt.Equal(failedAt) {
+		t.Errorf("unexpected dead letter %+v", first)
+	}
+
+	replayed, err := consumer.ReplayDeadLetters(ctx, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replayed != 1 {
+		t.Fatalf("expected 1 replayed, got %d", replayed)
+	}
+
+	remaining, err := consumer.DeadLetters(ctx, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].Payload != `{"id":"order-2"}` {
+		t.Errorf("expected only order-2 to remain dead-lettered, got %+v", remaining)
+	}
+
+	messages, err := client.XRange(ctx, StreamName, "-", "+").Result()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Values["payload"] != `{"id":"order-1"}` {
+		t.Fatalf("expected replayed message on the main stream, got %+v", messages)
+	}
+	if attempt := messageAttempt(messages[0]); attempt != 0 {
+		t.Errorf("expected replayed message to start with a fresh retry budget, got attempt %d", attempt)
+	}
+}
@@ -0,0 +1,116 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EnvelopeVersion is the schema version of the envelopes this service
+// publishes. Messages written before the envelope was versioned read as
+// version 0.
+const EnvelopeVersion = 1
+
+// EventEnvelope is a single stream event. Each field is stored as its own
+// stream message field (event_id, event, version, occurred_at, content_type
+// and payload), so consumers can read the metadata without decoding Data and
+// Data keeps whatever format the publisher's serializer produced.
+type EventEnvelope struct {
+	// ID identifies the event for deduplication. It is the stable ID from
+	// EventID when the publisher set one with WithEventID, otherwise a random
+	// UUID, and empty only on messages from before the envelope.
+	ID          string
+	Type        string
+	Version     int
+	OccurredAt  time.Time
+	ContentType string
+	Data        []byte
+}
+
+// Decode unmarshals Data with the serializer for its content type.
+func (e EventEnvelope) Decode(v interface{}) error {
+	s, err := SerializerFor(e.ContentType)
+	if err != nil {
+		return err
+	}
+	return s.Unmarshal(e.Data, v)
+}
+
+// values returns the stream message fields of the envelope. Empty optional
+// fields are left out so re-published legacy messages stay as they were.
+func (e EventEnvelope) values() map[string]interface{} {
+	values := map[string]interface{}{
+		"event":   e.Type,
+		"payload": string(e.Data),
+	}
+	if e.ID != "" {
+		values["event_id"] = e.ID
+	}
+	if e.Version > 0 {
+		values["version"] = e.Version
+	}
+	if !e.OccurredAt.IsZero() {
+		values["occurred_at"] = e.OccurredAt.UTC().Format(time.RFC3339Nano)
+	}
+	if e.ContentType != "" {
+		values["content_type"] = e.ContentType
+	}
+	return values
+}
+
+// parseEnvelope reads an envelope from stream message fields. Only event and
+// payload are required.
+func parseEnvelope(values map[string]interface{}) (EventEnvelope, error) {
+	var e EventEnvelope
+	var ok bool
+	if e.Type, ok = values["event"].(string); !ok {
+		return e, errors.New("invalid event type in message")
+	}
+	payload, ok := values["payload"].(string)
+	if !ok {
+		return e, errors.New("invalid payload in message")
+	}
+	e.Data = []byte(payload)
+	e.ID, _ = values["event_id"].(string)
+	e.ContentType, _ = values["content_type"].(string)
+	if v, ok := values["version"].(string); ok {
+		e.Version, _ = strconv.Atoi(v)
+	}
+	if v, ok := values["occurred_at"].(string); ok {
+		e.OccurredAt, _ = time.Parse(time.RFC3339Nano, v)
+	}
+	return e, nil
+}
+
+// newEnvelope builds the envelope for an event published now, taking its ID
+// and occurrence time from ctx when they were set.
+func newEnvelope(ctx context.Context, channel, contentType string, data []byte, now time.Time) EventEnvelope {
+	id := EventIDFromContext(ctx)
+	if id == "" {
+		id = uuid.NewString()
+	}
+	occurredAt, ok := ctx.Value(occurredAtKey{}).(time.Time)
+	if !ok {
+		occurredAt = now
+	}
+	return EventEnvelope{
+		ID:          id,
+		Type:        channel,
+		Version:     EnvelopeVersion,
+		OccurredAt:  occurredAt.UTC(),
+		ContentType: contentType,
+		Data:        data,
+	}
+}
+
+type occurredAtKey struct{}
+
+// WithOccurredAt returns a context that makes publishers stamp the event they
+// publish with t rather than the time of publishing, as for events relayed
+// from the outbox.
+func WithOccurredAt(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, occurredAtKey{}, t)
+}
@@ -0,0 +1,104 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/model"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+func TestPublisherWritesEnvelope(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	publisher := NewRedisPublisher(client)
+	publisher.now = func() time.Time { return now }
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	order := &model.Order{ID: "order-1", Product: "widget", Quantity: 1}
+	if err := publisher.Publish(ctx, "order.created", order); err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.Publish(ctx, "order.created", order); err != nil {
+		t.Fatal(err)
+	}
+	occurred := now.Add(-time.Minute)
+	if _, err := publisher.PublishRaw(WithOccurredAt(WithEventID(ctx, "event-1"), occurred), "order.updated", ContentTypeJSON, []byte(`{"id":"order-1"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := readMessages(t, client)
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+	var envelopes []EventEnvelope
+	for _, m := range messages {
+		e, err := parseEnvelope(m.Values)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		envelopes = append(envelopes, e)
+	}
+
+	first := envelopes[0]
+	if first.Type != "order.created" || first.Version != EnvelopeVersion || !first.OccurredAt.Equal(now) || first.ContentType != ContentTypeJSON {
+		t.Errorf("unexpected envelope %+v", first)
+	}
+	if first.ID == "" || first.ID == envelopes[1].ID {
+		t.Errorf("expected unique event IDs, got %q and %q", first.ID, envelopes[1].ID)
+	}
+	var decoded model.Order
+	if err := first.Decode(&decoded); err != nil || decoded.ID != "order-1" {
+		t.Errorf("expected the order as data, got %+v (%v)", decoded, err)
+	}
+	if envelopes[2].ID != "event-1" || !envelopes[2].OccurredAt.Equal(occurred) {
+		t.Errorf("expected the event ID and time from the context, got %+v", envelopes[2])
+	}
+}
+
+func TestConsumerParsesEnvelope(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	var received []EventEnvelope
+	consumer := NewConsumer(client, nil, zap.NewNop(), WithEventHandler("order.created", func(ctx context.Context, event EventEnvelope) error {
+		received = append(received, event)
+		return nil
+	}))
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRedisPublisher(client).Publish(WithEventID(ctx, "event-1"), "order.created", &model.Order{ID: "order-1"}); err != nil {
+		t.Fatal(err)
+	}
+	// Messages from before the envelope have no ID, version or time.
+	if err := client.XAdd(ctx, &redis.XAddArgs{Stream: StreamName, Values: map[string]interface{}{"event": "order.created", "payload": `{"id":"order-2"}`}}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	// Messages without a payload are dropped.
+	if err := client.XAdd(ctx, &redis.XAddArgs{Stream: StreamName, Values: map[string]interface{}{"event": "order.created"}}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range readMessages(t, client) {
+		consumer.processMessage(ctx, m)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(received))
+	}
+	if received[0].ID != "event-1" || received[0].Version != EnvelopeVersion || received[0].OccurredAt.IsZero() {
+		t.Errorf("unexpected envelope %+v", received[0])
+	}
+	if received[1].ID != "" || received[1].Version != 0 || string(received[1].Data) != `{"id":"order-2"}` {
+		t.Errorf("unexpected legacy envelope %+v", received[1])
+	}
+	if pending, err := client.XPending(ctx, StreamName, ConsumerGroup).Result(); err != nil || pending.Count != 0 {
+		t.Errorf("expected every message to be acked, got %+v (%v)", pending, err)
+	}
+}
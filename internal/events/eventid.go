@@ -0,0 +1,33 @@
+package events
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// eventIDNamespace scopes the name-based UUIDs returned by EventID.
+var eventIDNamespace = uuid.MustParse("6f1c8e5a-3d2b-4c47-9a0e-5b7d2f8c1e34")
+
+// EventID is the stable ID of the event of type channel that describes order
+// orderID at version. Unlike the stream message ID it is the same every time
+// the event is published, relayed, retried or replayed, so consumers can use
+// it to deduplicate.
+func EventID(channel, orderID string, version int64) string {
+	return uuid.NewSHA1(eventIDNamespace, []byte(channel+"\x00"+orderID+"\x00"+strconv.FormatInt(version, 10))).String()
+}
+
+type eventIDKey struct{}
+
+// WithEventID returns a context that makes publishers attach id to the event
+// they publish as the event_id field.
+func WithEventID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, eventIDKey{}, id)
+}
+
+// EventIDFromContext returns the ID set with WithEventID, or "".
+func EventIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(eventIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestEventIDDeterministic(t *testing.T) {
+	id := EventID("order.updated", "order-1", 2)
+	if again := EventID("order.updated", "order-1", 2); again != id {
+		t.Errorf("expected the same event to get the same ID, got %s and %s", id, again)
+	}
+	for name, other := range map[string]string{
+		"version": EventID("order.updated", "order-1", 3),
+		"channel": EventID("order.status_changed", "order-1", 2),
+		"order":   EventID("order.updated", "order-2", 2),
+	} {
+		if other == id {
+			t.Errorf("expected a different %s to give a different ID, got %s for both", name, id)
+		}
+	}
+}
+
+func TestEventIDSurvivesRetryAndReplay(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	var seen []string
+	consumer := NewConsumer(client, nil, zap.NewNop(),
+		WithRetryPolicy(RetryPolicy{MaxRetries: 1, BaseDelay: time.Second, MaxDelay: time.Minute}),
+		WithEventHandler("order.created", func(ctx context.Context, event EventEnvelope) error {
+			seen = append(seen, event.ID)
+			return errors.New("database unavailable")
+		}),
+	)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	consumer.now = func() time.Time { return now }
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	id := EventID("order.created", "order-1", 1)
+	if err := NewRedisPublisher(client).Publish(WithEventID(ctx, id), "order.created", map[string]string{"id": "order-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// First delivery fails and is scheduled for retry; the retry fails and
+	// is dead-lettered; the replay is delivered once more.
+	consumer.processMessage(ctx, readMessages(t, client)[0])
+	now = now.Add(time.Second)
+	if _, err := consumer.requeueDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	consumer.processMessage(ctx, readMessages(t, client)[0])
+	letters, err := consumer.DeadLetters(ctx, 10)
+	if err != nil || len(letters) != 1 || letters[0].EventID != id {
+		t.Fatalf("expected dead letter with event ID %s, got %+v (%v)", id, letters, err)
+	}
+	if _, err := consumer.ReplayDeadLetters(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	consumer.processMessage(ctx, readMessages(t, client)[0])
+
+	if len(seen) != 3 {
+		t.Fatalf("expected 3 deliveries, got %d", len(seen))
+	}
+	for i, got := range seen {
+		if got != id {
+			t.Errorf("delivery %d: expected event ID %s, got %q", i+1, id, got)
+		}
+	}
+}
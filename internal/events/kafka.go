@@ -0,0 +1,522 @@
+package events
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// kafkaVersions are the API versions KafkaClient sends, by request key: the
+// oldest ones that every broker from Kafka 0.11 to 4.x accepts. None of them
+// is a flexible version, so no request or response carries tagged fields.
+var kafkaVersions = map[int16]int16{
+	0:  3, // Produce
+	1:  4, // Fetch
+	2:  1, // ListOffsets
+	3:  4, // Metadata
+	8:  2, // OffsetCommit
+	9:  1, // OffsetFetch
+	10: 1, // FindCoordinator
+	11: 2, // JoinGroup
+	12: 1, // Heartbeat
+	13: 1, // LeaveGroup
+	14: 1, // SyncGroup
+}
+
+const (
+	// kafkaRequestTimeout bounds requests whose context has no deadline.
+	kafkaRequestTimeout = 30 * time.Second
+	kafkaDialTimeout    = 10 * time.Second
+	// kafkaMaxResponseSize guards against allocating for a corrupt length.
+	kafkaMaxResponseSize = 100 << 20
+)
+
+// ErrKafkaClosed is returned for requests on a closed KafkaClient.
+var ErrKafkaClosed = errors.New("kafka client closed")
+
+// KafkaClient is the Kafka client behind KafkaPublisher and KafkaConsumer. It
+// speaks just the requests those two need, over one connection per broker,
+// and caches the partition leaders of the topics it has been asked about.
+type KafkaClient struct {
+	seeds     []string
+	formatter *kmsg.RequestFormatter
+	dialer    net.Dialer
+
+	mu      sync.Mutex
+	closed  bool
+	conns   map[string]*kafkaConn
+	brokers map[int32]string
+	leaders map[string][]int32
+}
+
+// NewKafkaClient returns a client for the cluster reachable through the
+// host:port addresses in brokers. It connects lazily.
+func NewKafkaClient(brokers []string, clientID string) *KafkaClient {
+	return &KafkaClient{
+		seeds:     brokers,
+		formatter: kmsg.NewRequestFormatter(kmsg.FormatterClientID(clientID)),
+		dialer:    net.Dialer{Timeout: kafkaDialTimeout},
+		conns:     make(map[string]*kafkaConn),
+		brokers:   make(map[int32]string),
+		leaders:   make(map[string][]int32),
+	}
+}
+
+// Close closes the broker connections, failing requests still in flight.
+func (c *KafkaClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	var errs []error
+	for addr, conn := range c.conns {
+		errs = append(errs, conn.conn.Close())
+		delete(c.conns, addr)
+	}
+	return errors.Join(errs...)
+}
+
+// request sends req to the broker at addr and returns its response. Error
+// codes in the response are left to the caller.
+func (c *KafkaClient) request(ctx context.Context, addr string, req kmsg.Request) (kmsg.Response, error) {
+	req.SetVersion(kafkaVersions[req.Key()])
+	conn, err := c.conn(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := conn.roundTrip(ctx, c.formatter, req)
+	if err != nil {
+		// The connection may be halfway through a response, so it cannot be
+		// used again.
+		c.drop(addr, conn)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("kafka %s: %w", addr, err)
+	}
+	return resp, nil
+}
+
+// requestAny sends req to any broker that answers, trying the known brokers
+// before the seeds.
+func (c *KafkaClient) requestAny(ctx context.Context, req kmsg.Request) (kmsg.Response, error) {
+	c.mu.Lock()
+	addrs := make([]string, 0, len(c.brokers)+len(c.seeds))
+	for _, addr := range c.brokers {
+		addrs = append(addrs, addr)
+	}
+	c.mu.Unlock()
+	addrs = append(addrs, c.seeds...)
+
+	var errs []error
+	for _, addr := range addrs {
+		resp, err := c.request(ctx, addr, req)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("kafka: no brokers configured")
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (c *KafkaClient) conn(ctx context.Context, addr string) (*kafkaConn, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrKafkaClosed
+	}
+	if conn, ok := c.conns[addr]; ok {
+		c.mu.Unlock()
+		return conn, nil
+	}
+	c.mu.Unlock()
+
+	nc, err := c.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("kafka %s: %w", addr, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		_ = nc.Close()
+		return nil, ErrKafkaClosed
+	}
+	if conn, ok := c.conns[addr]; ok {
+		// Another request connected first.
+		_ = nc.Close()
+		return conn, nil
+	}
+	conn := &kafkaConn{conn: nc}
+	c.conns[addr] = conn
+	return conn, nil
+}
+
+// drop closes a connection that failed and forgets it.
+func (c *KafkaClient) drop(addr string, conn *kafkaConn) {
+	c.mu.Lock()
+	if c.conns[addr] == conn {
+		delete(c.conns, addr)
+	}
+	c.mu.Unlock()
+	_ = conn.conn.Close()
+}
+
+// kafkaConn is a broker connection. Brokers answer the requests on a
+// connection in order; holding mu for a whole round trip keeps one request
+// in flight so that answers cannot be mixed up.
+type kafkaConn struct {
+	mu          sync.Mutex
+	conn        net.Conn
+	correlation int32
+}
+
+func (k *kafkaConn) roundTrip(ctx context.Context, formatter *kmsg.RequestFormatter, req kmsg.Request) (kmsg.Response, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(kafkaRequestTimeout)
+	}
+	if err := k.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	// Cancelling ctx unblocks the read or write in progress. Waiting for the
+	// callback keeps it from cutting short the next request's deadline.
+	cancelled := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		_ = k.conn.SetDeadline(time.Now())
+		close(cancelled)
+	})
+	defer func() {
+		if !stop() {
+			<-cancelled
+		}
+	}()
+
+	k.correlation++
+	if _, err := k.conn.Write(formatter.AppendRequest(nil, req, k.correlation)); err != nil {
+		return nil, err
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(k.conn, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n < 4 || n > kafkaMaxResponseSize {
+		return nil, fmt.Errorf("invalid response size %d", n)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(k.conn, body); err != nil {
+		return nil, err
+	}
+	if id := int32(binary.BigEndian.Uint32(body)); id != k.correlation {
+		return nil, fmt.Errorf("response has correlation id %d, expected %d", id, k.correlation)
+	}
+	resp := req.ResponseKind()
+	resp.SetVersion(req.GetVersion())
+	if err := resp.ReadFrom(body[4:]); err != nil {
+		return nil, fmt.Errorf("decode response to request %d: %w", req.Key(), err)
+	}
+	return resp, nil
+}
+
+// refreshMetadata loads the brokers and the partition leaders of topic,
+// creating the topic if the cluster auto-creates topics.
+func (c *KafkaClient) refreshMetadata(ctx context.Context, topic string) error {
+	req := kmsg.NewPtrMetadataRequest()
+	t := kmsg.NewMetadataRequestTopic()
+	t.Topic = kmsg.StringPtr(topic)
+	req.Topics = append(req.Topics, t)
+	req.AllowAutoTopicCreation = true
+	resp, err := c.requestAny(ctx, req)
+	if err != nil {
+		return err
+	}
+	meta := resp.(*kmsg.MetadataResponse)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, b := range meta.Brokers {
+		c.brokers[b.NodeID] = net.JoinHostPort(b.Host, strconv.Itoa(int(b.Port)))
+	}
+	for _, t := range meta.Topics {
+		if t.Topic == nil || *t.Topic != topic {
+			continue
+		}
+		if err := kerr.ErrorForCode(t.ErrorCode); err != nil {
+			return fmt.Errorf("kafka: metadata for topic %s: %w", topic, err)
+		}
+		leaders := make([]int32, len(t.Partitions))
+		for _, p := range t.Partitions {
+			if p.Partition < 0 || int(p.Partition) >= len(leaders) {
+				return fmt.Errorf("kafka: metadata for topic %s has partition %d of %d", topic, p.Partition, len(leaders))
+			}
+			leaders[p.Partition] = p.Leader
+		}
+		c.leaders[topic] = leaders
+		return nil
+	}
+	return fmt.Errorf("kafka: metadata for topic %s: %w", topic, kerr.UnknownTopicOrPartition)
+}
+
+// partitions returns the leaders of the partitions of topic, by partition.
+func (c *KafkaClient) partitions(ctx context.Context, topic string) ([]int32, error) {
+	c.mu.Lock()
+	leaders, ok := c.leaders[topic]
+	c.mu.Unlock()
+	if ok {
+		return leaders, nil
+	}
+	if err := c.refreshMetadata(ctx, topic); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.leaders[topic], nil
+}
+
+// leader returns the address of the broker leading partition of topic.
+func (c *KafkaClient) leader(ctx context.Context, topic string, partition int32) (string, error) {
+	leaders, err := c.partitions(ctx, topic)
+	if err != nil {
+		return "", err
+	}
+	if partition < 0 || int(partition) >= len(leaders) {
+		return "", fmt.Errorf("kafka: topic %s has no partition %d", topic, partition)
+	}
+	c.mu.Lock()
+	addr, ok := c.brokers[leaders[partition]]
+	c.mu.Unlock()
+	if !ok {
+		c.forget(topic)
+		return "", fmt.Errorf("kafka: partition %d of topic %s: %w", partition, topic, kerr.LeaderNotAvailable)
+	}
+	return addr, nil
+}
+
+// forget drops the cached leaders of topic after a broker said they are out
+// of date, so that the next request loads them again.
+func (c *KafkaClient) forget(topic string) {
+	c.mu.Lock()
+	delete(c.leaders, topic)
+	c.mu.Unlock()
+}
+
+// staleMetadata reports whether err means the request went to a broker that
+// no longer leads the partition.
+func staleMetadata(err error) bool {
+	return errors.Is(err, kerr.NotLeaderForPartition) ||
+		errors.Is(err, kerr.LeaderNotAvailable) ||
+		errors.Is(err, kerr.UnknownTopicOrPartition) ||
+		errors.Is(err, kerr.FencedLeaderEpoch) ||
+		errors.Is(err, kerr.UnknownLeaderEpoch)
+}
+
+// coordinator returns the address of the broker coordinating group.
+func (c *KafkaClient) coordinator(ctx context.Context, group string) (string, error) {
+	req := kmsg.NewPtrFindCoordinatorRequest()
+	req.CoordinatorKey = group
+	resp, err := c.requestAny(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	r := resp.(*kmsg.FindCoordinatorResponse)
+	if err := kerr.ErrorForCode(r.ErrorCode); err != nil {
+		return "", fmt.Errorf("kafka: find coordinator of group %s: %w", group, err)
+	}
+	return net.JoinHostPort(r.Host, strconv.Itoa(int(r.Port))), nil
+}
+
+// produce appends records to partition of topic, waiting for every in-sync
+// replica to have them, and returns the offset of the first.
+func (c *KafkaClient) produce(ctx context.Context, topic string, partition int32, records []kmsg.Record) (int64, error) {
+	addr, err := c.leader(ctx, topic, partition)
+	if err != nil {
+		return 0, err
+	}
+	req := kmsg.NewPtrProduceRequest()
+	req.Acks = -1
+	p := kmsg.NewProduceRequestTopicPartition()
+	p.Partition = partition
+	p.Records = appendRecordBatch(nil, time.Now(), records)
+	t := kmsg.NewProduceRequestTopic()
+	t.Topic = topic
+	t.Partitions = append(t.Partitions, p)
+	req.Topics = append(req.Topics, t)
+
+	resp, err := c.request(ctx, addr, req)
+	if err != nil {
+		return 0, err
+	}
+	for _, t := range resp.(*kmsg.ProduceResponse).Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				if staleMetadata(err) {
+					c.forget(topic)
+				}
+				return 0, fmt.Errorf("kafka: produce to %s/%d: %w", topic, partition, err)
+			}
+			return p.BaseOffset, nil
+		}
+	}
+	return 0, fmt.Errorf("kafka: produce to %s/%d: empty response", topic, partition)
+}
+
+// kafkaRecord is a record read from a partition.
+type kafkaRecord struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   []kmsg.Header
+}
+
+// header returns the value of the last header named key.
+func (r kafkaRecord) header(key string) (string, bool) {
+	for i := len(r.Headers) - 1; i >= 0; i-- {
+		if r.Headers[i].Key == key {
+			return string(r.Headers[i].Value), true
+		}
+	}
+	return "", false
+}
+
+// Record batch layout (magic 2): the CRC sits at byte 17 and covers
+// everything from the attributes at byte 21 to the end of the batch. Length
+// counts the bytes after itself, which is the 49 fixed ones plus the records.
+const (
+	batchCRCOffset        = 17
+	batchAttributesOffset = 21
+	batchHeaderAfterLen   = 49
+
+	batchCompressionMask = 0x07
+	batchCompressionGzip = 1
+	batchControlFlag     = 0x20
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// appendRecordBatch appends records to dst as one uncompressed record batch
+// stamped with now.
+func appendRecordBatch(dst []byte, now time.Time, records []kmsg.Record) []byte {
+	var body []byte
+	for i := range records {
+		r := records[i]
+		r.OffsetDelta = int32(i)
+		r.TimestampDelta64 = 0
+		// Length is a varint of everything after it; encoding with a zero
+		// Length, which takes one byte, measures the rest.
+		r.Length = 0
+		r.Length = int32(len(r.AppendTo(nil)) - 1)
+		body = r.AppendTo(body)
+	}
+
+	batch := kmsg.NewRecordBatch()
+	batch.Length = int32(batchHeaderAfterLen + len(body))
+	batch.PartitionLeaderEpoch = -1
+	batch.Magic = 2
+	batch.LastOffsetDelta = int32(len(records) - 1)
+	batch.FirstTimestamp = now.UnixMilli()
+	batch.MaxTimestamp = batch.FirstTimestamp
+	batch.ProducerID = -1
+	batch.ProducerEpoch = -1
+	batch.FirstSequence = -1
+	batch.NumRecords = int32(len(records))
+	batch.Records = body
+
+	start := len(dst)
+	dst = batch.AppendTo(dst)
+	crc := crc32.Checksum(dst[start+batchAttributesOffset:], castagnoli)
+	binary.BigEndian.PutUint32(dst[start+batchCRCOffset:], crc)
+	return dst
+}
+
+// readRecordBatches returns the records in the record batches of a fetch
+// response from offset on. Transaction markers are skipped, and a batch cut
+// off at the end of the response is left for the next fetch.
+func readRecordBatches(topic string, partition int32, offset int64, data []byte) ([]kafkaRecord, error) {
+	var records []kafkaRecord
+	for len(data) >= 17 {
+		size := 12 + int(int32(binary.BigEndian.Uint32(data[8:12])))
+		if size < 12+batchHeaderAfterLen || size > len(data) {
+			break
+		}
+		if magic := data[16]; magic != 2 {
+			return records, fmt.Errorf("kafka: unsupported message format %d on %s/%d", magic, topic, partition)
+		}
+		raw := data[:size]
+		data = data[size:]
+
+		var batch kmsg.RecordBatch
+		if err := batch.ReadFrom(raw); err != nil {
+			return records, fmt.Errorf("kafka: decode record batch on %s/%d: %w", topic, partition, err)
+		}
+		if crc32.Checksum(raw[batchAttributesOffset:], castagnoli) != uint32(batch.CRC) {
+			return records, fmt.Errorf("kafka: corrupt record batch at offset %d on %s/%d", batch.FirstOffset, topic, partition)
+		}
+		if batch.Attributes&batchControlFlag != 0 {
+			continue
+		}
+		body := batch.Records
+		switch codec := batch.Attributes & batchCompressionMask; codec {
+		case 0:
+		case batchCompressionGzip:
+			zr, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				return records, fmt.Errorf("kafka: decompress record batch on %s/%d: %w", topic, partition, err)
+			}
+			if body, err = io.ReadAll(zr); err != nil {
+				return records, fmt.Errorf("kafka: decompress record batch on %s/%d: %w", topic, partition, err)
+			}
+		default:
+			return records, fmt.Errorf("kafka: unsupported compression codec %d on %s/%d", codec, topic, partition)
+		}
+
+		for i := int32(0); i < batch.NumRecords; i++ {
+			length, n := binary.Varint(body)
+			if n <= 0 || length < 0 || int(length) > len(body)-n {
+				return records, fmt.Errorf("kafka: truncated record in batch at offset %d on %s/%d", batch.FirstOffset, topic, partition)
+			}
+			var r kmsg.Record
+			if err := r.ReadFrom(body[:n+int(length)]); err != nil {
+				return records, fmt.Errorf("kafka: decode record in batch at offset %d on %s/%d: %w", batch.FirstOffset, topic, partition, err)
+			}
+			body = body[n+int(length):]
+
+			// A fetch starts at the batch holding offset, which may begin
+			// before it.
+			if off := batch.FirstOffset + int64(r.OffsetDelta); off >= offset {
+				records = append(records, kafkaRecord{
+					Topic:     topic,
+					Partition: partition,
+					Offset:    off,
+					Key:       r.Key,
+					Value:     r.Value,
+					Headers:   r.Headers,
+				})
+			}
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,638 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/metrics"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultKafkaSessionTimeout    = 30 * time.Second
+	DefaultKafkaHeartbeatInterval = 3 * time.Second
+	DefaultKafkaRebalanceTimeout  = time.Minute
+	DefaultKafkaFetchWait         = 500 * time.Millisecond
+	DefaultKafkaRetryDelay        = time.Second
+
+	kafkaFetchMaxBytes     = 50 << 20
+	kafkaPartitionMaxBytes = 1 << 20
+	kafkaLeaveTimeout      = 5 * time.Second
+	// kafkaEarliestOffset asks ListOffsets for the oldest offset kept.
+	kafkaEarliestOffset = -2
+)
+
+// KafkaDeadLetterSuffix names the dead letter topic of a topic by default.
+const KafkaDeadLetterSuffix = ".dlq"
+
+// KafkaConsumer reads events from a Kafka topic as a member of a consumer
+// group and hands them to the handlers of a Consumer, with its inline
+// retries and deduplication. The records of a partition are handled one at
+// a time in order, and the group's offsets are committed after each fetch.
+// An event that still fails after the inline retries, or that is malformed,
+// is produced to the dead letter topic with its error and skipped, so that
+// it does not hold up its partition.
+//
+// Consumer.Wait waits for Run to drain on shutdown as it does for Subscribe.
+type KafkaConsumer struct {
+	client   *KafkaClient
+	topic    string
+	group    string
+	consumer *Consumer
+	dlq      *KafkaPublisher
+	log      *zap.Logger
+	now      func() time.Time
+
+	sessionTimeout    time.Duration
+	heartbeatInterval time.Duration
+	rebalanceTimeout  time.Duration
+	fetchWait         time.Duration
+	retryDelay        time.Duration
+
+	// memberID is the ID the coordinator gave this member, kept so that a
+	// rejoin does not count as a new member.
+	memberID string
+}
+
+type KafkaConsumerOption func(*KafkaConsumer)
+
+// WithKafkaDeadLetterTopic sets the topic failed events are moved to. The
+// default is the consumed topic with KafkaDeadLetterSuffix appended.
+func WithKafkaDeadLetterTopic(topic string) KafkaConsumerOption {
+	return func(k *KafkaConsumer) {
+		k.dlq = NewKafkaPublisher(k.client, topic)
+	}
+}
+
+// WithKafkaSession sets how long the coordinator waits for a heartbeat
+// before it drops the member, and how often heartbeats are sent.
+func WithKafkaSession(timeout, heartbeat time.Duration) KafkaConsumerOption {
+	return func(k *KafkaConsumer) {
+		k.sessionTimeout = timeout
+		k.heartbeatInterval = heartbeat
+	}
+}
+
+func NewKafkaConsumer(client *KafkaClient, topic, group string, consumer *Consumer, log *zap.Logger, opts ...KafkaConsumerOption) *KafkaConsumer {
+	k := &KafkaConsumer{
+		client:   client,
+		topic:    topic,
+		group:    group,
+		consumer: consumer,
+		dlq:      NewKafkaPublisher(client, topic+KafkaDeadLetterSuffix),
+		log:      log,
+		now:      time.Now,
+
+		sessionTimeout:    DefaultKafkaSessionTimeout,
+		heartbeatInterval: DefaultKafkaHeartbeatInterval,
+		rebalanceTimeout:  DefaultKafkaRebalanceTimeout,
+		fetchWait:         DefaultKafkaFetchWait,
+		retryDelay:        DefaultKafkaRetryDelay,
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k
+}
+
+// kafkaSession is one generation of group membership.
+type kafkaSession struct {
+	coordinator string
+	memberID    string
+	generation  int32
+	partitions  []int32
+}
+
+// Run joins the group and consumes until ctx is cancelled, rejoining
+// whenever the group rebalances, and leaves the group on the way out.
+// Records that were already fetched are handled and committed after
+// cancellation, unless Consumer.Wait gives up on draining first.
+func (k *KafkaConsumer) Run(ctx context.Context) {
+	k.consumer.running.Add(1)
+	defer k.consumer.running.Done()
+	work, stop := k.consumer.detach(ctx)
+	defer stop()
+
+	for ctx.Err() == nil {
+		session, err := k.join(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				k.log.Error("kafka: failed to join consumer group", zap.String("group", k.group), zap.Error(err))
+				sleep(ctx, k.retryDelay)
+			}
+			continue
+		}
+		k.consume(ctx, work, session)
+	}
+	k.leave(ctx)
+}
+
+// join joins the group and returns the partitions it was assigned. The
+// member that the coordinator picks as leader assigns them for everyone.
+func (k *KafkaConsumer) join(ctx context.Context) (*kafkaSession, error) {
+	coordinator, err := k.client.coordinator(ctx, k.group)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := kmsg.NewConsumerMemberMetadata()
+	meta.Topics = []string{k.topic}
+	protocol := kmsg.NewJoinGroupRequestProtocol()
+	protocol.Name = "range"
+	protocol.Metadata = meta.AppendTo(nil)
+	req := kmsg.NewPtrJoinGroupRequest()
+	req.Group = k.group
+	req.SessionTimeoutMillis = int32(k.sessionTimeout.Milliseconds())
+	req.RebalanceTimeoutMillis = int32(k.rebalanceTimeout.Milliseconds())
+	req.MemberID = k.memberID
+	req.ProtocolType = "consumer"
+	req.Protocols = append(req.Protocols, protocol)
+
+	// The coordinator answers once every member has rejoined, which may take
+	// up to the rebalance timeout.
+	joinCtx, cancel := context.WithTimeout(ctx, k.rebalanceTimeout+kafkaRequestTimeout)
+	defer cancel()
+	resp, err := k.client.request(joinCtx, coordinator, req)
+	if err != nil {
+		return nil, err
+	}
+	joined := resp.(*kmsg.JoinGroupResponse)
+	if err := kerr.ErrorForCode(joined.ErrorCode); err != nil {
+		if errors.Is(err, kerr.UnknownMemberID) {
+			k.memberID = ""
+		}
+		return nil, fmt.Errorf("kafka: join group %s: %w", k.group, err)
+	}
+	k.memberID = joined.MemberID
+
+	sync := kmsg.NewPtrSyncGroupRequest()
+	sync.Group = k.group
+	sync.Generation = joined.Generation
+	sync.MemberID = joined.MemberID
+	if joined.LeaderID == joined.MemberID {
+		if sync.GroupAssignment, err = k.assign(ctx, joined.Members); err != nil {
+			return nil, err
+		}
+	}
+	resp, err = k.client.request(joinCtx, coordinator, sync)
+	if err != nil {
+		return nil, err
+	}
+	synced := resp.(*kmsg.SyncGroupResponse)
+	if err := kerr.ErrorForCode(synced.ErrorCode); err != nil {
+		return nil, fmt.Errorf("kafka: sync group %s: %w", k.group, err)
+	}
+
+	session := &kafkaSession{coordinator: coordinator, memberID: joined.MemberID, generation: joined.Generation}
+	if len(synced.MemberAssignment) == 0 {
+		return session, nil
+	}
+	var assignment kmsg.ConsumerMemberAssignment
+	if err := assignment.ReadFrom(synced.MemberAssignment); err != nil {
+		return nil, fmt.Errorf("kafka: decode assignment of group %s: %w", k.group, err)
+	}
+	for _, t := range assignment.Topics {
+		if t.Topic == k.topic {
+			session.partitions = append(session.partitions, t.Partitions...)
+		}
+	}
+	return session, nil
+}
+
+// assign splits the partitions of the topic between members the way Kafka's
+// range assignor does: in member ID order, each takes a run of consecutive
+// partitions, and when they do not divide evenly the first ones take one
+// more.
+func (k *KafkaConsumer) assign(ctx context.Context, members []kmsg.JoinGroupResponseMember) ([]kmsg.SyncGroupRequestGroupAssignment, error) {
+	// Partitions may have been added since the last rebalance.
+	if err := k.client.refreshMetadata(ctx, k.topic); err != nil {
+		return nil, err
+	}
+	leaders, err := k.client.partitions(ctx, k.topic)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.MemberID)
+	}
+	sort.Strings(ids)
+
+	assignments := make([]kmsg.SyncGroupRequestGroupAssignment, 0, len(ids))
+	next := 0
+	for i, id := range ids {
+		count := len(leaders) / len(ids)
+		if i < len(leaders)%len(ids) {
+			count++
+		}
+		t := kmsg.NewConsumerMemberAssignmentTopic()
+		t.Topic = k.topic
+		for p := next; p < next+count; p++ {
+			t.Partitions = append(t.Partitions, int32(p))
+		}
+		next += count
+
+		assignment := kmsg.NewConsumerMemberAssignment()
+		assignment.Topics = append(assignment.Topics, t)
+		a := kmsg.NewSyncGroupRequestGroupAssignment()
+		a.MemberID = id
+		a.MemberAssignment = assignment.AppendTo(nil)
+		assignments = append(assignments, a)
+	}
+	return assignments, nil
+}
+
+// consume fetches and handles the records of the session's partitions until
+// ctx is cancelled or the group rebalances. Records are handled with work.
+func (k *KafkaConsumer) consume(ctx, work context.Context, s *kafkaSession) {
+	k.log.Info("kafka: joined consumer group",
+		zap.String("group", k.group),
+		zap.Int32("generation", s.generation),
+		zap.Int32s("partitions", s.partitions),
+	)
+	sessionCtx, cancel := context.WithCancel(ctx)
+	heartbeats := make(chan struct{})
+	go func() {
+		defer close(heartbeats)
+		k.heartbeat(sessionCtx, cancel, s)
+	}()
+	defer func() {
+		cancel()
+		<-heartbeats
+	}()
+
+	offsets, err := k.committed(sessionCtx, s)
+	if err != nil {
+		if sessionCtx.Err() == nil {
+			k.log.Error("kafka: failed to load committed offsets", zap.String("group", k.group), zap.Error(err))
+			sleep(sessionCtx, k.retryDelay)
+		}
+		return
+	}
+
+	for sessionCtx.Err() == nil {
+		if len(offsets) == 0 {
+			// More members than partitions: this one idles until the next
+			// rebalance.
+			sleep(sessionCtx, k.fetchWait)
+			continue
+		}
+		records, fetchErr := k.fetch(sessionCtx, offsets)
+		if len(records) > 0 {
+			if !k.handle(work, s, offsets, records) {
+				return
+			}
+		}
+		if fetchErr != nil && sessionCtx.Err() == nil {
+			k.log.Error("kafka: failed to fetch", zap.String("topic", k.topic), zap.Error(fetchErr))
+			sleep(sessionCtx, k.retryDelay)
+		}
+	}
+}
+
+// handle processes records in order, advancing offsets past each one, and
+// commits the new offsets. It reports false if the group must be rejoined
+// or the consumer is shutting down past its drain deadline.
+func (k *KafkaConsumer) handle(ctx context.Context, s *kafkaSession, offsets map[int32]int64, records []kafkaRecord) bool {
+	handled := true
+	for _, r := range records {
+		if !k.process(ctx, r) {
+			handled = false
+			break
+		}
+		offsets[r.Partition] = r.Offset + 1
+	}
+	if ctx.Err() != nil {
+		// Past the drain deadline: what was not committed is handled again
+		// after the restart.
+		return false
+	}
+	if err := k.commit(ctx, s, offsets); err != nil {
+		// The next owner of the partitions starts from the last commit, so
+		// these records are handled again.
+		k.log.Error("kafka: failed to commit offsets", zap.String("group", k.group), zap.Error(err))
+		return false
+	}
+	return handled
+}
+
+// process handles one record. It reports false, leaving the record to be
+// fetched again, only when ctx was cancelled before the record was handled
+// or dead-lettered.
+func (k *KafkaConsumer) process(ctx context.Context, r kafkaRecord) bool {
+	messageID := strconv.Itoa(int(r.Partition)) + "-" + strconv.FormatInt(r.Offset, 10)
+	evt, err := recordEnvelope(r)
+	if err != nil {
+		k.log.Warn("malformed message", zap.String("message_id", messageID), zap.Error(err))
+		return k.deadLetter(ctx, r, messageID, malformed(err))
+	}
+	event := evt.Type
+	c := k.consumer
+
+	k.log.Info("event received", zap.String("event", event), zap.String("message_id", messageID), zap.String("event_id", evt.ID), zap.Int("version", evt.Version))
+
+	if c.alreadyProcessed(ctx, evt) {
+		k.log.Info("skipping duplicate event", zap.String("event", event), zap.String("message_id", messageID), zap.String("event_id", evt.ID))
+		metrics.EventsConsumed.WithLabelValues(event, metrics.OutcomeDuplicate).Inc()
+		return true
+	}
+
+	msgCtx := logger.WithContext(ctx, k.log.With(zap.String("event", event), zap.String("message_id", messageID)))
+
+	if handler, ok := c.handlers[event]; ok {
+		err = c.runWithRetry(msgCtx, handler, evt)
+	} else {
+		k.log.Debug("no handler for event", zap.String("event", event), zap.String("message_id", messageID))
+	}
+	if err != nil && ctx.Err() != nil {
+		k.log.Warn("leaving message uncommitted on shutdown", zap.String("message_id", messageID), zap.Error(err))
+		return false
+	}
+	metrics.EventsConsumed.WithLabelValues(event, metrics.Outcome(err)).Inc()
+
+	if err != nil {
+		return k.deadLetter(ctx, r, messageID, err)
+	}
+	c.markProcessed(ctx, evt)
+	return true
+}
+
+// deadLetter produces r to the dead letter topic with its error, the
+// message ID it had and when it failed. It keeps trying until that works,
+// since skipping the record without a copy would lose the event, and
+// reports false only if ctx was cancelled first.
+func (k *KafkaConsumer) deadLetter(ctx context.Context, r kafkaRecord, messageID string, cause error) bool {
+	record := kmsg.Record{Key: r.Key, Value: r.Value, Headers: append(slices.Clip(r.Headers),
+		kmsg.Header{Key: "error", Value: []byte(cause.Error())},
+		kmsg.Header{Key: "message_id", Value: []byte(messageID)},
+		kmsg.Header{Key: "failed_at", Value: []byte(k.now().UTC().Format(time.RFC3339Nano))},
+	)}
+	for {
+		_, err := k.dlq.produce(ctx, record)
+		if err == nil {
+			k.log.Warn("message dead-lettered", zap.String("message_id", messageID), zap.String("topic", k.dlq.topic), zap.Error(cause))
+			return true
+		}
+		k.log.Error("kafka: failed to dead-letter message", zap.String("message_id", messageID), zap.Error(err))
+		if !sleep(ctx, k.retryDelay) {
+			return false
+		}
+	}
+}
+
+// heartbeat keeps the session alive until ctx is done. When the coordinator
+// starts a rebalance or no longer accepts the session, it calls cancel so
+// that the consumer rejoins. Other failures are retried: the coordinator
+// only drops the member once the session timeout passes without one.
+func (k *KafkaConsumer) heartbeat(ctx context.Context, cancel context.CancelFunc, s *kafkaSession) {
+	ticker := time.NewTicker(k.heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		req := kmsg.NewPtrHeartbeatRequest()
+		req.Group = k.group
+		req.Generation = s.generation
+		req.MemberID = s.memberID
+		resp, err := k.client.request(ctx, s.coordinator, req)
+		if err == nil {
+			err = kerr.ErrorForCode(resp.(*kmsg.HeartbeatResponse).ErrorCode)
+		}
+		switch {
+		case err == nil, ctx.Err() != nil:
+		case errors.Is(err, kerr.RebalanceInProgress):
+			k.log.Info("kafka: consumer group is rebalancing", zap.String("group", k.group))
+			cancel()
+			return
+		case errors.Is(err, kerr.UnknownMemberID), errors.Is(err, kerr.IllegalGeneration), errors.Is(err, kerr.NotCoordinator):
+			k.log.Warn("kafka: consumer group session ended", zap.String("group", k.group), zap.Error(err))
+			cancel()
+			return
+		default:
+			k.log.Warn("kafka: heartbeat failed", zap.String("group", k.group), zap.Error(err))
+		}
+	}
+}
+
+// committed returns the offsets to resume the session's partitions from:
+// the group's committed offsets, or the earliest kept for partitions it has
+// not committed yet.
+func (k *KafkaConsumer) committed(ctx context.Context, s *kafkaSession) (map[int32]int64, error) {
+	offsets := make(map[int32]int64, len(s.partitions))
+	if len(s.partitions) == 0 {
+		return offsets, nil
+	}
+
+	req := kmsg.NewPtrOffsetFetchRequest()
+	req.Group = k.group
+	t := kmsg.NewOffsetFetchRequestTopic()
+	t.Topic = k.topic
+	t.Partitions = s.partitions
+	req.Topics = append(req.Topics, t)
+	resp, err := k.client.request(ctx, s.coordinator, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range resp.(*kmsg.OffsetFetchResponse).Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return nil, fmt.Errorf("kafka: committed offset of %s/%d: %w", k.topic, p.Partition, err)
+			}
+			if p.Offset >= 0 {
+				offsets[p.Partition] = p.Offset
+			}
+		}
+	}
+	for _, p := range s.partitions {
+		if _, ok := offsets[p]; ok {
+			continue
+		}
+		if offsets[p], err = k.earliest(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+	return offsets, nil
+}
+
+// earliest returns the oldest offset partition still has.
+func (k *KafkaConsumer) earliest(ctx context.Context, partition int32) (int64, error) {
+	addr, err := k.client.leader(ctx, k.topic, partition)
+	if err != nil {
+		return 0, err
+	}
+	req := kmsg.NewPtrListOffsetsRequest()
+	t := kmsg.NewListOffsetsRequestTopic()
+	t.Topic = k.topic
+	p := kmsg.NewListOffsetsRequestTopicPartition()
+	p.Partition = partition
+	p.Timestamp = kafkaEarliestOffset
+	t.Partitions = append(t.Partitions, p)
+	req.Topics = append(req.Topics, t)
+	resp, err := k.client.request(ctx, addr, req)
+	if err != nil {
+		return 0, err
+	}
+	for _, t := range resp.(*kmsg.ListOffsetsResponse).Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				if staleMetadata(err) {
+					k.client.forget(k.topic)
+				}
+				return 0, fmt.Errorf("kafka: earliest offset of %s/%d: %w", k.topic, partition, err)
+			}
+			return p.Offset, nil
+		}
+	}
+	return 0, fmt.Errorf("kafka: earliest offset of %s/%d: empty response", k.topic, partition)
+}
+
+// fetch reads from every partition in offsets, with one request per leader,
+// and returns the records by partition in offset order. A partition whose
+// offset is out of range, e.g. because retention deleted the records, is
+// moved to its earliest offset. Records read before a failure are returned
+// along with the error.
+func (k *KafkaConsumer) fetch(ctx context.Context, offsets map[int32]int64) ([]kafkaRecord, error) {
+	byLeader := make(map[string][]int32)
+	for p := range offsets {
+		addr, err := k.client.leader(ctx, k.topic, p)
+		if err != nil {
+			return nil, err
+		}
+		byLeader[addr] = append(byLeader[addr], p)
+	}
+
+	var records []kafkaRecord
+	for addr, partitions := range byLeader {
+		slices.Sort(partitions)
+		req := kmsg.NewPtrFetchRequest()
+		req.MaxWaitMillis = int32(k.fetchWait.Milliseconds())
+		req.MinBytes = 1
+		req.MaxBytes = kafkaFetchMaxBytes
+		t := kmsg.NewFetchRequestTopic()
+		t.Topic = k.topic
+		for _, p := range partitions {
+			fp := kmsg.NewFetchRequestTopicPartition()
+			fp.Partition = p
+			fp.FetchOffset = offsets[p]
+			fp.PartitionMaxBytes = kafkaPartitionMaxBytes
+			t.Partitions = append(t.Partitions, fp)
+		}
+		req.Topics = append(req.Topics, t)
+
+		resp, err := k.client.request(ctx, addr, req)
+		if err != nil {
+			return records, err
+		}
+		for _, t := range resp.(*kmsg.FetchResponse).Topics {
+			for _, p := range t.Partitions {
+				err := kerr.ErrorForCode(p.ErrorCode)
+				switch {
+				case err == nil:
+				case errors.Is(err, kerr.OffsetOutOfRange):
+					earliest, err := k.earliest(ctx, p.Partition)
+					if err != nil {
+						return records, err
+					}
+					k.log.Warn("kafka: offset out of range, resuming from the earliest",
+						zap.Int32("partition", p.Partition), zap.Int64("offset", offsets[p.Partition]), zap.Int64("earliest", earliest))
+					offsets[p.Partition] = earliest
+					continue
+				case staleMetadata(err):
+					k.client.forget(k.topic)
+					continue
+				default:
+					return records, fmt.Errorf("kafka: fetch %s/%d: %w", k.topic, p.Partition, err)
+				}
+
+				batch, err := readRecordBatches(k.topic, p.Partition, offsets[p.Partition], p.RecordBatches)
+				records = append(records, batch...)
+				if err != nil {
+					return records, err
+				}
+			}
+		}
+	}
+	return records, nil
+}
+
+// commit stores offsets as the group's position in the session's
+// partitions.
+func (k *KafkaConsumer) commit(ctx context.Context, s *kafkaSession, offsets map[int32]int64) error {
+	req := kmsg.NewPtrOffsetCommitRequest()
+	req.Group = k.group
+	req.Generation = s.generation
+	req.MemberID = s.memberID
+	t := kmsg.NewOffsetCommitRequestTopic()
+	t.Topic = k.topic
+	for partition, offset := range offsets {
+		p := kmsg.NewOffsetCommitRequestTopicPartition()
+		p.Partition = partition
+		p.Offset = offset
+		t.Partitions = append(t.Partitions, p)
+	}
+	req.Topics = append(req.Topics, t)
+
+	resp, err := k.client.request(ctx, s.coordinator, req)
+	if err != nil {
+		return err
+	}
+	for _, t := range resp.(*kmsg.OffsetCommitResponse).Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return fmt.Errorf("kafka: commit %s/%d: %w", k.topic, p.Partition, err)
+			}
+		}
+	}
+	return nil
+}
+
+// leave tells the coordinator this member is gone, so that its partitions
+// are handed to the others now rather than after the session timeout.
+func (k *KafkaConsumer) leave(ctx context.Context) {
+	if k.memberID == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), kafkaLeaveTimeout)
+	defer cancel()
+	coordinator, err := k.client.coordinator(ctx, k.group)
+	if err == nil {
+		req := kmsg.NewPtrLeaveGroupRequest()
+		req.Group = k.group
+		req.MemberID = k.memberID
+		var resp kmsg.Response
+		if resp, err = k.client.request(ctx, coordinator, req); err == nil {
+			err = kerr.ErrorForCode(resp.(*kmsg.LeaveGroupResponse).ErrorCode)
+		}
+	}
+	if err != nil {
+		k.log.Warn("kafka: failed to leave consumer group", zap.String("group", k.group), zap.Error(err))
+		return
+	}
+	k.memberID = ""
+	k.log.Info("kafka: left consumer group", zap.String("group", k.group))
+}
+
+// sleep waits for d or until ctx is done, and reports whether d passed.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
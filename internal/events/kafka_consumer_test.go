@@ -0,0 +1,164 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/model"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"go.uber.org/zap"
+)
+
+// runKafkaConsumer starts k and returns a function that stops it and waits
+// for it to drain.
+func runKafkaConsumer(t *testing.T, k *KafkaConsumer) func() {
+	t.Helper()
+	k.retryDelay = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	go k.Run(ctx)
+	return func() {
+		cancel()
+		waitCtx, done := context.WithTimeout(context.Background(), 3*time.Second)
+		defer done()
+		if err := k.consumer.Wait(waitCtx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func publishOrders(t *testing.T, pub *KafkaPublisher, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		if err := pub.Publish(context.Background(), "order.created", &model.Order{ID: id}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func (r *recordingUpdater) status(id string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.statuses[id]
+}
+
+func TestKafkaConsumerConfirmsCreatedOrders(t *testing.T) {
+	f := newFakeKafka(t, 2)
+	client := newTestKafkaClient(t, f)
+	publishOrders(t, NewKafkaPublisher(client, DefaultKafkaTopic), "order-1", "order-2", "order-3")
+
+	updater := &recordingUpdater{statuses: map[string]string{}}
+	consumer := NewConsumer(nil, updater, zap.NewNop())
+	stop := runKafkaConsumer(t, NewKafkaConsumer(client, DefaultKafkaTopic, ConsumerGroup, consumer, zap.NewNop()))
+
+	waitFor(t, func() bool {
+		return updater.status("order-1") == "confirmed" && updater.status("order-2") == "confirmed" && updater.status("order-3") == "confirmed"
+	})
+	stop()
+
+	var committed int64
+	for p := range int32(2) {
+		offset, _ := f.committedOffset(DefaultKafkaTopic, p)
+		committed += offset
+	}
+	if committed != 3 {
+		t.Errorf("expected offsets past all 3 records to be committed, got %d", committed)
+	}
+	if len(f.left) != 1 {
+		t.Errorf("expected the consumer to leave the group on shutdown, got %v", f.left)
+	}
+}
+
+func TestKafkaConsumerResumesFromCommittedOffset(t *testing.T) {
+	f := newFakeKafka(t, 1)
+	client := newTestKafkaClient(t, f)
+	publishOrders(t, NewKafkaPublisher(client, DefaultKafkaTopic), "order-1", "order-2")
+	f.commit(DefaultKafkaTopic, 0, 1)
+
+	updater := &recordingUpdater{statuses: map[string]string{}}
+	stop := runKafkaConsumer(t, NewKafkaConsumer(client, DefaultKafkaTopic, ConsumerGroup, NewConsumer(nil, updater, zap.NewNop()), zap.NewNop()))
+	waitFor(t, func() bool { return updater.status("order-2") == "confirmed" })
+	stop()
+
+	if status := updater.status("order-1"); status != "" {
+		t.Errorf("expected the committed record to be skipped, got status %q", status)
+	}
+}
+
+func TestKafkaConsumerResetsOutOfRangeOffset(t *testing.T) {
+	f := newFakeKafka(t, 1)
+	client := newTestKafkaClient(t, f)
+	publishOrders(t, NewKafkaPublisher(client, DefaultKafkaTopic), "order-1")
+	f.commit(DefaultKafkaTopic, 0, 5)
+
+	updater := &recordingUpdater{statuses: map[string]string{}}
+	stop := runKafkaConsumer(t, NewKafkaConsumer(client, DefaultKafkaTopic, ConsumerGroup, NewConsumer(nil, updater, zap.NewNop()), zap.NewNop()))
+	waitFor(t, func() bool { return updater.status("order-1") == "confirmed" })
+	stop()
+}
+
+func TestKafkaConsumerDeadLettersFailedEvents(t *testing.T) {
+	f := newFakeKafka(t, 1)
+	client := newTestKafkaClient(t, f)
+	publishOrders(t, NewKafkaPublisher(client, DefaultKafkaTopic), "order-1")
+	// A record without an event header is not an envelope.
+	if _, err := client.produce(context.Background(), DefaultKafkaTopic, 0, []kmsg.Record{{Value: []byte("garbage")}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	updater := &failingUpdater{}
+	consumer := NewConsumer(nil, updater, zap.NewNop(), WithRetryPolicy(RetryPolicy{InlineRetries: 1, InlineDelay: time.Millisecond}))
+	stop := runKafkaConsumer(t, NewKafkaConsumer(client, DefaultKafkaTopic, ConsumerGroup, consumer, zap.NewNop()))
+	waitFor(t, func() bool {
+		offset, _ := f.committedOffset(DefaultKafkaTopic, 0)
+		return offset == 2
+	})
+	stop()
+
+	if calls := updater.Calls(); calls != 2 {
+		t.Errorf("expected the failing event to be retried inline once, got %d calls", calls)
+	}
+	dead := f.records(t, DefaultKafkaTopic+KafkaDeadLetterSuffix)
+	if len(dead) != 2 {
+		t.Fatalf("expected both records dead-lettered, got %d", len(dead))
+	}
+	for i, r := range dead {
+		messageID, _ := r.header("message_id")
+		cause, _ := r.header("error")
+		if want := fmt.Sprintf("0-%d", i); messageID != want || cause == "" {
+			t.Errorf("expected message %s with its error, got %q, %q", want, messageID, cause)
+		}
+	}
+	if string(dead[0].Key) != "order-1" || string(dead[1].Value) != "garbage" {
+		t.Errorf("expected the original key and value to be kept, got %+v", dead)
+	}
+}
+
+func TestKafkaConsumerRejoinsOnRebalance(t *testing.T) {
+	f := newFakeKafka(t, 1)
+	client := newTestKafkaClient(t, f)
+	pub := NewKafkaPublisher(client, DefaultKafkaTopic)
+	f.rebalances = 1
+
+	updater := &recordingUpdater{statuses: map[string]string{}}
+	k := NewKafkaConsumer(client, DefaultKafkaTopic, ConsumerGroup, NewConsumer(nil, updater, zap.NewNop()), zap.NewNop(),
+		WithKafkaSession(time.Second, 10*time.Millisecond))
+	stop := runKafkaConsumer(t, k)
+	defer stop()
+
+	waitFor(t, func() bool {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.generation >= 2
+	})
+	publishOrders(t, pub, "order-1")
+	waitFor(t, func() bool { return updater.status("order-1") == "confirmed" })
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !slices.Equal(f.members, []string{"member-1"}) {
+		t.Errorf("expected the consumer to rejoin under its member id, got %v", f.members)
+	}
+}
@@ -0,0 +1,200 @@
+package events
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/orders-service/internal/metrics"
+	"github.com/orders-service/internal/model"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// DefaultKafkaTopic is the topic order events are published to by default.
+const DefaultKafkaTopic = "orders"
+
+// KafkaPublisher publishes events as records on a Kafka topic. A record
+// carries the envelope fields as headers named like the stream message
+// fields and the payload as its value, so consumers see the same envelope
+// as on Redis. Records are keyed by order ID, which keeps the events of an
+// order in order on one partition.
+type KafkaPublisher struct {
+	client     *KafkaClient
+	topic      string
+	serializer Serializer
+	now        func() time.Time
+	attempts   int
+	retryDelay time.Duration
+	next       atomic.Uint32
+}
+
+type KafkaPublisherOption func(*KafkaPublisher)
+
+// WithKafkaSerializer sets the payload format. The default is JSON.
+func WithKafkaSerializer(s Serializer) KafkaPublisherOption {
+	return func(p *KafkaPublisher) {
+		p.serializer = s
+	}
+}
+
+// WithKafkaRetry makes up to attempts tries to produce each event, like
+// WithRetry does for Redis.
+func WithKafkaRetry(attempts int, delay time.Duration) KafkaPublisherOption {
+	return func(p *KafkaPublisher) {
+		p.attempts = attempts
+		p.retryDelay = delay
+	}
+}
+
+func NewKafkaPublisher(client *KafkaClient, topic string, opts ...KafkaPublisherOption) *KafkaPublisher {
+	p := &KafkaPublisher{client: client, topic: topic, serializer: JSONSerializer{}, now: time.Now, attempts: 1}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, channel string, message interface{}) error {
+	data, err := p.serializer.Marshal(message)
+	if err != nil {
+		metrics.EventsPublished.WithLabelValues(channel, metrics.OutcomeError).Inc()
+		return err
+	}
+	_, err = p.PublishRaw(ctx, channel, p.serializer.ContentType(), data)
+	return err
+}
+
+// PublishRaw wraps payload in an EventEnvelope and produces it to the topic.
+// It returns where the record was written as "partition-offset".
+func (p *KafkaPublisher) PublishRaw(ctx context.Context, channel, contentType string, payload []byte) (string, error) {
+	record := envelopeRecord(newEnvelope(ctx, channel, contentType, payload, p.now()))
+
+	delay := p.retryDelay
+	position, err := p.produce(ctx, record)
+	for i := 1; err != nil && i < p.attempts; i++ {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			metrics.EventsPublished.WithLabelValues(channel, metrics.OutcomeError).Inc()
+			return "", err
+		case <-timer.C:
+		}
+		delay *= 2
+
+		metrics.EventPublishRetries.WithLabelValues(channel).Inc()
+		position, err = p.produce(ctx, record)
+	}
+	metrics.EventsPublished.WithLabelValues(channel, metrics.Outcome(err)).Inc()
+	return position, err
+}
+
+func (p *KafkaPublisher) produce(ctx context.Context, record kmsg.Record) (string, error) {
+	leaders, err := p.client.partitions(ctx, p.topic)
+	if err != nil {
+		return "", err
+	}
+	if len(leaders) == 0 {
+		return "", fmt.Errorf("kafka: topic %s has no partitions", p.topic)
+	}
+	partition := p.partition(record.Key, len(leaders))
+	offset, err := p.client.produce(ctx, p.topic, partition, []kmsg.Record{record})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", partition, offset), nil
+}
+
+// partition picks the partition for key the way Kafka's Java client does,
+// so that other producers put an order's records on the same partition.
+// Records without a key go round-robin.
+func (p *KafkaPublisher) partition(key []byte, n int) int32 {
+	if key == nil {
+		return int32((p.next.Add(1) - 1) % uint32(n))
+	}
+	return int32(int(murmur2(key)&0x7fffffff) % n)
+}
+
+// murmur2 is the hash Kafka's Java client partitions keys by.
+func murmur2(data []byte) int32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+	h := seed ^ uint32(len(data))
+	for i := 0; i+4 <= len(data); i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+	switch tail := data[len(data)&^3:]; len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// envelopeRecord returns the record for an envelope, keyed by orderKey.
+func envelopeRecord(e EventEnvelope) kmsg.Record {
+	values := e.values()
+	delete(values, "payload")
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	record := kmsg.Record{Key: orderKey(e), Value: e.Data}
+	for _, name := range names {
+		record.Headers = append(record.Headers, kmsg.Header{Key: name, Value: []byte(fmt.Sprint(values[name]))})
+	}
+	return record
+}
+
+// recordEnvelope reads the envelope back from a record.
+func recordEnvelope(r kafkaRecord) (EventEnvelope, error) {
+	values := map[string]interface{}{"payload": string(r.Value)}
+	for _, h := range r.Headers {
+		values[h.Key] = string(h.Value)
+	}
+	return parseEnvelope(values)
+}
+
+// orderKey returns the ID of the order an event is about, or nil when the
+// event is not about one order or its payload does not decode.
+func orderKey(e EventEnvelope) []byte {
+	var order *model.Order
+	switch e.Type {
+	case "order.created", "order.updated", "order.deleted":
+		var o model.Order
+		if e.Decode(&o) == nil {
+			order = &o
+		}
+	case "order.status_changed":
+		var change model.StatusChange
+		if e.Decode(&change) == nil {
+			order = change.Order
+		}
+	}
+	if order == nil || order.ID == "" {
+		return nil
+	}
+	return []byte(order.ID)
+}
@@ -0,0 +1,87 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/model"
+)
+
+func TestKafkaPublisherWritesEnvelope(t *testing.T) {
+	f := newFakeKafka(t, 1)
+	pub := NewKafkaPublisher(newTestKafkaClient(t, f), DefaultKafkaTopic)
+	occurredAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ctx := WithOccurredAt(WithEventID(context.Background(), "event-1"), occurredAt)
+
+	if err := pub.Publish(ctx, "order.created", &model.Order{ID: "order-1", Product: "widget"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	position, err := pub.PublishRaw(context.Background(), "order.status_changed", ContentTypeJSON, []byte(`{"from":"pending","to":"confirmed","order":{"id":"order-1"}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if position != "0-1" {
+		t.Errorf("expected position 0-1, got %q", position)
+	}
+
+	records := f.records(t, DefaultKafkaTopic)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	for _, r := range records {
+		if string(r.Key) != "order-1" {
+			t.Errorf("expected records keyed by order id, got %q", r.Key)
+		}
+	}
+	evt, err := recordEnvelope(records[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if evt.ID != "event-1" || evt.Type != "order.created" || evt.Version != EnvelopeVersion || !evt.OccurredAt.Equal(occurredAt) || evt.ContentType != ContentTypeJSON {
+		t.Errorf("unexpected envelope %+v", evt)
+	}
+	var order model.Order
+	if err := evt.Decode(&order); err != nil || order.Product != "widget" {
+		t.Errorf("expected the payload as the record value, got %s, %v", records[0].Value, err)
+	}
+	if event, _ := records[1].header("event"); event != "order.status_changed" {
+		t.Errorf("expected event header order.status_changed, got %q", event)
+	}
+}
+
+func TestKafkaPublisherPartitionsByOrder(t *testing.T) {
+	f := newFakeKafka(t, 4)
+	pub := NewKafkaPublisher(newTestKafkaClient(t, f), DefaultKafkaTopic)
+	ctx := context.Background()
+
+	for _, event := range []string{"order.created", "order.updated", "order.deleted"} {
+		if err := pub.Publish(ctx, event, &model.Order{ID: "order-1"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := pub.Publish(ctx, "order.status_changed", &model.StatusChange{From: "pending", To: "confirmed", Order: &model.Order{ID: "order-1"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := int32(int(murmur2([]byte("order-1"))&0x7fffffff) % 4)
+	for _, r := range f.records(t, DefaultKafkaTopic) {
+		if r.Partition != want {
+			t.Errorf("expected every event of order-1 on partition %d, got %d", want, r.Partition)
+		}
+	}
+
+	for range 4 {
+		if err := pub.Publish(ctx, "inventory.reserved", map[string]string{"sku": "widget"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	spread := make(map[int32]bool)
+	for _, r := range f.records(t, DefaultKafkaTopic) {
+		if r.Key == nil {
+			spread[r.Partition] = true
+		}
+	}
+	if len(spread) != 4 {
+		t.Errorf("expected unkeyed events round-robin over 4 partitions, got %v", spread)
+	}
+}
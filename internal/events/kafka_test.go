@@ -0,0 +1,412 @@
+package events
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// fakeKafka is a one-broker Kafka cluster in memory. It answers the requests
+// KafkaClient sends, for a single consumer group.
+type fakeKafka struct {
+	ln         net.Listener
+	host       string
+	port       int32
+	partitions int
+
+	mu          sync.Mutex
+	logs        map[string][][]fakeBatch
+	members     []string
+	nextMember  int
+	generation  int32
+	assignments map[string][]byte
+	committed   map[string]int64
+	left        []string
+	rebalances  int
+}
+
+type fakeBatch struct {
+	base, last int64
+	raw        []byte
+}
+
+func newFakeKafka(t *testing.T, partitions int) *fakeKafka {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addr := ln.Addr().(*net.TCPAddr)
+	f := &fakeKafka{
+		ln:          ln,
+		host:        addr.IP.String(),
+		port:        int32(addr.Port),
+		partitions:  partitions,
+		logs:        make(map[string][][]fakeBatch),
+		assignments: make(map[string][]byte),
+		committed:   make(map[string]int64),
+	}
+
+	var mu sync.Mutex
+	var conns []net.Conn
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+			go f.serve(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		ln.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	})
+	return f
+}
+
+func (f *fakeKafka) addr() string {
+	return net.JoinHostPort(f.host, strconv.Itoa(int(f.port)))
+}
+
+func (f *fakeKafka) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		frame := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(conn, frame); err != nil {
+			return
+		}
+		// Request header v1: key, version, correlation ID and client ID.
+		req := kmsg.RequestForKey(int16(binary.BigEndian.Uint16(frame)))
+		req.SetVersion(int16(binary.BigEndian.Uint16(frame[2:])))
+		body := frame[10:]
+		if n := int16(binary.BigEndian.Uint16(frame[8:])); n > 0 {
+			body = body[n:]
+		}
+		if req == nil || req.ReadFrom(body) != nil {
+			return
+		}
+
+		resp := f.handle(req)
+		resp.SetVersion(req.GetVersion())
+		out := append([]byte{0, 0, 0, 0}, frame[4:8]...)
+		out = resp.AppendTo(out)
+		binary.BigEndian.PutUint32(out, uint32(len(out)-4))
+		if _, err := conn.Write(out); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeKafka) handle(req kmsg.Request) kmsg.Response {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch req := req.(type) {
+	case *kmsg.MetadataRequest:
+		resp := kmsg.NewPtrMetadataResponse()
+		b := kmsg.NewMetadataResponseBroker()
+		b.NodeID, b.Host, b.Port = 1, f.host, f.port
+		resp.Brokers = append(resp.Brokers, b)
+		for _, rt := range req.Topics {
+			t := kmsg.NewMetadataResponseTopic()
+			t.Topic = rt.Topic
+			for p := 0; p < f.partitions; p++ {
+				mp := kmsg.NewMetadataResponseTopicPartition()
+				mp.Partition, mp.Leader = int32(p), 1
+				mp.Replicas, mp.ISR = []int32{1}, []int32{1}
+				t.Partitions = append(t.Partitions, mp)
+			}
+			resp.Topics = append(resp.Topics, t)
+		}
+		return resp
+
+	case *kmsg.ProduceRequest:
+		resp := kmsg.NewPtrProduceResponse()
+		for _, rt := range req.Topics {
+			t := kmsg.NewProduceResponseTopic()
+			t.Topic = rt.Topic
+			for _, rp := range rt.Partitions {
+				p := kmsg.NewProduceResponseTopicPartition()
+				p.Partition = rp.Partition
+				p.BaseOffset = f.append(rt.Topic, rp.Partition, rp.Records)
+				t.Partitions = append(t.Partitions, p)
+			}
+			resp.Topics = append(resp.Topics, t)
+		}
+		return resp
+
+	case *kmsg.FetchRequest:
+		resp := kmsg.NewPtrFetchResponse()
+		empty := true
+		for _, rt := range req.Topics {
+			t := kmsg.NewFetchResponseTopic()
+			t.Topic = rt.Topic
+			for _, rp := range rt.Partitions {
+				p := kmsg.NewFetchResponseTopicPartition()
+				p.Partition = rp.Partition
+				p.HighWatermark = f.next(rt.Topic, rp.Partition)
+				if rp.FetchOffset > p.HighWatermark {
+					p.ErrorCode = kerr.OffsetOutOfRange.Code
+				}
+				for _, b := range f.log(rt.Topic, rp.Partition) {
+					if p.ErrorCode == 0 && b.last >= rp.FetchOffset {
+						p.RecordBatches = append(p.RecordBatches, b.raw...)
+						empty = false
+					}
+				}
+				t.Partitions = append(t.Partitions, p)
+			}
+			resp.Topics = append(resp.Topics, t)
+		}
+		if empty {
+			// Stand in for the broker holding the request for MaxWait.
+			f.mu.Unlock()
+			time.Sleep(min(time.Duration(req.MaxWaitMillis)*time.Millisecond, 20*time.Millisecond))
+			f.mu.Lock()
+		}
+		return resp
+
+	case *kmsg.ListOffsetsRequest:
+		resp := kmsg.NewPtrListOffsetsResponse()
+		for _, rt := range req.Topics {
+			t := kmsg.NewListOffsetsResponseTopic()
+			t.Topic = rt.Topic
+			for _, rp := range rt.Partitions {
+				p := kmsg.NewListOffsetsResponseTopicPartition()
+				p.Partition = rp.Partition
+				if rp.Timestamp == -1 {
+					p.Offset = f.next(rt.Topic, rp.Partition)
+				}
+				t.Partitions = append(t.Partitions, p)
+			}
+			resp.Topics = append(resp.Topics, t)
+		}
+		return resp
+
+	case *kmsg.FindCoordinatorRequest:
+		resp := kmsg.NewPtrFindCoordinatorResponse()
+		resp.NodeID, resp.Host, resp.Port = 1, f.host, f.port
+		return resp
+
+	case *kmsg.JoinGroupRequest:
+		resp := kmsg.NewPtrJoinGroupResponse()
+		id := req.MemberID
+		if id == "" {
+			f.nextMember++
+			id = fmt.Sprintf("member-%d", f.nextMember)
+			f.members = append(f.members, id)
+		} else if !slices.Contains(f.members, id) {
+			resp.ErrorCode = kerr.UnknownMemberID.Code
+			return resp
+		}
+		f.generation++
+		resp.Generation = f.generation
+		resp.Protocol = kmsg.StringPtr("range")
+		resp.LeaderID = f.members[0]
+		resp.MemberID = id
+		if id == resp.LeaderID {
+			for _, m := range f.members {
+				member := kmsg.NewJoinGroupResponseMember()
+				member.MemberID = m
+				member.ProtocolMetadata = req.Protocols[0].Metadata
+				resp.Members = append(resp.Members, member)
+			}
+		}
+		return resp
+
+	case *kmsg.SyncGroupRequest:
+		resp := kmsg.NewPtrSyncGroupResponse()
+		for _, a := range req.GroupAssignment {
+			f.assignments[a.MemberID] = a.MemberAssignment
+		}
+		resp.MemberAssignment = f.assignments[req.MemberID]
+		return resp
+
+	case *kmsg.HeartbeatRequest:
+		resp := kmsg.NewPtrHeartbeatResponse()
+		if f.rebalances > 0 {
+			f.rebalances--
+			resp.ErrorCode = kerr.RebalanceInProgress.Code
+		}
+		return resp
+
+	case *kmsg.OffsetCommitRequest:
+		resp := kmsg.NewPtrOffsetCommitResponse()
+		for _, rt := range req.Topics {
+			t := kmsg.NewOffsetCommitResponseTopic()
+			t.Topic = rt.Topic
+			for _, rp := range rt.Partitions {
+				f.committed[fakeOffsetKey(rt.Topic, rp.Partition)] = rp.Offset
+				p := kmsg.NewOffsetCommitResponseTopicPartition()
+				p.Partition = rp.Partition
+				t.Partitions = append(t.Partitions, p)
+			}
+			resp.Topics = append(resp.Topics, t)
+		}
+		return resp
+
+	case *kmsg.OffsetFetchRequest:
+		resp := kmsg.NewPtrOffsetFetchResponse()
+		for _, rt := range req.Topics {
+			t := kmsg.NewOffsetFetchResponseTopic()
+			t.Topic = rt.Topic
+			for _, partition := range rt.Partitions {
+				p := kmsg.NewOffsetFetchResponseTopicPartition()
+				p.Partition = partition
+				p.Offset = -1
+				if offset, ok := f.committed[fakeOffsetKey(rt.Topic, partition)]; ok {
+					p.Offset = offset
+				}
+				t.Partitions = append(t.Partitions, p)
+			}
+			resp.Topics = append(resp.Topics, t)
+		}
+		return resp
+
+	case *kmsg.LeaveGroupRequest:
+		f.members = slices.DeleteFunc(f.members, func(m string) bool { return m == req.MemberID })
+		f.left = append(f.left, req.MemberID)
+		return kmsg.NewPtrLeaveGroupResponse()
+
+	default:
+		return req.ResponseKind()
+	}
+}
+
+func fakeOffsetKey(topic string, partition int32) string {
+	return topic + "/" + strconv.Itoa(int(partition))
+}
+
+func (f *fakeKafka) log(topic string, partition int32) []fakeBatch {
+	if f.logs[topic] == nil {
+		f.logs[topic] = make([][]fakeBatch, f.partitions)
+	}
+	return f.logs[topic][partition]
+}
+
+func (f *fakeKafka) next(topic string, partition int32) int64 {
+	log := f.log(topic, partition)
+	if len(log) == 0 {
+		return 0
+	}
+	return log[len(log)-1].last + 1
+}
+
+// append stores a produced record batch, giving it the next offsets.
+func (f *fakeKafka) append(topic string, partition int32, raw []byte) int64 {
+	base := f.next(topic, partition)
+	raw = slices.Clone(raw)
+	binary.BigEndian.PutUint64(raw, uint64(base))
+	lastDelta := int64(int32(binary.BigEndian.Uint32(raw[23:])))
+	f.logs[topic][partition] = append(f.logs[topic][partition], fakeBatch{base: base, last: base + lastDelta, raw: raw})
+	return base
+}
+
+// records returns what was produced to topic, partition by partition.
+func (f *fakeKafka) records(t *testing.T, topic string) []kafkaRecord {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var records []kafkaRecord
+	for p := range f.partitions {
+		var raw []byte
+		for _, b := range f.log(topic, int32(p)) {
+			raw = append(raw, b.raw...)
+		}
+		batch, err := readRecordBatches(topic, int32(p), 0, raw)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		records = append(records, batch...)
+	}
+	return records
+}
+
+func (f *fakeKafka) committedOffset(topic string, partition int32) (int64, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	offset, ok := f.committed[fakeOffsetKey(topic, partition)]
+	return offset, ok
+}
+
+func (f *fakeKafka) commit(topic string, partition int32, offset int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.committed[fakeOffsetKey(topic, partition)] = offset
+}
+
+func newTestKafkaClient(t *testing.T, f *fakeKafka) *KafkaClient {
+	t.Helper()
+	client := NewKafkaClient([]string{f.addr()}, "orders-service-test")
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestRecordBatchRoundTrip(t *testing.T) {
+	records := []kmsg.Record{
+		{Key: []byte("order-1"), Value: []byte(`{"id":"order-1"}`), Headers: []kmsg.Header{{Key: "event", Value: []byte("order.created")}}},
+		{Value: []byte(`{"id":"order-2"}`)},
+	}
+	raw := appendRecordBatch(nil, time.Now(), records)
+	binary.BigEndian.PutUint64(raw, 10)
+
+	got, err := readRecordBatches("orders", 0, 0, raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].Offset != 10 || got[1].Offset != 11 || string(got[0].Key) != "order-1" || got[1].Key != nil {
+		t.Fatalf("unexpected records %+v", got)
+	}
+	if event, _ := got[0].header("event"); event != "order.created" || string(got[1].Value) != `{"id":"order-2"}` {
+		t.Errorf("unexpected records %+v", got)
+	}
+
+	if got, _ := readRecordBatches("orders", 0, 11, raw); len(got) != 1 || got[0].Offset != 11 {
+		t.Errorf("expected records before the fetch offset to be skipped, got %+v", got)
+	}
+	if got, _ := readRecordBatches("orders", 0, 0, raw[:len(raw)-1]); len(got) != 0 {
+		t.Errorf("expected a cut-off batch to be left for the next fetch, got %+v", got)
+	}
+	raw[len(raw)-1] ^= 0xff
+	if _, err := readRecordBatches("orders", 0, 0, raw); err == nil {
+		t.Error("expected a corrupt batch to fail its CRC check")
+	}
+}
+
+func TestMurmur2MatchesJavaClient(t *testing.T) {
+	// Expected values from the Java client's own tests.
+	cases := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+	for key, want := range cases {
+		if got := murmur2([]byte(key)); got != want {
+			t.Errorf("murmur2(%q): expected %d, got %d", key, want, got)
+		}
+	}
+}
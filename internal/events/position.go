@@ -0,0 +1,114 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrGroupNotFound = errors.New("consumer group not found")
+
+type StreamPosition struct {
+	Stream          string `json:"stream"`
+	Group           string `json:"group"`
+	LastDeliveredID string `json:"last_delivered_id"`
+	Pending         int64  `json:"pending"`
+	LowestPendingID string `json:"lowest_pending_id,omitempty"`
+}
+
+// Processed reports whether the message with the given stream ID has been
+// delivered to the group and acknowledged.
+func (p *StreamPosition) Processed(id string) (bool, error) {
+	delivered, err := compareStreamIDs(id, p.LastDeliveredID)
+	if err != nil {
+		return false, err
+	}
+	if delivered > 0 {
+		return false, nil
+	}
+	if p.Pending == 0 || p.LowestPendingID == "" {
+		return true, nil
+	}
+	pending, err := compareStreamIDs(id, p.LowestPendingID)
+	if err != nil {
+		return false, err
+	}
+	return pending < 0, nil
+}
+
+func (c *Consumer) Position(ctx context.Context) (*StreamPosition, error) {
+	groups, err := c.client.XInfoGroups(ctx, StreamName).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	pos := &StreamPosition{Stream: StreamName, Group: ConsumerGroup}
+	found := false
+	for _, g := range groups {
+		if g.Name == ConsumerGroup {
+			pos.LastDeliveredID = g.LastDeliveredID
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, ErrGroupNotFound
+	}
+
+	pending, err := c.client.XPending(ctx, StreamName, ConsumerGroup).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	if pending != nil {
+		pos.Pending = pending.Count
+		if pending.Count > 0 {
+			pos.LowestPendingID = pending.Lower
+		}
+	}
+	return pos, nil
+}
+
+func compareStreamIDs(a, b string) (int, error) {
+	aMs, aSeq, err := parseStreamID(a)
+	if err != nil {
+		return 0, err
+	}
+	bMs, bSeq, err := parseStreamID(b)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case aMs != bMs:
+		if aMs < bMs {
+			return -1, nil
+		}
+		return 1, nil
+	case aSeq != bSeq:
+		if aSeq < bSeq {
+			return -1, nil
+		}
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func parseStreamID(id string) (uint64, uint64, error) {
+	msPart, seqPart, found := strings.Cut(id, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid stream id %q", id)
+	}
+	if !found {
+		return ms, 0, nil
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid stream id %q", id)
+	}
+	return ms, seq, nil
+}
@@ -0,0 +1,116 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+func newTestClient(t *testing.T) *redis.Client {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestPublishWithPositionReturnsStreamID(t *testing.T) {
+	client := newTestClient(t)
+	pub := NewRedisPublisher(client)
+	ctx := context.Background()
+
+	first, err := pub.PublishWithPosition(ctx, "order.created", map[string]string{"id": "1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := pub.PublishWithPosition(ctx, "order.created", map[string]string{"id": "2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cmp, err := compareStreamIDs(first, second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmp >= 0 {
+		t.Errorf("expected %s to sort before %s", first, second)
+	}
+}
+
+func TestConsumerPosition(t *testing.T) {
+	client := newTestClient(t)
+	pub := NewRedisPublisher(client)
+	consumer := NewConsumer(client, nil, zap.NewNop())
+	ctx := context.Background()
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := pub.PublishWithPosition(ctx, "order.created", map[string]string{"id": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := pub.PublishWithPosition(ctx, "order.created", map[string]string{"id": "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    ConsumerGroup,
+		Consumer: ConsumerName,
+		Streams:  []string{StreamName, ">"},
+		Count:    2,
+	}).Result(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.XAck(ctx, StreamName, ConsumerGroup, first).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := consumer.Position(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pos.LastDeliveredID != second {
+		t.Errorf("expected last delivered %s, got %s", second, pos.LastDeliveredID)
+	}
+	if pos.Pending != 1 || pos.LowestPendingID != second {
+		t.Errorf("expected 1 pending at %s, got %d at %s", second, pos.Pending, pos.LowestPendingID)
+	}
+
+	if processed, _ := pos.Processed(first); !processed {
+		t.Errorf("expected %s to be processed", first)
+	}
+	if processed, _ := pos.Processed(second); processed {
+		t.Errorf("expected %s to still be pending", second)
+	}
+
+	if err := client.XAck(ctx, StreamName, ConsumerGroup, second).Err(); err != nil {
+		t.Fatal(err)
+	}
+	pos, err = consumer.Position(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if processed, _ := pos.Processed(second); !processed {
+		t.Errorf("expected %s to be processed after ack", second)
+	}
+}
+
+func TestConsumerPositionGroupMissing(t *testing.T) {
+	client := newTestClient(t)
+	consumer := NewConsumer(client, nil, zap.NewNop())
+	ctx := context.Background()
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, "other-group", "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := consumer.Position(ctx); err != ErrGroupNotFound {
+		t.Errorf("expected ErrGroupNotFound, got %v", err)
+	}
+}
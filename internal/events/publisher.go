@@ -2,35 +2,158 @@ package events
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"time"
 
+	"github.com/orders-service/internal/metrics"
 	"github.com/redis/go-redis/v9"
 )
 
 const StreamName = "orders"
 
+const (
+	DefaultPublishAttempts   = 3
+	DefaultPublishRetryDelay = 50 * time.Millisecond
+	DefaultBreakerThreshold  = 5
+	DefaultBreakerCooldown   = 10 * time.Second
+	DefaultStreamMaxLen      = 100000
+)
+
 type Publisher interface {
 	Publish(ctx context.Context, channel string, message interface{}) error
 }
 
+type PositionPublisher interface {
+	Publisher
+	PublishWithPosition(ctx context.Context, channel string, message interface{}) (string, error)
+}
+
+// RawPublisher publishes payloads that were serialized ahead of time, such as
+// events relayed from the outbox.
+type RawPublisher interface {
+	PublishRaw(ctx context.Context, channel, contentType string, payload []byte) (string, error)
+}
+
 type RedisPublisher struct {
-	client *redis.Client
+	client     *redis.Client
+	serializer Serializer
+	now        func() time.Time
+	attempts   int
+	retryDelay time.Duration
+	breaker    *CircuitBreaker
+	maxLen     int64
+}
+
+type PublisherOption func(*RedisPublisher)
+
+// WithSerializer sets the payload format. The default is JSON.
+func WithSerializer(s Serializer) PublisherOption {
+	return func(p *RedisPublisher) {
+		p.serializer = s
+	}
+}
+
+// WithRetry makes up to attempts tries to append each event, waiting delay
+// before the first retry and doubling it for each one after that.
+func WithRetry(attempts int, delay time.Duration) PublisherOption {
+	return func(p *RedisPublisher) {
+		p.attempts = attempts
+		p.retryDelay = delay
+	}
+}
+
+// WithCircuitBreaker fails publishes fast with ErrCircuitOpen while b is open,
+// so that requests do not each wait out their retries while Redis is down.
+// Every try, including retries, counts towards opening it.
+func WithCircuitBreaker(b *CircuitBreaker) PublisherOption {
+	return func(p *RedisPublisher) {
+		p.breaker = b
+	}
+}
+
+// WithMaxLen has Redis trim the stream to about n entries as events are
+// appended, dropping the oldest. Trimming is approximate, so the stream may
+// briefly hold somewhat more. Zero leaves the stream unbounded.
+func WithMaxLen(n int64) PublisherOption {
+	return func(p *RedisPublisher) {
+		p.maxLen = n
+	}
 }
 
-func NewRedisPublisher(client *redis.Client) *RedisPublisher {
-	return &RedisPublisher{client: client}
+func NewRedisPublisher(client *redis.Client, opts ...PublisherOption) *RedisPublisher {
+	p := &RedisPublisher{client: client, serializer: JSONSerializer{}, now: time.Now, attempts: 1}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 func (p *RedisPublisher) Publish(ctx context.Context, channel string, message interface{}) error {
-	data, err := json.Marshal(message)
+	_, err := p.PublishWithPosition(ctx, channel, message)
+	return err
+}
+
+func (p *RedisPublisher) PublishWithPosition(ctx context.Context, channel string, message interface{}) (string, error) {
+	data, err := p.serializer.Marshal(message)
 	if err != nil {
-		return err
-	}
-	return p.client.XAdd(ctx, &redis.XAddArgs{
-		Stream: StreamName,
-		Values: map[string]interface{}{
-			"event":   channel,
-			"payload": string(data),
-		},
-	}).Err()
+		metrics.EventsPublished.WithLabelValues(channel, metrics.OutcomeError).Inc()
+		return "", err
+	}
+	return p.PublishRaw(ctx, channel, p.serializer.ContentType(), data)
+}
+
+// PublishRaw wraps payload in an EventEnvelope and appends it to the stream.
+func (p *RedisPublisher) PublishRaw(ctx context.Context, channel, contentType string, payload []byte) (string, error) {
+	args := p.xaddArgs(newEnvelope(ctx, channel, contentType, payload, p.now()))
+
+	delay := p.retryDelay
+	id, err := p.xadd(ctx, args)
+	for i := 1; err != nil && i < p.attempts && !errors.Is(err, ErrCircuitOpen); i++ {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			metrics.EventsPublished.WithLabelValues(channel, metrics.OutcomeError).Inc()
+			return "", err
+		case <-timer.C:
+		}
+		delay *= 2
+
+		metrics.EventPublishRetries.WithLabelValues(channel).Inc()
+		id, err = p.xadd(ctx, args)
+	}
+	metrics.EventsPublished.WithLabelValues(channel, metrics.Outcome(err)).Inc()
+	return id, err
+}
+
+func (p *RedisPublisher) xaddArgs(envelope EventEnvelope) *redis.XAddArgs {
+	return streamArgs(envelope.values(), p.maxLen)
+}
+
+// streamArgs appends values to the stream, trimming it to about maxLen
+// entries unless maxLen is zero.
+func streamArgs(values map[string]interface{}, maxLen int64) *redis.XAddArgs {
+	args := &redis.XAddArgs{Stream: StreamName, Values: values}
+	if maxLen > 0 {
+		args.MaxLen = maxLen
+		args.Approx = true
+	}
+	return args
+}
+
+func (p *RedisPublisher) xadd(ctx context.Context, args *redis.XAddArgs) (string, error) {
+	if p.breaker == nil {
+		return p.client.XAdd(ctx, args).Result()
+	}
+	if err := p.breaker.Allow(); err != nil {
+		return "", err
+	}
+	id, err := p.client.XAdd(ctx, args).Result()
+	if errors.Is(ctx.Err(), context.Canceled) {
+		// The caller went away, which says nothing about Redis.
+		p.breaker.release()
+	} else {
+		p.breaker.Record(err)
+	}
+	return id, err
 }
@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// benchmarkRTT approximates the network round trip to Redis, which in-memory
+// miniredis does not have and which is what batching saves.
+const benchmarkRTT = 500 * time.Microsecond
+
+// slowConn delays every write, and so every round trip, by benchmarkRTT.
+type slowConn struct {
+	net.Conn
+}
+
+func (c slowConn) Write(p []byte) (int, error) {
+	time.Sleep(benchmarkRTT)
+	return c.Conn.Write(p)
+}
+
+func newBenchmarkClient(b *testing.B) *redis.Client {
+	mr := miniredis.RunT(b)
+	client := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return slowConn{conn}, nil
+		},
+	})
+	b.Cleanup(func() { client.Close() })
+	return client
+}
+
+// The publish benchmarks run 64 concurrent publishers, as under a burst of
+// creates:
+//
+//	go test ./internal/events -run '^$' -bench Publish
+func BenchmarkPublish(b *testing.B) {
+	benchmarkPublisher(b, NewRedisPublisher(newBenchmarkClient(b)))
+}
+
+func BenchmarkPublishBatched(b *testing.B) {
+	pub := NewBatchPublisher(NewRedisPublisher(newBenchmarkClient(b)), 64, DefaultBatchInterval)
+	defer pub.Close(context.Background())
+	benchmarkPublisher(b, pub)
+}
+
+func benchmarkPublisher(b *testing.B, pub Publisher) {
+	ctx := context.Background()
+	order := map[string]interface{}{"id": "order-1", "product": "Widget", "quantity": 1, "created_at": time.Now()}
+
+	b.SetParallelism(64)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := pub.Publish(ctx, "order.created", order); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,17 @@
+package events
+
+import "testing"
+
+func TestXAddArgsMaxLen(t *testing.T) {
+	envelope := EventEnvelope{Type: "order.created", Data: []byte(`{"id":"1"}`)}
+
+	args := NewRedisPublisher(nil).xaddArgs(envelope)
+	if args.MaxLen != 0 || args.Approx {
+		t.Errorf("expected no trimming by default, got MaxLen %d, Approx %v", args.MaxLen, args.Approx)
+	}
+
+	args = NewRedisPublisher(nil, WithMaxLen(1000)).xaddArgs(envelope)
+	if args.Stream != StreamName || args.MaxLen != 1000 || !args.Approx {
+		t.Errorf("expected approximate trimming to 1000, got MaxLen %d, Approx %v", args.MaxLen, args.Approx)
+	}
+}
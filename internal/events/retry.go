@@ -0,0 +1,243 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/orders-service/internal/logger"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const RetryQueueKey = "orders.retry"
+
+// RetryPolicy controls how failed events are retried. Each delivery is first
+// retried in-process up to InlineRetries times, doubling from InlineDelay;
+// if it still fails it is scheduled on the retry queue with Backoff, and
+// after MaxRetries such deliveries it is dead-lettered.
+type RetryPolicy struct {
+	MaxRetries    int
+	BaseDelay     time.Duration
+	MaxDelay      time.Duration
+	PollInterval  time.Duration
+	InlineRetries int
+	InlineDelay   time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:    5,
+	BaseDelay:     time.Second,
+	MaxDelay:      5 * time.Minute,
+	PollInterval:  time.Second,
+	InlineRetries: 2,
+	InlineDelay:   100 * time.Millisecond,
+}
+
+// Backoff returns the delay before the given retry attempt (1-based),
+// doubling from BaseDelay and capped at MaxDelay.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	if delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// runWithRetry calls handler, retrying in-process on failure so transient
+// errors such as a database blip are absorbed without a round trip through
+// the retry queue. It returns the last handler error, or ctx.Err() if ctx is
+// cancelled while backing off.
+func (c *Consumer) runWithRetry(ctx context.Context, handler EventHandler, event EventEnvelope) error {
+	delay := c.retry.InlineDelay
+	err := callHandler(ctx, handler, event)
+	for i := 0; err != nil && !permanent(err) && i < c.retry.InlineRetries; i++ {
+		logger.FromContext(ctx).Warn("event handler failed, retrying", zap.Int("retry", i+1), zap.Duration("delay", delay), zap.Error(err))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+
+		err = callHandler(ctx, handler, event)
+	}
+	return err
+}
+
+// retryEntry is stored as JSON in the retry queue. Payloads that are not
+// valid UTF-8, such as protobuf, go in RawPayload so they survive encoding.
+type retryEntry struct {
+	MessageID   string    `json:"message_id"`
+	EventID     string    `json:"event_id,omitempty"`
+	Event       string    `json:"event"`
+	Version     int       `json:"version,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at,omitzero"`
+	ContentType string    `json:"content_type,omitempty"`
+	Payload     string    `json:"payload,omitempty"`
+	RawPayload  []byte    `json:"raw_payload,omitempty"`
+	Attempt     int       `json:"attempt"`
+	Error       string    `json:"error"`
+}
+
+func newRetryEntry(messageID string, event EventEnvelope, attempt int, cause error) retryEntry {
+	entry := retryEntry{
+		MessageID:   messageID,
+		EventID:     event.ID,
+		Event:       event.Type,
+		Version:     event.Version,
+		OccurredAt:  event.OccurredAt,
+		ContentType: event.ContentType,
+		Attempt:     attempt,
+		Error:       cause.Error(),
+	}
+	if utf8.Valid(event.Data) {
+		entry.Payload = string(event.Data)
+	} else {
+		entry.RawPayload = event.Data
+	}
+	return entry
+}
+
+func (e retryEntry) envelope() EventEnvelope {
+	data := []byte(e.Payload)
+	if e.RawPayload != nil {
+		data = e.RawPayload
+	}
+	return EventEnvelope{
+		ID:          e.EventID,
+		Type:        e.Event,
+		Version:     e.Version,
+		OccurredAt:  e.OccurredAt,
+		ContentType: e.ContentType,
+		Data:        data,
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) ConsumerOption {
+	return func(c *Consumer) {
+		c.retry = policy
+	}
+}
+
+func messageAttempt(message redis.XMessage) int {
+	raw, ok := message.Values["attempt"].(string)
+	if !ok {
+		return 0
+	}
+	attempt, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0
+	}
+	return attempt
+}
+
+// handleFailure schedules a failed message for a delayed retry, or moves it to
+// the dead-letter stream once the retry budget is spent or right away if it is
+// malformed or its handler panicked. It reports whether the message was handed off and can be acked.
+func (c *Consumer) handleFailure(ctx context.Context, message redis.XMessage, event EventEnvelope, cause error) bool {
+	attempt := messageAttempt(message) + 1
+
+	if attempt > c.retry.MaxRetries || permanent(cause) {
+		if err := c.deadLetter(ctx, message.ID, event, attempt-1, cause); err != nil {
+			c.log.Error("redis: failed to dead-letter message", zap.String("message_id", message.ID), zap.Error(err))
+			return false
+		}
+		c.log.Warn("message dead-lettered", zap.String("message_id", message.ID), zap.Int("retries", attempt-1), zap.Error(cause))
+		return true
+	}
+
+	entry, err := json.Marshal(newRetryEntry(message.ID, event, attempt, cause))
+	if err != nil {
+		c.log.Error("failed to encode retry entry", zap.String("message_id", message.ID), zap.Error(err))
+		return false
+	}
+
+	delay := c.retry.Backoff(attempt)
+	due := c.now().Add(delay)
+	if err := c.client.ZAdd(ctx, RetryQueueKey, redis.Z{Score: float64(due.UnixMilli()), Member: string(entry)}).Err(); err != nil {
+		c.log.Error("redis: failed to schedule retry", zap.String("message_id", message.ID), zap.Error(err))
+		return false
+	}
+
+	c.log.Warn("message scheduled for retry",
+		zap.String("message_id", message.ID),
+		zap.Int("attempt", attempt),
+		zap.Duration("delay", delay),
+		zap.Error(cause),
+	)
+	return true
+}
+
+// RunRetryLoop periodically re-injects retries that have become due back into
+// the main stream until ctx is cancelled.
+func (c *Consumer) RunRetryLoop(ctx context.Context) {
+	c.running.Add(1)
+	defer c.running.Done()
+
+	ticker := time.NewTicker(c.retry.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.requeueDue(ctx); err != nil && ctx.Err() == nil {
+				c.log.Error("redis: failed to requeue retries", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (c *Consumer) requeueDue(ctx context.Context) (int, error) {
+	due, err := c.client.ZRangeByScore(ctx, RetryQueueKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(c.now().UnixMilli(), 10),
+		Count: 100,
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	requeued := 0
+	for _, member := range due {
+		// ZREM acts as the claim so only one instance re-injects each entry.
+		removed, err := c.client.ZRem(ctx, RetryQueueKey, member).Result()
+		if err != nil {
+			return requeued, err
+		}
+		if removed == 0 {
+			continue
+		}
+
+		var entry retryEntry
+		if err := json.Unmarshal([]byte(member), &entry); err != nil {
+			c.log.Error("dropping malformed retry entry", zap.String("entry", member), zap.Error(err))
+			continue
+		}
+
+		values := entry.envelope().values()
+		values["attempt"] = entry.Attempt
+		err = c.client.XAdd(ctx, streamArgs(values, c.maxLen)).Err()
+		if err != nil {
+			if zerr := c.client.ZAdd(ctx, RetryQueueKey, redis.Z{Score: float64(c.now().UnixMilli()), Member: member}).Err(); zerr != nil {
+				c.log.Error("redis: failed to restore retry entry", zap.String("message_id", entry.MessageID), zap.Error(zerr))
+			}
+			return requeued, err
+		}
+		requeued++
+	}
+	return requeued, nil
+}
@@ -0,0 +1,232 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type failingUpdater struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *failingUpdater) UpdateOrderStatus(ctx context.Context, id string, status string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return errors.New("database unavailable")
+}
+
+func (f *failingUpdater) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func readMessages(t *testing.T, client *redis.Client) []redis.XMessage {
+	t.Helper()
+	streams, err := client.XReadGroup(context.Background(), &redis.XReadGroupArgs{
+		Group:    ConsumerGroup,
+		Consumer: ConsumerName,
+		Streams:  []string{StreamName, ">"},
+		Count:    10,
+		Block:    -1,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return streams[0].Messages
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, want := range expected {
+		if got := policy.Backoff(i + 1); got != want {
+			t.Errorf("attempt %d: expected %v, got %v", i+1, want, got)
+		}
+	}
+}
+
+func TestFailedMessageRescheduledThenDeadLettered(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	updater := &failingUpdater{}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	consumer := NewConsumer(client, updater, zap.NewNop(), WithRetryPolicy(RetryPolicy{
+		MaxRetries: 2,
+		BaseDelay:  time.Second,
+		MaxDelay:   time.Minute,
+	}))
+	consumer.now = func() time.Time { return now }
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRedisPublisher(client).Publish(ctx, "order.created", map[string]string{"id": "order-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := readMessages(t, client)
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+	consumer.processMessage(ctx, messages[0])
+
+	if updater.Calls() != 1 {
+		t.Fatalf("expected 1 handler call, got %d", updater.Calls())
+	}
+	scheduled, err := client.ZRangeWithScores(ctx, RetryQueueKey, 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scheduled) != 1 || int64(scheduled[0].Score) != now.Add(time.Second).UnixMilli() {
+		t.Fatalf("expected retry scheduled 1s out, got %+v", scheduled)
+	}
+	if pending, _ := client.XPending(ctx, StreamName, ConsumerGroup).Result(); pending.Count != 0 {
+		t.Errorf("expected original message to be acked, %d pending", pending.Count)
+	}
+
+	requeued, err := consumer.requeueDue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requeued != 0 || len(readMessages(t, client)) != 0 {
+		t.Fatal("expected retry not to be re-injected before it is due")
+	}
+
+	for _, delay := range []time.Duration{time.Second, 2 * time.Second} {
+		now = now.Add(delay)
+		if requeued, err := consumer.requeueDue(ctx); err != nil || requeued != 1 {
+			t.Fatalf("expected 1 requeued message, got %d (%v)", requeued, err)
+		}
+		messages := readMessages(t, client)
+		if len(messages) != 1 {
+			t.Fatalf("expected re-injected message, got %d", len(messages))
+		}
+		consumer.processMessage(ctx, messages[0])
+	}
+
+	if updater.Calls() != 3 {
+		t.Errorf("expected 3 handler calls, got %d", updater.Calls())
+	}
+	if n, _ := client.ZCard(ctx, RetryQueueKey).Result(); n != 0 {
+		t.Errorf("expected retry queue to be empty, got %d", n)
+	}
+
+	dead, err := client.XRange(ctx, DeadLetterStream, "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 {
+		t.Fatalf("expected 1 dead-lettered message, got %d", len(dead))
+	}
+	if dead[0].Values["retry_count"] != "2" || dead[0].Values["error"] != "database unavailable" {
+		t.Errorf("unexpected dead-letter entry %+v", dead[0].Values)
+	}
+}
+
+func TestRequeueTrimsStream(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	consumer := NewConsumer(client, &failingUpdater{}, zap.NewNop(), WithStreamMaxLen(3), WithRetryPolicy(RetryPolicy{
+		MaxRetries: 2,
+		BaseDelay:  time.Second,
+		MaxDelay:   time.Minute,
+	}))
+	consumer.now = func() time.Time { return now }
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRedisPublisher(client).Publish(ctx, "order.created", map[string]string{"id": "order-1"}); err != nil {
+		t.Fatal(err)
+	}
+	consumer.processMessage(ctx, readMessages(t, client)[0])
+	publishN(t, client, 5)
+
+	now = now.Add(time.Second)
+	if requeued, err := consumer.requeueDue(ctx); err != nil || requeued != 1 {
+		t.Fatalf("expected 1 requeued message, got %d (%v)", requeued, err)
+	}
+	if n, _ := client.XLen(ctx, StreamName).Result(); n > 3 {
+		t.Errorf("expected the requeue to trim the stream to about 3 entries, got %d", n)
+	}
+}
+
+func TestTransientFailureRetriedInline(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	calls := 0
+	consumer := NewConsumer(client, nil, zap.NewNop(),
+		WithRetryPolicy(RetryPolicy{MaxRetries: 1, BaseDelay: time.Second, MaxDelay: time.Minute, InlineRetries: 2, InlineDelay: time.Millisecond}),
+		WithEventHandler("order.created", func(ctx context.Context, event EventEnvelope) error {
+			calls++
+			if calls < 3 {
+				return errors.New("database unavailable")
+			}
+			return nil
+		}),
+	)
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRedisPublisher(client).Publish(ctx, "order.created", map[string]string{"id": "order-1"}); err != nil {
+		t.Fatal(err)
+	}
+	consumer.processMessage(ctx, readMessages(t, client)[0])
+
+	if calls != 3 {
+		t.Errorf("expected 3 handler calls, got %d", calls)
+	}
+	if pending := pendingCount(t, client); pending != 0 {
+		t.Errorf("expected message to be acked, %d pending", pending)
+	}
+	if n, _ := client.ZCard(ctx, RetryQueueKey).Result(); n != 0 {
+		t.Errorf("expected no delayed retries, got %d", n)
+	}
+}
+
+func TestShutdownDuringInlineRetryLeavesMessagePending(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	consumer := NewConsumer(client, nil, zap.NewNop(),
+		WithRetryPolicy(RetryPolicy{MaxRetries: 1, BaseDelay: time.Second, MaxDelay: time.Minute, InlineRetries: 2, InlineDelay: time.Minute}),
+		WithEventHandler("order.created", func(context.Context, EventEnvelope) error {
+			cancel()
+			return errors.New("database unavailable")
+		}),
+	)
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRedisPublisher(client).Publish(ctx, "order.created", map[string]string{"id": "order-1"}); err != nil {
+		t.Fatal(err)
+	}
+	consumer.processMessage(ctx, readMessages(t, client)[0])
+
+	if pending := pendingCount(t, client); pending != 1 {
+		t.Errorf("expected message to stay pending, %d pending", pending)
+	}
+	if n, _ := client.ZCard(context.Background(), RetryQueueKey).Result(); n != 0 {
+		t.Errorf("expected no delayed retries, got %d", n)
+	}
+}
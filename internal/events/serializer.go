@@ -0,0 +1,113 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/protoconv"
+	pb "github.com/orders-service/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// Serializer encodes event payloads. Its ContentType is written next to the
+// payload on the stream so consumers can pick the matching decoder.
+type Serializer interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type JSONSerializer struct{}
+
+func (JSONSerializer) ContentType() string { return ContentTypeJSON }
+
+func (JSONSerializer) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (JSONSerializer) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// ProtobufSerializer encodes proto messages as-is, orders as orders.Order and
+// status changes as orders.OrderStatusChanged.
+type ProtobufSerializer struct{}
+
+func (ProtobufSerializer) ContentType() string { return ContentTypeProtobuf }
+
+func (ProtobufSerializer) Marshal(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case proto.Message:
+		return proto.Marshal(m)
+	case *model.Order:
+		return proto.Marshal(protoconv.OrderToProto(m))
+	case *model.StatusChange:
+		return proto.Marshal(protoconv.StatusChangeToProto(m))
+	default:
+		return nil, fmt.Errorf("protobuf serializer: unsupported type %T", v)
+	}
+}
+
+func (ProtobufSerializer) Unmarshal(data []byte, v interface{}) error {
+	switch m := v.(type) {
+	case proto.Message:
+		return proto.Unmarshal(data, m)
+	case *model.Order:
+		var msg pb.Order
+		if err := proto.Unmarshal(data, &msg); err != nil {
+			return err
+		}
+		order, err := protoconv.OrderFromProto(&msg)
+		if err != nil {
+			return err
+		}
+		*m = *order
+		return nil
+	case *model.StatusChange:
+		var msg pb.OrderStatusChanged
+		if err := proto.Unmarshal(data, &msg); err != nil {
+			return err
+		}
+		change, err := protoconv.StatusChangeFromProto(&msg)
+		if err != nil {
+			return err
+		}
+		*m = *change
+		return nil
+	default:
+		return fmt.Errorf("protobuf serializer: unsupported type %T", v)
+	}
+}
+
+var serializers = map[string]Serializer{
+	ContentTypeJSON:     JSONSerializer{},
+	ContentTypeProtobuf: ProtobufSerializer{},
+}
+
+// SerializerFor returns the serializer for a content type. Messages written
+// before the content type was recorded carry none and are JSON.
+func SerializerFor(contentType string) (Serializer, error) {
+	if contentType == "" {
+		return JSONSerializer{}, nil
+	}
+	s, ok := serializers[contentType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported content type %q", contentType)
+	}
+	return s, nil
+}
+
+// ParseFormat maps an EVENT_FORMAT value ("json" or "protobuf") to its
+// serializer.
+func ParseFormat(format string) (Serializer, error) {
+	switch format {
+	case "json":
+		return JSONSerializer{}, nil
+	case "protobuf":
+		return ProtobufSerializer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown event format %q", format)
+	}
+}
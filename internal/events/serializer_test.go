@@ -0,0 +1,141 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/model"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+func TestProtobufSerializerRoundTripsOrder(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	order := &model.Order{ID: "order-1", Product: "widget", Quantity: 3, Status: model.StatusShipped, Price: 1250, Currency: "EUR", CreatedAt: created, UpdatedAt: created.Add(time.Hour),
+		Metadata: map[string]string{"channel": "web"}}
+
+	data, err := ProtobufSerializer{}.Marshal(order)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got model.Order
+	if err := (ProtobufSerializer{}).Unmarshal(data, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, *order) {
+		t.Errorf("expected %+v, got %+v", *order, got)
+	}
+
+	if _, err := (ProtobufSerializer{}).Marshal(map[string]string{"id": "order-1"}); err == nil {
+		t.Error("expected error for unsupported type")
+	}
+}
+
+func TestConsumerDecodesByContentType(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	var decoded []model.Order
+	consumer := NewConsumer(client, nil, zap.NewNop(), WithEventHandler("order.created", func(ctx context.Context, event EventEnvelope) error {
+		var order model.Order
+		if err := event.Decode(&order); err != nil {
+			return err
+		}
+		decoded = append(decoded, order)
+		return nil
+	}))
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	order := &model.Order{ID: "order-1", Product: "widget", Quantity: 2, Status: model.StatusPending}
+	if err := NewRedisPublisher(client, WithSerializer(ProtobufSerializer{})).Publish(ctx, "order.created", order); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRedisPublisher(client).Publish(ctx, "order.created", order); err != nil {
+		t.Fatal(err)
+	}
+	// Messages written before content types were recorded are JSON.
+	if err := client.XAdd(ctx, &redis.XAddArgs{Stream: StreamName, Values: map[string]interface{}{"event": "order.created", "payload": `{"id":"order-2"}`}}).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := readMessages(t, client)
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+	if messages[0].Values["content_type"] != ContentTypeProtobuf || messages[1].Values["content_type"] != ContentTypeJSON {
+		t.Errorf("unexpected content types %v, %v", messages[0].Values["content_type"], messages[1].Values["content_type"])
+	}
+	for _, m := range messages {
+		consumer.processMessage(ctx, m)
+	}
+
+	if len(decoded) != 3 {
+		t.Fatalf("expected 3 decoded orders, got %d", len(decoded))
+	}
+	if decoded[0].ID != "order-1" || decoded[0].Quantity != 2 || decoded[1].ID != "order-1" || decoded[2].ID != "order-2" {
+		t.Errorf("unexpected decoded orders %+v", decoded)
+	}
+}
+
+func TestProtobufPayloadSurvivesRetry(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var decoded []model.Order
+	consumer := NewConsumer(client, nil, zap.NewNop(),
+		WithRetryPolicy(RetryPolicy{MaxRetries: 1, BaseDelay: time.Second, MaxDelay: time.Minute}),
+		WithEventHandler("order.created", func(ctx context.Context, event EventEnvelope) error {
+			var order model.Order
+			if err := event.Decode(&order); err != nil {
+				return err
+			}
+			decoded = append(decoded, order)
+			if len(decoded) == 1 {
+				return errors.New("database unavailable")
+			}
+			return nil
+		}),
+	)
+	consumer.now = func() time.Time { return now }
+
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	order := &model.Order{ID: "order-1", Product: "widget", Quantity: 2, Status: model.StatusPending, Price: 999, Currency: "USD"}
+	if err := NewRedisPublisher(client, WithSerializer(ProtobufSerializer{})).Publish(ctx, "order.created", order); err != nil {
+		t.Fatal(err)
+	}
+	consumer.processMessage(ctx, readMessages(t, client)[0])
+
+	now = now.Add(time.Second)
+	if requeued, err := consumer.requeueDue(ctx); err != nil || requeued != 1 {
+		t.Fatalf("expected 1 requeued message, got %d (%v)", requeued, err)
+	}
+	consumer.processMessage(ctx, readMessages(t, client)[0])
+
+	if len(decoded) != 2 || !reflect.DeepEqual(decoded[1], decoded[0]) {
+		t.Errorf("expected the retried order to decode unchanged, got %+v", decoded)
+	}
+}
+
+func TestProtobufSerializerRoundTripsStatusChange(t *testing.T) {
+	change := &model.StatusChange{From: model.StatusPending, To: model.StatusConfirmed, Order: &model.Order{ID: "order-1", Status: model.StatusConfirmed}}
+
+	data, err := ProtobufSerializer{}.Marshal(change)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got model.StatusChange
+	if err := (ProtobufSerializer{}).Unmarshal(data, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.From != change.From || got.To != change.To || got.Order == nil || got.Order.ID != "order-1" {
+		t.Errorf("expected %+v, got %+v", change, got)
+	}
+}
@@ -0,0 +1,91 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/orders-service/internal/metrics"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const DefaultStatsInterval = 15 * time.Second
+
+type ConsumerStats struct {
+	Stream string `json:"stream"`
+	Group  string `json:"group"`
+	// Length is the number of entries in the stream, processed or not.
+	Length int64 `json:"length"`
+	// Lag is the number of entries not yet delivered to the group, or -1 if
+	// Redis cannot tell, e.g. before Redis 7 or after entries were deleted.
+	Lag int64 `json:"lag"`
+	// Pending is the number of entries delivered but not yet acked.
+	Pending   int64     `json:"pending"`
+	Consumers int64     `json:"consumers"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Stats reads how far the consumer group is behind the stream.
+func (c *Consumer) Stats(ctx context.Context) (*ConsumerStats, error) {
+	groups, err := c.client.XInfoGroups(ctx, StreamName).Result()
+	if err != nil {
+		return nil, err
+	}
+	stats := &ConsumerStats{Stream: StreamName, Group: ConsumerGroup, CheckedAt: c.now()}
+	found := false
+	for _, g := range groups {
+		if g.Name == ConsumerGroup {
+			stats.Lag = g.Lag
+			stats.Consumers = g.Consumers
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, ErrGroupNotFound
+	}
+
+	if stats.Length, err = c.client.XLen(ctx, StreamName).Result(); err != nil {
+		return nil, err
+	}
+	pending, err := c.client.XPending(ctx, StreamName, ConsumerGroup).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	if pending != nil {
+		stats.Pending = pending.Count
+	}
+	return stats, nil
+}
+
+// RunStatsLoop updates the consumer lag, pending and stream length gauges
+// every interval until ctx is cancelled.
+func (c *Consumer) RunStatsLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats, err := c.Stats(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					c.log.Error("redis: failed to read consumer stats", zap.Error(err))
+				}
+				continue
+			}
+			reportStats(stats)
+		}
+	}
+}
+
+func reportStats(stats *ConsumerStats) {
+	metrics.StreamLength.Set(float64(stats.Length))
+	metrics.ConsumerPending.Set(float64(stats.Pending))
+	if stats.Lag >= 0 {
+		metrics.ConsumerLag.Set(float64(stats.Lag))
+	}
+}
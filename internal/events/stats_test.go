@@ -0,0 +1,66 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/orders-service/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+func TestConsumerStats(t *testing.T) {
+	client := newTestClient(t)
+	pub := NewRedisPublisher(client)
+	consumer := NewConsumer(client, nil, zap.NewNop())
+	ctx := context.Background()
+
+	if err := client.XAdd(ctx, &redis.XAddArgs{Stream: StreamName, Values: map[string]interface{}{"event": "order.created"}}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := consumer.Stats(ctx); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("expected ErrGroupNotFound, got %v", err)
+	}
+	if err := client.Del(ctx, StreamName).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.XGroupCreateMkStream(ctx, StreamName, ConsumerGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		if err := pub.Publish(ctx, "order.created", map[string]string{"id": id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := consumer.Stats(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Length != 3 || stats.Lag != 3 || stats.Pending != 0 {
+		t.Errorf("expected length 3, lag 3 and nothing pending, got %+v", stats)
+	}
+
+	if err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: ConsumerGroup, Consumer: ConsumerName, Streams: []string{StreamName, ">"}, Count: 2,
+	}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = consumer.Stats(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Length != 3 || stats.Pending != 2 {
+		t.Errorf("expected length 3 and 2 pending, got %+v", stats)
+	}
+
+	reportStats(stats)
+	if got := testutil.ToFloat64(metrics.ConsumerPending); got != 2 {
+		t.Errorf("expected the pending gauge to be 2, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.StreamLength); got != 3 {
+		t.Errorf("expected the stream length gauge to be 3, got %v", got)
+	}
+}
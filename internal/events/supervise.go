@@ -0,0 +1,55 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/orders-service/internal/metrics"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultRestartDelay    = time.Second
+	DefaultMaxRestartDelay = 30 * time.Second
+)
+
+// Supervise runs fn until ctx is cancelled, restarting it whenever it
+// returns or panics while ctx is still alive. Restarts back off
+// exponentially from delay up to maxDelay; the backoff starts over once fn
+// has stayed up for maxDelay. Use it for the consumer loops, which would
+// otherwise stop processing for good.
+func Supervise(ctx context.Context, log *zap.Logger, name string, delay, maxDelay time.Duration, fn func(context.Context)) {
+	backoff := delay
+	for {
+		started := time.Now()
+		runGuarded(ctx, log, name, fn)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) >= maxDelay {
+			backoff = delay
+		}
+
+		metrics.ConsumerRestarts.WithLabelValues(name).Inc()
+		log.Warn("consumer loop stopped, restarting", zap.String("loop", name), zap.Duration("delay", backoff))
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, maxDelay)
+	}
+}
+
+// runGuarded runs fn, logging and recovering a panic.
+func runGuarded(ctx context.Context, log *zap.Logger, name string, fn func(context.Context)) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("panic in consumer loop", zap.String("loop", name), zap.Any("panic", r), zap.Stack("stack"))
+		}
+	}()
+	fn(ctx)
+}
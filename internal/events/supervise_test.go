@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestSuperviseRestartsUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := make(chan int, 10)
+	n := 0
+	done := make(chan struct{})
+	go func() {
+		Supervise(ctx, zap.NewNop(), "test", time.Millisecond, 10*time.Millisecond, func(ctx context.Context) {
+			n++
+			runs <- n
+			switch n {
+			case 1:
+				return
+			case 2:
+				panic("boom")
+			default:
+				<-ctx.Done()
+			}
+		})
+		close(done)
+	}()
+
+	for want := 1; want <= 3; want++ {
+		select {
+		case got := <-runs:
+			if got != want {
+				t.Fatalf("expected run %d, got %d", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected run %d after the previous one stopped", want)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Supervise to return once ctx is cancelled")
+	}
+	if len(runs) != 0 {
+		t.Errorf("expected no restart after cancellation, got %d", len(runs))
+	}
+}
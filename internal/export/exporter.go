@@ -0,0 +1,207 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/orders-service/internal/model"
+	"go.uber.org/zap"
+)
+
+const DefaultCursorName = "orders"
+
+// DefaultOverlap is how far behind its cursor each export looks again for
+// orders that committed late. It must be longer than the longest transaction
+// that creates orders.
+const DefaultOverlap = 5 * time.Minute
+
+type ObjectStore interface {
+	Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) error
+}
+
+type OrderSource interface {
+	StreamSince(ctx context.Context, createdAt time.Time, afterID string, fn func(model.Order) error) error
+}
+
+// Cursor is how far an export has got: the last order exported, in
+// (created_at, id) order, and the creation times of the orders exported
+// within the overlap window before it, by ID.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+	Recent    map[string]time.Time
+}
+
+type CursorStore interface {
+	LoadCursor(ctx context.Context, name string) (Cursor, error)
+	SaveCursor(ctx context.Context, name string, cursor Cursor) error
+}
+
+type Exporter struct {
+	source   OrderSource
+	store    ObjectStore
+	cursors  CursorStore
+	log      *zap.Logger
+	interval time.Duration
+	overlap  time.Duration
+	prefix   string
+	name     string
+	now      func() time.Time
+}
+
+type Option func(*Exporter)
+
+// WithOverlap sets how far behind its cursor each export looks again for
+// orders that committed late.
+func WithOverlap(d time.Duration) Option {
+	return func(e *Exporter) {
+		e.overlap = d
+	}
+}
+
+func NewExporter(source OrderSource, store ObjectStore, cursors CursorStore, interval time.Duration, prefix string, log *zap.Logger, opts ...Option) *Exporter {
+	e := &Exporter{
+		source:   source,
+		store:    store,
+		cursors:  cursors,
+		log:      log,
+		interval: interval,
+		overlap:  DefaultOverlap,
+		prefix:   prefix,
+		name:     DefaultCursorName,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+func (e *Exporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	e.log.Info("order exporter started", zap.Duration("interval", e.interval), zap.String("prefix", e.prefix))
+
+	for {
+		select {
+		case <-ctx.Done():
+			e.log.Info("order exporter shutting down")
+			return
+		case <-ticker.C:
+			key, count, err := e.ExportOnce(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				e.log.Error("failed to export orders", zap.Error(err))
+				continue
+			}
+			if count > 0 {
+				e.log.Info("orders exported", zap.String("key", key), zap.Int("count", count))
+			}
+		}
+	}
+}
+
+// ExportOnce writes every order not yet exported to a single NDJSON object
+// and advances the cursor. Orders are timestamped when their transaction
+// starts, so one can commit after a later order was already exported; each
+// export therefore reads again from the overlap window before the cursor and
+// skips the orders the cursor records as exported there. Rows are spooled to
+// a temporary file so memory use does not grow with the size of the export.
+func (e *Exporter) ExportOnce(ctx context.Context) (string, int, error) {
+	cursor, err := e.cursors.LoadCursor(ctx, e.name)
+	if err != nil {
+		return "", 0, fmt.Errorf("load cursor: %w", err)
+	}
+
+	tmp, err := os.CreateTemp("", "orders-export-*.ndjson")
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() {
+		if err := os.Remove(tmp.Name()); err != nil {
+			e.log.Warn("failed to remove export spool file", zap.String("path", tmp.Name()), zap.Error(err))
+		}
+	}()
+
+	next, count, err := e.spool(ctx, tmp, cursor)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	if count == 0 {
+		return "", 0, nil
+	}
+
+	spooled, err := os.Open(tmp.Name())
+	if err != nil {
+		return "", 0, err
+	}
+	// The file was only read, so closing it cannot lose data.
+	defer func() { _ = spooled.Close() }()
+
+	key := path.Join(e.prefix, fmt.Sprintf("orders-%s.ndjson", e.now().UTC().Format("20060102T150405Z")))
+	if err := e.store.Put(ctx, key, spooled, "application/x-ndjson"); err != nil {
+		return "", 0, fmt.Errorf("upload %s: %w", key, err)
+	}
+
+	if err := e.cursors.SaveCursor(ctx, e.name, next); err != nil {
+		return "", 0, fmt.Errorf("save cursor: %w", err)
+	}
+
+	return key, count, nil
+}
+
+// spool writes the orders cursor has not exported to w as NDJSON and returns
+// the cursor advanced past them.
+func (e *Exporter) spool(ctx context.Context, w io.Writer, cursor Cursor) (Cursor, int, error) {
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+
+	next := Cursor{CreatedAt: cursor.CreatedAt, ID: cursor.ID, Recent: make(map[string]time.Time, len(cursor.Recent))}
+	for id, createdAt := range cursor.Recent {
+		next.Recent[id] = createdAt
+	}
+
+	from := cursor.CreatedAt
+	if !from.IsZero() {
+		from = from.Add(-e.overlap)
+	}
+
+	count := 0
+	err := e.source.StreamSince(ctx, from, "", func(o model.Order) error {
+		if _, ok := cursor.Recent[o.ID]; ok {
+			return nil
+		}
+		if err := enc.Encode(o); err != nil {
+			return err
+		}
+		next.Recent[o.ID] = o.CreatedAt
+		if o.CreatedAt.After(next.CreatedAt) || (o.CreatedAt.Equal(next.CreatedAt) && o.ID > next.ID) {
+			next.CreatedAt, next.ID = o.CreatedAt, o.ID
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return Cursor{}, 0, fmt.Errorf("stream orders: %w", err)
+	}
+
+	horizon := next.CreatedAt.Add(-e.overlap)
+	for id, createdAt := range next.Recent {
+		if createdAt.Before(horizon) {
+			delete(next.Recent, id)
+		}
+	}
+	return next, count, buf.Flush()
+}
@@ -0,0 +1,206 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/model"
+	"go.uber.org/zap"
+)
+
+type fakeSource struct {
+	orders []model.Order
+}
+
+func (f *fakeSource) StreamSince(ctx context.Context, createdAt time.Time, afterID string, fn func(model.Order) error) error {
+	for _, o := range f.orders {
+		if o.CreatedAt.Before(createdAt) || (o.CreatedAt.Equal(createdAt) && o.ID <= afterID) {
+			continue
+		}
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type fakeStore struct {
+	objects map[string][]byte
+	mu      sync.Mutex
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{objects: make(map[string][]byte)}
+}
+
+func (f *fakeStore) Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[key] = data
+	return nil
+}
+
+type fakeCursors struct {
+	cursor Cursor
+}
+
+func (f *fakeCursors) LoadCursor(ctx context.Context, name string) (Cursor, error) {
+	return f.cursor, nil
+}
+
+func (f *fakeCursors) SaveCursor(ctx context.Context, name string, cursor Cursor) error {
+	f.cursor = cursor
+	return nil
+}
+
+func decodeNDJSON(t *testing.T, data []byte) []model.Order {
+	t.Helper()
+	var orders []model.Order
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var o model.Order
+		if err := json.Unmarshal(scanner.Bytes(), &o); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		orders = append(orders, o)
+	}
+	return orders
+}
+
+func TestExportOnce(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	source := &fakeSource{orders: []model.Order{
+		{ID: "a", Product: "Widget", Quantity: 1, Status: "pending", CreatedAt: base},
+		{ID: "b", Product: "Gadget", Quantity: 2, Status: "confirmed", CreatedAt: base.Add(time.Minute)},
+	}}
+	store := newFakeStore()
+	cursors := &fakeCursors{}
+
+	exp := NewExporter(source, store, cursors, time.Hour, "exports", zap.NewNop())
+	exp.now = func() time.Time { return base.Add(time.Hour) }
+
+	key, count, err := exp.ExportOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if key != "exports/orders-20240101T130000Z.ndjson" {
+		t.Errorf("unexpected key %s", key)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 orders exported, got %d", count)
+	}
+
+	orders := decodeNDJSON(t, store.objects[key])
+	if len(orders) != 2 {
+		t.Fatalf("expected 2 lines in export, got %d", len(orders))
+	}
+	if orders[0].ID != "a" || orders[1].ID != "b" {
+		t.Errorf("unexpected export contents: %+v", orders)
+	}
+
+	if cursors.cursor.ID != "b" || !cursors.cursor.CreatedAt.Equal(base.Add(time.Minute)) {
+		t.Errorf("expected cursor to advance to b, got %s at %v", cursors.cursor.ID, cursors.cursor.CreatedAt)
+	}
+}
+
+func TestExportOnceOnlyNewOrders(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	source := &fakeSource{orders: []model.Order{
+		{ID: "a", Product: "Widget", Quantity: 1, Status: "pending", CreatedAt: base},
+	}}
+	store := newFakeStore()
+	cursors := &fakeCursors{}
+
+	exp := NewExporter(source, store, cursors, time.Hour, "exports", zap.NewNop())
+	exp.now = func() time.Time { return base.Add(time.Hour) }
+
+	if _, _, err := exp.ExportOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	key, count, err := exp.ExportOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 0 || key != "" {
+		t.Errorf("expected empty export to be skipped, got %d orders at %q", count, key)
+	}
+
+	source.orders = append(source.orders, model.Order{ID: "c", Product: "Gizmo", Quantity: 3, Status: "pending", CreatedAt: base.Add(2 * time.Minute)})
+	exp.now = func() time.Time { return base.Add(2 * time.Hour) }
+
+	key, count, err = exp.ExportOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 new order exported, got %d", count)
+	}
+
+	orders := decodeNDJSON(t, store.objects[key])
+	if len(orders) != 1 || orders[0].ID != "c" {
+		t.Errorf("expected only order c in second export, got %+v", orders)
+	}
+	if len(store.objects) != 2 {
+		t.Errorf("expected 2 objects in store, got %d", len(store.objects))
+	}
+}
+
+func TestExportOnceIncludesLateCommittedOrders(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	source := &fakeSource{orders: []model.Order{
+		{ID: "b", Product: "Gadget", Quantity: 1, Status: "pending", CreatedAt: base.Add(time.Minute)},
+	}}
+	store := newFakeStore()
+	cursors := &fakeCursors{}
+
+	exp := NewExporter(source, store, cursors, time.Hour, "exports", zap.NewNop(), WithOverlap(5*time.Minute))
+	exp.now = func() time.Time { return base.Add(time.Hour) }
+	if _, _, err := exp.ExportOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a was created before b but committed after b was exported.
+	source.orders = []model.Order{
+		{ID: "a", Product: "Widget", Quantity: 1, Status: "pending", CreatedAt: base},
+		source.orders[0],
+		{ID: "c", Product: "Gizmo", Quantity: 1, Status: "pending", CreatedAt: base.Add(2 * time.Minute)},
+	}
+	exp.now = func() time.Time { return base.Add(2 * time.Hour) }
+	key, count, err := exp.ExportOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	orders := decodeNDJSON(t, store.objects[key])
+	if count != 2 || len(orders) != 2 || orders[0].ID != "a" || orders[1].ID != "c" {
+		t.Errorf("expected the late order a and the new order c without b again, got %+v", orders)
+	}
+	if cursors.cursor.ID != "c" || len(cursors.cursor.Recent) != 3 {
+		t.Errorf("expected cursor at c recording a, b and c, got %+v", cursors.cursor)
+	}
+
+	// Orders that fall out of the overlap window are forgotten.
+	source.orders = append(source.orders, model.Order{ID: "d", Product: "Gizmo", Quantity: 1, Status: "pending", CreatedAt: base.Add(10 * time.Minute)})
+	exp.now = func() time.Time { return base.Add(3 * time.Hour) }
+	key, count, err = exp.ExportOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if orders := decodeNDJSON(t, store.objects[key]); count != 1 || orders[0].ID != "d" {
+		t.Errorf("expected only d to be exported, got %+v", orders)
+	}
+	if _, ok := cursors.cursor.Recent["d"]; !ok || len(cursors.cursor.Recent) != 1 {
+		t.Errorf("expected only d to be remembered, got %v", cursors.cursor.Recent)
+	}
+}
@@ -0,0 +1,28 @@
+package export
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type S3ObjectStore struct {
+	client *s3.Client
+	bucket string
+}
+
+func NewS3ObjectStore(client *s3.Client, bucket string) *S3ObjectStore {
+	return &S3ObjectStore{client: client, bucket: bucket}
+}
+
+func (s *S3ObjectStore) Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
@@ -0,0 +1,134 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/orders-service/internal/auth"
+	"github.com/orders-service/internal/repo"
+	"github.com/orders-service/internal/service"
+	pb "github.com/orders-service/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// stubVerifier maps an authorization value or API key to an identity.
+type stubVerifier map[string]*auth.Identity
+
+func (v stubVerifier) Authenticate(ctx context.Context, authorization, apiKey string) (*auth.Identity, error) {
+	credential := authorization
+	if apiKey != "" {
+		credential = apiKey
+	}
+	if id, ok := v[credential]; ok {
+		return id, nil
+	}
+	return nil, auth.ErrInvalidToken
+}
+
+func TestAuthenticator(t *testing.T) {
+	authenticator := NewAuthenticator(stubVerifier{
+		"Bearer good": {Subject: "alice", Scopes: []string{auth.ScopeRead}},
+		"reader":      {Subject: "apikey:reader", Scopes: []string{auth.ScopeRead}},
+	}, zap.NewNop())
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authenticator.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(authenticator.StreamInterceptor()),
+	)
+	pb.RegisterOrderServiceServer(srv, NewServer(service.NewOrderService(repo.NewInMemoryOrderRepository(), nil), zap.NewNop()))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	conn := newBufconnClient(t, srv)
+	client := pb.NewOrderServiceClient(conn)
+	ctx := context.Background()
+
+	for name, token := range map[string]string{"missing": "", "invalid": "Bearer bad"} {
+		callCtx := ctx
+		if token != "" {
+			callCtx = metadata.AppendToOutgoingContext(ctx, "authorization", token)
+		}
+		if _, err := client.ListOrders(callCtx, &pb.ListOrdersRequest{}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s token: expected Unauthenticated, got %v", name, err)
+		}
+		stream, err := client.StreamOrders(callCtx, &pb.ListOrdersRequest{})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s token: expected Unauthenticated from stream, got %v", name, err)
+		}
+	}
+
+	authed := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer good")
+	if _, err := client.ListOrders(authed, &pb.ListOrdersRequest{}); err != nil {
+		t.Errorf("expected a valid token to be accepted, got %v", err)
+	}
+	reader := metadata.AppendToOutgoingContext(ctx, "x-api-key", "reader")
+	if _, err := client.ListOrders(reader, &pb.ListOrdersRequest{}); err != nil {
+		t.Errorf("expected a read-only key to list orders, got %v", err)
+	}
+	if _, err := client.DeleteOrder(reader, &pb.DeleteOrderRequest{Id: "order-1"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for a read-only key, got %v", err)
+	}
+	if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("expected health checks to skip auth, got %v", err)
+	}
+}
+
+func TestAuthorizationMatrix(t *testing.T) {
+	scopes := map[string][]string{
+		"none":       nil,
+		"read":       {auth.ScopeRead},
+		"write":      {auth.ScopeWrite},
+		"read+write": {auth.ScopeRead, auth.ScopeWrite},
+	}
+	verifier := stubVerifier{}
+	for name, granted := range scopes {
+		verifier["Bearer "+name] = &auth.Identity{Subject: name, Scopes: granted}
+	}
+	authenticator := NewAuthenticator(verifier, zap.NewNop())
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(authenticator.UnaryInterceptor()))
+	pb.RegisterOrderServiceServer(srv, NewServer(service.NewOrderService(repo.NewInMemoryOrderRepository(), nil), zap.NewNop()))
+	client := pb.NewOrderServiceClient(newBufconnClient(t, srv))
+
+	calls := map[string]struct {
+		scope string
+		call  func(ctx context.Context) error
+	}{
+		"GetOrder": {auth.ScopeRead, func(ctx context.Context) error {
+			_, err := client.GetOrder(ctx, &pb.GetOrderRequest{Id: "order-1"})
+			return err
+		}},
+		"ListOrders": {auth.ScopeRead, func(ctx context.Context) error {
+			_, err := client.ListOrders(ctx, &pb.ListOrdersRequest{})
+			return err
+		}},
+		"CreateOrder": {auth.ScopeWrite, func(ctx context.Context) error {
+			_, err := client.CreateOrder(ctx, &pb.CreateOrderRequest{CustomerId: "customer-1", Product: "Widget", Quantity: 1})
+			return err
+		}},
+		"UpdateOrder": {auth.ScopeWrite, func(ctx context.Context) error {
+			_, err := client.UpdateOrder(ctx, &pb.UpdateOrderRequest{Id: "order-1", Product: "Widget", Quantity: 1})
+			return err
+		}},
+		"DeleteOrder": {auth.ScopeWrite, func(ctx context.Context) error {
+			_, err := client.DeleteOrder(ctx, &pb.DeleteOrderRequest{Id: "order-1"})
+			return err
+		}},
+	}
+
+	for name, granted := range scopes {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+name)
+		for method, c := range calls {
+			allowed := (&auth.Identity{Scopes: granted}).HasScope(c.scope)
+			denied := status.Code(c.call(ctx)) == codes.PermissionDenied
+			if denied == allowed {
+				t.Errorf("%s with %s scopes: expected allowed=%v, got PermissionDenied=%v", method, name, allowed, denied)
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package grpc
+
+import (
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/protoconv"
+	"github.com/orders-service/internal/service"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain is the domain of the ErrorInfo details attached to errors.
+const errorDomain = "orders-service"
+
+// reasonEventNotPublished is the ErrorInfo reason of a CreateOrder that
+// saved the order but did not publish its event.
+const reasonEventNotPublished = "event_not_published"
+
+var serviceCodes = map[string]codes.Code{
+	service.CodeValidation:         codes.InvalidArgument,
+	service.CodeNotFound:           codes.NotFound,
+	service.CodeConflict:           codes.Aborted,
+	service.CodeFailedPrecondition: codes.FailedPrecondition,
+	service.CodePolicyDenied:       codes.FailedPrecondition,
+	service.CodeRateLimited:        codes.ResourceExhausted,
+	service.CodeUnavailable:        codes.Unavailable,
+	service.CodeInternal:           codes.Internal,
+}
+
+// serviceError converts err into a status with the gRPC code matching its
+// service error code. The service code is attached as the reason of an
+// ErrorInfo detail, so clients can tell failures apart as they can over HTTP.
+func serviceError(err error) error {
+	code := service.ErrorCode(err)
+	st := status.New(serviceCodes[code], service.ErrorMessage(err))
+	if detailed, detailErr := st.WithDetails(&errdetails.ErrorInfo{Reason: code, Domain: errorDomain}); detailErr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// eventNotPublishedError reports that order was saved even though CreateOrder
+// failed, so that clients do not retry and create it a second time. Like the
+// HTTP error it names the order; the order itself is attached as a detail
+// next to the ErrorInfo, which carries its id as order_id.
+func eventNotPublishedError(err *service.EventNotPublishedError, order *model.Order) error {
+	st := status.New(codes.Internal, err.Error())
+	info := &errdetails.ErrorInfo{Reason: reasonEventNotPublished, Domain: errorDomain, Metadata: map[string]string{"order_id": err.OrderID}}
+	if detailed, detailErr := st.WithDetails(info, protoconv.OrderToProto(order)); detailErr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
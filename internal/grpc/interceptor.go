@@ -0,0 +1,69 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type StreamLimiter struct {
+	limit  int
+	mu     sync.Mutex
+	active map[string]int
+}
+
+func NewStreamLimiter(limit int) *StreamLimiter {
+	return &StreamLimiter{limit: limit, active: make(map[string]int)}
+}
+
+func (l *StreamLimiter) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if l.limit <= 0 {
+			return handler(srv, ss)
+		}
+
+		key := clientKey(ss.Context())
+		if !l.acquire(key) {
+			return status.Errorf(codes.ResourceExhausted, "too many concurrent streams for client (limit %d)", l.limit)
+		}
+		defer l.release(key)
+
+		return handler(srv, ss)
+	}
+}
+
+func (l *StreamLimiter) acquire(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active[key] >= l.limit {
+		return false
+	}
+	l.active[key]++
+	return true
+}
+
+func (l *StreamLimiter) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active[key]--
+	if l.active[key] <= 0 {
+		delete(l.active, key)
+	}
+}
+
+func clientKey(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,106 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newBufconnClient(t *testing.T, srv *grpc.Server) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func openWatch(ctx context.Context, client healthpb.HealthClient) error {
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	_, err = stream.Recv()
+	return err
+}
+
+func TestStreamLimiterPerClient(t *testing.T) {
+	const limit = 2
+
+	srv := grpc.NewServer(grpc.ChainStreamInterceptor(NewStreamLimiter(limit).StreamInterceptor()))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	client := healthpb.NewHealthClient(newBufconnClient(t, srv))
+
+	cancels := make([]context.CancelFunc, 0, limit)
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	for i := 0; i < limit; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels = append(cancels, cancel)
+		if err := openWatch(ctx, client); err != nil {
+			t.Fatalf("stream %d: unexpected error: %v", i, err)
+		}
+	}
+
+	err := openWatch(context.Background(), client)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted beyond the limit, got %v", err)
+	}
+
+	cancels[0]()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		ctx, cancel := context.WithCancel(context.Background())
+		err := openWatch(ctx, client)
+		if err == nil {
+			cancels = append(cancels, cancel)
+			break
+		}
+		cancel()
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a slot to free up after closing a stream, got %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStreamLimiterDisabled(t *testing.T) {
+	srv := grpc.NewServer(grpc.ChainStreamInterceptor(NewStreamLimiter(0).StreamInterceptor()))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	client := healthpb.NewHealthClient(newBufconnClient(t, srv))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for i := 0; i < 5; i++ {
+		if err := openWatch(ctx, client); err != nil {
+			t.Fatalf("stream %d: unexpected error: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/orders-service/internal/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// LoggingUnaryInterceptor writes an access log entry for every call with its
+// method, status code and latency, the gRPC counterpart of
+// logger.Middleware. Calls without x-request-id metadata are given one, so
+// the access log and the handler's logs share it.
+func LoggingUnaryInterceptor(log *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, callLog := withRequestID(ctx, log)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(callLog, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func LoggingStreamInterceptor(log *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, callLog := withRequestID(ss.Context(), log)
+		start := time.Now()
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		logCall(callLog, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func withRequestID(ctx context.Context, log *zap.Logger) (context.Context, *zap.Logger) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	requestID := firstValue(md, requestIDMetadata)
+	if requestID == "" {
+		requestID = uuid.New().String()
+		md = md.Copy()
+		md.Set(requestIDMetadata, requestID)
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+	callLog := log.With(zap.String("request_id", requestID))
+	return logger.WithContext(ctx, callLog), callLog
+}
+
+func logCall(log *zap.Logger, method string, start time.Time, err error) {
+	log.Info("grpc request",
+		zap.String("method", method),
+		zap.String("code", status.Code(err).String()),
+		zap.Duration("latency", time.Since(start)),
+	)
+}
+
+// RecoveryUnaryInterceptor turns a panic in a handler into a codes.Internal
+// error instead of letting it take down the server, logging the panic with
+// its stack.
+func RecoveryUnaryInterceptor(log *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(log, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func RecoveryStreamInterceptor(log *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(log, info.FullMethod, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recovered(log *zap.Logger, method string, r interface{}) error {
+	log.Error("panic in gRPC handler", zap.String("method", method), zap.Any("panic", r), zap.Stack("stack"))
+	return status.Error(codes.Internal, "internal error")
+}
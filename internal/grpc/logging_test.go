@@ -0,0 +1,110 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+	"github.com/orders-service/internal/service"
+	pb "github.com/orders-service/proto"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type panickingRepo struct {
+	*repo.InMemoryOrderRepository
+}
+
+func (r panickingRepo) GetByID(ctx context.Context, id string) (*model.Order, error) {
+	panic("boom")
+}
+
+func (r panickingRepo) StreamSince(ctx context.Context, createdAt time.Time, afterID string, fn func(model.Order) error) error {
+	panic("boom")
+}
+
+func TestLoggingAndRecoveryInterceptors(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	log := zap.New(core)
+	store := panickingRepo{repo.NewInMemoryOrderRepository()}
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(LoggingUnaryInterceptor(log), RecoveryUnaryInterceptor(log)),
+		grpc.ChainStreamInterceptor(LoggingStreamInterceptor(log), RecoveryStreamInterceptor(log)),
+	)
+	pb.RegisterOrderServiceServer(srv, NewServer(service.NewOrderService(store, nil, service.WithOrderStream(store)), log))
+	client := pb.NewOrderServiceClient(newBufconnClient(t, srv))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-123")
+
+	_, err := client.GetOrder(ctx, &pb.GetOrderRequest{Id: "order-1"})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("expected Internal, got %v", err)
+	}
+
+	stream, err := client.StreamOrders(ctx, &pb.ListOrdersRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Internal {
+		t.Fatalf("expected Internal from the stream, got %v", err)
+	}
+
+	// The server survives the panics.
+	if _, err := client.CreateOrder(ctx, &pb.CreateOrderRequest{CustomerId: "customer-1", Product: "Widget", Quantity: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := logs.FilterMessage("panic in gRPC handler").Len(); n != 2 {
+		t.Errorf("expected 2 panic logs, got %d", n)
+	}
+	want := map[string]string{
+		pb.OrderService_GetOrder_FullMethodName:     codes.Internal.String(),
+		pb.OrderService_StreamOrders_FullMethodName: codes.Internal.String(),
+		pb.OrderService_CreateOrder_FullMethodName:  codes.OK.String(),
+	}
+	entries := logs.FilterMessage("grpc request").All()
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d access log entries, got %d", len(want), len(entries))
+	}
+	for _, entry := range entries {
+		fields := entry.ContextMap()
+		method, _ := fields["method"].(string)
+		if code, ok := want[method]; !ok || fields["code"] != code {
+			t.Errorf("%s: expected code %q, got %v", method, code, fields["code"])
+		}
+		if fields["request_id"] != "req-123" {
+			t.Errorf("%s: expected request_id req-123, got %v", method, fields["request_id"])
+		}
+		if _, ok := fields["latency"]; !ok {
+			t.Errorf("%s: expected a latency field", method)
+		}
+	}
+}
+
+func TestLoggingGeneratesRequestID(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	log := zap.New(core)
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(LoggingUnaryInterceptor(log)))
+	pb.RegisterOrderServiceServer(srv, NewServer(service.NewOrderService(repo.NewInMemoryOrderRepository(), nil), log))
+	client := pb.NewOrderServiceClient(newBufconnClient(t, srv))
+
+	var header metadata.MD
+	if _, err := client.CreateOrder(context.Background(), &pb.CreateOrderRequest{CustomerId: "customer-1", Product: "Widget", Quantity: 1}, grpc.Header(&header)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := header.Get("x-request-id")
+	if len(got) != 1 || got[0] == "" {
+		t.Fatalf("expected a generated request ID, got %v", got)
+	}
+	for _, entry := range logs.All() {
+		if id := entry.ContextMap()["request_id"]; id != got[0] {
+			t.Errorf("%q: expected request_id %s, got %v", entry.Message, got[0], id)
+		}
+	}
+}
@@ -2,31 +2,59 @@ package grpc
 
 import (
 	"context"
-	"database/sql"
 	"errors"
+	"fmt"
+	"net/url"
 
 	"github.com/google/uuid"
+	"github.com/orders-service/internal/admission"
+	"github.com/orders-service/internal/auth"
 	"github.com/orders-service/internal/logger"
 	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/protoconv"
 	"github.com/orders-service/internal/service"
 	pb "github.com/orders-service/proto"
 	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
 )
 
+// DefaultMaxListResponseBytes keeps ListOrders responses under the 4 MiB
+// that gRPC clients accept by default.
+const DefaultMaxListResponseBytes = 4 << 20
+
 type Server struct {
 	pb.UnimplementedOrderServiceServer
-	orderService *service.OrderService
-	log          *zap.Logger
+	orderService         *service.OrderService
+	log                  *zap.Logger
+	maxListResponseBytes int
+}
+
+type Option func(*Server)
+
+// WithMaxListResponseBytes overrides DefaultMaxListResponseBytes. Zero or
+// less disables the limit.
+func WithMaxListResponseBytes(n int) Option {
+	return func(s *Server) {
+		s.maxListResponseBytes = n
+	}
 }
 
-func NewServer(orderService *service.OrderService, log *zap.Logger) *Server {
-	return &Server{
-		orderService: orderService,
-		log:          log,
+func NewServer(orderService *service.OrderService, log *zap.Logger, opts ...Option) *Server {
+	s := &Server{
+		orderService:         orderService,
+		log:                  log,
+		maxListResponseBytes: DefaultMaxListResponseBytes,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 func (s *Server) CreateOrder(ctx context.Context, req *pb.CreateOrderRequest) (*pb.CreateOrderResponse, error) {
@@ -39,52 +67,163 @@ func (s *Server) CreateOrder(ctx context.Context, req *pb.CreateOrderRequest) (*
 	}
 
 	createReq := service.CreateOrderRequest{
-		Product:  req.Product,
-		Quantity: int(req.Quantity),
+		CustomerID:     req.CustomerId,
+		Product:        req.Product,
+		Quantity:       int(req.Quantity),
+		Price:          req.Price,
+		Currency:       req.Currency,
+		Metadata:       req.Metadata,
+		IdempotencyKey: idempotencyKey,
 	}
 
-	order, err := s.orderService.CreateOrder(ctx, createReq)
+	var order *service.OrderResult
+	var err error
+	if req.ValidateOnly {
+		order, err = s.orderService.ValidateOrder(ctx, createReq)
+	} else {
+		order, err = s.orderService.CreateOrder(ctx, createReq)
+	}
+	var partialErr *service.EventNotPublishedError
+	if errors.As(err, &partialErr) && order != nil {
+		log.Error("order created but its event was not published", zap.String("order_id", order.ID), zap.Error(err))
+		setCreatedHeaders(ctx, order.ID, idempotencyKey)
+		return nil, eventNotPublishedError(partialErr, order.Order)
+	}
 	if err != nil {
-		log.Error("failed to create order", zap.Error(err))
-		return nil, status.Error(codes.Internal, "failed to create order")
+		switch service.ErrorCode(err) {
+		case service.CodeInternal:
+			log.Error("failed to create order", zap.Error(err))
+			return nil, status.Error(codes.Internal, "failed to create order")
+		case service.CodeUnavailable:
+			log.Error("policy service unavailable", zap.Error(err))
+		}
+		return nil, serviceError(err)
+	}
+	if req.ValidateOnly {
+		return &pb.CreateOrderResponse{
+			Order:    protoconv.OrderToProto(order.Order),
+			Warnings: warningsToProto(order.Warnings),
+		}, nil
 	}
 
 	log.Info("order created via gRPC", zap.String("order_id", order.ID))
+	setStreamPosition(ctx, order.StreamPosition)
+	setCreatedHeaders(ctx, order.ID, idempotencyKey)
 	return &pb.CreateOrderResponse{
-		Order: modelToProto(order),
+		Order:    protoconv.OrderToProto(order.Order),
+		Warnings: warningsToProto(order.Warnings),
 	}, nil
 }
 
+// BatchCreateOrders creates all orders or none. Invalid orders are reported
+// as INVALID_ARGUMENT with a BadRequest detail naming each "orders[i].field".
+func (s *Server) BatchCreateOrders(ctx context.Context, req *pb.BatchCreateOrdersRequest) (*pb.BatchCreateOrdersResponse, error) {
+	ctx, log := s.setupContext(ctx)
+
+	createReqs := make([]service.CreateOrderRequest, len(req.Orders))
+	for i, o := range req.Orders {
+		createReqs[i] = service.CreateOrderRequest{
+			CustomerID: o.CustomerId,
+			Product:    o.Product,
+			Quantity:   int(o.Quantity),
+			Price:      o.Price,
+			Currency:   o.Currency,
+			Metadata:   o.Metadata,
+		}
+	}
+
+	results, err := s.orderService.CreateOrders(ctx, createReqs)
+	if err != nil {
+		var batchErr *service.BatchValidationError
+		if errors.As(err, &batchErr) {
+			return nil, batchValidationStatus(batchErr).Err()
+		}
+		if errors.Is(err, service.ErrBatchUnavailable) {
+			return nil, status.Error(codes.Unimplemented, err.Error())
+		}
+		switch service.ErrorCode(err) {
+		case service.CodeInternal:
+			log.Error("failed to create orders", zap.Error(err))
+			return nil, status.Error(codes.Internal, "failed to create orders")
+		case service.CodeUnavailable:
+			log.Error("policy service unavailable", zap.Error(err))
+		}
+		return nil, serviceError(err)
+	}
+
+	resp := &pb.BatchCreateOrdersResponse{Orders: make([]*pb.CreateOrderResponse, len(results))}
+	for i, result := range results {
+		resp.Orders[i] = &pb.CreateOrderResponse{
+			Order:    protoconv.OrderToProto(result.Order),
+			Warnings: warningsToProto(result.Warnings),
+		}
+	}
+	log.Info("orders created via gRPC", zap.Int("orders", len(results)))
+	setStreamPosition(ctx, results[len(results)-1].StreamPosition)
+	return resp, nil
+}
+
+func batchValidationStatus(err *service.BatchValidationError) *status.Status {
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(err.Errors))
+	for i, e := range err.Errors {
+		violations[i] = &errdetails.BadRequest_FieldViolation{
+			Field:       fmt.Sprintf("orders[%d].%s", e.Index, e.Field),
+			Description: e.Message,
+		}
+	}
+	st := status.New(codes.InvalidArgument, err.Error())
+	if detailed, detailErr := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); detailErr == nil {
+		return detailed
+	}
+	return st
+}
+
 func (s *Server) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.GetOrderResponse, error) {
 	ctx, log := s.setupContext(ctx)
 
 	order, err := s.orderService.GetOrder(ctx, req.Id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, service.ErrOrderNotFound) {
 			log.Warn("order not found", zap.String("order_id", req.Id))
-			return nil, status.Error(codes.NotFound, "order not found")
+			return nil, serviceError(err)
 		}
 		log.Error("failed to get order", zap.String("order_id", req.Id), zap.Error(err))
 		return nil, status.Error(codes.Internal, "failed to get order")
 	}
 
 	return &pb.GetOrderResponse{
-		Order: modelToProto(order),
+		Order: protoconv.OrderToProto(order),
 	}, nil
 }
 
 func (s *Server) ListOrders(ctx context.Context, req *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
 	ctx, log := s.setupContext(ctx)
 
-	orders, err := s.orderService.GetOrders(ctx)
+	orders, err := s.orderService.ListOrders(ctx, service.ListQuery{CustomerID: req.CustomerId})
 	if err != nil {
+		if errors.Is(err, admission.ErrOverloaded) {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		if errors.Is(err, service.ErrListFilterUnavailable) {
+			return nil, status.Error(codes.Unimplemented, err.Error())
+		}
 		log.Error("failed to list orders", zap.Error(err))
 		return nil, status.Error(codes.Internal, "failed to list orders")
 	}
 
 	pbOrders := make([]*pb.Order, len(orders))
+	size := 0
 	for i, o := range orders {
-		pbOrders[i] = modelToProto(&o)
+		pbOrders[i] = protoconv.OrderToProto(&o)
+		if s.maxListResponseBytes > 0 {
+			// Each element is encoded as a tag byte, a length and the order.
+			size += 1 + protowire.SizeBytes(proto.Size(pbOrders[i]))
+			if size > s.maxListResponseBytes {
+				log.Warn("list response too large", zap.Int("orders", len(orders)), zap.Int("limit_bytes", s.maxListResponseBytes))
+				return nil, status.Errorf(codes.ResourceExhausted,
+					"response would exceed %d bytes for %d orders; use StreamOrders instead", s.maxListResponseBytes, len(orders))
+			}
+		}
 	}
 
 	return &pb.ListOrdersResponse{
@@ -92,39 +231,145 @@ func (s *Server) ListOrders(ctx context.Context, req *pb.ListOrdersRequest) (*pb
 	}, nil
 }
 
+func (s *Server) StreamOrders(req *pb.ListOrdersRequest, stream grpc.ServerStreamingServer[pb.Order]) error {
+	ctx, log := s.setupContext(stream.Context())
+
+	send := func(o model.Order) error {
+		return stream.Send(protoconv.OrderToProto(&o))
+	}
+	var err error
+	if req.CustomerId != "" {
+		err = s.streamCustomerOrders(ctx, req.CustomerId, send)
+	} else {
+		err = s.orderService.StreamOrders(ctx, send)
+	}
+	if err != nil {
+		if errors.Is(err, admission.ErrOverloaded) {
+			return status.Error(codes.Unavailable, err.Error())
+		}
+		if errors.Is(err, service.ErrListFilterUnavailable) {
+			return status.Error(codes.Unimplemented, err.Error())
+		}
+		if _, ok := status.FromError(err); ok || ctx.Err() != nil {
+			return err
+		}
+		log.Error("failed to stream orders", zap.Error(err))
+		return status.Error(codes.Internal, "failed to stream orders")
+	}
+	return nil
+}
+
+// streamCustomerOrders sends one customer's orders. They are loaded in one
+// query: a customer's orders are few enough to fit in memory.
+func (s *Server) streamCustomerOrders(ctx context.Context, customerID string, send func(model.Order) error) error {
+	orders, err := s.orderService.GetOrdersByCustomer(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	for _, o := range orders {
+		if err := send(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) CountOrders(ctx context.Context, req *pb.CountOrdersRequest) (*pb.CountOrdersResponse, error) {
+	ctx, log := s.setupContext(ctx)
+
+	var orderStatus string
+	if req.Status != pb.OrderStatus_ORDER_STATUS_UNSPECIFIED {
+		orderStatus = protoconv.StatusFromProto(req.Status)
+	}
+
+	count, err := s.orderService.CountOrders(ctx, orderStatus)
+	if err != nil {
+		log.Error("failed to count orders", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to count orders")
+	}
+
+	return &pb.CountOrdersResponse{
+		Count: int64(count),
+	}, nil
+}
+
 func (s *Server) UpdateOrder(ctx context.Context, req *pb.UpdateOrderRequest) (*pb.UpdateOrderResponse, error) {
 	ctx, log := s.setupContext(ctx)
 
 	updateReq := service.UpdateOrderRequest{
 		Product:  req.Product,
 		Quantity: int(req.Quantity),
-		Status:   protoStatusToString(req.Status),
+		Status:   protoconv.StatusFromProto(req.Status),
+		Price:    req.Price,
+		Currency: req.Currency,
+		Version:  req.Version,
+	}
+	// An empty map is indistinguishable from an unset one in proto3.
+	if len(req.Metadata) > 0 {
+		updateReq.Metadata = req.Metadata
+	} else if req.ClearMetadata {
+		updateReq.Metadata = map[string]string{}
 	}
 
 	order, err := s.orderService.UpdateOrder(ctx, req.Id, updateReq)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		switch service.ErrorCode(err) {
+		case service.CodeInternal:
+			log.Error("failed to update order", zap.String("order_id", req.Id), zap.Error(err))
+			return nil, status.Error(codes.Internal, "failed to update order")
+		case service.CodeNotFound:
 			log.Warn("order not found", zap.String("order_id", req.Id))
-			return nil, status.Error(codes.NotFound, "order not found")
+		case service.CodeUnavailable:
+			log.Error("policy service unavailable", zap.Error(err))
 		}
-		log.Error("failed to update order", zap.String("order_id", req.Id), zap.Error(err))
-		return nil, status.Error(codes.Internal, "failed to update order")
+		return nil, serviceError(err)
 	}
 
 	log.Info("order updated via gRPC", zap.String("order_id", order.ID))
+	setStreamPosition(ctx, order.StreamPosition)
 	return &pb.UpdateOrderResponse{
-		Order: modelToProto(order),
+		Order:    protoconv.OrderToProto(order.Order),
+		Warnings: warningsToProto(order.Warnings),
 	}, nil
 }
 
+func (s *Server) UpdateOrderStatus(ctx context.Context, req *pb.UpdateOrderStatusRequest) (*pb.UpdateOrderStatusResponse, error) {
+	ctx, log := s.setupContext(ctx)
+
+	if _, ok := pb.OrderStatus_name[int32(req.Status)]; !ok || req.Status == pb.OrderStatus_ORDER_STATUS_UNSPECIFIED {
+		return nil, serviceError(&service.ValidationError{Field: "status", Message: "must be one of: pending, confirmed, shipped, delivered, cancelled"})
+	}
+	newStatus := protoconv.StatusFromProto(req.Status)
+
+	if err := s.orderService.UpdateOrderStatus(ctx, req.Id, newStatus); err != nil {
+		switch service.ErrorCode(err) {
+		case service.CodeInternal:
+			log.Error("failed to update order status", zap.String("order_id", req.Id), zap.Error(err))
+			return nil, status.Error(codes.Internal, "failed to update order status")
+		case service.CodeNotFound:
+			log.Warn("order not found", zap.String("order_id", req.Id))
+		}
+		return nil, serviceError(err)
+	}
+
+	order, err := s.orderService.GetOrder(ctx, req.Id)
+	if err != nil {
+		log.Error("failed to get updated order", zap.String("order_id", req.Id), zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to get updated order")
+	}
+
+	log.Info("order status updated via gRPC", zap.String("order_id", req.Id), zap.String("status", newStatus))
+	return &pb.UpdateOrderStatusResponse{Order: protoconv.OrderToProto(order)}, nil
+}
+
 func (s *Server) DeleteOrder(ctx context.Context, req *pb.DeleteOrderRequest) (*pb.DeleteOrderResponse, error) {
 	ctx, log := s.setupContext(ctx)
 
 	err := s.orderService.DeleteOrder(ctx, req.Id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, service.ErrOrderNotFound) {
 			log.Warn("order not found", zap.String("order_id", req.Id))
-			return nil, status.Error(codes.NotFound, "order not found")
+			return nil, serviceError(err)
 		}
 		log.Error("failed to delete order", zap.String("order_id", req.Id), zap.Error(err))
 		return nil, status.Error(codes.Internal, "failed to delete order")
@@ -134,9 +379,44 @@ func (s *Server) DeleteOrder(ctx context.Context, req *pb.DeleteOrderRequest) (*
 	return &pb.DeleteOrderResponse{}, nil
 }
 
+func (s *Server) AddOrderTag(ctx context.Context, req *pb.AddOrderTagRequest) (*pb.AddOrderTagResponse, error) {
+	ctx, log := s.setupContext(ctx)
+
+	tags, err := s.orderService.AddTag(ctx, req.Id, req.Tag)
+	if err != nil {
+		if service.ErrorCode(err) != service.CodeInternal {
+			return nil, serviceError(err)
+		}
+		log.Error("failed to add order tag", zap.String("order_id", req.Id), zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to add order tag")
+	}
+
+	return &pb.AddOrderTagResponse{Tags: tags}, nil
+}
+
+// requestIDMetadata carries the request ID, as X-Request-ID does over HTTP.
+const requestIDMetadata = "x-request-id"
+
+// setupContext adds the call's logger to ctx. The request ID is taken from
+// the incoming x-request-id metadata, or generated if there is none, and
+// echoed back in the response header.
 func (s *Server) setupContext(ctx context.Context) (context.Context, *zap.Logger) {
-	requestID := uuid.New().String()
+	requestID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMetadata); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
 	log := s.log.With(zap.String("request_id", requestID))
+	if err := grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, requestID)); err != nil {
+		log.Warn("failed to set request ID header", zap.Error(err))
+	}
+	if id, ok := auth.IdentityFromContext(ctx); ok {
+		log = log.With(zap.String("subject", id.Subject))
+	}
 	ctx = logger.WithContext(ctx, log)
 	return ctx, log
 }
@@ -153,38 +433,31 @@ func getIdempotencyKey(ctx context.Context) string {
 	return ""
 }
 
-func modelToProto(o *model.Order) *pb.Order {
-	return &pb.Order{
-		Id:        o.ID,
-		Product:   o.Product,
-		Quantity:  int64(o.Quantity),
-		Status:    stringToProtoStatus(o.Status),
-		CreatedAt: o.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+func setStreamPosition(ctx context.Context, position string) {
+	if position == "" {
+		return
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs("x-stream-position", position)); err != nil {
+		logger.FromContext(ctx).Warn("failed to set stream position header", zap.Error(err))
 	}
 }
 
-func stringToProtoStatus(s string) pb.OrderStatus {
-	switch s {
-	case "pending":
-		return pb.OrderStatus_ORDER_STATUS_PENDING
-	case "confirmed":
-		return pb.OrderStatus_ORDER_STATUS_CONFIRMED
-	case "cancelled":
-		return pb.OrderStatus_ORDER_STATUS_CANCELLED
-	default:
-		return pb.OrderStatus_ORDER_STATUS_UNSPECIFIED
+// setCreatedHeaders sends the created order's canonical REST path as
+// "location" and echoes the idempotency key that was honoured, if any.
+func setCreatedHeaders(ctx context.Context, orderID, idempotencyKey string) {
+	md := metadata.Pairs("location", "/orders/"+url.PathEscape(orderID))
+	if idempotencyKey != "" {
+		md.Set("x-idempotency-key", idempotencyKey)
+	}
+	if err := grpc.SetHeader(ctx, md); err != nil {
+		logger.FromContext(ctx).Warn("failed to set created order headers", zap.Error(err))
 	}
 }
 
-func protoStatusToString(s pb.OrderStatus) string {
-	switch s {
-	case pb.OrderStatus_ORDER_STATUS_PENDING:
-		return "pending"
-	case pb.OrderStatus_ORDER_STATUS_CONFIRMED:
-		return "confirmed"
-	case pb.OrderStatus_ORDER_STATUS_CANCELLED:
-		return "cancelled"
-	default:
-		return "pending"
+func warningsToProto(warnings []service.Warning) []*pb.Warning {
+	result := make([]*pb.Warning, len(warnings))
+	for i, w := range warnings {
+		result[i] = &pb.Warning{Field: w.Field, Message: w.Message}
 	}
+	return result
 }
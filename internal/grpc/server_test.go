@@ -0,0 +1,419 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+	"github.com/orders-service/internal/service"
+	pb "github.com/orders-service/proto"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestListOrdersByCustomer(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	srv := NewServer(service.NewOrderService(store, nil, service.WithOrderFinder(store)), zap.NewNop())
+	ctx := context.Background()
+
+	for _, customer := range []string{"alice", "bob"} {
+		if _, err := srv.CreateOrder(ctx, &pb.CreateOrderRequest{CustomerId: customer, Product: "Widget", Quantity: 1}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := srv.CreateOrder(ctx, &pb.CreateOrderRequest{Product: "Widget", Quantity: 1}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument without a customer, got %v", err)
+	}
+
+	resp, err := srv.ListOrders(ctx, &pb.ListOrdersRequest{CustomerId: "bob"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Orders) != 1 || resp.Orders[0].CustomerId != "bob" {
+		t.Errorf("expected bob's order only, got %v", resp.Orders)
+	}
+}
+
+func TestBatchCreateOrders(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	srv := NewServer(service.NewOrderService(store, nil, service.WithBatchCreate(store)), zap.NewNop())
+	ctx := context.Background()
+
+	resp, err := srv.BatchCreateOrders(ctx, &pb.BatchCreateOrdersRequest{Orders: []*pb.CreateOrderRequest{
+		{CustomerId: "customer-1", Product: "Widget", Quantity: 1},
+		{CustomerId: "customer-1", Product: "Gadget", Quantity: 2},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Orders) != 2 || resp.Orders[1].Order.Product != "Gadget" || resp.Orders[1].Order.Id == "" {
+		t.Fatalf("unexpected response %v", resp)
+	}
+
+	_, err = srv.BatchCreateOrders(ctx, &pb.BatchCreateOrdersRequest{Orders: []*pb.CreateOrderRequest{
+		{CustomerId: "customer-1", Product: "Widget", Quantity: 1},
+		{CustomerId: "customer-1", Product: "Gadget", Quantity: 0},
+	}})
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			violations = br.FieldViolations
+		}
+	}
+	if len(violations) != 1 || violations[0].Field != "orders[1].quantity" {
+		t.Errorf("expected a violation for orders[1].quantity, got %v", violations)
+	}
+	if n, _ := store.CountOrders(ctx); n != 2 {
+		t.Errorf("expected the rejected batch not to be stored, got %d orders", n)
+	}
+}
+
+// headerStream records the headers a handler sets with grpc.SetHeader.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return "/orders.OrderService/CreateOrder" }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *headerStream) SetTrailer(md metadata.MD) error { return nil }
+
+func TestCreateOrderSetsLocationHeader(t *testing.T) {
+	srv := NewServer(service.NewOrderService(repo.NewInMemoryOrderRepository(), nil), zap.NewNop())
+	stream := &headerStream{}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-idempotency-key", "key-1"))
+	ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+	resp, err := srv.CreateOrder(ctx, &pb.CreateOrderRequest{CustomerId: "customer-1", Product: "Widget", Quantity: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := stream.header.Get("location"); len(got) != 1 || got[0] != "/orders/"+resp.Order.Id {
+		t.Errorf("expected location /orders/%s, got %v", resp.Order.Id, got)
+	}
+	if got := stream.header.Get("x-idempotency-key"); len(got) != 1 || got[0] != "key-1" {
+		t.Errorf("expected the idempotency key to be echoed, got %v", got)
+	}
+}
+
+func TestOrderTotal(t *testing.T) {
+	srv := NewServer(service.NewOrderService(repo.NewInMemoryOrderRepository(), nil), zap.NewNop())
+
+	resp, err := srv.CreateOrder(context.Background(), &pb.CreateOrderRequest{CustomerId: "customer-1", Product: "Widget", Quantity: 4, Price: 125})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Order.Total == nil || *resp.Order.Total != 500 {
+		t.Errorf("expected total 500, got %v", resp.Order.Total)
+	}
+}
+
+func TestOrderMetadata(t *testing.T) {
+	srv := NewServer(service.NewOrderService(repo.NewInMemoryOrderRepository(), nil), zap.NewNop())
+	ctx := context.Background()
+
+	created, err := srv.CreateOrder(ctx, &pb.CreateOrderRequest{CustomerId: "customer-1", Product: "Widget", Quantity: 1,
+		Metadata: map[string]string{"channel": "web"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Order.Metadata["channel"] != "web" {
+		t.Errorf("expected the metadata to be returned, got %v", created.Order.Metadata)
+	}
+
+	update := &pb.UpdateOrderRequest{Id: created.Order.Id, Product: "Widget", Quantity: 2, Status: pb.OrderStatus_ORDER_STATUS_PENDING}
+	updated, err := srv.UpdateOrder(ctx, update)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Order.Metadata["channel"] != "web" {
+		t.Errorf("expected unset metadata to be kept, got %v", updated.Order.Metadata)
+	}
+
+	update.ClearMetadata = true
+	if updated, err = srv.UpdateOrder(ctx, update); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(updated.Order.Metadata) != 0 {
+		t.Errorf("expected clear_metadata to remove the metadata, got %v", updated.Order.Metadata)
+	}
+
+	_, err = srv.CreateOrder(ctx, &pb.CreateOrderRequest{CustomerId: "customer-1", Product: "Widget", Quantity: 1,
+		Metadata: map[string]string{"": "web"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected INVALID_ARGUMENT for an empty key, got %v", err)
+	}
+}
+
+func TestCreateOrderValidateOnly(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	srv := NewServer(service.NewOrderService(store, nil), zap.NewNop())
+	ctx := context.Background()
+
+	resp, err := srv.CreateOrder(ctx, &pb.CreateOrderRequest{CustomerId: "customer-1", Product: " Widget ", Quantity: 2, Price: 100, Currency: "usd", ValidateOnly: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o := resp.Order; o.Id != "" || o.CreatedAt != "" || o.Product != "Widget" || o.Currency != "USD" || o.Status != pb.OrderStatus_ORDER_STATUS_PENDING {
+		t.Errorf("expected the normalized order without an id or timestamps, got %v", o)
+	}
+
+	_, err = srv.CreateOrder(ctx, &pb.CreateOrderRequest{CustomerId: "customer-1", Product: "Widget", ValidateOnly: true})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected INVALID_ARGUMENT for a missing quantity, got %v", err)
+	}
+
+	if count, _ := store.CountOrders(ctx); count != 0 {
+		t.Errorf("expected nothing to be saved, got %d orders", count)
+	}
+}
+
+func TestUpdateOrderValidatesProductAndQuantity(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	srv := NewServer(service.NewOrderService(store, nil), zap.NewNop())
+	ctx := context.Background()
+
+	created, err := srv.CreateOrder(ctx, &pb.CreateOrderRequest{CustomerId: "customer-1", Product: "Widget", Quantity: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		product  string
+		quantity int64
+		field    string
+	}{
+		{"empty product", "  ", 1, "product"},
+		{"long product", strings.Repeat("a", 256), 1, "product"},
+		{"zero quantity", "Widget", 0, "quantity"},
+		{"negative quantity", "Widget", -1, "quantity"},
+	}
+	for _, tt := range tests {
+		_, err := srv.UpdateOrder(ctx, &pb.UpdateOrderRequest{Id: created.Order.Id, Product: tt.product, Quantity: tt.quantity, Status: pb.OrderStatus_ORDER_STATUS_PENDING})
+		if st := status.Convert(err); st.Code() != codes.InvalidArgument || !strings.Contains(st.Message(), tt.field) {
+			t.Errorf("%s: expected InvalidArgument naming %s, got %v", tt.name, tt.field, err)
+		}
+	}
+
+	stored, err := store.GetByID(ctx, created.Order.Id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored.Product != "Widget" || stored.Quantity != 1 || stored.Version != 1 {
+		t.Errorf("expected the order to be unchanged, got %+v", stored)
+	}
+}
+
+// cancellingRepo cancels the request context once an order is saved, as if
+// the client went away between the write and the publish.
+type cancellingRepo struct {
+	*repo.InMemoryOrderRepository
+	cancel context.CancelFunc
+}
+
+func (r *cancellingRepo) Create(ctx context.Context, order *model.Order) error {
+	err := r.InMemoryOrderRepository.Create(ctx, order)
+	r.cancel()
+	return err
+}
+
+func TestCreateOrderReportsSavedOrderWhenEventNotPublished(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &cancellingRepo{InMemoryOrderRepository: repo.NewInMemoryOrderRepository(), cancel: cancel}
+	srv := NewServer(service.NewOrderService(store, nil), zap.NewNop())
+
+	_, err := srv.CreateOrder(ctx, &pb.CreateOrderRequest{CustomerId: "customer-1", Product: "Widget", Quantity: 1})
+	st := status.Convert(err)
+	if st.Code() != codes.Internal {
+		t.Fatalf("expected INTERNAL, got %v", err)
+	}
+	var info *errdetails.ErrorInfo
+	var order *pb.Order
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			info = d
+		case *pb.Order:
+			order = d
+		}
+	}
+	if info == nil || info.Reason != reasonEventNotPublished {
+		t.Fatalf("expected an %s ErrorInfo, got %v", reasonEventNotPublished, st.Details())
+	}
+	if order == nil || order.Id == "" || info.Metadata["order_id"] != order.Id || !strings.Contains(st.Message(), order.Id) {
+		t.Fatalf("expected the saved order in the details and message, got %v: %v", st.Message(), st.Details())
+	}
+	if _, err := store.GetByID(context.Background(), order.Id); err != nil {
+		t.Errorf("expected the order to be saved, got %v", err)
+	}
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	srv := NewServer(service.NewOrderService(repo.NewInMemoryOrderRepository(), nil), zap.New(core))
+
+	stream := &headerStream{}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "req-123"))
+	ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+	if _, err := srv.CreateOrder(ctx, &pb.CreateOrderRequest{CustomerId: "customer-1", Product: "Widget", Quantity: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := stream.header.Get("x-request-id"); len(got) != 1 || got[0] != "req-123" {
+		t.Errorf("expected the request ID to be echoed, got %v", got)
+	}
+	for _, entry := range logs.All() {
+		if id := entry.ContextMap()["request_id"]; id != "req-123" {
+			t.Errorf("%q: expected request_id req-123, got %v", entry.Message, id)
+		}
+	}
+
+	stream = &headerStream{}
+	ctx = grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	if _, err := srv.CreateOrder(ctx, &pb.CreateOrderRequest{CustomerId: "customer-1", Product: "Widget", Quantity: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := stream.header.Get("x-request-id"); len(got) != 1 || got[0] == "" || got[0] == "req-123" {
+		t.Errorf("expected a fresh request ID, got %v", got)
+	}
+}
+
+func TestListOrdersTooLargeSuggestsStreaming(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	for i := 0; i < 50; i++ {
+		o := &model.Order{ID: fmt.Sprintf("order-%02d", i), Product: "Widget", Quantity: 1, Status: model.StatusPending}
+		if err := store.Create(context.Background(), o); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	newClient := func(limit int) pb.OrderServiceClient {
+		srv := grpc.NewServer()
+		pb.RegisterOrderServiceServer(srv, NewServer(service.NewOrderService(store, nil, service.WithOrderStream(store)), zap.NewNop(), WithMaxListResponseBytes(limit)))
+		return pb.NewOrderServiceClient(newBufconnClient(t, srv))
+	}
+	ctx := context.Background()
+
+	if resp, err := newClient(DefaultMaxListResponseBytes).ListOrders(ctx, &pb.ListOrdersRequest{}); err != nil || len(resp.Orders) != 50 {
+		t.Fatalf("expected 50 orders under the default limit, got %v (%v)", resp, err)
+	}
+
+	client := newClient(1024)
+	_, err := client.ListOrders(ctx, &pb.ListOrdersRequest{})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+	if !strings.Contains(status.Convert(err).Message(), "StreamOrders") {
+		t.Errorf("expected the error to point to StreamOrders, got %q", status.Convert(err).Message())
+	}
+
+	stream, err := client.StreamOrders(ctx, &pb.ListOrdersRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ids []string
+	for {
+		o, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, o.Id)
+	}
+	if len(ids) != 50 || ids[0] != "order-00" || ids[49] != "order-49" {
+		t.Errorf("expected all 50 orders oldest first, got %v", ids)
+	}
+}
+
+func TestServiceErrorDetails(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	if err := store.Create(context.Background(), &model.Order{ID: "order-1", Product: "Widget", Quantity: 1, Status: model.StatusPending}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	srv := NewServer(service.NewOrderService(store, nil), zap.NewNop())
+	ctx := context.Background()
+
+	_, getErr := srv.GetOrder(ctx, &pb.GetOrderRequest{Id: "missing"})
+	_, updateErr := srv.UpdateOrder(ctx, &pb.UpdateOrderRequest{Id: "order-1", Product: "Widget", Quantity: 1, Status: pb.OrderStatus_ORDER_STATUS_PENDING, Version: 7})
+	tests := []struct {
+		name   string
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		{"not found", getErr, codes.NotFound, service.CodeNotFound},
+		{"stale version", updateErr, codes.Aborted, service.CodeConflict},
+	}
+	for _, tt := range tests {
+		st := status.Convert(tt.err)
+		if st.Code() != tt.code {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.code, tt.err)
+			continue
+		}
+		var reason string
+		for _, d := range st.Details() {
+			if info, ok := d.(*errdetails.ErrorInfo); ok {
+				reason = info.Reason
+			}
+		}
+		if reason != tt.reason {
+			t.Errorf("%s: expected ErrorInfo reason %q, got %q", tt.name, tt.reason, reason)
+		}
+	}
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	if err := store.Create(context.Background(), &model.Order{ID: "order-1", Product: "Widget", Quantity: 1, Status: model.StatusPending}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	srv := NewServer(service.NewOrderService(store, nil), zap.NewNop())
+	ctx := context.Background()
+
+	resp, err := srv.UpdateOrderStatus(ctx, &pb.UpdateOrderStatusRequest{Id: "order-1", Status: pb.OrderStatus_ORDER_STATUS_CONFIRMED})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Order.Status != pb.OrderStatus_ORDER_STATUS_CONFIRMED || resp.Order.Product != "Widget" || resp.Order.Version != 2 {
+		t.Errorf("expected the refreshed, confirmed order, got %+v", resp.Order)
+	}
+
+	tests := []struct {
+		name string
+		req  *pb.UpdateOrderStatusRequest
+		code codes.Code
+	}{
+		{"not found", &pb.UpdateOrderStatusRequest{Id: "missing", Status: pb.OrderStatus_ORDER_STATUS_SHIPPED}, codes.NotFound},
+		{"unspecified status", &pb.UpdateOrderStatusRequest{Id: "order-1"}, codes.InvalidArgument},
+		{"unknown status", &pb.UpdateOrderStatusRequest{Id: "order-1", Status: pb.OrderStatus(42)}, codes.InvalidArgument},
+		{"invalid transition", &pb.UpdateOrderStatusRequest{Id: "order-1", Status: pb.OrderStatus_ORDER_STATUS_PENDING}, codes.FailedPrecondition},
+	}
+	for _, tt := range tests {
+		if _, err := srv.UpdateOrderStatus(ctx, tt.req); status.Code(err) != tt.code {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.code, err)
+		}
+	}
+}
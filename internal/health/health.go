@@ -0,0 +1,333 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const DefaultDBQuery = "SELECT 1 FROM orders LIMIT 1"
+
+// DefaultCheckTimeout bounds each readiness check so an unreachable
+// dependency fails the probe instead of hanging it.
+const DefaultCheckTimeout = time.Second
+
+var (
+	ErrCheckTimeout = errors.New("check timed out")
+	ErrCheckPending = errors.New("previous check has not returned yet")
+)
+
+const (
+	StageConnection = "connection"
+	StageQuery      = "query"
+)
+
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+type CheckError struct {
+	Stage string
+	Err   error
+}
+
+func (e *CheckError) Error() string {
+	return fmt.Sprintf("%s failed: %v", e.Stage, e.Err)
+}
+
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
+
+type DBChecker struct {
+	db    *sql.DB
+	query string
+}
+
+func NewDBChecker(db *sql.DB, query string) *DBChecker {
+	if query == "" {
+		query = DefaultDBQuery
+	}
+	return &DBChecker{db: db, query: query}
+}
+
+func (c *DBChecker) Check(ctx context.Context) error {
+	if err := c.db.PingContext(ctx); err != nil {
+		return &CheckError{Stage: StageConnection, Err: err}
+	}
+
+	rows, err := c.db.QueryContext(ctx, c.query)
+	if err != nil {
+		return &CheckError{Stage: StageQuery, Err: err}
+	}
+	defer rows.Close()
+	if err := rows.Err(); err != nil {
+		return &CheckError{Stage: StageQuery, Err: err}
+	}
+	return nil
+}
+
+type redisPinger interface {
+	Ping(ctx context.Context) *redis.StatusCmd
+}
+
+type RedisChecker struct {
+	client redisPinger
+}
+
+func NewRedisChecker(client redisPinger) *RedisChecker {
+	return &RedisChecker{client: client}
+}
+
+func (c *RedisChecker) Check(ctx context.Context) error {
+	if err := c.client.Ping(ctx).Err(); err != nil {
+		return &CheckError{Stage: StageConnection, Err: err}
+	}
+	return nil
+}
+
+// SchemaVersionFunc reports the applied schema version, or "" if no
+// migrations have been applied.
+type SchemaVersionFunc func(ctx context.Context) (string, error)
+
+type Handler struct {
+	checks        map[string]Checker
+	version       string
+	schemaVersion SchemaVersionFunc
+	timeout       time.Duration
+
+	// abandoned tracks, per check and for the schema version lookup under
+	// "", the calls that are still running after a probe gave up on them.
+	abandoned map[string]*stragglers
+}
+
+type Option func(*Handler)
+
+// WithVersion sets the code version reported by /version.
+func WithVersion(version string) Option {
+	return func(h *Handler) {
+		h.version = version
+	}
+}
+
+// WithSchemaVersion adds the applied schema version to /version and /readyz.
+func WithSchemaVersion(fn SchemaVersionFunc) Option {
+	return func(h *Handler) {
+		h.schemaVersion = fn
+	}
+}
+
+// WithCheckTimeout overrides DefaultCheckTimeout.
+func WithCheckTimeout(timeout time.Duration) Option {
+	return func(h *Handler) {
+		if timeout > 0 {
+			h.timeout = timeout
+		}
+	}
+}
+
+func NewHandler(checks map[string]Checker, opts ...Option) *Handler {
+	h := &Handler{checks: checks, timeout: DefaultCheckTimeout, abandoned: map[string]*stragglers{"": {}}}
+	for name := range checks {
+		h.abandoned[name] = &stragglers{}
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *Handler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/livez", h.Livez)
+	r.GET("/health", h.Livez)
+	r.GET("/readyz", h.Readyz)
+	r.GET("/version", h.Version)
+}
+
+func (h *Handler) Version(c *gin.Context) {
+	body := gin.H{"version": h.version}
+	h.addSchemaVersion(c.Request.Context(), body)
+	c.JSON(http.StatusOK, body)
+}
+
+// addSchemaVersion sets schema_version on body, to null if no migrations are
+// applied yet. A failed lookup is reported in schema_version_error rather
+// than failing the request.
+func (h *Handler) addSchemaVersion(ctx context.Context, body gin.H) {
+	if h.schemaVersion == nil {
+		return
+	}
+	version, err := h.schemaVersion(ctx)
+	setSchemaVersion(body, version, err)
+}
+
+func setSchemaVersion(body gin.H, version string, err error) {
+	if err != nil {
+		body["schema_version"] = nil
+		body["schema_version_error"] = err.Error()
+		return
+	}
+	if version == "" {
+		body["schema_version"] = nil
+		return
+	}
+	body["schema_version"] = version
+}
+
+// Livez reports that the process is up and serving. It checks no
+// dependencies, so a database outage does not get the instance restarted.
+func (h *Handler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz runs every check and looks up the schema version concurrently,
+// each bounded by the check timeout, and returns 503 if any check fails.
+// Every check reports its latency_ms.
+func (h *Handler) Readyz(c *gin.Context) {
+	ctx := c.Request.Context()
+	names := make([]string, 0, len(h.checks))
+	for name := range h.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errs := make([]error, len(names))
+	latencies := make([]time.Duration, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			_, errs[i] = bounded(ctx, h.timeout, h.abandoned[name], func(ctx context.Context) (struct{}, error) {
+				return struct{}{}, h.checks[name].Check(ctx)
+			})
+			latencies[i] = time.Since(start)
+		}()
+	}
+	var version string
+	var versionErr error
+	if h.schemaVersion != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			version, versionErr = bounded(ctx, h.timeout, h.abandoned[""], h.schemaVersion)
+		}()
+	}
+	wg.Wait()
+
+	ready := true
+	results := make(gin.H, len(names))
+	for i, name := range names {
+		latency := float64(latencies[i].Microseconds()) / 1000
+		err := errs[i]
+		if err == nil {
+			results[name] = gin.H{"status": "ok", "latency_ms": latency}
+			continue
+		}
+		ready = false
+		result := gin.H{"status": "error", "error": err.Error(), "latency_ms": latency}
+		var checkErr *CheckError
+		if errors.As(err, &checkErr) {
+			result["stage"] = checkErr.Stage
+			result["error"] = checkErr.Err.Error()
+		}
+		results[name] = result
+	}
+
+	body := gin.H{"status": "ready", "checks": results}
+	if h.schemaVersion != nil {
+		setSchemaVersion(body, version, versionErr)
+	}
+	if !ready {
+		body["status"] = "not ready"
+		c.JSON(http.StatusServiceUnavailable, body)
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// bounded calls fn with a context that times out after timeout and returns
+// when fn does or the timeout passes, whichever comes first, so that a call
+// that does not honour its context, such as a Redis ping blocked on a socket
+// read, cannot hold up the probe. While a call abandoned that way is still
+// running, later calls fail straight away rather than piling up behind it.
+// Calls that overlap without timing out, such as probes from the kubelet and
+// a load balancer, run side by side.
+func bounded[T any](ctx context.Context, timeout time.Duration, abandoned *stragglers, fn func(context.Context) (T, error)) (T, error) {
+	var zero T
+	if abandoned.running() {
+		return zero, ErrCheckPending
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	call := abandoned.start()
+	go func() {
+		value, err := fn(ctx)
+		call.finish()
+		done <- result{value, err}
+	}()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		call.abandon()
+		return zero, fmt.Errorf("%w after %s", ErrCheckTimeout, timeout)
+	}
+}
+
+// stragglers counts the calls bounded gave up on that have not returned yet.
+type stragglers struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (s *stragglers) running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.n > 0
+}
+
+func (s *stragglers) start() *boundedCall {
+	return &boundedCall{stragglers: s}
+}
+
+// boundedCall is one call made by bounded. It counts as a straggler from
+// when it is abandoned until it finishes, if it has not finished already.
+type boundedCall struct {
+	*stragglers
+	finished  bool
+	abandoned bool
+}
+
+func (c *boundedCall) finish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.finished = true
+	if c.abandoned {
+		c.n--
+	}
+}
+
+func (c *boundedCall) abandon() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.finished {
+		c.abandoned = true
+		c.n++
+	}
+}
@@ -0,0 +1,353 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type readyzResponse struct {
+	Status string                    `json:"status"`
+	Checks map[string]map[string]any `json:"checks"`
+}
+
+func serveReadyz(t *testing.T, checks map[string]Checker) (int, readyzResponse) {
+	t.Helper()
+	r := gin.New()
+	NewHandler(checks).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var body readyzResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response body %q: %v", w.Body.String(), err)
+	}
+	return w.Code, body
+}
+
+func TestReadyzQueryable(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectPing()
+	mock.ExpectQuery("SELECT 1 FROM orders LIMIT 1").
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+
+	code, body := serveReadyz(t, map[string]Checker{"postgres": NewDBChecker(db, "")})
+	if code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", code)
+	}
+	if body.Checks["postgres"]["status"] != "ok" {
+		t.Errorf("expected postgres ok, got %v", body.Checks["postgres"])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReadyzPingableButUnqueryable(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectPing()
+	mock.ExpectQuery("SELECT 1 FROM orders LIMIT 1").
+		WillReturnError(errors.New(`relation "orders" does not exist`))
+
+	code, body := serveReadyz(t, map[string]Checker{"postgres": NewDBChecker(db, "")})
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", code)
+	}
+	if body.Status != "not ready" {
+		t.Errorf("expected not ready, got %s", body.Status)
+	}
+	if body.Checks["postgres"]["stage"] != StageQuery {
+		t.Errorf("expected query stage failure, got %v", body.Checks["postgres"])
+	}
+}
+
+func TestReadyzConnectionFailed(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	code, body := serveReadyz(t, map[string]Checker{"postgres": NewDBChecker(db, "")})
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", code)
+	}
+	if body.Checks["postgres"]["stage"] != StageConnection {
+		t.Errorf("expected connection stage failure, got %v", body.Checks["postgres"])
+	}
+}
+
+func TestDBCheckerCustomQuery(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectPing()
+	mock.ExpectQuery("SELECT count").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	if err := NewDBChecker(db, "SELECT count(*) FROM schema_check").Check(t.Context()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVersionReportsSchemaVersion(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	schemaVersion := "007_order_projection"
+	r := gin.New()
+	NewHandler(map[string]Checker{"postgres": NewDBChecker(db, "")},
+		WithVersion("1.4.0"),
+		WithSchemaVersion(func(context.Context) (string, error) { return schemaVersion, nil }),
+	).RegisterRoutes(r)
+
+	get := func(path string) map[string]interface{} {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var body map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid response body %q: %v", w.Body.String(), err)
+		}
+		return body
+	}
+
+	body := get("/version")
+	if body["version"] != "1.4.0" || body["schema_version"] != "007_order_projection" {
+		t.Errorf("unexpected version response %v", body)
+	}
+
+	mock.ExpectPing()
+	mock.ExpectQuery("SELECT 1 FROM orders LIMIT 1").
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+	if body := get("/readyz"); body["schema_version"] != "007_order_projection" {
+		t.Errorf("expected readiness to report schema version, got %v", body)
+	}
+
+	schemaVersion = ""
+	body = get("/version")
+	if v, ok := body["schema_version"]; !ok || v != nil {
+		t.Errorf("expected null schema_version before migrations, got %v", body)
+	}
+}
+
+type blockingChecker struct{}
+
+type okChecker struct{}
+
+func (okChecker) Check(ctx context.Context) error {
+	return nil
+}
+
+func (blockingChecker) Check(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestReadyzRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	defer client.Close()
+	checks := map[string]Checker{"redis": NewRedisChecker(client)}
+
+	if code, body := serveReadyz(t, checks); code != http.StatusOK || body.Checks["redis"]["status"] != "ok" {
+		t.Errorf("expected redis ok, got %d %v", code, body.Checks["redis"])
+	}
+
+	mr.Close()
+	code, body := serveReadyz(t, checks)
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", code)
+	}
+	if body.Checks["redis"]["stage"] != StageConnection {
+		t.Errorf("expected connection stage failure, got %v", body.Checks["redis"])
+	}
+}
+
+func TestReadyzCheckTimeout(t *testing.T) {
+	r := gin.New()
+	NewHandler(map[string]Checker{"postgres": blockingChecker{}, "redis": blockingChecker{}},
+		WithCheckTimeout(20*time.Millisecond),
+	).RegisterRoutes(r)
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected hung checks to be cut off by the timeout, took %v", elapsed)
+	}
+}
+
+// stuckChecker ignores its context, like a Redis ping blocked on a socket
+// read, and returns only once release is closed.
+type stuckChecker struct {
+	release chan struct{}
+}
+
+func (c stuckChecker) Check(ctx context.Context) error {
+	<-c.release
+	return nil
+}
+
+func TestReadyzAbandonsChecksThatIgnoreTheTimeout(t *testing.T) {
+	stuck := stuckChecker{release: make(chan struct{})}
+	release := sync.OnceFunc(func() { close(stuck.release) })
+	defer release()
+	r := gin.New()
+	NewHandler(map[string]Checker{"postgres": okChecker{}, "redis": stuck},
+		WithCheckTimeout(20*time.Millisecond),
+		WithSchemaVersion(func(context.Context) (string, error) {
+			<-stuck.release
+			return "017_order_ids_as_text", nil
+		}),
+	).RegisterRoutes(r)
+
+	get := func() (int, map[string]any) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var body map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid response body %q: %v", w.Body.String(), err)
+		}
+		return w.Code, body
+	}
+	check := func(body map[string]any, name string) map[string]any {
+		t.Helper()
+		checks, _ := body["checks"].(map[string]any)
+		result, _ := checks[name].(map[string]any)
+		return result
+	}
+
+	start := time.Now()
+	code, body := get()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the stuck check to be abandoned, took %v", elapsed)
+	}
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", code)
+	}
+	if redis := check(body, "redis"); redis["status"] != "error" || !strings.Contains(fmt.Sprint(redis["error"]), ErrCheckTimeout.Error()) {
+		t.Errorf("expected redis to time out, got %v", redis)
+	}
+	if postgres := check(body, "postgres"); postgres["status"] != "ok" {
+		t.Errorf("expected postgres ok, got %v", postgres)
+	}
+	for _, name := range []string{"postgres", "redis"} {
+		if _, ok := check(body, name)["latency_ms"].(float64); !ok {
+			t.Errorf("expected %s to report latency_ms, got %v", name, check(body, name))
+		}
+	}
+	if body["schema_version"] != nil || body["schema_version_error"] == nil {
+		t.Errorf("expected the schema version lookup to time out, got %v", body)
+	}
+
+	if _, body := get(); !strings.Contains(fmt.Sprint(check(body, "redis")["error"]), ErrCheckPending.Error()) {
+		t.Errorf("expected no second check while the first is stuck, got %v", check(body, "redis"))
+	}
+
+	release()
+	deadline := time.Now().Add(time.Second)
+	for {
+		code, body := get()
+		if code == http.StatusOK && body["schema_version"] == "017_order_ids_as_text" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected readiness to recover once the stuck calls returned, got %d %v", code, body)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLivezIgnoresDependencies(t *testing.T) {
+	r := gin.New()
+	NewHandler(map[string]Checker{"postgres": blockingChecker{}}).RegisterRoutes(r)
+
+	for _, path := range []string{"/livez", "/health"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d", path, w.Code)
+		}
+	}
+}
+
+// slowChecker takes a while but honours its context, like a healthy
+// dependency under load.
+type slowChecker struct {
+	delay time.Duration
+}
+
+func (c slowChecker) Check(ctx context.Context) error {
+	select {
+	case <-time.After(c.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestReadyzAllowsOverlappingProbes(t *testing.T) {
+	r := gin.New()
+	NewHandler(map[string]Checker{"redis": slowChecker{delay: 50 * time.Millisecond}}).RegisterRoutes(r)
+
+	codes := make([]int, 2)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			codes[i] = w.Code
+		}()
+	}
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("probe %d: expected status 200, got %d", i, code)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/service"
+	"go.uber.org/zap"
+)
+
+type batchCreateRequest struct {
+	Orders []service.CreateOrderRequest `json:"orders" binding:"required"`
+}
+
+// CreateOrders creates every order in the batch or none of them. Invalid
+// orders are reported as {"errors": [{"index", "field", "message"}]}.
+func (h *Handler) CreateOrders(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+
+	var req batchCreateRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	results, err := h.orderService.CreateOrders(c.Request.Context(), req.Orders)
+	if err != nil {
+		var batchErr *service.BatchValidationError
+		var validationErr *service.ValidationError
+		var rateErr *service.RateLimitError
+		switch {
+		case errors.As(err, &batchErr):
+			resp := errorResponse(service.CodeValidation, batchErr.Error())
+			resp["errors"] = batchErr.Errors
+			c.JSON(http.StatusBadRequest, resp)
+		case errors.As(err, &validationErr):
+			c.JSON(http.StatusBadRequest, validationErrorResponse(validationErr))
+		case errors.As(err, &rateErr):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rateErr.RetryAfter.Seconds()))))
+			writeError(c, err)
+		case errors.Is(err, service.ErrBatchUnavailable):
+			c.JSON(http.StatusNotFound, errorResponse(service.CodeNotFound, err.Error()))
+		case h.policyError(c, err):
+		default:
+			log.Error("failed to create orders", zap.Error(err))
+			writeError(c, err)
+		}
+		return
+	}
+
+	log.Info("orders created", zap.Int("orders", len(results)))
+	setStreamPosition(c, results[len(results)-1].StreamPosition)
+	c.JSON(http.StatusCreated, gin.H{"orders": results})
+}
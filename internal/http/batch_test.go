@@ -0,0 +1,53 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/repo"
+	"github.com/orders-service/internal/service"
+)
+
+func TestCreateOrdersBatch(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	r := gin.New()
+	NewHandler(service.NewOrderService(store, nil, service.WithBatchCreate(store))).RegisterRoutes(r)
+
+	w := doRequest(r, http.MethodPost, "/orders/batch", "application/json",
+		`{"orders":[{"customer_id":"customer-1","product":"Widget","quantity":1},{"customer_id":"customer-1","product":"Gadget","quantity":3,"price":250}]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Orders []service.OrderResult `json:"orders"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if len(created.Orders) != 2 || created.Orders[0].ID == "" || created.Orders[1].Product != "Gadget" {
+		t.Fatalf("unexpected response %s", w.Body.String())
+	}
+	if w := doRequest(r, http.MethodGet, "/orders/"+created.Orders[1].ID, "", ""); w.Code != http.StatusOK {
+		t.Errorf("expected created order to be readable, got %d", w.Code)
+	}
+
+	w = doRequest(r, http.MethodPost, "/orders/batch", "application/json",
+		`{"orders":[{"customer_id":"customer-1","product":"Widget","quantity":1},{"customer_id":"customer-1","product":"Gadget","quantity":0}]}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var rejected struct {
+		Errors []service.BatchItemError `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &rejected); err != nil {
+		t.Fatal(err)
+	}
+	if len(rejected.Errors) != 1 || rejected.Errors[0].Index != 1 || rejected.Errors[0].Field != "quantity" {
+		t.Errorf("unexpected errors %s", w.Body.String())
+	}
+	if w := doRequest(r, http.MethodPost, "/orders/batch", "application/json", `{"orders":[]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an empty batch, got %d", w.Code)
+	}
+}
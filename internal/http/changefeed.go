@@ -0,0 +1,140 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/admission"
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/service"
+	"go.uber.org/zap"
+)
+
+// DefaultChangeFeedPollInterval is how often a tailing change feed checks for
+// new changes once it has caught up.
+const DefaultChangeFeedPollInterval = time.Second
+
+type changeFeedParams struct {
+	From  int64  `form:"from" binding:"gte=0"`
+	Limit *int   `form:"limit" binding:"omitempty,gte=1,lte=1000"`
+	Mode  string `form:"mode" binding:"omitempty,oneof=batch tail"`
+}
+
+// GetChangeFeed returns the changes after ?from=<seq>. In the default batch
+// mode it answers {"changes": [...], "next": seq} with up to ?limit= changes;
+// resume with from=next. With ?mode=tail it streams every change as a line
+// of NDJSON and keeps the response open for new ones.
+func (h *Handler) GetChangeFeed(c *gin.Context) {
+	var p changeFeedParams
+	if err := bindQuery(c, &p); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(err))
+		return
+	}
+	if p.Mode == "tail" {
+		h.tailChangeFeed(c, p.From)
+		return
+	}
+
+	limit := 0
+	if p.Limit != nil {
+		limit = *p.Limit
+	}
+	page, err := h.orderService.Changes(c.Request.Context(), p.From, limit)
+	if err != nil {
+		h.changeFeedError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+type orderEventsParams struct {
+	From  int64 `form:"from" binding:"gte=0"`
+	Limit *int  `form:"limit" binding:"omitempty,gte=1,lte=1000"`
+}
+
+// GetOrderEvents returns the lifecycle of an order as recorded in the change
+// feed: {"events": [...], "next": seq} with up to ?limit= events after
+// ?from=<seq>, oldest first.
+func (h *Handler) GetOrderEvents(c *gin.Context) {
+	var p orderEventsParams
+	if err := bindQuery(c, &p); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(err))
+		return
+	}
+
+	limit := 0
+	if p.Limit != nil {
+		limit = *p.Limit
+	}
+	id := c.Param("id")
+	page, err := h.orderService.OrderEvents(c.Request.Context(), id, p.From, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrOrderNotFound) {
+			logger.FromContext(c.Request.Context()).Warn("order not found", zap.String("order_id", id))
+			writeError(c, err)
+			return
+		}
+		h.changeFeedError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) tailChangeFeed(c *gin.Context, from int64) {
+	log := logger.FromContext(c.Request.Context())
+
+	// Check availability before committing to a streaming response.
+	if _, err := h.orderService.Changes(c.Request.Context(), from, 1); err != nil {
+		h.changeFeedError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-h.streamsDone:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	clearDeadlines(c)
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	enc := json.NewEncoder(c.Writer)
+	err := h.orderService.TailChanges(ctx, from, h.changeFeedPoll, func(page *service.ChangePage) error {
+		for _, change := range page.Changes {
+			if err := enc.Encode(change); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Warn("change feed tail ended", zap.Int64("from", from), zap.Error(err))
+	}
+}
+
+func (h *Handler) changeFeedError(c *gin.Context, err error) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, validationErrorResponse(validationErr))
+	case errors.Is(err, service.ErrChangeFeedUnavailable):
+		c.JSON(http.StatusNotFound, errorResponse(service.CodeNotFound, err.Error()))
+	case errors.Is(err, admission.ErrOverloaded):
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, errorResponse(service.CodeUnavailable, err.Error()))
+	default:
+		logger.FromContext(c.Request.Context()).Error("failed to read change feed", zap.Error(err))
+		writeError(c, err)
+	}
+}
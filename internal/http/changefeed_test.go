@@ -0,0 +1,210 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+	"github.com/orders-service/internal/service"
+)
+
+type noTx struct{}
+
+func (noTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type memChangeFeed struct {
+	mu      sync.Mutex
+	changes []model.OrderChange
+}
+
+func (f *memChangeFeed) Append(ctx context.Context, change *model.OrderChange) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	change.Seq = int64(len(f.changes) + 1)
+	f.changes = append(f.changes, *change)
+	return nil
+}
+
+func (f *memChangeFeed) Since(ctx context.Context, seq int64, limit int) ([]model.OrderChange, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	changes := []model.OrderChange{}
+	for _, c := range f.changes {
+		if c.Seq > seq && len(changes) < limit {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+func (f *memChangeFeed) ForOrder(ctx context.Context, orderID string, seq int64, limit int) ([]model.OrderChange, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	changes := []model.OrderChange{}
+	for _, c := range f.changes {
+		if c.OrderID == orderID && c.Seq > seq && len(changes) < limit {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+func newChangeFeedRouter() (*gin.Engine, *Handler, *service.OrderService) {
+	svc := service.NewOrderService(repo.NewInMemoryOrderRepository(), nil, service.WithChangeFeed(noTx{}, &memChangeFeed{}))
+	h := NewHandler(svc)
+	h.changeFeedPoll = 5 * time.Millisecond
+	r := gin.New()
+	h.RegisterRoutes(r)
+	return r, h, svc
+}
+
+func TestGetChangeFeedBatch(t *testing.T) {
+	r, _, _ := newChangeFeedRouter()
+	for i := 0; i < 3; i++ {
+		if w := doRequest(r, http.MethodPost, "/orders", "application/json", `{"customer_id":"customer-1","product":"Widget","quantity":1}`); w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d", w.Code)
+		}
+	}
+
+	var seqs []int64
+	from := "0"
+	for {
+		w := doRequest(r, http.MethodGet, "/orders/changefeed?limit=2&from="+from, "", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var page service.ChangePage
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Changes) == 0 {
+			break
+		}
+		for _, c := range page.Changes {
+			seqs = append(seqs, c.Seq)
+		}
+		from = strconv.FormatInt(page.Next, 10)
+	}
+	if len(seqs) != 3 {
+		t.Fatalf("expected 3 changes, got %v", seqs)
+	}
+	for i, seq := range seqs {
+		if seq != int64(i+1) {
+			t.Errorf("expected gapless sequence numbers, got %v", seqs)
+			break
+		}
+	}
+
+	if w := doRequest(r, http.MethodGet, "/orders/changefeed?from=-1", "", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a negative from, got %d", w.Code)
+	}
+	if w := doRequest(newTestRouter(newMemRepo()), http.MethodGet, "/orders/changefeed", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 without a change feed, got %d", w.Code)
+	}
+}
+
+func TestGetOrderEvents(t *testing.T) {
+	r, _, svc := newChangeFeedRouter()
+	ctx := context.Background()
+	order, err := svc.CreateOrder(ctx, service.CreateOrderRequest{CustomerID: "customer-1", Product: "Widget", Quantity: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.UpdateOrderStatus(ctx, order.ID, "confirmed"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w := doRequest(r, http.MethodGet, "/orders/"+order.ID+"/events", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var page service.OrderEventPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", page.Events)
+	}
+	if e := page.Events[0]; e.Event != service.OrderCreatedChannel || e.Status != "pending" {
+		t.Errorf("expected the creation first, got %+v", e)
+	}
+	if e := page.Events[1]; e.Event != service.OrderStatusChangedEvent || e.From != "pending" || e.Status != "confirmed" {
+		t.Errorf("expected the status change second, got %+v", e)
+	}
+
+	w = doRequest(r, http.MethodGet, "/orders/"+order.ID+"/events?limit=1&from="+strconv.FormatInt(page.Events[0].Seq, 10), "", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Events) != 1 || page.Events[0].Event != service.OrderStatusChangedEvent {
+		t.Errorf("expected to resume after the creation, got %+v", page.Events)
+	}
+
+	if w := doRequest(r, http.MethodGet, "/orders/missing/events", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown order, got %d", w.Code)
+	}
+	if w := doRequest(r, http.MethodGet, "/orders/"+order.ID+"/events?limit=0", "", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a zero limit, got %d", w.Code)
+	}
+	if w := doRequest(newTestRouter(newMemRepo()), http.MethodGet, "/orders/"+order.ID+"/events", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 without a change feed, got %d", w.Code)
+	}
+}
+
+func TestGetChangeFeedTail(t *testing.T) {
+	r, h, svc := newChangeFeedRouter()
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	if _, err := svc.CreateOrder(context.Background(), service.CreateOrderRequest{CustomerID: "customer-1", Product: "Widget", Quantity: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := http.Get(srv.URL + "/orders/changefeed?mode=tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected NDJSON, got %q", ct)
+	}
+
+	lines := bufio.NewScanner(resp.Body)
+	next := func() model.OrderChange {
+		t.Helper()
+		if !lines.Scan() {
+			t.Fatalf("expected another change, got %v", lines.Err())
+		}
+		var c model.OrderChange
+		if err := json.Unmarshal(lines.Bytes(), &c); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	if c := next(); c.Seq != 1 || c.EventType != service.OrderCreatedChannel {
+		t.Fatalf("expected the existing change first, got %+v", c)
+	}
+	if _, err := svc.CreateOrder(context.Background(), service.CreateOrderRequest{CustomerID: "customer-1", Product: "Gadget", Quantity: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c := next(); c.Seq != 2 {
+		t.Errorf("expected the tail to pick up seq 2, got %+v", c)
+	}
+
+	h.StopStreams()
+	if lines.Scan() {
+		t.Errorf("expected the stream to end on shutdown, got %s", lines.Text())
+	}
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/auth"
+	"github.com/orders-service/internal/events"
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/service"
+	"go.uber.org/zap"
+)
+
+type ConsumerStatsReader interface {
+	Stats(ctx context.Context) (*events.ConsumerStats, error)
+}
+
+type ConsumerStatsHandler struct {
+	stats ConsumerStatsReader
+}
+
+func NewConsumerStatsHandler(stats ConsumerStatsReader) *ConsumerStatsHandler {
+	return &ConsumerStatsHandler{stats: stats}
+}
+
+func (h *ConsumerStatsHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/admin/consumer/stats", RequireScope(auth.ScopeRead), h.GetStats)
+}
+
+func (h *ConsumerStatsHandler) GetStats(c *gin.Context) {
+	stats, err := h.stats.Stats(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, events.ErrGroupNotFound) {
+			c.JSON(http.StatusNotFound, errorResponse(service.CodeNotFound, err.Error()))
+			return
+		}
+		logger.FromContext(c.Request.Context()).Error("failed to read consumer stats", zap.Error(err))
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/events"
+)
+
+type fixedStats struct {
+	stats *events.ConsumerStats
+	err   error
+}
+
+func (f *fixedStats) Stats(ctx context.Context) (*events.ConsumerStats, error) {
+	return f.stats, f.err
+}
+
+func TestGetConsumerStats(t *testing.T) {
+	r := gin.New()
+	NewConsumerStatsHandler(&fixedStats{stats: &events.ConsumerStats{
+		Stream: events.StreamName, Group: events.ConsumerGroup, Length: 10, Lag: 4, Pending: 2, Consumers: 1,
+	}}).RegisterRoutes(r)
+
+	w := doRequest(r, http.MethodGet, "/admin/consumer/stats", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var stats events.ConsumerStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Lag != 4 || stats.Pending != 2 || stats.Length != 10 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	r = gin.New()
+	NewConsumerStatsHandler(&fixedStats{err: events.ErrGroupNotFound}).RegisterRoutes(r)
+	w = doRequest(r, http.MethodGet, "/admin/consumer/stats", "", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig is the cross-origin policy applied by CORS.
+type CORSConfig struct {
+	// AllowedOrigins are the origins browsers may call the API from, such as
+	// "https://admin.example.com". "*" allows any origin but never with
+	// credentials.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// DefaultCORSConfig allows no origins. Callers set AllowedOrigins; the
+// methods and headers cover what the API uses.
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowedHeaders: []string{"Authorization", "Content-Type", APIKeyHeader, "Idempotency-Key", "If-Match", "If-None-Match", "X-Request-ID"},
+		ExposedHeaders: []string{"ETag", "Location", "Retry-After", "X-Request-ID", StreamPositionHeader, StaleHeader, AsOfHeader},
+		MaxAge:         10 * time.Minute,
+	}
+}
+
+// CORS adds CORS headers for requests from allowed origins and answers their
+// preflight requests with 204 before authentication runs. Preflights from
+// other origins get 403; their simple requests are served without CORS
+// headers, so browsers withhold the response.
+func CORS(cfg CORSConfig) gin.HandlerFunc {
+	origins := make(map[string]bool, len(cfg.AllowedOrigins))
+	anyOrigin := false
+	for _, o := range cfg.AllowedOrigins {
+		if o == "*" {
+			anyOrigin = true
+		}
+		origins[strings.TrimSuffix(o, "/")] = true
+	}
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		switch {
+		case origins[origin]:
+			c.Header("Access-Control-Allow-Origin", origin)
+			if cfg.AllowCredentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+		case anyOrigin:
+			c.Header("Access-Control-Allow-Origin", "*")
+		case preflight:
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(CodeForbidden, "origin not allowed"))
+			return
+		default:
+			c.Next()
+			return
+		}
+
+		if !preflight {
+			if exposed != "" {
+				c.Header("Access-Control-Expose-Headers", exposed)
+			}
+			c.Next()
+			return
+		}
+		c.Header("Access-Control-Allow-Methods", methods)
+		c.Header("Access-Control-Allow-Headers", headers)
+		if cfg.MaxAge > 0 {
+			c.Header("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/orders-service/internal/auth"
+	"github.com/orders-service/internal/service"
+)
+
+func TestCORS(t *testing.T) {
+	cfg := DefaultCORSConfig()
+	cfg.AllowedOrigins = []string{"https://admin.example.com"}
+	cfg.AllowCredentials = true
+
+	r := NewRouter(LenientRouting)
+	r.Use(CORS(cfg))
+	r.Use(Authenticate(stubVerifier{"Bearer good": {Subject: "alice", Scopes: []string{auth.ScopeRead}}}, PublicPaths{}))
+	NewHandler(service.NewOrderService(newMemRepo(), nil)).RegisterRoutes(r)
+
+	do := func(method, path, origin string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Origin", origin)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, path := range []string{"/orders", "/orders/order-1"} {
+		w := do(http.MethodOptions, path, "https://admin.example.com",
+			"Access-Control-Request-Method", http.MethodPut, "Access-Control-Request-Headers", "authorization, content-type")
+		if w.Code != http.StatusNoContent {
+			t.Errorf("%s: expected preflight status 204, got %d", path, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://admin.example.com" {
+			t.Errorf("%s: expected the origin to be allowed, got %q", path, got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST, PUT, DELETE" {
+			t.Errorf("%s: expected allowed methods, got %q", path, got)
+		}
+		if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+			t.Errorf("%s: expected max age 600, got %q", path, got)
+		}
+	}
+
+	w := do(http.MethodOptions, "/orders", "https://evil.example.com", "Access-Control-Request-Method", http.MethodPost)
+	if w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected preflight from another origin to be refused, got %d %q", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	w = do(http.MethodGet, "/orders", "https://admin.example.com", "Authorization", "Bearer good")
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://admin.example.com" || w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("expected CORS headers on the response, got %v", w.Header())
+	}
+	if w.Header().Get("Vary") != "Origin" {
+		t.Errorf("expected Vary: Origin, got %q", w.Header().Get("Vary"))
+	}
+
+	// Errors must carry the headers too, or the browser hides them.
+	if w := do(http.MethodGet, "/orders", "https://admin.example.com"); w.Code != http.StatusUnauthorized || w.Header().Get("Access-Control-Allow-Origin") == "" {
+		t.Errorf("expected a 401 with CORS headers, got %d %v", w.Code, w.Header())
+	}
+
+	if w := do(http.MethodGet, "/orders", "https://evil.example.com", "Authorization", "Bearer good"); w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected no CORS headers for another origin, got %q", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	cfg := DefaultCORSConfig()
+	cfg.AllowedOrigins = []string{"*"}
+	cfg.AllowCredentials = true
+
+	r := NewRouter(LenientRouting)
+	r.Use(CORS(cfg))
+	NewHandler(service.NewOrderService(newMemRepo(), nil)).RegisterRoutes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("Origin", "https://anywhere.example.com")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("expected any origin to be allowed, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("expected no credentials with a wildcard origin, got %q", got)
+	}
+}
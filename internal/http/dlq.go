@@ -0,0 +1,88 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/auth"
+	"github.com/orders-service/internal/events"
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/service"
+	"go.uber.org/zap"
+)
+
+const defaultDeadLetterLimit = 100
+
+type DeadLetterStore interface {
+	DeadLetters(ctx context.Context, count int64) ([]events.DeadLetter, error)
+	ReplayDeadLetters(ctx context.Context, count int64) (int, error)
+}
+
+type DeadLetterHandler struct {
+	store DeadLetterStore
+}
+
+func NewDeadLetterHandler(store DeadLetterStore) *DeadLetterHandler {
+	return &DeadLetterHandler{store: store}
+}
+
+func (h *DeadLetterHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/admin/dlq", RequireScope(auth.ScopeRead), h.List)
+	r.POST("/admin/dlq/replay", RequireScope(auth.ScopeWrite), h.Replay)
+}
+
+func (h *DeadLetterHandler) List(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+
+	limit, ok := deadLetterLimit(c)
+	if !ok {
+		return
+	}
+
+	letters, err := h.store.DeadLetters(c.Request.Context(), limit)
+	if err != nil {
+		log.Error("failed to read dead letters", zap.Error(err))
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": letters})
+}
+
+func (h *DeadLetterHandler) Replay(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+
+	limit, ok := deadLetterLimit(c)
+	if !ok {
+		return
+	}
+
+	replayed, err := h.store.ReplayDeadLetters(c.Request.Context(), limit)
+	if err != nil {
+		log.Error("failed to replay dead letters", zap.Int("replayed", replayed), zap.Error(err))
+		resp := errorResponse(service.CodeInternal, service.ErrorMessage(err))
+		resp["replayed"] = replayed
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+
+	log.Info("dead letters replayed", zap.Int("replayed", replayed))
+	c.JSON(http.StatusOK, gin.H{"replayed": replayed})
+}
+
+type deadLetterParams struct {
+	Limit *int64 `form:"limit" binding:"omitempty,gte=1"`
+}
+
+func deadLetterLimit(c *gin.Context) (int64, bool) {
+	var params deadLetterParams
+	if err := bindQuery(c, &params); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(err))
+		return 0, false
+	}
+	if params.Limit == nil {
+		return defaultDeadLetterLimit, true
+	}
+	return *params.Limit, true
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/service"
+)
+
+// Codes for failures detected by the HTTP layer before reaching the service.
+const (
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodePreconditionFailed   = "precondition_failed"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+)
+
+var codeStatus = map[string]int{
+	service.CodeValidation:         http.StatusBadRequest,
+	service.CodeNotFound:           http.StatusNotFound,
+	service.CodeConflict:           http.StatusConflict,
+	service.CodeFailedPrecondition: http.StatusConflict,
+	service.CodePolicyDenied:       http.StatusUnprocessableEntity,
+	service.CodeRateLimited:        http.StatusTooManyRequests,
+	service.CodeUnavailable:        http.StatusServiceUnavailable,
+	service.CodeInternal:           http.StatusInternalServerError,
+}
+
+// errorResponse is the body of every error response:
+// {"error": {"code": "...", "message": "..."}}.
+func errorResponse(code, message string) gin.H {
+	return gin.H{"error": gin.H{"code": code, "message": message}}
+}
+
+// writeError answers with the status matching err's service error code.
+// Errors without a code become a 500 with a generic message, so callers
+// should log them first.
+func writeError(c *gin.Context, err error) {
+	code := service.ErrorCode(err)
+	if code == service.CodeValidation {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(err))
+		return
+	}
+	c.JSON(codeStatus[code], errorResponse(code, service.ErrorMessage(err)))
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errPreconditionFailed is returned by ifMatchVersion for If-Match values
+// that cannot match any version of the order.
+var errPreconditionFailed = errors.New("precondition failed: order does not match If-Match")
+
+// orderETag is the strong entity tag of an order at version. The tag is the
+// version itself, so If-Match is the same guard as the version field.
+func orderETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersion returns the version named by the request's If-Match header,
+// or 0 if the header is absent or "*". Only a single strong tag issued by
+// orderETag can match; weak tags, lists and anything else fail the
+// precondition.
+func ifMatchVersion(c *gin.Context) (int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if len(header) < 3 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, errPreconditionFailed
+	}
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil || version < 1 {
+		return 0, errPreconditionFailed
+	}
+	return version, nil
+}
+
+// notModified reports whether If-None-Match names the order's current tag,
+// in which case the caller answers 304 without a body.
+func notModified(c *gin.Context, etag string) bool {
+	for _, tag := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			c.Header("ETag", etag)
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -1,11 +1,18 @@
 package http
 
 import (
-	"database/sql"
 	"errors"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/orders-service/internal/admission"
+	"github.com/orders-service/internal/auth"
 	"github.com/orders-service/internal/logger"
 	"github.com/orders-service/internal/model"
 	"github.com/orders-service/internal/service"
@@ -13,39 +20,105 @@ import (
 )
 
 type Handler struct {
-	orderService *service.OrderService
+	orderService   *service.OrderService
+	changeFeedPoll time.Duration
+	streamsDone    chan struct{}
+	stopOnce       sync.Once
 }
 
 func NewHandler(orderService *service.OrderService) *Handler {
-	return &Handler{orderService: orderService}
+	return &Handler{
+		orderService:   orderService,
+		changeFeedPoll: DefaultChangeFeedPollInterval,
+		streamsDone:    make(chan struct{}),
+	}
+}
+
+// StopStreams ends open change feed tails so that a graceful shutdown does
+// not wait for them. Register it with http.Server.RegisterOnShutdown.
+func (h *Handler) StopStreams() {
+	h.stopOnce.Do(func() { close(h.streamsDone) })
 }
 
 func (h *Handler) RegisterRoutes(r *gin.Engine) {
-	r.POST("/orders", h.CreateOrder)
-	r.GET("/orders/:id", h.GetOrder)
-	r.GET("/orders", h.GetOrders)
-	r.PUT("/orders/:id", h.UpdateOrder)
-	r.DELETE("/orders/:id", h.DeleteOrder)
+	read, write := RequireScope(auth.ScopeRead), RequireScope(auth.ScopeWrite)
+	orders := r.Group("/orders", RequireContentType(binding.MIMEJSON))
+	orders.POST("", write, h.CreateOrder)
+	orders.POST("/batch", write, h.CreateOrders)
+	orders.GET("/:id", read, h.GetOrder)
+	orders.GET("/:id/history", read, h.GetOrderHistory)
+	orders.GET("/:id/events", read, h.GetOrderEvents)
+	orders.GET("/:id/tags", read, h.ListTags)
+	orders.POST("/:id/tags", write, h.AddTag)
+	orders.DELETE("/:id/tags/:tag", write, h.RemoveTag)
+	orders.GET("", read, h.GetOrders)
+	orders.GET("/count", read, h.CountOrders)
+	orders.GET("/changefeed", read, h.GetChangeFeed)
+	orders.GET("/stats", read, h.GetOrderStats)
+	orders.PUT("/:id", write, h.UpdateOrder)
+	orders.DELETE("/:id", write, h.DeleteOrder)
+}
+
+type createParams struct {
+	ValidateOnly bool `form:"validate_only"`
 }
 
+// CreateOrder creates an order. With ?validate_only=true it only runs the
+// checks and answers 200 with the order as it would be created.
 func (h *Handler) CreateOrder(c *gin.Context) {
 	log := logger.FromContext(c.Request.Context())
 
+	var p createParams
+	if err := bindQuery(c, &p); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(err))
+		return
+	}
 	var req service.CreateOrderRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Warn("invalid request body", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
+	req.IdempotencyKey = c.GetHeader("Idempotency-Key")
 
-	order, err := h.orderService.CreateOrder(c.Request.Context(), req)
+	var order *service.OrderResult
+	var err error
+	if p.ValidateOnly {
+		order, err = h.orderService.ValidateOrder(c.Request.Context(), req)
+	} else {
+		order, err = h.orderService.CreateOrder(c.Request.Context(), req)
+	}
 	if err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(validationErr))
+			return
+		}
+		if errors.Is(err, service.ErrIdempotencyKeyInProgress) {
+			writeError(c, err)
+			return
+		}
+		var rateErr *service.RateLimitError
+		if errors.As(err, &rateErr) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rateErr.RetryAfter.Seconds()))))
+			writeError(c, err)
+			return
+		}
+		if h.policyError(c, err) {
+			return
+		}
 		log.Error("failed to create order", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
+		return
+	}
+
+	if p.ValidateOnly {
+		c.JSON(http.StatusOK, order)
 		return
 	}
 
 	log.Info("order created", zap.String("order_id", order.ID))
+	setStreamPosition(c, order.StreamPosition)
+	c.Header("Location", orderLocation(order.ID))
+	c.Header("ETag", orderETag(order.Version))
 	c.JSON(http.StatusCreated, order)
 }
 
@@ -55,34 +128,261 @@ func (h *Handler) GetOrder(c *gin.Context) {
 
 	order, err := h.orderService.GetOrder(c.Request.Context(), id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, service.ErrOrderNotFound) {
 			log.Warn("order not found", zap.String("order_id", id))
-			c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+			writeError(c, err)
 			return
 		}
 		log.Error("failed to get order", zap.String("order_id", id), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
+	etag := orderETag(order.Version)
+	if notModified(c, etag) {
+		return
+	}
+	c.Header("ETag", etag)
 	c.JSON(http.StatusOK, order)
 }
 
+// listParams holds the query parameters accepted by GET /orders. Limit only
+// applies to product searches, which cannot be combined with the filters or
+// sorting.
+type listParams struct {
+	Product    string    `form:"product"`
+	Limit      *int      `form:"limit" binding:"omitempty,gte=1,lte=200"`
+	CustomerID string    `form:"customer_id" binding:"omitempty,max=255"`
+	Status     string    `form:"status" binding:"omitempty,oneof=pending confirmed shipped delivered cancelled"`
+	From       time.Time `form:"from"`
+	To         time.Time `form:"to" binding:"omitempty,gtfield=From"`
+	Sort       string    `form:"sort" binding:"omitempty,oneof=created_at quantity status product"`
+	Order      string    `form:"order" binding:"omitempty,oneof=asc desc"`
+}
+
+func (p listParams) filtered() bool {
+	return p.CustomerID != "" || p.Status != "" || !p.From.IsZero() || !p.To.IsZero() || p.Sort != "" || p.Order != ""
+}
+
 func (h *Handler) GetOrders(c *gin.Context) {
 	log := logger.FromContext(c.Request.Context())
 
+	var params listParams
+	if err := bindQuery(c, &params); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(err))
+		return
+	}
+	if _, ok := c.GetQuery("product"); ok {
+		if params.filtered() {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(queryErrors{{Field: "product", Message: "cannot be combined with customer_id, status, from, to, sort or order"}}))
+			return
+		}
+		h.searchOrders(c, params)
+		return
+	}
+	if params.Limit != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(queryErrors{{Field: "limit", Message: "is only supported with product"}}))
+		return
+	}
+	if params.filtered() {
+		h.filterOrders(c, params)
+		return
+	}
+
 	orders, err := h.orderService.GetOrders(c.Request.Context())
 	if err != nil {
+		if errors.Is(err, admission.ErrOverloaded) {
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, errorResponse(service.CodeUnavailable, err.Error()))
+			return
+		}
 		log.Error("failed to get orders", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
+		return
+	}
+
+	if orders == nil {
+		orders = []model.Order{}
+	}
+
+	total, err := h.orderService.CountOrders(c.Request.Context(), "")
+	if err != nil {
+		log.Error("failed to count orders", zap.Error(err))
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": orders, "total": total})
+}
+
+// searchOrders answers GET /orders?product=. Total is the number of matches
+// returned, not of all matching orders.
+func (h *Handler) searchOrders(c *gin.Context, params listParams) {
+	var limit int
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+
+	orders, err := h.orderService.SearchOrdersByProduct(c.Request.Context(), params.Product, limit)
+	if err != nil {
+		var validationErr *service.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			c.JSON(http.StatusBadRequest, validationErrorResponse(validationErr))
+		case errors.Is(err, service.ErrSearchUnavailable):
+			c.JSON(http.StatusNotFound, errorResponse(service.CodeNotFound, err.Error()))
+		case errors.Is(err, admission.ErrOverloaded):
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, errorResponse(service.CodeUnavailable, err.Error()))
+		default:
+			logger.FromContext(c.Request.Context()).Error("failed to search orders", zap.Error(err))
+			writeError(c, err)
+		}
 		return
 	}
 
 	if orders == nil {
 		orders = []model.Order{}
 	}
+	c.JSON(http.StatusOK, gin.H{"items": orders, "total": len(orders)})
+}
 
-	c.JSON(http.StatusOK, orders)
+// filterOrders answers GET /orders with customer_id, status, from, to, sort or
+// order. Total is the number of matching orders.
+func (h *Handler) filterOrders(c *gin.Context, params listParams) {
+	orders, err := h.orderService.ListOrders(c.Request.Context(), service.ListQuery{
+		CustomerID: params.CustomerID,
+		Status:     params.Status,
+		From:       params.From,
+		To:         params.To,
+		Sort:       params.Sort,
+		Order:      params.Order,
+	})
+	if err != nil {
+		var validationErr *service.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			c.JSON(http.StatusBadRequest, validationErrorResponse(validationErr))
+		case errors.Is(err, service.ErrListFilterUnavailable):
+			c.JSON(http.StatusNotFound, errorResponse(service.CodeNotFound, err.Error()))
+		case errors.Is(err, admission.ErrOverloaded):
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, errorResponse(service.CodeUnavailable, err.Error()))
+		default:
+			logger.FromContext(c.Request.Context()).Error("failed to list orders", zap.Error(err))
+			writeError(c, err)
+		}
+		return
+	}
+
+	if orders == nil {
+		orders = []model.Order{}
+	}
+	c.JSON(http.StatusOK, gin.H{"items": orders, "total": len(orders)})
+}
+
+type countParams struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending confirmed shipped delivered cancelled"`
+}
+
+func (h *Handler) CountOrders(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+
+	var params countParams
+	if err := bindQuery(c, &params); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(err))
+		return
+	}
+
+	count, err := h.orderService.CountOrders(c.Request.Context(), params.Status)
+	if err != nil {
+		log.Error("failed to count orders", zap.Error(err))
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"count": count})
+}
+
+func (h *Handler) GetOrderHistory(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id := c.Param("id")
+
+	q, err := parseHistoryQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(err))
+		return
+	}
+
+	page, err := h.orderService.GetOrderHistory(c.Request.Context(), id, q)
+	if err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(validationErr))
+			return
+		}
+		if errors.Is(err, admission.ErrOverloaded) {
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, errorResponse(service.CodeUnavailable, err.Error()))
+			return
+		}
+		log.Error("failed to get order history", zap.String("order_id", id), zap.Error(err))
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+type historyParams struct {
+	EventType string    `form:"event_type" binding:"omitempty,oneof=order.created order.updated order.deleted order.status_changed"`
+	From      time.Time `form:"from"`
+	To        time.Time `form:"to" binding:"omitempty,gtfield=From"`
+	Cursor    string    `form:"cursor"`
+	Limit     *int      `form:"limit" binding:"omitempty,gte=1,lte=200"`
+}
+
+func parseHistoryQuery(c *gin.Context) (service.HistoryQuery, error) {
+	var p historyParams
+	if err := bindQuery(c, &p); err != nil {
+		return service.HistoryQuery{}, err
+	}
+	q := service.HistoryQuery{
+		EventType: p.EventType,
+		From:      p.From,
+		To:        p.To,
+		Cursor:    p.Cursor,
+	}
+	if p.Limit != nil {
+		q.Limit = *p.Limit
+	}
+	return q, nil
+}
+
+const (
+	StaleHeader = "X-Data-Stale"
+	AsOfHeader  = "X-Data-As-Of"
+)
+
+func (h *Handler) GetOrderStats(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+
+	stats, err := h.orderService.GetOrderStats(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, service.ErrStatsUnavailable) {
+			c.JSON(http.StatusNotFound, errorResponse(service.CodeNotFound, err.Error()))
+			return
+		}
+		log.Error("failed to get order stats", zap.Error(err))
+		writeError(c, err)
+		return
+	}
+
+	c.Header(AsOfHeader, stats.ComputedAt.UTC().Format(time.RFC3339))
+	if stats.Stale {
+		c.Header(StaleHeader, "true")
+	}
+	c.JSON(http.StatusOK, stats)
 }
 
 func (h *Handler) UpdateOrder(c *gin.Context) {
@@ -90,25 +390,56 @@ func (h *Handler) UpdateOrder(c *gin.Context) {
 	id := c.Param("id")
 
 	var req service.UpdateOrderRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Warn("invalid request body", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
+		return
+	}
+	ifMatch, err := ifMatchVersion(c)
+	if err == nil && ifMatch != 0 && req.Version != 0 && req.Version != ifMatch {
+		err = errPreconditionFailed
+	}
+	if err != nil {
+		c.JSON(http.StatusPreconditionFailed, errorResponse(CodePreconditionFailed, err.Error()))
 		return
 	}
+	if ifMatch != 0 {
+		req.Version = ifMatch
+	}
 
 	order, err := h.orderService.UpdateOrder(c.Request.Context(), id, req)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(validationErr))
+			return
+		}
+		if errors.Is(err, service.ErrOrderNotFound) {
 			log.Warn("order not found", zap.String("order_id", id))
-			c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+			writeError(c, err)
+			return
+		}
+		if errors.Is(err, service.ErrConflict) {
+			if ifMatch != 0 {
+				c.JSON(http.StatusPreconditionFailed, errorResponse(CodePreconditionFailed, errPreconditionFailed.Error()))
+				return
+			}
+			writeError(c, err)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidTransition) {
+			writeError(c, err)
+			return
+		}
+		if h.policyError(c, err) {
 			return
 		}
 		log.Error("failed to update order", zap.String("order_id", id), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
 	log.Info("order updated", zap.String("order_id", order.ID))
+	setStreamPosition(c, order.StreamPosition)
+	c.Header("ETag", orderETag(order.Version))
 	c.JSON(http.StatusOK, order)
 }
 
@@ -118,16 +449,45 @@ func (h *Handler) DeleteOrder(c *gin.Context) {
 
 	err := h.orderService.DeleteOrder(c.Request.Context(), id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, service.ErrOrderNotFound) {
 			log.Warn("order not found", zap.String("order_id", id))
-			c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+			writeError(c, err)
 			return
 		}
 		log.Error("failed to delete order", zap.String("order_id", id), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
 	log.Info("order deleted", zap.String("order_id", id))
 	c.JSON(http.StatusNoContent, nil)
 }
+
+// orderLocation is the canonical URL path of an order.
+func orderLocation(id string) string {
+	return "/orders/" + url.PathEscape(id)
+}
+
+func setStreamPosition(c *gin.Context, position string) {
+	if position != "" {
+		c.Header(StreamPositionHeader, position)
+	}
+}
+
+// policyError writes the response for a policy denial (422) or an
+// unavailable policy service (503) and reports whether err was one of them.
+func (h *Handler) policyError(c *gin.Context, err error) bool {
+	var deniedErr *service.PolicyDeniedError
+	switch {
+	case errors.As(err, &deniedErr):
+		resp := errorResponse(service.CodePolicyDenied, service.ErrorMessage(err))
+		resp["reason"] = deniedErr.Reason
+		c.JSON(http.StatusUnprocessableEntity, resp)
+		return true
+	case errors.Is(err, service.ErrPolicyUnavailable):
+		logger.FromContext(c.Request.Context()).Error("policy service unavailable", zap.Error(err))
+		writeError(c, err)
+		return true
+	}
+	return false
+}
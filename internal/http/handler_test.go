@@ -0,0 +1,1001 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/admission"
+	"github.com/orders-service/internal/idempotency"
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/policy"
+	"github.com/orders-service/internal/ratelimit"
+	"github.com/orders-service/internal/repo"
+	"github.com/orders-service/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type memRepo struct {
+	orders map[string]*model.Order
+	mu     sync.RWMutex
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{orders: make(map[string]*model.Order)}
+}
+
+func (m *memRepo) Create(ctx context.Context, order *model.Order) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+	m.orders[order.ID] = order
+	return nil
+}
+
+func (m *memRepo) GetByID(ctx context.Context, id string) (*model.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	order, ok := m.orders[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return order, nil
+}
+
+func (m *memRepo) GetAll(ctx context.Context) ([]model.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var result []model.Order
+	for _, o := range m.orders {
+		result = append(result, *o)
+	}
+	return result, nil
+}
+
+func (m *memRepo) Update(ctx context.Context, order *model.Order) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.orders[order.ID]; !ok {
+		return sql.ErrNoRows
+	}
+	order.UpdatedAt = time.Now()
+	m.orders[order.ID] = order
+	return nil
+}
+
+func (m *memRepo) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.orders[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.orders, id)
+	return nil
+}
+
+func (m *memRepo) CountOrders(ctx context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.orders), nil
+}
+
+func (m *memRepo) CountOrdersByStatus(ctx context.Context, status string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	count := 0
+	for _, o := range m.orders {
+		if o.Status == status {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func newTestRouter(repo *memRepo) *gin.Engine {
+	r := gin.New()
+	NewHandler(service.NewOrderService(repo, nil)).RegisterRoutes(r)
+	return r
+}
+
+func doRequest(r http.Handler, method, path, contentType, body string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) errorBody {
+	t.Helper()
+	var body struct {
+		Error errorBody `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("expected a JSON error body, got %q", w.Body.String())
+	}
+	return body.Error
+}
+
+func decodeFieldErrors(t *testing.T, w *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	var body struct {
+		Errors []FieldError `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response body %q: %v", w.Body.String(), err)
+	}
+	result := make(map[string]string, len(body.Errors))
+	for _, fe := range body.Errors {
+		result[fe.Field] = fe.Message
+	}
+	return result
+}
+
+func TestCreateOrderFieldErrors(t *testing.T) {
+	r := newTestRouter(newMemRepo())
+
+	w := doRequest(r, http.MethodPost, "/orders", "application/json", `{"quantity":0}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	errs := decodeFieldErrors(t, w)
+	if errs["customer_id"] != "is required" {
+		t.Errorf("expected customer_id to be required, got %q", errs["customer_id"])
+	}
+	if errs["product"] != "is required" {
+		t.Errorf("expected product to be required, got %q", errs["product"])
+	}
+	if errs["quantity"] != "must be greater than 0" {
+		t.Errorf("expected quantity message, got %q", errs["quantity"])
+	}
+}
+
+func TestCreateOrderServiceValidationError(t *testing.T) {
+	r := newTestRouter(newMemRepo())
+
+	w := doRequest(r, http.MethodPost, "/orders", "application/json", `{"customer_id":"customer-1","product":"   ","quantity":1}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	errs := decodeFieldErrors(t, w)
+	if errs["product"] != "must not be empty" {
+		t.Errorf("expected product message, got %v", errs)
+	}
+}
+
+func TestCreateOrderMetadata(t *testing.T) {
+	r := newTestRouter(newMemRepo())
+
+	w := doRequest(r, http.MethodPost, "/orders", "application/json", `{"customer_id":"customer-1","product":"Widget","quantity":1,"metadata":{"channel":"web","promo":"SPRING"}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	var order model.Order
+	if err := json.Unmarshal(w.Body.Bytes(), &order); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.Metadata["channel"] != "web" || order.Metadata["promo"] != "SPRING" {
+		t.Errorf("expected the metadata to be returned, got %v", order.Metadata)
+	}
+
+	w = doRequest(r, http.MethodPost, "/orders", "application/json", `{"customer_id":"customer-1","product":"Widget","quantity":1,"metadata":{"channel":1}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	if errs := decodeFieldErrors(t, w); errs["metadata.channel"] != "must be a string" {
+		t.Errorf("expected a metadata type error, got %v", errs)
+	}
+}
+
+func TestCreateOrderValidateOnly(t *testing.T) {
+	repo := newMemRepo()
+	r := newTestRouter(repo)
+
+	w := doRequest(r, http.MethodPost, "/orders?validate_only=true", "application/json", `{"customer_id":"customer-1","product":" Widget ","quantity":2,"price":100,"currency":"usd"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	if loc := w.Header().Get("Location"); loc != "" {
+		t.Errorf("expected no Location header, got %q", loc)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body["product"] != "Widget" || body["currency"] != "USD" || body["status"] != "pending" {
+		t.Errorf("expected the normalized order, got %v", body)
+	}
+	for _, field := range []string{"id", "created_at", "updated_at"} {
+		if _, ok := body[field]; ok {
+			t.Errorf("expected no %s, got %v", field, body[field])
+		}
+	}
+	if len(repo.orders) != 0 {
+		t.Errorf("expected nothing to be saved, got %d orders", len(repo.orders))
+	}
+
+	w = doRequest(r, http.MethodPost, "/orders?validate_only=true", "application/json", `{"customer_id":"customer-1","product":"Widget","quantity":2,"price":-1}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	if errs := decodeFieldErrors(t, w); errs["price"] == "" {
+		t.Errorf("expected a price error, got %v", errs)
+	}
+
+	w = doRequest(r, http.MethodPost, "/orders?validate_only=maybe", "application/json", `{"customer_id":"customer-1","product":"Widget","quantity":2}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	if errs := decodeFieldErrors(t, w); errs["validate_only"] != "must be true or false" {
+		t.Errorf("expected a validate_only error, got %v", errs)
+	}
+	if len(repo.orders) != 0 {
+		t.Errorf("expected nothing to be saved, got %d orders", len(repo.orders))
+	}
+}
+
+func TestCreateOrderWrongFieldType(t *testing.T) {
+	r := newTestRouter(newMemRepo())
+
+	w := doRequest(r, http.MethodPost, "/orders", "application/json", `{"customer_id":"customer-1","product":"Widget","quantity":"many"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	errs := decodeFieldErrors(t, w)
+	if errs["quantity"] != "must be a number" {
+		t.Errorf("expected quantity type error, got %v", errs)
+	}
+}
+
+func TestUpdateOrderFieldErrors(t *testing.T) {
+	repo := newMemRepo()
+	repo.orders["test-id"] = &model.Order{ID: "test-id", Product: "Test", Quantity: 1, Status: "pending"}
+	r := newTestRouter(repo)
+
+	w := doRequest(r, http.MethodPut, "/orders/test-id", "application/json", `{"product":"Test","quantity":-1}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	errs := decodeFieldErrors(t, w)
+	if errs["quantity"] != "must be greater than 0" || errs["status"] != "is required" {
+		t.Errorf("unexpected field errors: %v", errs)
+	}
+}
+
+func TestUpdateOrderStaleVersionConflict(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	if err := store.Create(context.Background(), &model.Order{ID: "order-1", Product: "Widget", Quantity: 1, Status: "pending"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := gin.New()
+	NewHandler(service.NewOrderService(store, nil)).RegisterRoutes(r)
+
+	w := doRequest(r, http.MethodGet, "/orders/order-1", "", "")
+	var order model.Order
+	if err := json.Unmarshal(w.Body.Bytes(), &order); err != nil {
+		t.Fatal(err)
+	}
+	if order.Version != 1 {
+		t.Fatalf("expected version 1 in read response, got %d", order.Version)
+	}
+
+	body := `{"product":"Widget","quantity":2,"status":"pending","version":1}`
+	if w := doRequest(r, http.MethodPut, "/orders/order-1", "application/json", body); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doRequest(r, http.MethodPut, "/orders/order-1", "application/json", body); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 for stale version, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUpdateOrderInvalidTransition(t *testing.T) {
+	repo := newMemRepo()
+	repo.orders["order-1"] = &model.Order{ID: "order-1", Product: "Widget", Quantity: 1, Status: model.StatusCancelled}
+	r := newTestRouter(repo)
+
+	w := doRequest(r, http.MethodPut, "/orders/order-1", "application/json", `{"product":"Widget","quantity":1,"status":"pending"}`)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d: %s", w.Code, w.Body.String())
+	}
+	if body := decodeError(t, w); body.Code != service.CodeFailedPrecondition {
+		t.Errorf("expected code %q, got %+v", service.CodeFailedPrecondition, body)
+	}
+	if repo.orders["order-1"].Status != model.StatusCancelled {
+		t.Errorf("expected the order to stay cancelled, got %s", repo.orders["order-1"].Status)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	r := newTestRouter(newMemRepo())
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"missing order", http.MethodGet, "/orders/missing", "", http.StatusNotFound, service.CodeNotFound},
+		{"delete missing order", http.MethodDelete, "/orders/missing", "", http.StatusNotFound, service.CodeNotFound},
+		{"invalid body", http.MethodPost, "/orders", `{"customer_id":"customer-1","product":"","quantity":1}`, http.StatusBadRequest, service.CodeValidation},
+		{"wrong content type", http.MethodPost, "/orders", `{}`, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		contentType := "application/json"
+		if tt.status == http.StatusUnsupportedMediaType {
+			contentType = "text/plain"
+		}
+		w := doRequest(r, tt.method, tt.path, contentType, tt.body)
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, w.Code)
+			continue
+		}
+		if body := decodeError(t, w); body.Code != tt.code || body.Message == "" {
+			t.Errorf("%s: expected code %q with a message, got %+v", tt.name, tt.code, body)
+		}
+	}
+}
+
+func TestUpdateOrderETag(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	if err := store.Create(context.Background(), &model.Order{ID: "order-1", Product: "Widget", Quantity: 1, Status: "pending"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := gin.New()
+	NewHandler(service.NewOrderService(store, nil)).RegisterRoutes(r)
+
+	put := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/orders/order-1", strings.NewReader(`{"product":"Widget","quantity":2,"status":"pending"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", etag)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	etag := doRequest(r, http.MethodGet, "/orders/order-1", "", "").Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("expected ETag \"1\", got %q", etag)
+	}
+
+	w := put(etag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("ETag"); got != `"2"` {
+		t.Errorf("expected new ETag \"2\", got %q", got)
+	}
+
+	for _, stale := range []string{etag, `W/"2"`, `"abc"`} {
+		if w := put(stale); w.Code != http.StatusPreconditionFailed {
+			t.Errorf("If-Match %s: expected status 412, got %d: %s", stale, w.Code, w.Body.String())
+		}
+	}
+	if order, _ := store.GetByID(context.Background(), "order-1"); order.Version != 2 {
+		t.Errorf("expected failed preconditions to leave the order at version 2, got %d", order.Version)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/order-1", nil)
+	req.Header.Set("If-None-Match", `"2"`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status 304 for current If-None-Match, got %d", w.Code)
+	}
+}
+
+func TestGetOrderIfNoneMatch(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	if err := store.Create(context.Background(), &model.Order{ID: "order-1", CustomerID: "customer-1", Product: "Widget", Quantity: 1, Status: "pending"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := gin.New()
+	NewHandler(service.NewOrderService(store, nil)).RegisterRoutes(r)
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orders/order-1", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"1"` {
+		t.Fatalf("expected status 200 with ETag \"1\", got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+
+	// If-None-Match uses the weak comparison, so W/ tags and lists match too.
+	for _, tag := range []string{`"1"`, `W/"1"`, `"7", "1"`, `*`} {
+		w := get(tag)
+		if w.Code != http.StatusNotModified {
+			t.Errorf("If-None-Match %s: expected status 304, got %d", tag, w.Code)
+		}
+		if w.Body.Len() != 0 || w.Header().Get("ETag") != `"1"` {
+			t.Errorf("If-None-Match %s: expected an empty body with the ETag, got %q %q", tag, w.Body.String(), w.Header().Get("ETag"))
+		}
+	}
+
+	if w := get(`"0"`); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"id":"order-1"`) {
+		t.Errorf("expected status 200 with the order for a stale tag, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCreateOrderIdempotencyKeyHeader(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	repo := newMemRepo()
+	svc := service.NewOrderService(repo, nil, service.WithIdempotencyStore(idempotency.NewRedisStore(client, time.Hour)))
+	r := gin.New()
+	NewHandler(svc).RegisterRoutes(r)
+
+	send := func() model.Order {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"customer_id":"customer-1","product":"Widget","quantity":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "abc-123")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+		var order model.Order
+		if err := json.Unmarshal(w.Body.Bytes(), &order); err != nil {
+			t.Fatal(err)
+		}
+		return order
+	}
+
+	first := send()
+	second := send()
+	if first.ID != second.ID {
+		t.Errorf("expected replay to return order %s, got %s", first.ID, second.ID)
+	}
+	if len(repo.orders) != 1 {
+		t.Errorf("expected 1 order persisted, got %d", len(repo.orders))
+	}
+}
+
+type failingIDGenerator struct{}
+
+func (failingIDGenerator) NewID(ctx context.Context) (string, error) {
+	return "", errors.New("entropy source unavailable")
+}
+
+func TestCreateOrderIDGenerationFailure(t *testing.T) {
+	repo := newMemRepo()
+	r := gin.New()
+	NewHandler(service.NewOrderService(repo, nil, service.WithIDGenerator(failingIDGenerator{}))).RegisterRoutes(r)
+
+	w := doRequest(r, http.MethodPost, "/orders", "application/json", `{"customer_id":"customer-1","product":"Widget","quantity":1}`)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", w.Code)
+	}
+
+	body := decodeError(t, w)
+	if body.Code != service.CodeInternal || body.Message != "internal error" {
+		t.Errorf("expected a generic internal error, got %+v", body)
+	}
+	if len(repo.orders) != 0 {
+		t.Error("expected no order to be persisted")
+	}
+}
+
+func TestGetOrderHistoryInvalidParams(t *testing.T) {
+	r := newTestRouter(newMemRepo())
+
+	tests := []struct {
+		query string
+		field string
+	}{
+		{"event_type=order.exploded", "event_type"},
+		{"from=yesterday", "from"},
+		{"limit=0", "limit"},
+		{"limit=500", "limit"},
+		{"from=2024-01-02T00:00:00Z&to=2024-01-01T00:00:00Z", "to"},
+	}
+
+	for _, tt := range tests {
+		w := doRequest(r, http.MethodGet, "/orders/abc/history?"+tt.query, "", "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", tt.query, w.Code)
+			continue
+		}
+		if _, ok := decodeFieldErrors(t, w)[tt.field]; !ok {
+			t.Errorf("%s: expected error for field %s, got %s", tt.query, tt.field, w.Body.String())
+		}
+	}
+}
+
+func TestQueryParamsReportAllErrors(t *testing.T) {
+	r := newTestRouter(newMemRepo())
+
+	tests := []struct {
+		path   string
+		fields []string
+	}{
+		{
+			"/orders/abc/history?event_type=order.exploded&from=yesterday&limit=abc&page=2",
+			[]string{"event_type", "from", "limit", "page"},
+		},
+		{
+			"/orders/abc/history?from=2024-01-02T00:00:00Z&to=2024-01-01T00:00:00Z&limit=0&limit=5",
+			[]string{"to", "limit"},
+		},
+		{"/orders/count?status=lost&sort=asc", []string{"status", "sort"}},
+		{"/orders?colour=red", []string{"colour"}},
+	}
+
+	for _, tt := range tests {
+		w := doRequest(r, http.MethodGet, tt.path, "", "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", tt.path, w.Code)
+			continue
+		}
+		errs := decodeFieldErrors(t, w)
+		if len(errs) != len(tt.fields) {
+			t.Errorf("%s: expected %d errors, got %s", tt.path, len(tt.fields), w.Body.String())
+		}
+		for _, field := range tt.fields {
+			if _, ok := errs[field]; !ok {
+				t.Errorf("%s: expected error for %s, got %s", tt.path, field, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestGetOrdersIncludesTotal(t *testing.T) {
+	repo := newMemRepo()
+	repo.orders["a"] = &model.Order{ID: "a", Product: "Widget", Quantity: 1, Status: "pending"}
+	repo.orders["b"] = &model.Order{ID: "b", Product: "Gadget", Quantity: 2, Status: "confirmed"}
+	r := newTestRouter(repo)
+
+	w := doRequest(r, http.MethodGet, "/orders", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var body struct {
+		Items []model.Order `json:"items"`
+		Total int           `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Items) != 2 || body.Total != 2 {
+		t.Errorf("expected 2 items and total 2, got %d items and total %d", len(body.Items), body.Total)
+	}
+
+	w = doRequest(r, http.MethodGet, "/orders/count?status=pending", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var count struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &count); err != nil {
+		t.Fatal(err)
+	}
+	if count.Count != 1 {
+		t.Errorf("expected 1 pending order, got %d", count.Count)
+	}
+}
+
+func TestCreateOrderWarnings(t *testing.T) {
+	decode := func(w *httptest.ResponseRecorder) []service.Warning {
+		t.Helper()
+		var body struct {
+			Warnings []service.Warning `json:"warnings"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Warnings == nil {
+			t.Fatalf("expected warnings array in response, got %s", w.Body.String())
+		}
+		return body.Warnings
+	}
+
+	w := doRequest(newTestRouter(newMemRepo()), http.MethodPost, "/orders", "application/json", `{"customer_id":"customer-1","product":"Widget","quantity":5000}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	if warnings := decode(w); len(warnings) != 0 {
+		t.Errorf("expected no warnings by default, got %+v", warnings)
+	}
+
+	r := gin.New()
+	NewHandler(service.NewOrderService(newMemRepo(), nil, service.WithSoftChecks(service.LargeQuantityCheck(1000)))).RegisterRoutes(r)
+
+	w = doRequest(r, http.MethodPost, "/orders", "application/json", `{"customer_id":"customer-1","product":"Widget","quantity":5000}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	warnings := decode(w)
+	if len(warnings) != 1 || warnings[0].Field != "quantity" {
+		t.Errorf("expected quantity warning, got %+v", warnings)
+	}
+}
+
+func TestCreateOrderProductRateLimit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	limiter := ratelimit.NewRedisTokenBucket(client, "ratelimit:product:", 2, time.Minute)
+	r := gin.New()
+	NewHandler(service.NewOrderService(newMemRepo(), nil, service.WithProductLimiter(limiter))).RegisterRoutes(r)
+
+	for i := 0; i < 2; i++ {
+		w := doRequest(r, http.MethodPost, "/orders", "application/json", `{"customer_id":"customer-1","product":"Widget","quantity":1}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("request %d: expected status 201, got %d", i+1, w.Code)
+		}
+	}
+
+	w := doRequest(r, http.MethodPost, "/orders", "application/json", `{"customer_id":"customer-1","product":"Widget","quantity":1}`)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+
+	w = doRequest(r, http.MethodPost, "/orders", "application/json", `{"customer_id":"customer-1","product":"Gadget","quantity":1}`)
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected other product to be unaffected, got status %d", w.Code)
+	}
+}
+
+func TestListShedUnderPoolSaturation(t *testing.T) {
+	repo := newMemRepo()
+	repo.orders["a"] = &model.Order{ID: "a", Product: "Widget", Quantity: 1, Status: "pending"}
+
+	saturated := func() sql.DBStats { return sql.DBStats{MaxOpenConnections: 10, InUse: 10} }
+	ctrl := admission.NewController(saturated, map[admission.Priority]float64{admission.PriorityLow: 0.8})
+
+	r := gin.New()
+	NewHandler(service.NewOrderService(repo, nil, service.WithAdmission(ctrl))).RegisterRoutes(r)
+
+	w := doRequest(r, http.MethodGet, "/orders", "", "")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected list to be shed with 503, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+
+	w = doRequest(r, http.MethodGet, "/orders/a", "", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("expected get to succeed under saturation, got %d", w.Code)
+	}
+}
+
+type blockingStats struct {
+	release chan struct{}
+	stats   model.OrderStats
+}
+
+func (b *blockingStats) Stats(ctx context.Context) (*model.OrderStats, error) {
+	select {
+	case <-b.release:
+		stats := b.stats
+		stats.ComputedAt = time.Now()
+		return &stats, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestGetOrderStatsStaleHeader(t *testing.T) {
+	source := &blockingStats{release: make(chan struct{}, 1), stats: model.OrderStats{Total: 2}}
+	r := gin.New()
+	NewHandler(service.NewOrderService(newMemRepo(), nil, service.WithStats(source, 20*time.Millisecond))).RegisterRoutes(r)
+
+	source.release <- struct{}{}
+	w := doRequest(r, http.MethodGet, "/orders/stats", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if w.Header().Get(StaleHeader) != "" {
+		t.Error("expected fresh response without stale header")
+	}
+
+	w = doRequest(r, http.MethodGet, "/orders/stats", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if w.Header().Get(StaleHeader) != "true" || w.Header().Get(AsOfHeader) == "" {
+		t.Errorf("expected stale headers, got %v", w.Header())
+	}
+	var body struct {
+		Total int  `json:"total"`
+		Stale bool `json:"stale"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if !body.Stale || body.Total != 2 {
+		t.Errorf("expected stale body with total 2, got %+v", body)
+	}
+	source.release <- struct{}{}
+}
+
+func TestCreateOrderPolicy(t *testing.T) {
+	policySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in policy.Input
+		json.NewDecoder(r.Body).Decode(&in)
+		switch in.Product {
+		case "Gadget":
+			json.NewEncoder(w).Encode(policy.Decision{Allow: false, Reason: "gadgets are embargoed"})
+		case "Broken":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			json.NewEncoder(w).Encode(policy.Decision{Allow: true})
+		}
+	}))
+	t.Cleanup(policySrv.Close)
+
+	newRouter := func(failOpen bool) *gin.Engine {
+		r := gin.New()
+		evaluator := policy.NewHTTPEvaluator(policySrv.URL)
+		NewHandler(service.NewOrderService(newMemRepo(), nil, service.WithPolicy(evaluator, failOpen))).RegisterRoutes(r)
+		return r
+	}
+
+	tests := []struct {
+		name     string
+		product  string
+		failOpen bool
+		want     int
+	}{
+		{"allowed", "Widget", false, http.StatusCreated},
+		{"denied", "Gadget", false, http.StatusUnprocessableEntity},
+		{"denied fail open", "Gadget", true, http.StatusUnprocessableEntity},
+		{"policy error fail closed", "Broken", false, http.StatusServiceUnavailable},
+		{"policy error fail open", "Broken", true, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(newRouter(tt.failOpen), http.MethodPost, "/orders", "application/json", `{"customer_id":"customer-1","product":"`+tt.product+`","quantity":1}`)
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			if tt.want == http.StatusUnprocessableEntity {
+				var body struct {
+					Reason string `json:"reason"`
+				}
+				json.Unmarshal(w.Body.Bytes(), &body)
+				if body.Reason != "gadgets are embargoed" {
+					t.Errorf("expected the policy reason in the response, got %s", w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCreateOrderLocationHeader(t *testing.T) {
+	r := newTestRouter(newMemRepo())
+
+	w := doRequest(r, http.MethodPost, "/orders", "application/json", `{"customer_id":"customer-1","product":"Widget","quantity":1}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	var order model.Order
+	if err := json.Unmarshal(w.Body.Bytes(), &order); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Header().Get("Location"); got != "/orders/"+order.ID {
+		t.Errorf("expected Location /orders/%s, got %q", order.ID, got)
+	}
+	if w := doRequest(r, http.MethodGet, w.Header().Get("Location"), "", ""); w.Code != http.StatusOK {
+		t.Errorf("expected the Location to resolve, got status %d", w.Code)
+	}
+}
+
+func TestGetOrdersFilterByStatusAndCreatedAt(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	for id, status := range map[string]string{"a": "pending", "b": "shipped", "c": "shipped"} {
+		if err := store.Create(context.Background(), &model.Order{ID: id, Product: "Widget", Quantity: 1, Status: status}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	r := gin.New()
+	NewHandler(service.NewOrderService(store, nil, service.WithOrderFinder(store))).RegisterRoutes(r)
+
+	now := time.Now().UTC()
+	from := now.Add(-time.Hour).Format(time.RFC3339)
+	to := now.Add(time.Hour).Format(time.RFC3339)
+	for path, want := range map[string]int{
+		"/orders?status=shipped&from=" + from + "&to=" + to: 2,
+		"/orders?status=pending":                            1,
+		"/orders?from=" + to:                                0,
+	} {
+		w := doRequest(r, http.MethodGet, path, "", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+		var body struct {
+			Items []model.Order `json:"items"`
+			Total int           `json:"total"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Total != want || len(body.Items) != want {
+			t.Errorf("%s: expected %d orders, got %+v", path, want, body)
+		}
+	}
+
+	for path, field := range map[string]string{
+		"/orders?from=" + to + "&to=" + from: "to",
+		"/orders?from=yesterday":             "from",
+		"/orders?status=lost":                "status",
+		"/orders?product=w&status=shipped":   "product",
+	} {
+		w := doRequest(r, http.MethodGet, path, "", "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", path, w.Code)
+			continue
+		}
+		if errs := decodeFieldErrors(t, w); errs[field] == "" {
+			t.Errorf("%s: expected an error for %s, got %v", path, field, errs)
+		}
+	}
+}
+
+func TestGetOrdersByCustomer(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	r := gin.New()
+	NewHandler(service.NewOrderService(store, nil, service.WithOrderFinder(store))).RegisterRoutes(r)
+	for _, customer := range []string{"alice", "bob", "alice"} {
+		if w := doRequest(r, http.MethodPost, "/orders", "application/json", `{"customer_id":"`+customer+`","product":"Widget","quantity":1}`); w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	w := doRequest(r, http.MethodGet, "/orders?customer_id=alice", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Items []model.Order `json:"items"`
+		Total int           `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Total != 2 {
+		t.Fatalf("expected 2 orders for alice, got %+v", body)
+	}
+	for _, o := range body.Items {
+		if o.CustomerID != "alice" {
+			t.Errorf("expected only alice's orders, got %+v", o)
+		}
+	}
+}
+
+func TestGetOrdersSort(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	for id, quantity := range map[string]int{"a": 3, "b": 1, "c": 2} {
+		if err := store.Create(context.Background(), &model.Order{ID: id, Product: "Widget", Quantity: quantity, Status: "pending"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	r := gin.New()
+	NewHandler(service.NewOrderService(store, nil, service.WithOrderFinder(store))).RegisterRoutes(r)
+
+	for path, want := range map[string]string{
+		"/orders?sort=quantity&order=asc": "bca",
+		"/orders?sort=quantity":           "acb",
+	} {
+		w := doRequest(r, http.MethodGet, path, "", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+		var body struct {
+			Items []model.Order `json:"items"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		for _, o := range body.Items {
+			got += o.ID
+		}
+		if got != want {
+			t.Errorf("%s: expected order %s, got %s", path, want, got)
+		}
+	}
+
+	for path, field := range map[string]string{
+		"/orders?sort=price":                  "sort",
+		"/orders?sort=id%3B%20DROP%20TABLE":   "sort",
+		"/orders?sort=quantity&order=upwards": "order",
+		"/orders?product=w&sort=quantity":     "product",
+	} {
+		w := doRequest(r, http.MethodGet, path, "", "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", path, w.Code)
+			continue
+		}
+		if errs := decodeFieldErrors(t, w); errs[field] == "" {
+			t.Errorf("%s: expected an error for %s, got %v", path, field, errs)
+		}
+	}
+}
+
+func TestGetOrdersSearchByProduct(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	for _, product := range []string{"Blue Widget", "widget stand", "Gadget"} {
+		if err := store.Create(context.Background(), &model.Order{ID: product, Product: product, Quantity: 1, Status: "pending"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	r := gin.New()
+	NewHandler(service.NewOrderService(store, nil, service.WithProductSearch(store))).RegisterRoutes(r)
+
+	w := doRequest(r, http.MethodGet, "/orders?product=WIDGET", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Items []model.Order `json:"items"`
+		Total int           `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Total != 2 || len(body.Items) != 2 {
+		t.Errorf("expected 2 case-insensitive matches, got %+v", body)
+	}
+
+	for path, field := range map[string]string{
+		"/orders?product=" + strings.Repeat("x", service.MaxProductSearchLength+1): "product",
+		"/orders?product=%20":       "product",
+		"/orders?product=w&limit=0": "limit",
+		"/orders?limit=10":          "limit",
+	} {
+		w := doRequest(r, http.MethodGet, path, "", "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", path, w.Code)
+			continue
+		}
+		if errs := decodeFieldErrors(t, w); errs[field] == "" {
+			t.Errorf("%s: expected an error for %s, got %v", path, field, errs)
+		}
+	}
+}
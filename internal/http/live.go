@@ -0,0 +1,135 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/auth"
+	"github.com/orders-service/internal/events"
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/service"
+	"go.uber.org/zap"
+)
+
+// liveKeepAlive is how often an idle live stream sends a comment so that
+// proxies do not close it.
+const liveKeepAlive = 15 * time.Second
+
+type LiveUpdates interface {
+	Subscribe(customerID string, types ...string) (<-chan events.OrderUpdate, func())
+	Resume(ctx context.Context, customerID, after string) (<-chan events.OrderUpdate, func(), error)
+}
+
+type LiveHandler struct {
+	updates   LiveUpdates
+	keepAlive time.Duration
+}
+
+func NewLiveHandler(updates LiveUpdates) *LiveHandler {
+	return &LiveHandler{updates: updates, keepAlive: liveKeepAlive}
+}
+
+func (h *LiveHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/orders/stream", RequireScope(auth.ScopeRead), h.Stream)
+	r.GET("/orders/events", RequireScope(auth.ScopeRead), h.Events)
+}
+
+type liveParams struct {
+	CustomerID string `form:"customer_id" binding:"omitempty,max=255"`
+}
+
+// Stream sends order updates as server-sent events, named after the event
+// type, until the client disconnects. A client that falls behind is
+// disconnected and should reconnect.
+func (h *LiveHandler) Stream(c *gin.Context) {
+	var p liveParams
+	if err := bindQuery(c, &p); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(err))
+		return
+	}
+	updates, unsubscribe := h.updates.Subscribe(p.CustomerID,
+		service.OrderCreatedChannel, service.OrderUpdatedChannel, service.OrderStatusChangedEvent)
+	defer unsubscribe()
+	h.serve(c, p.CustomerID, updates, false)
+}
+
+// Events sends every order lifecycle event, including order.deleted, as
+// server-sent events whose id is the event's stream ID. A client that
+// reconnects with Last-Event-ID first gets the events it missed, as far as
+// the stream still holds them, which is what EventSource does by itself.
+func (h *LiveHandler) Events(c *gin.Context) {
+	var p liveParams
+	if err := bindQuery(c, &p); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(err))
+		return
+	}
+
+	var updates <-chan events.OrderUpdate
+	var unsubscribe func()
+	if after := c.GetHeader("Last-Event-ID"); after != "" {
+		var err error
+		updates, unsubscribe, err = h.updates.Resume(c.Request.Context(), p.CustomerID, after)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse(service.CodeValidation, "Last-Event-ID: "+err.Error()))
+			return
+		}
+	} else {
+		updates, unsubscribe = h.updates.Subscribe(p.CustomerID)
+	}
+	defer unsubscribe()
+	h.serve(c, p.CustomerID, updates, true)
+}
+
+// serve writes updates as server-sent events until the channel is closed or
+// the client disconnects, with a keep-alive comment whenever it is idle.
+func (h *LiveHandler) serve(c *gin.Context, customerID string, updates <-chan events.OrderUpdate, withIDs bool) {
+	log := logger.FromContext(c.Request.Context())
+	clearDeadlines(c)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(h.keepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case update, ok := <-updates:
+			if !ok {
+				log.Info("live update stream closed", zap.String("customer_id", customerID))
+				return
+			}
+			if withIDs {
+				c.Writer.WriteString("id:" + update.StreamID + "\n")
+			}
+			c.SSEvent(update.Event, update)
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// clearDeadlines lifts the server's read and write timeouts from a
+// long-lived streaming response, which would otherwise be cut off once they
+// pass. Streams end on client disconnect or shutdown instead.
+func clearDeadlines(c *gin.Context) {
+	rc := http.NewResponseController(c.Writer)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logger.FromContext(c.Request.Context()).Warn("failed to clear read deadline", zap.Error(err))
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logger.FromContext(c.Request.Context()).Warn("failed to clear write deadline", zap.Error(err))
+	}
+}
@@ -0,0 +1,208 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/events"
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/service"
+)
+
+type fakeLiveUpdates struct {
+	customerID   string
+	types        []string
+	after        string
+	updates      chan events.OrderUpdate
+	subscribed   chan struct{}
+	unsubscribed chan struct{}
+}
+
+func newFakeLiveUpdates() *fakeLiveUpdates {
+	return &fakeLiveUpdates{
+		updates:      make(chan events.OrderUpdate, 1),
+		subscribed:   make(chan struct{}),
+		unsubscribed: make(chan struct{}),
+	}
+}
+
+func (f *fakeLiveUpdates) Subscribe(customerID string, types ...string) (<-chan events.OrderUpdate, func()) {
+	f.customerID, f.types = customerID, types
+	close(f.subscribed)
+	return f.updates, func() { close(f.unsubscribed) }
+}
+
+func (f *fakeLiveUpdates) Resume(ctx context.Context, customerID, after string) (<-chan events.OrderUpdate, func(), error) {
+	if after == "bogus" {
+		return nil, nil, errors.New(`invalid stream id "bogus"`)
+	}
+	f.after = after
+	updates, unsubscribe := f.Subscribe(customerID)
+	return updates, unsubscribe, nil
+}
+
+func readEvents(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestLiveStream(t *testing.T) {
+	live := newFakeLiveUpdates()
+	r := gin.New()
+	NewLiveHandler(live).RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/orders/stream?customer_id=alice")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	<-live.subscribed
+	if live.customerID != "alice" {
+		t.Errorf("expected a subscription for alice, got %q", live.customerID)
+	}
+	if strings.Join(live.types, ",") != "order.created,order.updated,order.status_changed" {
+		t.Errorf("expected a subscription to creates, updates and status changes, got %v", live.types)
+	}
+
+	live.updates <- events.OrderUpdate{Event: "order.created", Order: &model.Order{ID: "order-1", CustomerID: "alice"}}
+	close(live.updates)
+
+	body := readEvents(t, resp)
+	if strings.Contains(body, "id:") {
+		t.Errorf("expected no event ids, got %q", body)
+	}
+	if !strings.Contains(body, "event:order.created") || !strings.Contains(body, `"id":"order-1"`) {
+		t.Errorf("expected an order.created event for order-1, got %q", body)
+	}
+}
+
+func TestLiveStreamRejectsBadQuery(t *testing.T) {
+	r := gin.New()
+	// Registered alongside /orders/:id, as in main.
+	NewHandler(service.NewOrderService(newMemRepo(), nil)).RegisterRoutes(r)
+	NewLiveHandler(&fakeLiveUpdates{}).RegisterRoutes(r)
+
+	for _, path := range []string{"/orders/stream?customer=alice", "/orders/events?customer=alice"} {
+		w := doRequest(r, http.MethodGet, path, "", "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestOrderEvents(t *testing.T) {
+	live := newFakeLiveUpdates()
+	r := gin.New()
+	NewLiveHandler(live).RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/orders/events", nil)
+	req.Header.Set("Last-Event-ID", "1700000000000-0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" || resp.Header.Get("Cache-Control") != "no-cache" {
+		t.Fatalf("expected an uncached event stream, got %d %s", resp.StatusCode, resp.Header)
+	}
+	<-live.subscribed
+	if live.after != "1700000000000-0" || len(live.types) != 0 {
+		t.Errorf("expected every event after Last-Event-ID, got after %q and types %v", live.after, live.types)
+	}
+
+	live.updates <- events.OrderUpdate{StreamID: "1700000000001-0", Event: "order.deleted", Order: &model.Order{ID: "order-1"}}
+	close(live.updates)
+
+	body := readEvents(t, resp)
+	if !strings.Contains(body, "id:1700000000001-0\nevent:order.deleted") {
+		t.Errorf("expected an order.deleted event with its stream id, got %q", body)
+	}
+}
+
+func TestOrderEventsHeartbeatAndDisconnect(t *testing.T) {
+	live := newFakeLiveUpdates()
+	h := NewLiveHandler(live)
+	h.keepAlive = 10 * time.Millisecond
+	r := gin.New()
+	h.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/orders/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	if !scanner.Scan() || scanner.Text() != ": keep-alive" {
+		t.Errorf("expected a keep-alive comment, got %q", scanner.Text())
+	}
+
+	cancel()
+	select {
+	case <-live.unsubscribed:
+	case <-time.After(2 * time.Second):
+		t.Error("expected the handler to unsubscribe when the client disconnects")
+	}
+}
+
+func TestOrderEventsRejectsBadLastEventID(t *testing.T) {
+	r := gin.New()
+	NewLiveHandler(newFakeLiveUpdates()).RegisterRoutes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/events", nil)
+	req.Header.Set("Last-Event-ID", "bogus")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestLiveStreamOutlivesServerTimeouts(t *testing.T) {
+	live := newFakeLiveUpdates()
+	r := gin.New()
+	NewLiveHandler(live).RegisterRoutes(r)
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.ReadTimeout = 50 * time.Millisecond
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/orders/events")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	<-live.subscribed
+
+	time.Sleep(200 * time.Millisecond)
+	live.updates <- events.OrderUpdate{StreamID: "1-0", Event: "order.created", Order: &model.Order{ID: "order-1"}}
+	close(live.updates)
+
+	if body := readEvents(t, resp); !strings.Contains(body, "event:order.created") {
+		t.Errorf("expected the stream to outlive the server timeouts, got %q", body)
+	}
+}
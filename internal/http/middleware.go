@@ -0,0 +1,193 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/auth"
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/service"
+	"go.uber.org/zap"
+)
+
+// DefaultMaxBodyBytes is the default request body limit of MaxBodyBytes.
+const DefaultMaxBodyBytes = 1 << 20
+
+// MaxBodyBytes rejects request bodies larger than limit bytes with 413. A
+// declared Content-Length over the limit is rejected before the handler runs;
+// other bodies are cut off at the limit and the handler's read fails with
+// *http.MaxBytesError. A limit of zero or less disables the check.
+func MaxBodyBytes(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, bodyTooLargeResponse(limit))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+func bodyTooLargeResponse(limit int64) gin.H {
+	return errorResponse(CodePayloadTooLarge, fmt.Sprintf("request body exceeds %d bytes", limit))
+}
+
+// RateLimiter takes a token for key and reports how long until the next
+// one when none is left.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (bool, time.Duration, error)
+}
+
+// RateLimitByIP answers requests from a client IP that has exhausted its
+// tokens with 429 and a Retry-After header. If the limiter fails the request
+// is let through. The client IP honours X-Forwarded-For only from the
+// engine's trusted proxies.
+func RateLimitByIP(limiter RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		allowed, retryAfter, err := limiter.Allow(c.Request.Context(), ip)
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Error("failed to check client rate limit", zap.String("client_ip", ip), zap.Error(err))
+			c.Next()
+			return
+		}
+		if !allowed {
+			logger.FromContext(c.Request.Context()).Warn("client rate limit exceeded", zap.String("client_ip", ip), zap.Duration("retry_after", retryAfter))
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, errorResponse(service.CodeRateLimited, "rate limit exceeded"))
+			return
+		}
+		c.Next()
+	}
+}
+
+func RequireContentType(allowed ...string) gin.HandlerFunc {
+	expected := strings.Join(allowed, " or ")
+
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodDelete:
+			c.Next()
+			return
+		}
+
+		contentType := c.ContentType()
+		for _, ct := range allowed {
+			if strings.EqualFold(contentType, ct) {
+				c.Next()
+				return
+			}
+		}
+
+		msg := fmt.Sprintf("unsupported Content-Type %q, expected %s", contentType, expected)
+		if contentType == "" {
+			msg = "missing Content-Type header, expected " + expected
+		}
+		c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, errorResponse(CodeUnsupportedMediaType, msg))
+	}
+}
+
+// RequireBearerToken rejects requests whose Authorization header does not
+// carry token as a bearer token.
+func RequireBearerToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(CodeUnauthorized, "unauthorized"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// APIKeyHeader carries the API key of service-to-service callers.
+const APIKeyHeader = "X-API-Key"
+
+// CredentialVerifier checks the Authorization and X-API-Key values of a
+// request and returns the caller's identity.
+type CredentialVerifier interface {
+	Authenticate(ctx context.Context, authorization, apiKey string) (*auth.Identity, error)
+}
+
+// authEnabledKey marks requests that went through Authenticate, so that
+// RequireScope can tell authentication being off from a public path.
+const authEnabledKey = "auth_enabled"
+
+// PublicPaths lists the requests Authenticate lets through without
+// credentials. Exact paths must match in full, so that a route added below a
+// probe is not exempted by accident; Prefixes also cover every path below
+// them.
+type PublicPaths struct {
+	Exact    []string
+	Prefixes []string
+}
+
+func (p PublicPaths) match(path string) bool {
+	for _, exact := range p.Exact {
+		if path == exact {
+			return true
+		}
+	}
+	for _, prefix := range p.Prefixes {
+		if path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticate rejects requests without a valid API key or bearer token with
+// 401 and stores the caller's identity in the request context. Requests for
+// the public paths are not checked.
+func Authenticate(verifier CredentialVerifier, public PublicPaths) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(authEnabledKey, true)
+		if public.match(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		id, err := verifier.Authenticate(ctx, c.GetHeader("Authorization"), c.GetHeader(APIKeyHeader))
+		if err != nil {
+			logger.FromContext(ctx).Warn("unauthenticated request", zap.Error(err))
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(CodeUnauthorized, "unauthorized"))
+			return
+		}
+		log := logger.FromContext(ctx).With(zap.String("subject", id.Subject))
+		c.Request = c.Request.WithContext(logger.WithContext(auth.WithIdentity(ctx, id), log))
+		c.Next()
+	}
+}
+
+// RequireScope answers 403 to authenticated callers that lack scope. When
+// authentication is on, requests without an identity, such as those for a
+// public path, are answered 401; when it is off, every request passes.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := auth.IdentityFromContext(c.Request.Context()); !ok && c.GetBool(authEnabledKey) {
+			logger.FromContext(c.Request.Context()).Warn("unauthenticated request", zap.String("scope", scope))
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(CodeUnauthorized, "unauthorized"))
+			return
+		}
+		if err := auth.Authorize(c.Request.Context(), scope); err != nil {
+			logger.FromContext(c.Request.Context()).Warn("missing scope", zap.String("scope", scope))
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(CodeForbidden, err.Error()))
+			return
+		}
+		c.Next()
+	}
+}
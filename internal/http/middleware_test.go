@@ -0,0 +1,288 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/auth"
+	"github.com/orders-service/internal/ratelimit"
+	"github.com/orders-service/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRequireContentType(t *testing.T) {
+	r := newTestRouter(newMemRepo())
+	body := `{"customer_id":"customer-1","product":"Widget","quantity":1}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		want        int
+	}{
+		{"missing", "", http.StatusUnsupportedMediaType},
+		{"text/plain", "text/plain", http.StatusUnsupportedMediaType},
+		{"form", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"json", "application/json", http.StatusCreated},
+		{"json with charset", "application/json; charset=utf-8", http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(r, http.MethodPost, "/orders", tt.contentType, body)
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			if tt.want == http.StatusUnsupportedMediaType && !strings.Contains(w.Body.String(), "application/json") {
+				t.Errorf("expected error to mention application/json, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireContentTypeExemptsGet(t *testing.T) {
+	r := newTestRouter(newMemRepo())
+
+	w := doRequest(r, http.MethodGet, "/orders", "", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	r := gin.New()
+	r.Use(MaxBodyBytes(64))
+	NewHandler(service.NewOrderService(newMemRepo(), nil)).RegisterRoutes(r)
+
+	small := `{"customer_id":"customer-1","product":"Widget","quantity":1}`
+	large := `{"customer_id":"customer-1","product":"` + strings.Repeat("x", 100) + `","quantity":1}`
+
+	if w := doRequest(r, http.MethodPost, "/orders", "application/json", small); w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 under the limit, got %d", w.Code)
+	}
+	if w := doRequest(r, http.MethodPost, "/orders", "application/json", large); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413 for a declared oversized body, got %d", w.Code)
+	}
+
+	// Without a Content-Length the limit is only hit while decoding.
+	req := httptest.NewRequest(http.MethodPost, "/orders", io.NopCloser(strings.NewReader(large)))
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413 for a streamed oversized body, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRateLimitByIP(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+
+	r := gin.New()
+	r.Use(RateLimitByIP(ratelimit.NewRedisTokenBucket(client, "ratelimit:ip:", 1, time.Minute, ratelimit.WithBurst(2))))
+	NewHandler(service.NewOrderService(newMemRepo(), nil)).RegisterRoutes(r)
+
+	get := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := get("10.0.0.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %d", i+1, w.Code)
+		}
+	}
+	w := get("10.0.0.1:5678")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected Retry-After 60, got %q", w.Header().Get("Retry-After"))
+	}
+	if w := get("10.0.0.2:1234"); w.Code != http.StatusOK {
+		t.Errorf("expected another client to be unaffected, got status %d", w.Code)
+	}
+
+	mr.Close()
+	if w := get("10.0.0.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("expected requests to be let through when Redis is down, got status %d", w.Code)
+	}
+}
+
+// stubVerifier maps an Authorization header or API key to an identity.
+type stubVerifier map[string]*auth.Identity
+
+func (v stubVerifier) Authenticate(ctx context.Context, authorization, apiKey string) (*auth.Identity, error) {
+	credential := authorization
+	if apiKey != "" {
+		credential = apiKey
+	}
+	if id, ok := v[credential]; ok {
+		return id, nil
+	}
+	return nil, auth.ErrInvalidToken
+}
+
+func TestAuthenticate(t *testing.T) {
+	r := gin.New()
+	r.Use(Authenticate(stubVerifier{"Bearer good": {Subject: "alice", Scopes: []string{auth.ScopeRead}}, "key-1": {Subject: "apikey:jobs"}}, PublicPaths{
+		Exact:    []string{"/livez", "/metrics"},
+		Prefixes: []string{"/admin/projection"},
+	}))
+	r.GET("/livez", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/metrics/db", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/admin/projection/rebuild", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/whoami", func(c *gin.Context) {
+		id, _ := auth.IdentityFromContext(c.Request.Context())
+		c.String(http.StatusOK, id.Subject)
+	})
+
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("/whoami", "Authorization", "Bearer good"); w.Code != http.StatusOK || w.Body.String() != "alice" {
+		t.Errorf("expected alice to be authenticated, got %d %q", w.Code, w.Body.String())
+	}
+	if w := get("/whoami", APIKeyHeader, "key-1"); w.Code != http.StatusOK || w.Body.String() != "apikey:jobs" {
+		t.Errorf("expected the API key to be accepted, got %d %q", w.Code, w.Body.String())
+	}
+	for _, header := range [][]string{nil, {"Authorization", "Bearer bad"}, {"Authorization", "Basic good"}, {APIKeyHeader, "key-2"}} {
+		w := get("/whoami", header...)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%v: expected status 401, got %d", header, w.Code)
+		}
+		if w.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("%v: expected a Bearer challenge, got %q", header, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+	if w := get("/livez"); w.Code != http.StatusOK {
+		t.Errorf("expected public path to skip auth, got %d", w.Code)
+	}
+	if w := get("/metrics/db"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected a path below an exact public path to need auth, got %d", w.Code)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/admin/projection/rebuild", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected a path below a public prefix to skip auth, got %d", w.Code)
+	}
+}
+
+func TestRequireScopeAPIKeys(t *testing.T) {
+	r := gin.New()
+	r.Use(Authenticate(stubVerifier{
+		"reader": {Subject: "apikey:reader", Scopes: []string{auth.ScopeRead}},
+		"writer": {Subject: "apikey:writer", Scopes: []string{auth.ScopeRead, auth.ScopeWrite}},
+	}, PublicPaths{}))
+	NewHandler(service.NewOrderService(newMemRepo(), nil)).RegisterRoutes(r)
+
+	do := func(method, key string) int {
+		var body io.Reader
+		if method == http.MethodPost {
+			body = strings.NewReader(`{"customer_id":"customer-1","product":"Widget","quantity":1}`)
+		}
+		req := httptest.NewRequest(method, "/orders", body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, tt := range []struct {
+		method, key string
+		want        int
+	}{
+		{http.MethodGet, "reader", http.StatusOK},
+		{http.MethodPost, "reader", http.StatusForbidden},
+		{http.MethodGet, "writer", http.StatusOK},
+		{http.MethodPost, "writer", http.StatusCreated},
+	} {
+		if got := do(tt.method, tt.key); got != tt.want {
+			t.Errorf("%s /orders with %s key: expected status %d, got %d", tt.method, tt.key, tt.want, got)
+		}
+	}
+}
+
+func TestRequireScopeMatrix(t *testing.T) {
+	scopes := map[string][]string{
+		"none":       nil,
+		"read":       {auth.ScopeRead},
+		"write":      {auth.ScopeWrite},
+		"read+write": {auth.ScopeRead, auth.ScopeWrite},
+	}
+	verifier := stubVerifier{}
+	for name, granted := range scopes {
+		verifier["Bearer "+name] = &auth.Identity{Subject: name, Scopes: granted}
+	}
+	r := gin.New()
+	r.Use(Authenticate(verifier, PublicPaths{}))
+	NewHandler(service.NewOrderService(newMemRepo(), nil)).RegisterRoutes(r)
+
+	routes := []struct {
+		method, path, scope string
+	}{
+		{http.MethodGet, "/orders", auth.ScopeRead},
+		{http.MethodGet, "/orders/order-1", auth.ScopeRead},
+		{http.MethodGet, "/orders/count", auth.ScopeRead},
+		{http.MethodPost, "/orders", auth.ScopeWrite},
+		{http.MethodPut, "/orders/order-1", auth.ScopeWrite},
+		{http.MethodDelete, "/orders/order-1", auth.ScopeWrite},
+		{http.MethodPost, "/orders/order-1/tags", auth.ScopeWrite},
+	}
+
+	for name, granted := range scopes {
+		for _, rt := range routes {
+			req := httptest.NewRequest(rt.method, rt.path, strings.NewReader(`{}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+name)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			allowed := (&auth.Identity{Scopes: granted}).HasScope(rt.scope)
+			if denied := w.Code == http.StatusForbidden; denied == allowed {
+				t.Errorf("%s %s with %s scopes: expected allowed=%v, got status %d", rt.method, rt.path, name, allowed, w.Code)
+			}
+		}
+	}
+}
+
+func TestRequireScopeRejectsPublicPathsWhenAuthIsOn(t *testing.T) {
+	do := func(r *gin.Engine) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public/secret", nil))
+		return w.Code
+	}
+	route := func(r *gin.Engine) *gin.Engine {
+		r.GET("/public/secret", RequireScope(auth.ScopeRead), func(c *gin.Context) { c.Status(http.StatusOK) })
+		return r
+	}
+
+	withAuth := gin.New()
+	withAuth.Use(Authenticate(stubVerifier{}, PublicPaths{Prefixes: []string{"/public"}}))
+	if code := do(route(withAuth)); code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 without an identity, got %d", code)
+	}
+	if code := do(route(gin.New())); code != http.StatusOK {
+		t.Errorf("expected status 200 with authentication off, got %d", code)
+	}
+}
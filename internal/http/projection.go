@@ -0,0 +1,50 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/projection"
+	"github.com/orders-service/internal/service"
+)
+
+type ProjectionRebuilder interface {
+	Start(ctx context.Context) error
+	Progress() projection.Progress
+}
+
+type ProjectionHandler struct {
+	rebuilder ProjectionRebuilder
+	token     string
+}
+
+// NewProjectionHandler serves the projection admin routes, which require
+// token as a bearer token.
+func NewProjectionHandler(rebuilder ProjectionRebuilder, token string) *ProjectionHandler {
+	return &ProjectionHandler{rebuilder: rebuilder, token: token}
+}
+
+func (h *ProjectionHandler) RegisterRoutes(r *gin.Engine) {
+	admin := r.Group("/admin/projection", RequireBearerToken(h.token))
+	admin.POST("/rebuild", h.Rebuild)
+	admin.GET("/rebuild", h.GetProgress)
+}
+
+func (h *ProjectionHandler) Rebuild(c *gin.Context) {
+	// The rebuild outlives the request, so detach it from cancellation.
+	if err := h.rebuilder.Start(context.WithoutCancel(c.Request.Context())); err != nil {
+		if errors.Is(err, projection.ErrRebuildInProgress) {
+			c.JSON(http.StatusConflict, errorResponse(service.CodeConflict, err.Error()))
+			return
+		}
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, h.rebuilder.Progress())
+}
+
+func (h *ProjectionHandler) GetProgress(c *gin.Context) {
+	c.JSON(http.StatusOK, h.rebuilder.Progress())
+}
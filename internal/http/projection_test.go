@@ -0,0 +1,55 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/projection"
+)
+
+type busyRebuilder struct {
+	running bool
+}
+
+func (b *busyRebuilder) Start(ctx context.Context) error {
+	if b.running {
+		return projection.ErrRebuildInProgress
+	}
+	b.running = true
+	return nil
+}
+
+func (b *busyRebuilder) Progress() projection.Progress {
+	return projection.Progress{Running: b.running}
+}
+
+func TestProjectionRebuild(t *testing.T) {
+	r := gin.New()
+	NewProjectionHandler(&busyRebuilder{}, "secret").RegisterRoutes(r)
+
+	rebuild := func(auth string) int {
+		req := httptest.NewRequest(http.MethodPost, "/admin/projection/rebuild", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := rebuild(""); code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 without token, got %d", code)
+	}
+	if code := rebuild("Bearer wrong"); code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 with wrong token, got %d", code)
+	}
+	if code := rebuild("Bearer secret"); code != http.StatusAccepted {
+		t.Errorf("expected status 202, got %d", code)
+	}
+	if code := rebuild("Bearer secret"); code != http.StatusConflict {
+		t.Errorf("expected status 409 for concurrent rebuild, got %d", code)
+	}
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// queryErrors lists every invalid query parameter of a request.
+type queryErrors []FieldError
+
+func (e queryErrors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// bindQuery fills the struct pointed to by dst from the query string using its
+// form tags, then runs its binding validation. Unlike gin's binding it does
+// not stop at the first problem: parse failures, validation failures and
+// unsupported parameters are all collected into a single queryErrors.
+func bindQuery(c *gin.Context, dst interface{}) error {
+	v := reflect.ValueOf(dst).Elem()
+	t := v.Type()
+	values := c.Request.URL.Query()
+
+	var errs queryErrors
+	known := make(map[string]bool, t.NumField())
+	failed := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("form")
+		if name == "" || name == "-" {
+			continue
+		}
+		known[name] = true
+
+		raw := values[name]
+		if len(raw) == 0 || raw[0] == "" {
+			continue
+		}
+		if len(raw) > 1 {
+			errs = append(errs, FieldError{Field: name, Message: "must be given at most once"})
+			failed[name] = true
+			continue
+		}
+		if msg := setQueryField(v.Field(i), raw[0]); msg != "" {
+			errs = append(errs, FieldError{Field: name, Message: msg})
+			failed[name] = true
+		}
+	}
+
+	if err := binding.Validator.ValidateStruct(dst); err != nil {
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			return err
+		}
+		for _, fe := range validationErrs {
+			if !failed[fe.Field()] {
+				errs = append(errs, FieldError{Field: fe.Field(), Message: validationMessage(fe)})
+			}
+		}
+	}
+
+	var unknown []string
+	for name := range values {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, FieldError{Field: name, Message: "is not a supported parameter"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func setQueryField(field reflect.Value, raw string) string {
+	if field.Kind() == reflect.Ptr {
+		// Pointer fields distinguish an absent parameter from a zero value.
+		elem := reflect.New(field.Type().Elem())
+		if msg := setQueryField(elem.Elem(), raw); msg != "" {
+			return msg
+		}
+		field.Set(elem)
+		return ""
+	}
+	if field.Type() == timeType {
+		ts, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return "must be an RFC 3339 timestamp"
+		}
+		field.Set(reflect.ValueOf(ts))
+		return ""
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return "must be an integer"
+		}
+		field.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "must be true or false"
+		}
+		field.SetBool(b)
+	default:
+		return "has an unsupported type"
+	}
+	return ""
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/service"
+)
+
+type RouterConfig struct {
+	// RedirectTrailingSlash redirects /orders/ to /orders and vice versa
+	// when only the other form is routed.
+	RedirectTrailingSlash bool
+	// RedirectFixedPath redirects paths that only differ in case or contain
+	// superfluous elements such as ../ or // to the routed path.
+	RedirectFixedPath bool
+}
+
+// LenientRouting redirects common client path mistakes; StrictRouting
+// answers them with 404.
+var (
+	LenientRouting = RouterConfig{RedirectTrailingSlash: true, RedirectFixedPath: true}
+	StrictRouting  = RouterConfig{}
+)
+
+// NewRouter returns an engine with the given path handling. Redirects use 301
+// for GET and 307 for other methods so request bodies are preserved; paths
+// that still do not match get a JSON 404.
+func NewRouter(cfg RouterConfig) *gin.Engine {
+	r := gin.New()
+	r.RedirectTrailingSlash = cfg.RedirectTrailingSlash
+	// gin's own fixed-path lookup panics on trees with parameters, so case
+	// correction is done in the NoRoute handler against the route table.
+	r.RedirectFixedPath = false
+	r.NoRoute(notFound(r, cfg.RedirectFixedPath))
+	return r
+}
+
+func notFound(r *gin.Engine, fixPath bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if fixPath {
+			if fixed, ok := fixedPath(r.Routes(), c.Request.Method, c.Request.URL.Path); ok {
+				code := http.StatusMovedPermanently
+				if c.Request.Method != http.MethodGet {
+					code = http.StatusTemporaryRedirect
+				}
+				target := *c.Request.URL
+				target.Path = fixed
+				c.Redirect(code, target.String())
+				return
+			}
+		}
+		c.JSON(http.StatusNotFound, errorResponse(service.CodeNotFound, "route not found"))
+	}
+}
+
+// fixedPath finds the route for method whose static segments match p
+// case-insensitively after cleaning, preferring routes with more static
+// segments. Parameter segments keep the request's value.
+func fixedPath(routes gin.RoutesInfo, method, p string) (string, bool) {
+	segments := strings.Split(strings.Trim(path.Clean("/"+p), "/"), "/")
+
+	best, bestStatic := "", -1
+	for _, route := range routes {
+		if route.Method != method {
+			continue
+		}
+		pattern := strings.Split(strings.Trim(route.Path, "/"), "/")
+		if len(pattern) != len(segments) {
+			continue
+		}
+
+		fixed := make([]string, len(pattern))
+		static := 0
+		for i, seg := range pattern {
+			switch {
+			case strings.HasPrefix(seg, ":"):
+				fixed[i] = segments[i]
+			case strings.EqualFold(seg, segments[i]):
+				fixed[i] = seg
+				static++
+			default:
+				fixed = nil
+			}
+			if fixed == nil {
+				break
+			}
+		}
+		if fixed != nil && static > bestStatic {
+			best, bestStatic = "/"+strings.Join(fixed, "/"), static
+		}
+	}
+	if bestStatic < 0 || best == p {
+		return "", false
+	}
+	return best, true
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/orders-service/internal/service"
+)
+
+func TestRouterPathHandling(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      RouterConfig
+		method   string
+		path     string
+		code     int
+		location string
+	}{
+		{"lenient trailing slash", LenientRouting, http.MethodGet, "/orders/", http.StatusMovedPermanently, "/orders"},
+		{"lenient wrong case", LenientRouting, http.MethodGet, "/Orders", http.StatusMovedPermanently, "/orders"},
+		{"lenient wrong case with id", LenientRouting, http.MethodGet, "/ORDERS/abc/HISTORY", http.StatusMovedPermanently, "/orders/abc/history"},
+		{"lenient wrong case static", LenientRouting, http.MethodGet, "/Orders/Count", http.StatusMovedPermanently, "/orders/count"},
+		{"lenient cleaned path", LenientRouting, http.MethodGet, "/orders//abc/../abc/History", http.StatusMovedPermanently, "/orders/abc/history"},
+		{"lenient post keeps method", LenientRouting, http.MethodPost, "/orders/", http.StatusTemporaryRedirect, "/orders"},
+		{"strict trailing slash", StrictRouting, http.MethodGet, "/orders/", http.StatusNotFound, ""},
+		{"strict wrong case", StrictRouting, http.MethodGet, "/Orders", http.StatusNotFound, ""},
+		{"strict wrong case with id", StrictRouting, http.MethodGet, "/ORDERS/abc", http.StatusNotFound, ""},
+		{"unknown route", LenientRouting, http.MethodGet, "/widgets", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		r := NewRouter(tt.cfg)
+		NewHandler(service.NewOrderService(newMemRepo(), nil)).RegisterRoutes(r)
+
+		w := doRequest(r, tt.method, tt.path, "", "")
+		if w.Code != tt.code {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.code, w.Code)
+			continue
+		}
+		if got := w.Header().Get("Location"); got != tt.location {
+			t.Errorf("%s: expected Location %q, got %q", tt.name, tt.location, got)
+		}
+		if tt.code == http.StatusNotFound {
+			if body := decodeError(t, w); body.Code != service.CodeNotFound {
+				t.Errorf("%s: expected a not_found error body, got %q", tt.name, w.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/auth"
+	"github.com/orders-service/internal/events"
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/service"
+	"go.uber.org/zap"
+)
+
+const StreamPositionHeader = "X-Stream-Position"
+
+type PositionReader interface {
+	Position(ctx context.Context) (*events.StreamPosition, error)
+}
+
+type StreamHandler struct {
+	positions PositionReader
+}
+
+func NewStreamHandler(positions PositionReader) *StreamHandler {
+	return &StreamHandler{positions: positions}
+}
+
+func (h *StreamHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/stream/position", RequireScope(auth.ScopeRead), h.GetPosition)
+}
+
+type positionResponse struct {
+	*events.StreamPosition
+	Processed *bool `json:"processed,omitempty"`
+}
+
+func (h *StreamHandler) GetPosition(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+
+	pos, err := h.positions.Position(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, events.ErrGroupNotFound) {
+			c.JSON(http.StatusNotFound, errorResponse(service.CodeNotFound, err.Error()))
+			return
+		}
+		log.Error("failed to read stream position", zap.Error(err))
+		writeError(c, err)
+		return
+	}
+
+	resp := positionResponse{StreamPosition: pos}
+	if id := c.Query("id"); id != "" {
+		processed, err := pos.Processed(id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse(service.CodeValidation, err.Error()))
+			return
+		}
+		resp.Processed = &processed
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/events"
+	"github.com/orders-service/internal/service"
+)
+
+type positionPublisher struct {
+	next string
+}
+
+func (p *positionPublisher) Publish(ctx context.Context, channel string, message interface{}) error {
+	return nil
+}
+
+func (p *positionPublisher) PublishWithPosition(ctx context.Context, channel string, message interface{}) (string, error) {
+	return p.next, nil
+}
+
+type fixedPositions struct {
+	pos *events.StreamPosition
+}
+
+func (f *fixedPositions) Position(ctx context.Context) (*events.StreamPosition, error) {
+	return f.pos, nil
+}
+
+func TestCreateOrderReturnsStreamPosition(t *testing.T) {
+	r := gin.New()
+	svc := service.NewOrderService(newMemRepo(), &positionPublisher{next: "1700000000000-3"})
+	NewHandler(svc).RegisterRoutes(r)
+
+	w := doRequest(r, http.MethodPost, "/orders", "application/json", `{"customer_id":"customer-1","product":"Widget","quantity":1}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	if got := w.Header().Get(StreamPositionHeader); got != "1700000000000-3" {
+		t.Errorf("expected stream position header, got %q", got)
+	}
+}
+
+func TestGetStreamPosition(t *testing.T) {
+	r := gin.New()
+	NewStreamHandler(&fixedPositions{pos: &events.StreamPosition{
+		Stream:          events.StreamName,
+		Group:           events.ConsumerGroup,
+		LastDeliveredID: "100-0",
+		Pending:         1,
+		LowestPendingID: "100-0",
+	}}).RegisterRoutes(r)
+
+	tests := []struct {
+		query     string
+		processed bool
+	}{
+		{"?id=99-5", true},
+		{"?id=100-0", false},
+		{"?id=101-0", false},
+	}
+
+	for _, tt := range tests {
+		w := doRequest(r, http.MethodGet, "/stream/position"+tt.query, "", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", tt.query, w.Code)
+		}
+		var body struct {
+			LastDeliveredID string `json:"last_delivered_id"`
+			Processed       *bool  `json:"processed"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.LastDeliveredID != "100-0" {
+			t.Errorf("%s: expected last delivered 100-0, got %s", tt.query, body.LastDeliveredID)
+		}
+		if body.Processed == nil || *body.Processed != tt.processed {
+			t.Errorf("%s: expected processed=%v, got %v", tt.query, tt.processed, body.Processed)
+		}
+	}
+
+	w := doRequest(r, http.MethodGet, "/stream/position?id=garbage", "", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid id, got %d", w.Code)
+	}
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/service"
+	"go.uber.org/zap"
+)
+
+type addTagRequest struct {
+	Tag string `json:"tag" binding:"required"`
+}
+
+func (h *Handler) ListTags(c *gin.Context) {
+	id := c.Param("id")
+
+	tags, err := h.orderService.ListTags(c.Request.Context(), id)
+	if err != nil {
+		h.tagError(c, id, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+func (h *Handler) AddTag(c *gin.Context) {
+	id := c.Param("id")
+
+	var req addTagRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tags, err := h.orderService.AddTag(c.Request.Context(), id, req.Tag)
+	if err != nil {
+		h.tagError(c, id, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+func (h *Handler) RemoveTag(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.orderService.RemoveTag(c.Request.Context(), id, c.Param("tag")); err != nil {
+		if errors.Is(err, service.ErrTagNotFound) {
+			writeError(c, err)
+			return
+		}
+		h.tagError(c, id, err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func (h *Handler) tagError(c *gin.Context, id string, err error) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, validationErrorResponse(validationErr))
+	case errors.Is(err, service.ErrTagLimitExceeded):
+		writeError(c, err)
+	case errors.Is(err, service.ErrOrderNotFound):
+		writeError(c, err)
+	default:
+		logger.FromContext(c.Request.Context()).Error("failed to update order tags", zap.String("order_id", id), zap.Error(err))
+		writeError(c, err)
+	}
+}
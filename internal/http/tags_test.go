@@ -0,0 +1,46 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+	"github.com/orders-service/internal/service"
+)
+
+func TestAddTagStatusCodes(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	if err := store.Create(context.Background(), &model.Order{ID: "order-1", Product: "Widget", Quantity: 1, Status: "pending"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := gin.New()
+	NewHandler(service.NewOrderService(store, nil, service.WithTags(store, 1))).RegisterRoutes(r)
+
+	tests := []struct {
+		path string
+		body string
+		want int
+	}{
+		{"/orders/order-1/tags", `{"tag":"urgent"}`, http.StatusOK},
+		{"/orders/order-1/tags", `{"tag":"gift"}`, http.StatusConflict},
+		{"/orders/order-1/tags", `{"tag":"no spaces"}`, http.StatusBadRequest},
+		{"/orders/order-1/tags", `{}`, http.StatusBadRequest},
+		{"/orders/missing/tags", `{"tag":"urgent"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := doRequest(r, http.MethodPost, tt.path, "application/json", tt.body)
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected status %d, got %d (%s)", tt.path, tt.body, tt.want, w.Code, w.Body.String())
+		}
+	}
+
+	if w := doRequest(r, http.MethodDelete, "/orders/order-1/tags/urgent", "application/json", ""); w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+	if w := doRequest(r, http.MethodPost, "/orders/order-1/tags", "application/json", `{"tag":"gift"}`); w.Code != http.StatusOK {
+		t.Errorf("expected tag to fit after removal, got %d", w.Code)
+	}
+}
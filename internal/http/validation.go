@@ -0,0 +1,127 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/service"
+	"go.uber.org/zap"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(fld reflect.StructField) string {
+			name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+			if name == "" {
+				name = fld.Tag.Get("form")
+			}
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+func fieldErrors(err error) []FieldError {
+	var queryErrs queryErrors
+	if errors.As(err, &queryErrs) {
+		return queryErrs
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		result := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			result = append(result, FieldError{Field: fe.Field(), Message: validationMessage(fe)})
+		}
+		return result
+	}
+
+	var serviceErr *service.ValidationError
+	if errors.As(err, &serviceErr) {
+		return []FieldError{{Field: serviceErr.Field, Message: serviceErr.Message}}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []FieldError{{Field: typeErr.Field, Message: typeMessage(typeErr.Type)}}
+	}
+
+	return []FieldError{{Field: "body", Message: "malformed JSON"}}
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "lte":
+		return "must be less than or equal to " + fe.Param()
+	case "gtfield":
+		return "must be after " + strings.ToLower(fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	default:
+		return "is invalid"
+	}
+}
+
+func typeMessage(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "must be a number"
+	case reflect.String:
+		return "must be a string"
+	case reflect.Bool:
+		return "must be a boolean"
+	default:
+		return "has an invalid type"
+	}
+}
+
+// bindJSON decodes the request body into dst. If that fails it writes 413
+// for a body over the MaxBodyBytes limit and 400 otherwise, and returns false.
+func bindJSON(c *gin.Context, dst interface{}) bool {
+	err := c.ShouldBindJSON(dst)
+	if err == nil {
+		return true
+	}
+	logger.FromContext(c.Request.Context()).Warn("invalid request body", zap.Error(err))
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, bodyTooLargeResponse(tooLarge.Limit))
+		return false
+	}
+	c.JSON(http.StatusBadRequest, validationErrorResponse(err))
+	return false
+}
+
+// validationErrorResponse lists every invalid field in errors, next to the
+// usual error object.
+func validationErrorResponse(err error) gin.H {
+	resp := errorResponse(service.CodeValidation, "request is invalid")
+	resp["errors"] = fieldErrors(err)
+	return resp
+}
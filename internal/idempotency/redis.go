@@ -0,0 +1,62 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPrefix    = "idempotency:"
+	pendingValue = "pending"
+)
+
+type RedisStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisStore(client *redis.Client, ttl time.Duration) *RedisStore {
+	return &RedisStore{client: client, ttl: ttl}
+}
+
+func (s *RedisStore) Reserve(ctx context.Context, key string) (string, bool, error) {
+	k := redisKey(key)
+
+	ok, err := s.client.SetNX(ctx, k, pendingValue, s.ttl).Result()
+	if err != nil {
+		return "", false, err
+	}
+	if ok {
+		return "", true, nil
+	}
+
+	value, err := s.client.Get(ctx, k).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if value == pendingValue {
+		return "", false, nil
+	}
+	return value, false, nil
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key string, orderID string) error {
+	return s.client.Set(ctx, redisKey(key), orderID, s.ttl).Err()
+}
+
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, redisKey(key)).Err()
+}
+
+func redisKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return keyPrefix + hex.EncodeToString(sum[:])
+}
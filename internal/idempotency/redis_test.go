@@ -0,0 +1,96 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(client, time.Hour), mr
+}
+
+func TestRedisStoreReserveAndComplete(t *testing.T) {
+	store, _ := newTestStore(t)
+	ctx := context.Background()
+
+	_, reserved, err := store.Reserve(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reserved {
+		t.Fatal("expected first reserve to succeed")
+	}
+
+	orderID, reserved, err := store.Reserve(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reserved || orderID != "" {
+		t.Errorf("expected in-progress key, got reserved=%v order=%q", reserved, orderID)
+	}
+
+	if err := store.Complete(ctx, "key-1", "order-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	orderID, reserved, err = store.Reserve(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reserved || orderID != "order-1" {
+		t.Errorf("expected completed key to return order-1, got reserved=%v order=%q", reserved, orderID)
+	}
+}
+
+func TestRedisStoreHashesKeyAndExpires(t *testing.T) {
+	store, mr := newTestStore(t)
+	ctx := context.Background()
+
+	if _, _, err := store.Reserve(ctx, "raw-client-key"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mr.Exists("idempotency:raw-client-key") {
+		t.Error("expected the raw key not to be stored")
+	}
+	if !mr.Exists(redisKey("raw-client-key")) {
+		t.Error("expected the hashed key to be stored")
+	}
+
+	mr.FastForward(2 * time.Hour)
+
+	_, reserved, err := store.Reserve(ctx, "raw-client-key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reserved {
+		t.Error("expected key to be reservable again after the TTL")
+	}
+}
+
+func TestRedisStoreRelease(t *testing.T) {
+	store, _ := newTestStore(t)
+	ctx := context.Background()
+
+	if _, _, err := store.Reserve(ctx, "key-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Release(ctx, "key-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, reserved, err := store.Reserve(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reserved {
+		t.Error("expected released key to be reservable")
+	}
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"context"
+	"fmt"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -9,9 +10,19 @@ import (
 
 type ctxKey struct{}
 
-func New() (*zap.Logger, error) {
+// New builds the service logger at the given level ("debug", "info", "warn",
+// "error"; empty means info). The returned AtomicLevel can be changed at
+// runtime.
+func New(level string) (*zap.Logger, zap.AtomicLevel, error) {
+	atomicLevel := zap.NewAtomicLevelAt(zap.InfoLevel)
+	if level != "" {
+		if err := atomicLevel.UnmarshalText([]byte(level)); err != nil {
+			return nil, atomicLevel, fmt.Errorf("invalid log level %q: %w", level, err)
+		}
+	}
+
 	cfg := zap.Config{
-		Level:       zap.NewAtomicLevelAt(zap.InfoLevel),
+		Level:       atomicLevel,
 		Development: false,
 		Encoding:    "json",
 		EncoderConfig: zapcore.EncoderConfig{
@@ -31,7 +42,8 @@ func New() (*zap.Logger, error) {
 		ErrorOutputPaths: []string{"stdout"},
 	}
 
-	return cfg.Build()
+	log, err := cfg.Build()
+	return log, atomicLevel, err
 }
 
 func WithContext(ctx context.Context, l *zap.Logger) context.Context {
@@ -42,6 +54,6 @@ func FromContext(ctx context.Context) *zap.Logger {
 	if l, ok := ctx.Value(ctxKey{}).(*zap.Logger); ok {
 		return l
 	}
-	l, _ := New()
+	l, _, _ := New("")
 	return l
 }
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestNewLevel(t *testing.T) {
+	tests := []struct {
+		level    string
+		expected zap.AtomicLevel
+	}{
+		{"", zap.NewAtomicLevelAt(zap.InfoLevel)},
+		{"debug", zap.NewAtomicLevelAt(zap.DebugLevel)},
+		{"warn", zap.NewAtomicLevelAt(zap.WarnLevel)},
+		{"ERROR", zap.NewAtomicLevelAt(zap.ErrorLevel)},
+	}
+
+	for _, tt := range tests {
+		log, level, err := New(tt.level)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", tt.level, err)
+		}
+		if level.Level() != tt.expected.Level() {
+			t.Errorf("level %q: expected %s, got %s", tt.level, tt.expected.Level(), level.Level())
+		}
+		if log.Core().Enabled(level.Level()-1) && level.Level() > zap.DebugLevel {
+			t.Errorf("level %q: expected lower levels to be disabled", tt.level)
+		}
+	}
+
+	if _, _, err := New("verbose"); err == nil {
+		t.Error("expected error for invalid level")
+	}
+}
+
+func TestAtomicLevelChangedAtRuntime(t *testing.T) {
+	log, level, err := New("info")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if log.Core().Enabled(zap.DebugLevel) {
+		t.Fatal("expected debug to be disabled initially")
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"level":"debug"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	level.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !log.Core().Enabled(zap.DebugLevel) {
+		t.Error("expected debug to be enabled after PUT")
+	}
+}
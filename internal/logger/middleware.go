@@ -1,6 +1,12 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -10,7 +16,70 @@ import (
 
 const RequestIDKey = "request_id"
 
-func Middleware(log *zap.Logger) gin.HandlerFunc {
+// BodyKey is the gin context key under which BufferBody stores the captured
+// request body as a CapturedBody.
+const BodyKey = "request_body"
+
+const DefaultBodyLogLimit = 4096
+
+// DefaultRedactedFields are the JSON keys masked in logged bodies unless
+// configured otherwise.
+var DefaultRedactedFields = []string{"password", "secret", "token", "access_token", "refresh_token", "api_key", "authorization"}
+
+type CapturedBody struct {
+	Data []byte
+	// Truncated reports that the body was longer than the capture limit and
+	// Data holds only its start.
+	Truncated bool
+}
+
+type MiddlewareOption func(*middlewareConfig)
+
+type middlewareConfig struct {
+	logBody       bool
+	debugBodies   bool
+	responseLimit int
+	redact        map[string]bool
+}
+
+// WithRequestBody adds the request body captured by BufferBody to the access
+// log. Without BufferBody in the chain no body is logged.
+func WithRequestBody() MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.logBody = true
+	}
+}
+
+// WithDebugBodies logs the request body captured by BufferBody and the first
+// limit bytes of the response body in a separate debug-level "http bodies"
+// entry. The response is only captured while debug logging is enabled.
+func WithDebugBodies(limit int) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.debugBodies = true
+		c.responseLimit = limit
+	}
+}
+
+// WithRedactedFields masks the values of the given JSON object keys, matched
+// case-insensitively at any depth, in every logged body. Bodies that are not
+// valid JSON, including truncated ones, are then left out of the log.
+func WithRedactedFields(fields ...string) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		if c.redact == nil {
+			c.redact = make(map[string]bool, len(fields))
+		}
+		for _, f := range fields {
+			c.redact[strings.ToLower(f)] = true
+		}
+	}
+}
+
+func Middleware(log *zap.Logger, opts ...MiddlewareOption) gin.HandlerFunc {
+	var cfg middlewareConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	return func(c *gin.Context) {
 		start := time.Now()
 
@@ -25,15 +94,144 @@ func Middleware(log *zap.Logger) gin.HandlerFunc {
 		reqLogger := log.With(zap.String("request_id", requestID))
 		c.Request = c.Request.WithContext(WithContext(c.Request.Context(), reqLogger))
 
+		var response *bodyCapture
+		if cfg.debugBodies && reqLogger.Core().Enabled(zap.DebugLevel) {
+			response = &bodyCapture{ResponseWriter: c.Writer, limit: cfg.responseLimit}
+			c.Writer = response
+		}
+
 		c.Next()
 
 		latency := time.Since(start)
 
-		reqLogger.Info("http request",
+		fields := []zap.Field{
 			zap.String("method", c.Request.Method),
 			zap.String("path", c.Request.URL.Path),
 			zap.Int("status", c.Writer.Status()),
 			zap.Duration("latency", latency),
-		)
+		}
+		if cfg.logBody {
+			if body, ok := c.Get(BodyKey); ok {
+				fields = append(fields, cfg.bodyFields("body", body.(CapturedBody))...)
+			}
+		}
+		reqLogger.Info("http request", fields...)
+
+		if response != nil {
+			var bodies []zap.Field
+			if body, ok := c.Get(BodyKey); ok {
+				bodies = append(bodies, cfg.bodyFields("request_body", body.(CapturedBody))...)
+			}
+			bodies = append(bodies, cfg.bodyFields("response_body", CapturedBody{Data: response.buf.Bytes(), Truncated: response.truncated})...)
+			reqLogger.Debug("http bodies", bodies...)
+		}
+	}
+}
+
+// bodyFields returns the log fields for a captured body under key, redacted
+// as configured.
+func (cfg *middlewareConfig) bodyFields(key string, body CapturedBody) []zap.Field {
+	data := body.Data
+	if len(cfg.redact) > 0 && len(data) > 0 {
+		var err error
+		if data, err = redactJSON(data, cfg.redact); err != nil {
+			return []zap.Field{zap.String(key+"_omitted", "not valid JSON")}
+		}
+	}
+	fields := []zap.Field{zap.ByteString(key, data)}
+	if body.Truncated {
+		fields = append(fields, zap.Bool(key+"_truncated", true))
+	}
+	return fields
+}
+
+// redactedValue replaces the values of redacted fields.
+const redactedValue = "[REDACTED]"
+
+func redactJSON(data []byte, fields map[string]bool) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("trailing data after JSON value")
+	}
+	return json.Marshal(redact(v, fields))
+}
+
+func redact(v interface{}, fields map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			if fields[strings.ToLower(k)] {
+				v[k] = redactedValue
+			} else {
+				v[k] = redact(item, fields)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item, fields)
+		}
+	}
+	return v
+}
+
+// bodyCapture keeps the first limit bytes written to the response.
+type bodyCapture struct {
+	gin.ResponseWriter
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (w *bodyCapture) capture(b []byte) {
+	room := w.limit - w.buf.Len()
+	if len(b) > room {
+		b = b[:max(room, 0)]
+		w.truncated = true
+	}
+	w.buf.Write(b)
+}
+
+func (w *bodyCapture) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyCapture) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// BufferBody reads the request body once, keeping up to limit bytes under
+// BodyKey, and replaces it with a reader that replays what was read followed
+// by the rest of the original body. Body logging and handler binding can then
+// both see the body; bodies longer than limit are captured truncated but
+// still reach the handler in full.
+func BufferBody(limit int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body := c.Request.Body
+		if body == nil || body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		data, _ := io.ReadAll(io.LimitReader(body, int64(limit)+1))
+		captured := CapturedBody{Data: data}
+		if len(data) > limit {
+			captured = CapturedBody{Data: data[:limit], Truncated: true}
+		}
+		c.Set(BodyKey, captured)
+
+		// Read errors are not handled here: replaying the original body
+		// surfaces them to the handler as if it had read it directly.
+		c.Request.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(data), body), body}
+		c.Next()
 	}
 }
@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type bindTarget struct {
+	Product  string `json:"product"`
+	Quantity int    `json:"quantity"`
+}
+
+func serveWithBodyLogging(t *testing.T, limit int, body string) (bindTarget, map[string]interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	var bound bindTarget
+	r := gin.New()
+	r.Use(Middleware(zap.New(core), WithRequestBody()), BufferBody(limit))
+	r.POST("/orders", func(c *gin.Context) {
+		if err := c.ShouldBindJSON(&bound); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	entries := logs.FilterMessage("http request").All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 access log entry, got %d", len(entries))
+	}
+	return bound, entries[0].ContextMap()
+}
+
+func TestBodyLoggingKeepsBodyForBinding(t *testing.T) {
+	body := `{"product":"Widget","quantity":3}`
+	bound, fields := serveWithBodyLogging(t, DefaultBodyLogLimit, body)
+
+	if bound.Product != "Widget" || bound.Quantity != 3 {
+		t.Errorf("expected handler to bind the full body, got %+v", bound)
+	}
+	if fields["body"] != body {
+		t.Errorf("expected logged body %q, got %v", body, fields["body"])
+	}
+	if _, ok := fields["body_truncated"]; ok {
+		t.Error("expected body not to be marked truncated")
+	}
+}
+
+func TestBodyLoggingTruncatesLargeBodies(t *testing.T) {
+	body := `{"product":"Widget","quantity":3}`
+	bound, fields := serveWithBodyLogging(t, 10, body)
+
+	if bound.Product != "Widget" || bound.Quantity != 3 {
+		t.Errorf("expected handler to bind the full body, got %+v", bound)
+	}
+	if fields["body"] != body[:10] || fields["body_truncated"] != true {
+		t.Errorf("expected truncated body %q, got %v", body[:10], fields)
+	}
+}
+
+func TestDebugBodiesRedactsRequestAndResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	var bound bindTarget
+	r := gin.New()
+	r.Use(Middleware(zap.New(core), WithDebugBodies(DefaultBodyLogLimit), WithRedactedFields("Password", "token")), BufferBody(DefaultBodyLogLimit))
+	r.POST("/orders", func(c *gin.Context) {
+		if err := c.ShouldBindJSON(&bound); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": "order-1", "auth": gin.H{"token": "secret-token"}})
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"product":"Widget","quantity":3,"password":"hunter2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if bound.Product != "Widget" || bound.Quantity != 3 {
+		t.Errorf("expected handler to bind the full body, got %+v", bound)
+	}
+	if !strings.Contains(w.Body.String(), "secret-token") {
+		t.Errorf("expected the client to receive the unredacted response, got %s", w.Body.String())
+	}
+
+	entries := logs.FilterMessage("http bodies").All()
+	if len(entries) != 1 || entries[0].Level != zapcore.DebugLevel {
+		t.Fatalf("expected 1 debug body entry, got %v", entries)
+	}
+	fields := entries[0].ContextMap()
+	if got := fields["request_body"]; got != `{"password":"[REDACTED]","product":"Widget","quantity":3}` {
+		t.Errorf("unexpected request body %v", got)
+	}
+	if got := fields["response_body"]; got != `{"auth":{"token":"[REDACTED]"},"id":"order-1"}` {
+		t.Errorf("unexpected response body %v", got)
+	}
+	if _, ok := logs.FilterMessage("http request").All()[0].ContextMap()["body"]; ok {
+		t.Error("expected the access log entry to carry no body")
+	}
+}
+
+func TestDebugBodiesCapsResponseAndOmitsUnredactable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	r := gin.New()
+	r.Use(Middleware(zap.New(core), WithDebugBodies(8), WithRedactedFields("token")))
+	r.GET("/orders", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"token": "secret-token"})
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+	if w.Body.String() != `{"token":"secret-token"}` {
+		t.Errorf("expected the full response to reach the client, got %s", w.Body.String())
+	}
+	fields := logs.FilterMessage("http bodies").All()[0].ContextMap()
+	if _, ok := fields["response_body"]; ok {
+		t.Errorf("expected a truncated body to be left out when redacting, got %v", fields["response_body"])
+	}
+	if fields["response_body_omitted"] != "not valid JSON" {
+		t.Errorf("expected the omission to be noted, got %v", fields)
+	}
+}
+
+func TestDebugBodiesSkippedAboveDebugLevel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	r := gin.New()
+	r.Use(Middleware(zap.New(core), WithDebugBodies(DefaultBodyLogLimit)))
+	r.GET("/orders", func(c *gin.Context) {
+		if _, ok := c.Writer.(*bodyCapture); ok {
+			t.Error("expected the response not to be captured at info level")
+		}
+		c.Status(http.StatusOK)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+	if n := logs.FilterMessage("http bodies").Len(); n != 0 {
+		t.Errorf("expected no body entries, got %d", n)
+	}
+}
@@ -0,0 +1,228 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "orders"
+
+const (
+	OutcomeSuccess   = "success"
+	OutcomeNotFound  = "not_found"
+	OutcomeError     = "error"
+	OutcomeDuplicate = "duplicate"
+)
+
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	GRPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC requests by method and status code.",
+	}, []string{"method", "code"})
+
+	GRPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "gRPC request latency by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	OrderOperations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "repository_operations_total",
+		Help:      "Order repository operations by type and outcome.",
+	}, []string{"operation", "outcome"})
+
+	OrderOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "repository_operation_duration_seconds",
+		Help:      "Order repository operation latency by type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	EventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_published_total",
+		Help:      "Events published to the stream by event type and outcome.",
+	}, []string{"event", "outcome"})
+
+	EventPublishRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "event_publish_retries_total",
+		Help:      "Stream appends retried after a failure, by event type.",
+	}, []string{"event"})
+
+	EventPublishBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "event_publish_batch_size",
+		Help:      "Events appended to the stream per pipelined batch.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 9),
+	})
+
+	PublisherCircuitState = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "event_publisher_circuit_state",
+		Help:      "State of the event publisher circuit breaker: 0 closed, 1 half-open, 2 open.",
+	})
+
+	EventsConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_consumed_total",
+		Help:      "Events handled by the consumer by event type and outcome.",
+	}, []string{"event", "outcome"})
+
+	StreamLength = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "stream_length",
+		Help:      "Entries in the orders stream, processed or not.",
+	})
+
+	ConsumerLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "consumer_lag",
+		Help:      "Stream entries not yet delivered to the consumer group.",
+	})
+
+	ConsumerPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "consumer_pending",
+		Help:      "Stream entries delivered to the consumer group but not yet acked.",
+	})
+
+	ConsumerRestarts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "consumer_restarts_total",
+		Help:      "Consumer loops restarted after returning or panicking, by loop.",
+	}, []string{"loop"})
+
+	OrderCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "order_cache_lookups_total",
+		Help:      "GetOrder cache lookups by result (hit or miss).",
+	}, []string{"result"})
+)
+
+var httpInFlight, grpcInFlight atomic.Int64
+
+func init() {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	}, func() float64 { return float64(httpInFlight.Load()) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_in_flight",
+		Help:      "gRPC calls and streams currently being served.",
+	}, func() float64 { return float64(grpcInFlight.Load()) })
+}
+
+// HTTPInFlight returns the number of HTTP requests that have entered
+// GinMiddleware and not yet returned. Shutdown logs it when the drain
+// deadline passes.
+func HTTPInFlight() int64 {
+	return httpInFlight.Load()
+}
+
+// GRPCInFlight is HTTPInFlight for the gRPC interceptors.
+func GRPCInFlight() int64 {
+	return grpcInFlight.Load()
+}
+
+func RegisterDBStats(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
+
+func Outcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, sql.ErrNoRows):
+		return OutcomeNotFound
+	default:
+		return OutcomeError
+	}
+}
+
+// ObserveOperation records the outcome and latency of a repository call
+// started at start.
+func ObserveOperation(operation string, start time.Time, err error) {
+	OrderOperations.WithLabelValues(operation, Outcome(err)).Inc()
+	OrderOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		httpInFlight.Add(1)
+		defer httpInFlight.Add(-1)
+
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		HTTPRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		HTTPDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		grpcInFlight.Add(1)
+		defer grpcInFlight.Add(-1)
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observeGRPC(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		grpcInFlight.Add(1)
+		defer grpcInFlight.Add(-1)
+
+		start := time.Now()
+		err := handler(srv, ss)
+		observeGRPC(info.FullMethod, start, err)
+		return err
+	}
+}
+
+func observeGRPC(method string, start time.Time, err error) {
+	GRPCRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+	GRPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGinMiddlewareRecordsRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware())
+	r.GET("/orders/:id", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+
+	before := testutil.ToFloat64(HTTPRequests.WithLabelValues(http.MethodGet, "/orders/:id", "404"))
+
+	for _, id := range []string{"a", "b"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/"+id, nil))
+	}
+
+	after := testutil.ToFloat64(HTTPRequests.WithLabelValues(http.MethodGet, "/orders/:id", "404"))
+	if after-before != 2 {
+		t.Errorf("expected 2 requests recorded under the route template, got %v", after-before)
+	}
+}
+
+func TestUnaryServerInterceptorRecordsCode(t *testing.T) {
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/orders.OrderService/GetOrder"}
+
+	before := testutil.ToFloat64(GRPCRequests.WithLabelValues(info.FullMethod, codes.NotFound.String()))
+
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "order not found")
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound to pass through, got %v", err)
+	}
+
+	after := testutil.ToFloat64(GRPCRequests.WithLabelValues(info.FullMethod, codes.NotFound.String()))
+	if after-before != 1 {
+		t.Errorf("expected 1 request recorded, got %v", after-before)
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{nil, OutcomeSuccess},
+		{sql.ErrNoRows, OutcomeNotFound},
+		{errors.New("connection refused"), OutcomeError},
+	}
+
+	for _, tt := range tests {
+		if got := Outcome(tt.err); got != tt.expected {
+			t.Errorf("Outcome(%v) = %s, expected %s", tt.err, got, tt.expected)
+		}
+	}
+}
+
+func TestInFlightCounters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware())
+	var during int64
+	r.GET("/slow", func(c *gin.Context) {
+		during = HTTPInFlight()
+		c.Status(http.StatusOK)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if during != 1 {
+		t.Errorf("expected 1 HTTP request in flight while handling, got %d", during)
+	}
+	if n := HTTPInFlight(); n != 0 {
+		t.Errorf("expected 0 HTTP requests in flight after return, got %d", n)
+	}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/orders.OrderService/GetOrder"}
+	_, _ = UnaryServerInterceptor()(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		during = GRPCInFlight()
+		return nil, nil
+	})
+	if during != 1 {
+		t.Errorf("expected 1 gRPC call in flight while handling, got %d", during)
+	}
+	if n := GRPCInFlight(); n != 0 {
+		t.Errorf("expected 0 gRPC calls in flight after return, got %d", n)
+	}
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	OrderID   string          `json:"order_id"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// OrderChange is an entry of the change feed. Seq increases in commit order.
+type OrderChange struct {
+	Seq       int64           `json:"seq"`
+	OrderID   string          `json:"order_id"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// OrderEvent is a step in the lifecycle of an order: an event and the
+// order's status after it. From is set for order.status_changed.
+type OrderEvent struct {
+	Seq        int64     `json:"seq"`
+	Event      string    `json:"event"`
+	Status     string    `json:"status"`
+	From       string    `json:"from,omitempty"`
+	Version    int64     `json:"version"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
@@ -2,10 +2,36 @@ package model
 
 import "time"
 
+const (
+	StatusPending   = "pending"
+	StatusConfirmed = "confirmed"
+	StatusShipped   = "shipped"
+	StatusDelivered = "delivered"
+	StatusCancelled = "cancelled"
+)
+
 type Order struct {
-	ID        string    `json:"id"`
-	Product   string    `json:"product"`
-	Quantity  int       `json:"quantity"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
+	ID string `json:"id,omitempty"`
+	// CustomerID is the owner of the order. It is set on creation and never
+	// changes.
+	CustomerID string    `json:"customer_id"`
+	Product    string    `json:"product"`
+	Quantity   int       `json:"quantity"`
+	Status     string    `json:"status"`
+	Price      int64     `json:"price"`
+	Currency   string    `json:"currency"`
+	CreatedAt  time.Time `json:"created_at,omitzero"`
+	UpdatedAt  time.Time `json:"updated_at,omitzero"`
+	// Version starts at 1 and is incremented on every write.
+	Version int64 `json:"version"`
+	// Metadata holds free-form attributes set by clients, such as the sales
+	// channel or a promo code.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// StatusChange is the payload of an order.status_changed event.
+type StatusChange struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Order *Order `json:"order"`
 }
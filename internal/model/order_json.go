@@ -0,0 +1,28 @@
+package model
+
+import "encoding/json"
+
+// orderFields has Order's fields but not its methods, so OrderJSON can embed
+// it without picking up Order.MarshalJSON.
+type orderFields Order
+
+// OrderJSON is the JSON form of an order: its fields plus the computed total.
+// Types that embed an order should embed OrderJSON in their own MarshalJSON.
+type OrderJSON struct {
+	orderFields
+	// Total is Price × Quantity. It is omitted for the rare legacy order
+	// whose total does not fit in an int64.
+	Total *int64 `json:"total,omitempty"`
+}
+
+func (o *Order) JSON() OrderJSON {
+	v := OrderJSON{orderFields: orderFields(*o)}
+	if total, err := o.Total(); err == nil {
+		v.Total = &total
+	}
+	return v
+}
+
+func (o Order) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.JSON())
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestOrderJSONTotal(t *testing.T) {
+	data, err := json.Marshal(&Order{ID: "o1", Quantity: 3, Price: 250, Currency: "EUR"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body["total"] != float64(750) {
+		t.Errorf("expected total 750, got %v", body["total"])
+	}
+	if body["id"] != "o1" || body["currency"] != "EUR" {
+		t.Errorf("expected order fields alongside the total, got %s", data)
+	}
+
+	var decoded Order
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded.Price != 250 || decoded.Quantity != 3 {
+		t.Errorf("expected the order to round-trip, got %+v", decoded)
+	}
+
+	data, err = json.Marshal(Order{Quantity: 2, Price: math.MaxInt64})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body = nil
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := body["total"]; ok {
+		t.Errorf("expected no total when it overflows, got %v", body["total"])
+	}
+}
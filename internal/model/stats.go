@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type OrderStats struct {
+	Total         int            `json:"total"`
+	ByStatus      map[string]int `json:"by_status"`
+	TotalQuantity int64          `json:"total_quantity"`
+	// RevenueByCurrency sums price * quantity in minor units per currency.
+	RevenueByCurrency map[string]int64 `json:"revenue_by_currency"`
+	ComputedAt        time.Time        `json:"computed_at"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"errors"
+	"math"
+)
+
+var ErrTotalOverflow = errors.New("total exceeds the int64 range")
+
+// Total returns Price × Quantity in minor units, or ErrTotalOverflow if the
+// product does not fit in an int64.
+func (o *Order) Total() (int64, error) {
+	return MulTotal(o.Price, int64(o.Quantity))
+}
+
+// MulTotal returns a × b, or ErrTotalOverflow if it does not fit in an int64.
+func MulTotal(a, b int64) (int64, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, ErrTotalOverflow
+	}
+	c := a * b
+	if c/b != a {
+		return 0, ErrTotalOverflow
+	}
+	return c, nil
+}
+
+// AddTotals returns a + b, or ErrTotalOverflow if it does not fit in an int64.
+func AddTotals(a, b int64) (int64, error) {
+	c := a + b
+	if (b > 0 && c < a) || (b < 0 && c > a) {
+		return 0, ErrTotalOverflow
+	}
+	return c, nil
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestMulTotal(t *testing.T) {
+	tests := []struct {
+		a, b     int64
+		want     int64
+		overflow bool
+	}{
+		{500, 3, 1500, false},
+		{0, math.MaxInt64, 0, false},
+		{math.MaxInt64, 1, math.MaxInt64, false},
+		{math.MaxInt64 / 2, 2, math.MaxInt64 - 1, false},
+		{math.MaxInt64/2 + 1, 2, 0, true},
+		{math.MaxInt64, math.MaxInt32, 0, true},
+		{1 << 32, 1 << 31, 0, true},
+		{math.MinInt64, -1, 0, true},
+		{-1, math.MinInt64, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := MulTotal(tt.a, tt.b)
+		if tt.overflow {
+			if !errors.Is(err, ErrTotalOverflow) {
+				t.Errorf("%d × %d: expected ErrTotalOverflow, got %d (%v)", tt.a, tt.b, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%d × %d: expected %d, got %d (%v)", tt.a, tt.b, tt.want, got, err)
+		}
+	}
+}
+
+func TestAddTotals(t *testing.T) {
+	if got, err := AddTotals(math.MaxInt64-1, 1); err != nil || got != math.MaxInt64 {
+		t.Errorf("expected MaxInt64, got %d (%v)", got, err)
+	}
+	if _, err := AddTotals(math.MaxInt64, 1); !errors.Is(err, ErrTotalOverflow) {
+		t.Errorf("expected ErrTotalOverflow, got %v", err)
+	}
+	if _, err := AddTotals(math.MinInt64, -1); !errors.Is(err, ErrTotalOverflow) {
+		t.Errorf("expected ErrTotalOverflow for underflow, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+// Package ordercache caches orders by ID in Redis.
+package ordercache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/orders-service/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "order:"
+
+// RedisCache stores orders as JSON under order:<id> for a fixed TTL.
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisCache(client *redis.Client, ttl time.Duration) *RedisCache {
+	return &RedisCache{client: client, ttl: ttl}
+}
+
+// Get returns the cached order with id and whether there was one.
+func (c *RedisCache) Get(ctx context.Context, id string) (*model.Order, bool, error) {
+	data, err := c.client.Get(ctx, keyPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var order model.Order
+	if err := json.Unmarshal(data, &order); err != nil {
+		return nil, false, err
+	}
+	return &order, true, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, order *model.Order) error {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, keyPrefix+order.ID, data, c.ttl).Err()
+}
+
+func (c *RedisCache) Delete(ctx context.Context, id string) error {
+	return c.client.Del(ctx, keyPrefix+id).Err()
+}
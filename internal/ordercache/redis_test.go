@@ -0,0 +1,47 @@
+package ordercache
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/orders-service/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisCache(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	cache := NewRedisCache(client, time.Minute)
+	ctx := context.Background()
+
+	if _, ok, err := cache.Get(ctx, "order-1"); err != nil || ok {
+		t.Fatalf("expected a miss, got ok=%v err=%v", ok, err)
+	}
+
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	order := &model.Order{ID: "order-1", CustomerID: "customer-1", Product: "Widget", Quantity: 2, Status: model.StatusPending, CreatedAt: created, UpdatedAt: created, Version: 3}
+	if err := cache.Set(ctx, order); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, ok, err := cache.Get(ctx, "order-1")
+	if err != nil || !ok {
+		t.Fatalf("expected a hit, got ok=%v err=%v", ok, err)
+	}
+	if !reflect.DeepEqual(*got, *order) {
+		t.Errorf("expected %+v, got %+v", *order, *got)
+	}
+	if ttl := mr.TTL("order:order-1"); ttl != time.Minute {
+		t.Errorf("expected a TTL of 1m, got %v", ttl)
+	}
+
+	if err := cache.Delete(ctx, "order-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok, _ := cache.Get(ctx, "order-1"); ok {
+		t.Error("expected the order to be evicted")
+	}
+}
@@ -0,0 +1,148 @@
+// Package outbox publishes events that were recorded in the outbox table but
+// not published when their write committed.
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/orders-service/internal/events"
+	"github.com/orders-service/internal/repo"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultBatchSize = 100
+	// DefaultMinAge leaves the writing request time to publish and mark its
+	// own events before the relay considers them lost.
+	DefaultMinAge    = 5 * time.Second
+	DefaultRetention = 24 * time.Hour
+
+	purgeInterval = time.Hour
+)
+
+// Relay publishes unpublished outbox messages with their stored payload and
+// content type and marks them published. Delivery is at-least-once: a crash
+// between publishing and marking publishes a message again. Relays on several
+// instances can run side by side; each locks the rows it is working on.
+type Relay struct {
+	tx        repo.Transactor
+	store     repo.OutboxRepository
+	publisher events.RawPublisher
+	log       *zap.Logger
+	batchSize int
+	minAge    time.Duration
+	retention time.Duration
+	now       func() time.Time
+	lastPurge time.Time
+}
+
+type Option func(*Relay)
+
+func WithBatchSize(n int) Option {
+	return func(r *Relay) {
+		r.batchSize = n
+	}
+}
+
+func WithMinAge(d time.Duration) Option {
+	return func(r *Relay) {
+		r.minAge = d
+	}
+}
+
+// WithRetention sets how long published messages are kept before they are
+// deleted.
+func WithRetention(d time.Duration) Option {
+	return func(r *Relay) {
+		r.retention = d
+	}
+}
+
+func NewRelay(tx repo.Transactor, store repo.OutboxRepository, publisher events.RawPublisher, log *zap.Logger, opts ...Option) *Relay {
+	r := &Relay{
+		tx:        tx,
+		store:     store,
+		publisher: publisher,
+		log:       log,
+		batchSize: DefaultBatchSize,
+		minAge:    DefaultMinAge,
+		retention: DefaultRetention,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run relays the outbox every interval until ctx is cancelled, draining any
+// backlog batch by batch.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				n, err := r.RelayOnce(ctx)
+				if err != nil && ctx.Err() == nil {
+					r.log.Error("failed to relay outbox", zap.Error(err))
+				}
+				if err != nil || n < r.batchSize {
+					break
+				}
+			}
+			r.purge(ctx)
+		}
+	}
+}
+
+// RelayOnce publishes one batch of unpublished messages in id order and
+// returns how many were published. It stops at the first publish failure so
+// the remaining messages keep their order; they are retried on the next call.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	var published []int64
+	var publishErr error
+	err := r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		messages, err := r.store.ListUnpublished(ctx, r.minAge, r.batchSize)
+		if err != nil {
+			return err
+		}
+		for _, m := range messages {
+			if _, err := r.publisher.PublishRaw(events.WithOccurredAt(events.WithEventID(ctx, m.EventID), m.CreatedAt), m.Channel, m.ContentType, m.Payload); err != nil {
+				publishErr = err
+				break
+			}
+			published = append(published, m.ID)
+		}
+		return r.store.MarkPublished(ctx, published...)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(published) > 0 {
+		r.log.Info("relayed outbox messages", zap.Int("count", len(published)))
+	}
+	return len(published), publishErr
+}
+
+func (r *Relay) purge(ctx context.Context) {
+	now := r.now()
+	if now.Sub(r.lastPurge) < purgeInterval {
+		return
+	}
+	r.lastPurge = now
+
+	deleted, err := r.store.DeletePublishedOlderThan(ctx, r.retention)
+	if err != nil {
+		r.log.Error("failed to purge published outbox messages", zap.Error(err))
+		return
+	}
+	if deleted > 0 {
+		r.log.Info("purged published outbox messages", zap.Int64("count", deleted))
+	}
+}
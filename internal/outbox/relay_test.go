@@ -0,0 +1,131 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/orders-service/internal/events"
+	"github.com/orders-service/internal/repo"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type noTx struct{}
+
+func (noTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type memOutbox struct {
+	messages  []repo.OutboxMessage
+	published map[int64]bool
+}
+
+func (m *memOutbox) Add(ctx context.Context, msg *repo.OutboxMessage) error {
+	msg.ID = int64(len(m.messages) + 1)
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+func (m *memOutbox) ListUnpublished(ctx context.Context, minAge time.Duration, limit int) ([]repo.OutboxMessage, error) {
+	var result []repo.OutboxMessage
+	for _, msg := range m.messages {
+		if !m.published[msg.ID] && len(result) < limit {
+			result = append(result, msg)
+		}
+	}
+	return result, nil
+}
+
+func (m *memOutbox) MarkPublished(ctx context.Context, ids ...int64) error {
+	for _, id := range ids {
+		m.published[id] = true
+	}
+	return nil
+}
+
+func (m *memOutbox) DeletePublishedOlderThan(ctx context.Context, age time.Duration) (int64, error) {
+	return 0, nil
+}
+
+type failingPublisher struct {
+	next   events.RawPublisher
+	failAt int
+	calls  int
+}
+
+func (p *failingPublisher) PublishRaw(ctx context.Context, channel, contentType string, payload []byte) (string, error) {
+	p.calls++
+	if p.calls == p.failAt {
+		return "", errors.New("redis unavailable")
+	}
+	return p.next.PublishRaw(ctx, channel, contentType, payload)
+}
+
+func newTestClient(t *testing.T) *redis.Client {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestRelayPublishesUnpublishedMessages(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	store := &memOutbox{published: map[int64]bool{}}
+	for _, msg := range []repo.OutboxMessage{
+		{Channel: "order.created", ContentType: "application/json", Payload: []byte(`{"id":"order-1"}`)},
+		{Channel: "order.updated", ContentType: "application/x-protobuf", Payload: []byte{0x0a, 0x07}},
+	} {
+		msg := msg
+		_ = store.Add(ctx, &msg)
+	}
+
+	relay := NewRelay(noTx{}, store, events.NewRedisPublisher(client), zap.NewNop())
+	n, err := relay.RelayOnce(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 relayed messages, got %d (%v)", n, err)
+	}
+
+	entries, err := client.XRange(ctx, events.StreamName, "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Values["event"] != "order.created" || entries[1].Values["content_type"] != "application/x-protobuf" || entries[1].Values["payload"] != "\x0a\x07" {
+		t.Fatalf("unexpected stream entries %+v", entries)
+	}
+	if !store.published[1] || !store.published[2] {
+		t.Errorf("expected messages to be marked published, got %v", store.published)
+	}
+
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 0 {
+		t.Errorf("expected nothing left to relay, got %d (%v)", n, err)
+	}
+}
+
+func TestRelayStopsAtFirstPublishFailure(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	store := &memOutbox{published: map[int64]bool{}}
+	for i := 0; i < 3; i++ {
+		_ = store.Add(ctx, &repo.OutboxMessage{Channel: "order.created", ContentType: "application/json", Payload: []byte(`{}`)})
+	}
+
+	publisher := &failingPublisher{next: events.NewRedisPublisher(client), failAt: 2}
+	relay := NewRelay(noTx{}, store, publisher, zap.NewNop())
+	n, err := relay.RelayOnce(ctx)
+	if err == nil || n != 1 {
+		t.Fatalf("expected 1 relayed message and an error, got %d (%v)", n, err)
+	}
+	if !store.published[1] || store.published[2] || store.published[3] {
+		t.Errorf("expected only the first message to be marked published, got %v", store.published)
+	}
+
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 2 {
+		t.Errorf("expected the remaining 2 messages on retry, got %d (%v)", n, err)
+	}
+}
@@ -0,0 +1,167 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/orders-service/internal/model"
+)
+
+const (
+	DefaultCacheTTL = 5 * time.Second
+	DefaultTimeout  = 2 * time.Second
+
+	// maxCacheEntries bounds the decision cache; expired entries are swept
+	// when it fills up.
+	maxCacheEntries = 10000
+)
+
+// Input is the document POSTed to the policy endpoint.
+type Input struct {
+	ID         string `json:"id"`
+	CustomerID string `json:"customer_id"`
+	Product    string `json:"product"`
+	Quantity   int    `json:"quantity"`
+	Status     string `json:"status"`
+	Price      int64  `json:"price"`
+	Currency   string `json:"currency"`
+}
+
+// Decision is the policy endpoint's response.
+type Decision struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason"`
+}
+
+type cachedDecision struct {
+	Decision
+	expires time.Time
+}
+
+// HTTPEvaluator asks an external policy service whether an order is allowed.
+// Decisions are cached per Input for a short TTL so retries and repeated
+// writes of the same order do not hit the service every time.
+type HTTPEvaluator struct {
+	url    string
+	client *http.Client
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedDecision
+}
+
+type Option func(*HTTPEvaluator)
+
+func WithHTTPClient(client *http.Client) Option {
+	return func(e *HTTPEvaluator) {
+		e.client = client
+	}
+}
+
+// WithCacheTTL sets how long decisions are cached; zero disables caching.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(e *HTTPEvaluator) {
+		e.ttl = ttl
+	}
+}
+
+func NewHTTPEvaluator(url string, opts ...Option) *HTTPEvaluator {
+	e := &HTTPEvaluator{
+		url:    url,
+		client: &http.Client{Timeout: DefaultTimeout},
+		ttl:    DefaultCacheTTL,
+		now:    time.Now,
+		cache:  make(map[string]cachedDecision),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Evaluate POSTs the order as JSON and expects a 200 response with a
+// Decision. Any other status is an error.
+func (e *HTTPEvaluator) Evaluate(ctx context.Context, order *model.Order) (bool, string, error) {
+	body, err := json.Marshal(Input{
+		ID:         order.ID,
+		CustomerID: order.CustomerID,
+		Product:    order.Product,
+		Quantity:   order.Quantity,
+		Status:     order.Status,
+		Price:      order.Price,
+		Currency:   order.Currency,
+	})
+	if err != nil {
+		return false, "", err
+	}
+	key := string(body)
+	if d, ok := e.cached(key); ok {
+		return d.Allow, d.Reason, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return false, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return false, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return false, "", fmt.Errorf("policy service returned status %d", resp.StatusCode)
+	}
+	var d Decision
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return false, "", fmt.Errorf("decode policy decision: %w", err)
+	}
+
+	e.store(key, d)
+	return d.Allow, d.Reason, nil
+}
+
+func (e *HTTPEvaluator) cached(key string) (Decision, bool) {
+	if e.ttl <= 0 {
+		return Decision{}, false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	d, ok := e.cache[key]
+	if !ok || !e.now().Before(d.expires) {
+		return Decision{}, false
+	}
+	return d.Decision, true
+}
+
+func (e *HTTPEvaluator) store(key string, d Decision) {
+	if e.ttl <= 0 {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	if len(e.cache) >= maxCacheEntries {
+		for k, v := range e.cache {
+			if !now.Before(v.expires) {
+				delete(e.cache, k)
+			}
+		}
+		if len(e.cache) >= maxCacheEntries {
+			e.cache = make(map[string]cachedDecision)
+		}
+	}
+	e.cache[key] = cachedDecision{Decision: d, expires: now.Add(e.ttl)}
+}
@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/model"
+)
+
+// newPolicyServer denies orders for products in denied and counts requests.
+func newPolicyServer(t *testing.T, denied map[string]string) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var in Input
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reason, deny := denied[in.Product]
+		json.NewEncoder(w).Encode(Decision{Allow: !deny, Reason: reason})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestHTTPEvaluatorAllowAndDeny(t *testing.T) {
+	srv, _ := newPolicyServer(t, map[string]string{"Gadget": "gadgets are embargoed"})
+	e := NewHTTPEvaluator(srv.URL)
+
+	allow, _, err := e.Evaluate(context.Background(), &model.Order{ID: "order-1", Product: "Widget", Quantity: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !allow {
+		t.Error("expected Widget to be allowed")
+	}
+
+	allow, reason, err := e.Evaluate(context.Background(), &model.Order{ID: "order-2", Product: "Gadget", Quantity: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if allow || reason != "gadgets are embargoed" {
+		t.Errorf("expected Gadget to be denied with a reason, got allow=%v reason=%q", allow, reason)
+	}
+}
+
+func TestHTTPEvaluatorCachesDecisions(t *testing.T) {
+	srv, calls := newPolicyServer(t, nil)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	e := NewHTTPEvaluator(srv.URL, WithCacheTTL(time.Second))
+	e.now = func() time.Time { return now }
+
+	order := &model.Order{ID: "order-1", Product: "Widget", Quantity: 1}
+	for i := 0; i < 3; i++ {
+		if _, _, err := e.Evaluate(context.Background(), order); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected 1 policy request within the TTL, got %d", n)
+	}
+
+	if _, _, err := e.Evaluate(context.Background(), &model.Order{ID: "order-1", Product: "Widget", Quantity: 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now = now.Add(time.Second)
+	if _, _, err := e.Evaluate(context.Background(), order); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("expected a changed order and an expired decision to be re-evaluated, got %d requests", n)
+	}
+}
+
+func TestHTTPEvaluatorErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+
+	e := NewHTTPEvaluator(srv.URL)
+	if _, _, err := e.Evaluate(context.Background(), &model.Order{ID: "order-1", Product: "Widget"}); err == nil {
+		t.Fatal("expected an error for a 500 response")
+	}
+	if len(e.cache) != 0 {
+		t.Error("expected errors not to be cached")
+	}
+}
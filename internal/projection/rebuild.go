@@ -0,0 +1,137 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+	"go.uber.org/zap"
+)
+
+const DefaultBatchSize = 500
+
+var ErrRebuildInProgress = errors.New("projection rebuild already in progress")
+
+type OrderSource interface {
+	StreamSince(ctx context.Context, createdAt time.Time, afterID string, fn func(model.Order) error) error
+}
+
+type Progress struct {
+	Running    bool      `json:"running"`
+	Processed  int       `json:"processed"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Rebuilder repopulates the order projection from the orders table. Only one
+// rebuild runs at a time per instance.
+type Rebuilder struct {
+	source    OrderSource
+	store     repo.ProjectionRepository
+	batchSize int
+	log       *zap.Logger
+	now       func() time.Time
+
+	mu       sync.Mutex
+	progress Progress
+}
+
+func NewRebuilder(source OrderSource, store repo.ProjectionRepository, batchSize int, log *zap.Logger) *Rebuilder {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &Rebuilder{
+		source:    source,
+		store:     store,
+		batchSize: batchSize,
+		log:       log,
+		now:       time.Now,
+	}
+}
+
+func (r *Rebuilder) Progress() Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.progress
+}
+
+// Start begins a rebuild in the background and returns immediately, or
+// returns ErrRebuildInProgress if one is already running.
+func (r *Rebuilder) Start(ctx context.Context) error {
+	if err := r.begin(); err != nil {
+		return err
+	}
+	go r.run(ctx)
+	return nil
+}
+
+// Rebuild runs a rebuild to completion.
+func (r *Rebuilder) Rebuild(ctx context.Context) error {
+	if err := r.begin(); err != nil {
+		return err
+	}
+	return r.run(ctx)
+}
+
+func (r *Rebuilder) begin() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.progress.Running {
+		return ErrRebuildInProgress
+	}
+	r.progress = Progress{Running: true, StartedAt: r.now()}
+	return nil
+}
+
+// run truncates the projection and streams every order into it in batches,
+// so memory stays flat regardless of table size. Reads of the projection see
+// it partially populated until the rebuild finishes.
+func (r *Rebuilder) run(ctx context.Context) error {
+	r.log.Info("projection rebuild started")
+
+	err := r.store.Truncate(ctx)
+	if err == nil {
+		batch := make([]model.Order, 0, r.batchSize)
+		flush := func() error {
+			if err := r.store.Upsert(ctx, batch); err != nil {
+				return err
+			}
+			r.mu.Lock()
+			r.progress.Processed += len(batch)
+			r.mu.Unlock()
+			batch = batch[:0]
+			return nil
+		}
+
+		err = r.source.StreamSince(ctx, time.Time{}, "", func(o model.Order) error {
+			batch = append(batch, o)
+			if len(batch) < r.batchSize {
+				return nil
+			}
+			return flush()
+		})
+		if err == nil {
+			err = flush()
+		}
+	}
+
+	r.mu.Lock()
+	r.progress.Running = false
+	r.progress.FinishedAt = r.now()
+	if err != nil {
+		r.progress.Error = err.Error()
+	}
+	processed := r.progress.Processed
+	r.mu.Unlock()
+
+	if err != nil {
+		r.log.Error("projection rebuild failed", zap.Int("processed", processed), zap.Error(err))
+		return err
+	}
+	r.log.Info("projection rebuild finished", zap.Int("processed", processed))
+	return nil
+}
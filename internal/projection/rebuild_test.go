@@ -0,0 +1,130 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/model"
+	"go.uber.org/zap"
+)
+
+type sliceSource struct {
+	orders  []model.Order
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *sliceSource) StreamSince(ctx context.Context, createdAt time.Time, afterID string, fn func(model.Order) error) error {
+	if s.started != nil {
+		close(s.started)
+		<-s.release
+	}
+	for _, o := range s.orders {
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type memStore struct {
+	mu      sync.Mutex
+	rows    map[string]model.Order
+	batches int
+}
+
+func (m *memStore) Upsert(ctx context.Context, orders []model.Order) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, o := range orders {
+		m.rows[o.ID] = o
+	}
+	m.batches++
+	return nil
+}
+
+func (m *memStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.rows, id)
+	return nil
+}
+
+func (m *memStore) Truncate(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rows = make(map[string]model.Order)
+	return nil
+}
+
+func TestRebuildRepopulatesProjection(t *testing.T) {
+	source := &sliceSource{}
+	for i := 0; i < 5; i++ {
+		source.orders = append(source.orders, model.Order{ID: fmt.Sprintf("order-%d", i), Product: "Widget", Quantity: i + 1, Status: "pending"})
+	}
+	store := &memStore{rows: map[string]model.Order{
+		"ghost":   {ID: "ghost", Status: "pending"},
+		"order-0": {ID: "order-0", Status: "cancelled"},
+	}}
+
+	rebuilder := NewRebuilder(source, store, 2, zap.NewNop())
+	if err := rebuilder.Rebuild(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(store.rows) != len(source.orders) {
+		t.Fatalf("expected %d rows, got %d", len(source.orders), len(store.rows))
+	}
+	for _, o := range source.orders {
+		if !reflect.DeepEqual(store.rows[o.ID], o) {
+			t.Errorf("expected projection row %+v, got %+v", o, store.rows[o.ID])
+		}
+	}
+	if store.batches != 3 {
+		t.Errorf("expected 3 batches of at most 2, got %d", store.batches)
+	}
+
+	progress := rebuilder.Progress()
+	if progress.Running || progress.Processed != 5 || progress.Error != "" {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+}
+
+func TestRebuildRejectsConcurrentRebuild(t *testing.T) {
+	source := &sliceSource{
+		orders:  []model.Order{{ID: "order-1"}},
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	store := &memStore{rows: map[string]model.Order{}}
+	rebuilder := NewRebuilder(source, store, 10, zap.NewNop())
+
+	if err := rebuilder.Start(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-source.started
+
+	if !rebuilder.Progress().Running {
+		t.Error("expected rebuild to be reported as running")
+	}
+	if err := rebuilder.Rebuild(context.Background()); !errors.Is(err, ErrRebuildInProgress) {
+		t.Errorf("expected ErrRebuildInProgress, got %v", err)
+	}
+
+	close(source.release)
+	deadline := time.Now().Add(time.Second)
+	for rebuilder.Progress().Running {
+		if time.Now().After(deadline) {
+			t.Fatal("rebuild did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if rebuilder.Progress().Processed != 1 {
+		t.Errorf("expected 1 processed order, got %d", rebuilder.Progress().Processed)
+	}
+}
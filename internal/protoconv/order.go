@@ -0,0 +1,124 @@
+// Package protoconv converts between the service model and the protobuf
+// types shared by the gRPC API and the protobuf event format.
+package protoconv
+
+import (
+	"time"
+
+	"github.com/orders-service/internal/model"
+	pb "github.com/orders-service/proto"
+)
+
+const timeLayout = "2006-01-02T15:04:05Z07:00"
+
+func OrderToProto(o *model.Order) *pb.Order {
+	p := &pb.Order{
+		Id:         o.ID,
+		CustomerId: o.CustomerID,
+		Product:    o.Product,
+		Quantity:   int64(o.Quantity),
+		Status:     StatusToProto(o.Status),
+		CreatedAt:  formatTime(o.CreatedAt),
+		Price:      o.Price,
+		Currency:   o.Currency,
+		UpdatedAt:  formatTime(o.UpdatedAt),
+		Version:    o.Version,
+		Metadata:   o.Metadata,
+	}
+	if total, err := o.Total(); err == nil {
+		p.Total = &total
+	}
+	return p
+}
+
+// formatTime leaves unset times empty, as OrderFromProto expects them.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(timeLayout)
+}
+
+// OrderFromProto is the inverse of OrderToProto. Empty timestamps are left
+// as the zero time.
+func OrderFromProto(o *pb.Order) (*model.Order, error) {
+	order := &model.Order{
+		ID:         o.Id,
+		CustomerID: o.CustomerId,
+		Product:    o.Product,
+		Quantity:   int(o.Quantity),
+		Status:     StatusFromProto(o.Status),
+		Price:      o.Price,
+		Currency:   o.Currency,
+		Version:    o.Version,
+	}
+	if len(o.Metadata) > 0 {
+		order.Metadata = o.Metadata
+	}
+	var err error
+	if o.CreatedAt != "" {
+		if order.CreatedAt, err = time.Parse(timeLayout, o.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+	if o.UpdatedAt != "" {
+		if order.UpdatedAt, err = time.Parse(timeLayout, o.UpdatedAt); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+func StatusChangeToProto(c *model.StatusChange) *pb.OrderStatusChanged {
+	return &pb.OrderStatusChanged{
+		From:  StatusToProto(c.From),
+		To:    StatusToProto(c.To),
+		Order: OrderToProto(c.Order),
+	}
+}
+
+func StatusChangeFromProto(c *pb.OrderStatusChanged) (*model.StatusChange, error) {
+	change := &model.StatusChange{From: StatusFromProto(c.From), To: StatusFromProto(c.To)}
+	if c.Order != nil {
+		order, err := OrderFromProto(c.Order)
+		if err != nil {
+			return nil, err
+		}
+		change.Order = order
+	}
+	return change, nil
+}
+
+func StatusToProto(s string) pb.OrderStatus {
+	switch s {
+	case model.StatusPending:
+		return pb.OrderStatus_ORDER_STATUS_PENDING
+	case model.StatusConfirmed:
+		return pb.OrderStatus_ORDER_STATUS_CONFIRMED
+	case model.StatusShipped:
+		return pb.OrderStatus_ORDER_STATUS_SHIPPED
+	case model.StatusDelivered:
+		return pb.OrderStatus_ORDER_STATUS_DELIVERED
+	case model.StatusCancelled:
+		return pb.OrderStatus_ORDER_STATUS_CANCELLED
+	default:
+		return pb.OrderStatus_ORDER_STATUS_UNSPECIFIED
+	}
+}
+
+func StatusFromProto(s pb.OrderStatus) string {
+	switch s {
+	case pb.OrderStatus_ORDER_STATUS_PENDING:
+		return model.StatusPending
+	case pb.OrderStatus_ORDER_STATUS_CONFIRMED:
+		return model.StatusConfirmed
+	case pb.OrderStatus_ORDER_STATUS_SHIPPED:
+		return model.StatusShipped
+	case pb.OrderStatus_ORDER_STATUS_DELIVERED:
+		return model.StatusDelivered
+	case pb.OrderStatus_ORDER_STATUS_CANCELLED:
+		return model.StatusCancelled
+	default:
+		return model.StatusPending
+	}
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucket refills at ARGV[4] tokens per window, holding at most
+// ARGV[1] tokens. It returns {allowed, retry_after_ms}.
+var tokenBucket = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local window_ms = tonumber(ARGV[2])
+local now_ms = tonumber(ARGV[3])
+local refill = tonumber(ARGV[4])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil then
+  tokens = capacity
+  ts = now_ms
+end
+
+local rate = refill / window_ms
+tokens = math.min(capacity, tokens + math.max(0, now_ms - ts) * rate)
+
+local allowed = 0
+local retry_after = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry_after = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now_ms)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate))
+return {allowed, retry_after}
+`)
+
+type RedisTokenBucket struct {
+	client *redis.Client
+	prefix string
+	limit  int
+	burst  int
+	window time.Duration
+	now    func() time.Time
+}
+
+type Option func(*RedisTokenBucket)
+
+// WithBurst lets the bucket hold burst tokens instead of limit, so a client
+// that has been idle can exceed the steady rate for a short while.
+func WithBurst(burst int) Option {
+	return func(b *RedisTokenBucket) {
+		b.burst = burst
+	}
+}
+
+// NewRedisTokenBucket allows limit requests per window for each key.
+func NewRedisTokenBucket(client *redis.Client, prefix string, limit int, window time.Duration, opts ...Option) *RedisTokenBucket {
+	b := &RedisTokenBucket{client: client, prefix: prefix, limit: limit, burst: limit, window: window, now: time.Now}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Allow takes a token from the bucket for key. When none is available it
+// returns false and how long until the next token.
+func (b *RedisTokenBucket) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	res, err := tokenBucket.Run(ctx, b.client, []string{b.prefix + key},
+		b.burst, b.window.Milliseconds(), b.now().UnixMilli(), b.limit).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
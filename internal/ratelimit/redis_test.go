@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestBucket(t *testing.T, limit int, window time.Duration) *RedisTokenBucket {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisTokenBucket(client, "ratelimit:test:", limit, window)
+}
+
+func TestRedisTokenBucket(t *testing.T) {
+	bucket := newTestBucket(t, 3, time.Minute)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	bucket.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		allowed, _, err := bucket.Allow(ctx, "widget")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !allowed {
+			t.Fatalf("expected request %d to be allowed", i+1)
+		}
+	}
+
+	allowed, retryAfter, err := bucket.Allow(ctx, "widget")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if allowed {
+		t.Fatal("expected bucket to be exhausted")
+	}
+	if retryAfter != 20*time.Second {
+		t.Errorf("expected retry after 20s, got %v", retryAfter)
+	}
+
+	allowed, _, err = bucket.Allow(ctx, "gadget")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !allowed {
+		t.Error("expected a different key to be unaffected")
+	}
+
+	now = now.Add(20 * time.Second)
+	allowed, _, err = bucket.Allow(ctx, "widget")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !allowed {
+		t.Error("expected a token to be refilled")
+	}
+}
+
+func TestRedisTokenBucketBurst(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	bucket := NewRedisTokenBucket(client, "ratelimit:test:", 2, time.Second, WithBurst(5))
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	bucket.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if allowed, _, err := bucket.Allow(ctx, "10.0.0.1"); err != nil || !allowed {
+			t.Fatalf("expected request %d to be allowed within the burst, got %v (%v)", i+1, allowed, err)
+		}
+	}
+	allowed, retryAfter, err := bucket.Allow(ctx, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if allowed || retryAfter != 500*time.Millisecond {
+		t.Fatalf("expected to be limited for 500ms at 2/s, got %v after %v", allowed, retryAfter)
+	}
+
+	now = now.Add(time.Second)
+	for i := 0; i < 2; i++ {
+		if allowed, _, _ := bucket.Allow(ctx, "10.0.0.1"); !allowed {
+			t.Fatalf("expected refilled request %d to be allowed", i+1)
+		}
+	}
+	if allowed, _, _ := bucket.Allow(ctx, "10.0.0.1"); allowed {
+		t.Error("expected the refill to be limited to the rate, not the burst")
+	}
+}
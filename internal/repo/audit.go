@@ -0,0 +1,131 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/orders-service/internal/model"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type AuditRepository interface {
+	Record(ctx context.Context, entry *model.AuditEntry) error
+	History(ctx context.Context, orderID string, filter HistoryFilter) ([]model.AuditEntry, string, error)
+}
+
+type HistoryFilter struct {
+	EventType string
+	From      time.Time
+	To        time.Time
+	Cursor    string
+	Limit     int
+}
+
+type PostgresAuditRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresAuditRepository(db *sql.DB) *PostgresAuditRepository {
+	return &PostgresAuditRepository{db: db}
+}
+
+func (r *PostgresAuditRepository) Record(ctx context.Context, entry *model.AuditEntry) error {
+	query := `INSERT INTO order_audit (order_id, event_type, payload) VALUES ($1, $2, $3) RETURNING id, created_at`
+	return conn(ctx, r.db).QueryRowContext(ctx, query, entry.OrderID, entry.EventType, []byte(entry.Payload)).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// History returns entries for an order newest first. The returned cursor is
+// empty when there are no further pages.
+func (r *PostgresAuditRepository) History(ctx context.Context, orderID string, filter HistoryFilter) ([]model.AuditEntry, string, error) {
+	conds := []string{"order_id = $1"}
+	args := []interface{}{orderID}
+	add := func(cond string, arg ...interface{}) {
+		placeholders := make([]interface{}, len(arg))
+		for i := range arg {
+			args = append(args, arg[i])
+			placeholders[i] = len(args)
+		}
+		conds = append(conds, fmt.Sprintf(cond, placeholders...))
+	}
+
+	if filter.EventType != "" {
+		add("event_type = $%d", filter.EventType)
+	}
+	if !filter.From.IsZero() {
+		add("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("created_at < $%d", filter.To)
+	}
+	if filter.Cursor != "" {
+		createdAt, id, err := decodeHistoryCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		add("(created_at, id) < ($%d, $%d)", createdAt, id)
+	}
+
+	args = append(args, filter.Limit+1)
+	query := fmt.Sprintf(`SELECT id, order_id, event_type, payload, created_at FROM order_audit WHERE %s ORDER BY created_at DESC, id DESC LIMIT $%d`,
+		strings.Join(conds, " AND "), len(args))
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	entries := []model.AuditEntry{}
+	for rows.Next() {
+		var e model.AuditEntry
+		var payload []byte
+		if err := rows.Scan(&e.ID, &e.OrderID, &e.EventType, &payload, &e.CreatedAt); err != nil {
+			return nil, "", err
+		}
+		e.Payload = payload
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+		last := entries[len(entries)-1]
+		next = encodeHistoryCursor(last.CreatedAt, last.ID)
+	}
+	return entries, next, nil
+}
+
+func encodeHistoryCursor(createdAt time.Time, id int64) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + strconv.FormatInt(id, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeHistoryCursor(cursor string) (time.Time, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	ts, idStr, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	return createdAt, id, nil
+}
@@ -0,0 +1,104 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAuditHistoryFilteredPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	cursorAt := from.Add(12 * time.Hour)
+
+	query := regexp.QuoteMeta(`SELECT id, order_id, event_type, payload, created_at FROM order_audit WHERE order_id = $1 AND event_type = $2 AND created_at >= $3 AND created_at < $4 AND (created_at, id) < ($5, $6) ORDER BY created_at DESC, id DESC LIMIT $7`)
+	mock.ExpectQuery(query).
+		WithArgs("order-1", "order.updated", from, to, cursorAt, int64(10), 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "event_type", "payload", "created_at"}).
+			AddRow(9, "order-1", "order.updated", []byte(`{"status":"shipped"}`), from.Add(11*time.Hour)).
+			AddRow(7, "order-1", "order.updated", []byte(`{"status":"confirmed"}`), from.Add(10*time.Hour)).
+			AddRow(4, "order-1", "order.updated", []byte(`{"status":"pending"}`), from.Add(9*time.Hour)))
+
+	repo := NewPostgresAuditRepository(db)
+	entries, next, err := repo.History(context.Background(), "order-1", HistoryFilter{
+		EventType: "order.updated",
+		From:      from,
+		To:        to,
+		Cursor:    encodeHistoryCursor(cursorAt, 10),
+		Limit:     2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].ID != 9 || entries[1].ID != 7 {
+		t.Errorf("expected newest-first entries 9, 7, got %d, %d", entries[0].ID, entries[1].ID)
+	}
+	if string(entries[0].Payload) != `{"status":"shipped"}` {
+		t.Errorf("unexpected payload %s", entries[0].Payload)
+	}
+
+	createdAt, id, err := decodeHistoryCursor(next)
+	if err != nil {
+		t.Fatalf("unexpected error decoding cursor: %v", err)
+	}
+	if id != 7 || !createdAt.Equal(from.Add(10*time.Hour)) {
+		t.Errorf("expected cursor at entry 7, got %d at %v", id, createdAt)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAuditHistoryEmpty(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM order_audit WHERE order_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`)).
+		WithArgs("order-1", 51).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "event_type", "payload", "created_at"}))
+
+	repo := NewPostgresAuditRepository(db)
+	entries, next, err := repo.History(context.Background(), "order-1", HistoryFilter{Limit: 50})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entries == nil || len(entries) != 0 {
+		t.Errorf("expected empty non-nil slice, got %v", entries)
+	}
+	if next != "" {
+		t.Errorf("expected no next cursor, got %q", next)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAuditHistoryInvalidCursor(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, _, err = NewPostgresAuditRepository(db).History(context.Background(), "order-1", HistoryFilter{Cursor: "not-a-cursor", Limit: 10})
+	if !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/orders-service/internal/model"
+)
+
+// changeFeedLockKey is the advisory lock that serializes appends to the
+// change feed.
+const changeFeedLockKey = 0x6f726465726368 // "orderch"
+
+type ChangeFeedRepository interface {
+	// Append records change and sets its Seq and CreatedAt. It must be called
+	// inside a transaction.
+	Append(ctx context.Context, change *model.OrderChange) error
+	// Since returns up to limit changes with a sequence number above seq, in
+	// sequence order.
+	Since(ctx context.Context, seq int64, limit int) ([]model.OrderChange, error)
+	// ForOrder is Since for the changes of a single order.
+	ForOrder(ctx context.Context, orderID string, seq int64, limit int) ([]model.OrderChange, error)
+}
+
+type PostgresChangeFeedRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresChangeFeedRepository(db *sql.DB) *PostgresChangeFeedRepository {
+	return &PostgresChangeFeedRepository{db: db}
+}
+
+// Append takes a transaction-level advisory lock before drawing the sequence
+// number, so changes commit in sequence order and a reader that has seen seq
+// n will never later find a committed change below n. Concurrent writers
+// queue on the lock only from the append until their commit.
+func (r *PostgresChangeFeedRepository) Append(ctx context.Context, change *model.OrderChange) error {
+	tx := txFromContext(ctx)
+	if tx == nil {
+		return ErrNoTransaction
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, changeFeedLockKey); err != nil {
+		return err
+	}
+	query := `INSERT INTO order_changes (order_id, event_type, payload) VALUES ($1, $2, $3) RETURNING seq, created_at`
+	return tx.QueryRowContext(ctx, query, change.OrderID, change.EventType, []byte(change.Payload)).Scan(&change.Seq, &change.CreatedAt)
+}
+
+func (r *PostgresChangeFeedRepository) Since(ctx context.Context, seq int64, limit int) ([]model.OrderChange, error) {
+	query := `SELECT seq, order_id, event_type, payload, created_at FROM order_changes WHERE seq > $1 ORDER BY seq LIMIT $2`
+	return r.query(ctx, query, seq, limit)
+}
+
+func (r *PostgresChangeFeedRepository) ForOrder(ctx context.Context, orderID string, seq int64, limit int) ([]model.OrderChange, error) {
+	query := `SELECT seq, order_id, event_type, payload, created_at FROM order_changes WHERE order_id = $1 AND seq > $2 ORDER BY seq LIMIT $3`
+	return r.query(ctx, query, orderID, seq, limit)
+}
+
+func (r *PostgresChangeFeedRepository) query(ctx context.Context, query string, args ...interface{}) ([]model.OrderChange, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []model.OrderChange{}
+	for rows.Next() {
+		var c model.OrderChange
+		var payload []byte
+		if err := rows.Scan(&c.Seq, &c.OrderID, &c.EventType, &payload, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		c.Payload = payload
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
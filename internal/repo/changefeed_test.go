@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/orders-service/internal/model"
+)
+
+func TestPostgresChangeFeedAppendLocksInTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock\\(\\$1\\)").
+		WithArgs(changeFeedLockKey).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("INSERT INTO order_changes \\(order_id, event_type, payload\\)").
+		WithArgs("order-1", "order.created", []byte(`{"id":"order-1"}`)).
+		WillReturnRows(sqlmock.NewRows([]string{"seq", "created_at"}).AddRow(42, now))
+	mock.ExpectCommit()
+
+	store := NewPostgresChangeFeedRepository(db)
+	change := &model.OrderChange{OrderID: "order-1", EventType: "order.created", Payload: []byte(`{"id":"order-1"}`)}
+	if err := store.Append(context.Background(), change); !errors.Is(err, ErrNoTransaction) {
+		t.Fatalf("expected ErrNoTransaction outside a transaction, got %v", err)
+	}
+	err = NewTxManager(db).WithinTx(context.Background(), func(ctx context.Context) error {
+		return store.Append(ctx, change)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if change.Seq != 42 || !change.CreatedAt.Equal(now) {
+		t.Errorf("expected seq and created_at from database, got %+v", change)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresChangeFeedSince(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT seq, order_id, event_type, payload, created_at FROM order_changes WHERE seq > \\$1 ORDER BY seq LIMIT \\$2").
+		WithArgs(int64(10), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"seq", "order_id", "event_type", "payload", "created_at"}).
+			AddRow(11, "order-1", "order.created", []byte(`{}`), now).
+			AddRow(12, "order-1", "order.updated", []byte(`{}`), now))
+
+	changes, err := NewPostgresChangeFeedRepository(db).Since(context.Background(), 10, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Seq != 11 || changes[1].EventType != "order.updated" {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresChangeFeedForOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT seq, order_id, event_type, payload, created_at FROM order_changes WHERE order_id = \\$1 AND seq > \\$2 ORDER BY seq LIMIT \\$3").
+		WithArgs("order-1", int64(0), 100).
+		WillReturnRows(sqlmock.NewRows([]string{"seq", "order_id", "event_type", "payload", "created_at"}).
+			AddRow(3, "order-1", "order.created", []byte(`{}`), now).
+			AddRow(7, "order-1", "order.deleted", []byte(`{}`), now))
+
+	changes, err := NewPostgresChangeFeedRepository(db).ForOrder(context.Background(), "order-1", 0, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Seq != 3 || changes[1].EventType != "order.deleted" {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
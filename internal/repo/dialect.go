@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Drivers the repositories run on, by their database/sql name.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite3"
+)
+
+// SQLiteURLPrefix is the DATABASE_URL prefix that selects SQLite. The rest of
+// the URL is the path of the database file, e.g. sqlite://orders.db.
+const SQLiteURLPrefix = "sqlite://"
+
+// ParseDatabaseURL returns the driver and data source name for a
+// DATABASE_URL. Anything but a sqlite:// URL is handed to Postgres as is.
+func ParseDatabaseURL(url string) (driver, dsn string) {
+	if path, ok := strings.CutPrefix(url, SQLiteURLPrefix); ok {
+		return DriverSQLite, path
+	}
+	return DriverPostgres, url
+}
+
+var numberedParam = regexp.MustCompile(`\$(\d+)`)
+
+// Rebind rewrites the $1-style placeholders queries are written with into
+// the syntax of driver. SQLite gets ?1, which binds by number like Postgres
+// does, so a parameter may be used twice or out of order.
+func Rebind(driver, query string) string {
+	if driver != DriverSQLite {
+		return query
+	}
+	return numberedParam.ReplaceAllString(query, "?$1")
+}
+
+// driverOf reports which driver db was opened with. Anything but SQLite is
+// taken to be Postgres.
+func driverOf(db *sql.DB) string {
+	if _, ok := db.Driver().(*sqlite3.SQLiteDriver); ok {
+		return DriverSQLite
+	}
+	return DriverPostgres
+}
@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/orders-service/internal/export"
+)
+
+type PostgresExportCursorRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresExportCursorRepository(db *sql.DB) *PostgresExportCursorRepository {
+	return &PostgresExportCursorRepository{db: db}
+}
+
+func (r *PostgresExportCursorRepository) LoadCursor(ctx context.Context, name string) (export.Cursor, error) {
+	query := `SELECT last_created_at, last_id, recent_ids FROM export_cursors WHERE name = $1`
+
+	var cursor export.Cursor
+	var recent []byte
+	err := r.db.QueryRowContext(ctx, query, name).Scan(&cursor.CreatedAt, &cursor.ID, &recent)
+	if errors.Is(err, sql.ErrNoRows) {
+		return export.Cursor{}, nil
+	}
+	if err != nil {
+		return export.Cursor{}, err
+	}
+	if err := json.Unmarshal(recent, &cursor.Recent); err != nil {
+		return export.Cursor{}, fmt.Errorf("decode recent_ids: %w", err)
+	}
+	return cursor, nil
+}
+
+func (r *PostgresExportCursorRepository) SaveCursor(ctx context.Context, name string, cursor export.Cursor) error {
+	recent, err := json.Marshal(cursor.Recent)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO export_cursors (name, last_created_at, last_id, recent_ids, updated_at) VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (name) DO UPDATE SET last_created_at = EXCLUDED.last_created_at, last_id = EXCLUDED.last_id, recent_ids = EXCLUDED.recent_ids, updated_at = NOW()`
+	_, err = r.db.ExecContext(ctx, query, name, cursor.CreatedAt, cursor.ID, string(recent))
+	return err
+}
@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/orders-service/internal/export"
+)
+
+func TestPostgresExportCursorRoundTrip(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT last_created_at, last_id, recent_ids FROM export_cursors WHERE name = \\$1").
+		WithArgs("orders").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("INSERT INTO export_cursors (.+) ON CONFLICT \\(name\\) DO UPDATE").
+		WithArgs("orders", now, "order-2", `{"order-1":"2024-01-01T11:59:00Z","order-2":"2024-01-01T12:00:00Z"}`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT last_created_at, last_id, recent_ids FROM export_cursors WHERE name = \\$1").
+		WithArgs("orders").
+		WillReturnRows(sqlmock.NewRows([]string{"last_created_at", "last_id", "recent_ids"}).
+			AddRow(now, "order-2", []byte(`{"order-1":"2024-01-01T11:59:00Z","order-2":"2024-01-01T12:00:00Z"}`)))
+
+	store := NewPostgresExportCursorRepository(db)
+	ctx := context.Background()
+	cursor, err := store.LoadCursor(ctx, "orders")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cursor.CreatedAt.IsZero() || cursor.ID != "" || len(cursor.Recent) != 0 {
+		t.Errorf("expected an empty cursor before the first export, got %+v", cursor)
+	}
+
+	saved := export.Cursor{CreatedAt: now, ID: "order-2", Recent: map[string]time.Time{"order-1": now.Add(-time.Minute), "order-2": now}}
+	if err := store.SaveCursor(ctx, "orders", saved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cursor, err = store.LoadCursor(ctx, "orders")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cursor.ID != "order-2" || !cursor.CreatedAt.Equal(now) || len(cursor.Recent) != 2 || !cursor.Recent["order-1"].Equal(now.Add(-time.Minute)) {
+		t.Errorf("unexpected cursor %+v", cursor)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
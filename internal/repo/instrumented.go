@@ -0,0 +1,66 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/orders-service/internal/metrics"
+	"github.com/orders-service/internal/model"
+)
+
+type InstrumentedOrderRepository struct {
+	next OrderRepository
+}
+
+func NewInstrumentedOrderRepository(next OrderRepository) *InstrumentedOrderRepository {
+	return &InstrumentedOrderRepository{next: next}
+}
+
+func (r *InstrumentedOrderRepository) Create(ctx context.Context, order *model.Order) error {
+	start := time.Now()
+	err := r.next.Create(ctx, order)
+	metrics.ObserveOperation("create", start, err)
+	return err
+}
+
+func (r *InstrumentedOrderRepository) GetByID(ctx context.Context, id string) (*model.Order, error) {
+	start := time.Now()
+	order, err := r.next.GetByID(ctx, id)
+	metrics.ObserveOperation("get", start, err)
+	return order, err
+}
+
+func (r *InstrumentedOrderRepository) GetAll(ctx context.Context) ([]model.Order, error) {
+	start := time.Now()
+	orders, err := r.next.GetAll(ctx)
+	metrics.ObserveOperation("list", start, err)
+	return orders, err
+}
+
+func (r *InstrumentedOrderRepository) Update(ctx context.Context, order *model.Order) error {
+	start := time.Now()
+	err := r.next.Update(ctx, order)
+	metrics.ObserveOperation("update", start, err)
+	return err
+}
+
+func (r *InstrumentedOrderRepository) Delete(ctx context.Context, id string) error {
+	start := time.Now()
+	err := r.next.Delete(ctx, id)
+	metrics.ObserveOperation("delete", start, err)
+	return err
+}
+
+func (r *InstrumentedOrderRepository) CountOrders(ctx context.Context) (int, error) {
+	start := time.Now()
+	count, err := r.next.CountOrders(ctx)
+	metrics.ObserveOperation("count", start, err)
+	return count, err
+}
+
+func (r *InstrumentedOrderRepository) CountOrdersByStatus(ctx context.Context, status string) (int, error) {
+	start := time.Now()
+	count, err := r.next.CountOrdersByStatus(ctx, status)
+	metrics.ObserveOperation("count", start, err)
+	return count, err
+}
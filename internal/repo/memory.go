@@ -0,0 +1,339 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/orders-service/internal/model"
+)
+
+// InMemoryURL is the DATABASE_URL that selects the in-memory repository.
+const InMemoryURL = "memory://"
+
+var ErrDuplicateOrder = errors.New("order already exists")
+
+// InMemoryOrderRepository is a map-backed OrderRepository and TagRepository
+// for tests and for running the service without Postgres. It mirrors the
+// Postgres repositories: missing orders are reported as sql.ErrNoRows and
+// timestamps are assigned on write. Data does not survive a restart.
+type InMemoryOrderRepository struct {
+	mu     sync.RWMutex
+	orders map[string]model.Order
+	tags   map[string]map[string]struct{}
+	now    func() time.Time
+}
+
+func NewInMemoryOrderRepository() *InMemoryOrderRepository {
+	return &InMemoryOrderRepository{
+		orders: make(map[string]model.Order),
+		tags:   make(map[string]map[string]struct{}),
+		now:    func() time.Time { return time.Now().UTC() },
+	}
+}
+
+func (r *InMemoryOrderRepository) Create(ctx context.Context, order *model.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.orders[order.ID]; ok {
+		return ErrDuplicateOrder
+	}
+	now := r.now()
+	order.CreatedAt = now
+	order.UpdatedAt = now
+	order.Version = 1
+	r.orders[order.ID] = stored(order)
+	return nil
+}
+
+// stored copies order for the map, so that the caller's metadata map is not
+// shared with it.
+func stored(order *model.Order) model.Order {
+	o := *order
+	o.Metadata = maps.Clone(order.Metadata)
+	return o
+}
+
+func (r *InMemoryOrderRepository) CreateBatch(ctx context.Context, orders []*model.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := make(map[string]struct{}, len(orders))
+	for _, order := range orders {
+		if _, ok := r.orders[order.ID]; ok {
+			return ErrDuplicateOrder
+		}
+		if _, ok := seen[order.ID]; ok {
+			return ErrDuplicateOrder
+		}
+		seen[order.ID] = struct{}{}
+	}
+	now := r.now()
+	for _, order := range orders {
+		order.CreatedAt = now
+		order.UpdatedAt = now
+		order.Version = 1
+		r.orders[order.ID] = stored(order)
+	}
+	return nil
+}
+
+func (r *InMemoryOrderRepository) GetByID(ctx context.Context, id string) (*model.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	order, ok := r.orders[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &order, nil
+}
+
+func (r *InMemoryOrderRepository) GetAll(ctx context.Context) ([]model.Order, error) {
+	orders := r.snapshot(func(model.Order) bool { return true })
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.After(orders[j].CreatedAt)
+	})
+	return orders, nil
+}
+
+func (r *InMemoryOrderRepository) FindOrders(ctx context.Context, filter OrderFilter) ([]model.Order, error) {
+	var compare func(a, b model.Order) int
+	switch filter.Sort {
+	case "", SortCreatedAt:
+		compare = func(a, b model.Order) int { return a.CreatedAt.Compare(b.CreatedAt) }
+	case SortQuantity:
+		compare = func(a, b model.Order) int { return a.Quantity - b.Quantity }
+	case SortStatus:
+		compare = func(a, b model.Order) int { return strings.Compare(a.Status, b.Status) }
+	case SortProduct:
+		compare = func(a, b model.Order) int { return strings.Compare(a.Product, b.Product) }
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSortField, filter.Sort)
+	}
+
+	orders := r.snapshot(func(o model.Order) bool {
+		return (filter.CustomerID == "" || o.CustomerID == filter.CustomerID) &&
+			(filter.Status == "" || o.Status == filter.Status) &&
+			(filter.CreatedFrom.IsZero() || !o.CreatedAt.Before(filter.CreatedFrom)) &&
+			(filter.CreatedTo.IsZero() || o.CreatedAt.Before(filter.CreatedTo))
+	})
+	sort.Slice(orders, func(i, j int) bool {
+		c := compare(orders[i], orders[j])
+		if c == 0 {
+			c = strings.Compare(orders[i].ID, orders[j].ID)
+		}
+		if filter.Ascending {
+			return c < 0
+		}
+		return c > 0
+	})
+	return orders, nil
+}
+
+func (r *InMemoryOrderRepository) SearchByProduct(ctx context.Context, q string, limit int) ([]model.Order, error) {
+	q = strings.ToLower(q)
+	orders := r.snapshot(func(o model.Order) bool {
+		return strings.Contains(strings.ToLower(o.Product), q)
+	})
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.After(orders[j].CreatedAt)
+	})
+	if len(orders) > limit {
+		orders = orders[:limit]
+	}
+	return orders, nil
+}
+
+func (r *InMemoryOrderRepository) Update(ctx context.Context, order *model.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.orders[order.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if existing.Version != order.Version {
+		return ErrVersionConflict
+	}
+	existing.Product = order.Product
+	existing.Quantity = order.Quantity
+	existing.Status = order.Status
+	existing.Price = order.Price
+	existing.Currency = order.Currency
+	existing.Metadata = maps.Clone(order.Metadata)
+	existing.UpdatedAt = r.now()
+	existing.Version++
+	r.orders[order.ID] = existing
+
+	order.UpdatedAt = existing.UpdatedAt
+	order.Version = existing.Version
+	return nil
+}
+
+func (r *InMemoryOrderRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.orders[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.orders, id)
+	delete(r.tags, id)
+	return nil
+}
+
+func (r *InMemoryOrderRepository) CountOrders(ctx context.Context) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.orders), nil
+}
+
+func (r *InMemoryOrderRepository) CountOrdersByStatus(ctx context.Context, status string) (int, error) {
+	return len(r.snapshot(func(o model.Order) bool { return o.Status == status })), nil
+}
+
+func (r *InMemoryOrderRepository) Stats(ctx context.Context) (*model.OrderStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := &model.OrderStats{
+		ByStatus:          make(map[string]int),
+		RevenueByCurrency: make(map[string]int64),
+		ComputedAt:        time.Now(),
+	}
+	for _, o := range r.orders {
+		stats.ByStatus[o.Status]++
+		stats.Total++
+		stats.TotalQuantity += int64(o.Quantity)
+		if o.Status != model.StatusCancelled {
+			total, err := o.Total()
+			if err == nil {
+				total, err = model.AddTotals(stats.RevenueByCurrency[o.Currency], total)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("revenue in %s: %w", o.Currency, err)
+			}
+			stats.RevenueByCurrency[o.Currency] = total
+		}
+	}
+	return stats, nil
+}
+
+func (r *InMemoryOrderRepository) ListInStatusOlderThan(ctx context.Context, status string, age time.Duration, limit int) ([]model.Order, error) {
+	cutoff := r.now().Add(-age)
+	orders := r.snapshot(func(o model.Order) bool {
+		return o.Status == status && o.UpdatedAt.Before(cutoff)
+	})
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].UpdatedAt.Before(orders[j].UpdatedAt)
+	})
+	if len(orders) > limit {
+		orders = orders[:limit]
+	}
+	return orders, nil
+}
+
+func (r *InMemoryOrderRepository) TransitionStatus(ctx context.Context, id, from, to string) (*model.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, ok := r.orders[id]
+	if !ok || order.Status != from {
+		return nil, sql.ErrNoRows
+	}
+	order.Status = to
+	order.UpdatedAt = r.now()
+	order.Version++
+	r.orders[id] = order
+	return &order, nil
+}
+
+// StreamSince calls fn for each order after (createdAt, afterID) in
+// (created_at, id) order. It iterates over a snapshot, so fn may write to the
+// repository.
+func (r *InMemoryOrderRepository) StreamSince(ctx context.Context, createdAt time.Time, afterID string, fn func(model.Order) error) error {
+	orders := r.snapshot(func(o model.Order) bool {
+		return o.CreatedAt.After(createdAt) || (o.CreatedAt.Equal(createdAt) && o.ID > afterID)
+	})
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+		}
+		return orders[i].ID < orders[j].ID
+	})
+	for _, o := range orders {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *InMemoryOrderRepository) AddTag(ctx context.Context, orderID, tag string, limit int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.orders[orderID]; !ok {
+		return sql.ErrNoRows
+	}
+	tags := r.tags[orderID]
+	if _, ok := tags[tag]; ok {
+		return nil
+	}
+	if len(tags) >= limit {
+		return ErrTagLimit
+	}
+	if tags == nil {
+		tags = make(map[string]struct{})
+		r.tags[orderID] = tags
+	}
+	tags[tag] = struct{}{}
+	return nil
+}
+
+func (r *InMemoryOrderRepository) RemoveTag(ctx context.Context, orderID, tag string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tags[orderID][tag]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.tags[orderID], tag)
+	return nil
+}
+
+func (r *InMemoryOrderRepository) ListTags(ctx context.Context, orderID string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tags := make([]string, 0, len(r.tags[orderID]))
+	for tag := range r.tags[orderID] {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+func (r *InMemoryOrderRepository) snapshot(keep func(model.Order) bool) []model.Order {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var orders []model.Order
+	for _, o := range r.orders {
+		if keep(o) {
+			orders = append(orders, o)
+		}
+	}
+	return orders
+}
@@ -0,0 +1,164 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/model"
+)
+
+func newTestMemoryRepo(now *time.Time) *InMemoryOrderRepository {
+	r := NewInMemoryOrderRepository()
+	r.now = func() time.Time { return *now }
+	return r
+}
+
+func TestInMemoryOrderRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	r := newTestMemoryRepo(&now)
+
+	order := &model.Order{ID: "order-1", Product: "widget", Quantity: 2, Status: model.StatusPending, Price: 500, Currency: "USD"}
+	if err := r.Create(ctx, order); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !order.CreatedAt.Equal(now) || !order.UpdatedAt.Equal(now) {
+		t.Errorf("expected timestamps to be set, got %v / %v", order.CreatedAt, order.UpdatedAt)
+	}
+	if err := r.Create(ctx, &model.Order{ID: "order-1"}); !errors.Is(err, ErrDuplicateOrder) {
+		t.Errorf("expected ErrDuplicateOrder, got %v", err)
+	}
+
+	got, err := r.GetByID(ctx, "order-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got.Product = "changed"
+	if stored, _ := r.GetByID(ctx, "order-1"); stored.Product != "widget" {
+		t.Error("expected GetByID to return a copy")
+	}
+
+	now = now.Add(time.Minute)
+	order.Status = model.StatusConfirmed
+	if err := r.Update(ctx, order); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, _ = r.GetByID(ctx, "order-1")
+	if got.Status != model.StatusConfirmed || !got.UpdatedAt.Equal(now) || got.CreatedAt.Equal(now) || got.Version != 2 {
+		t.Errorf("unexpected order after update %+v", got)
+	}
+	stale := *got
+	stale.Version = 1
+	if err := r.Update(ctx, &stale); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict for stale version, got %v", err)
+	}
+
+	if err := r.Delete(ctx, "order-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, err := range map[string]error{
+		"get":    func() error { _, err := r.GetByID(ctx, "order-1"); return err }(),
+		"update": r.Update(ctx, order),
+		"delete": r.Delete(ctx, "order-1"),
+	} {
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("%s: expected sql.ErrNoRows, got %v", name, err)
+		}
+	}
+}
+
+func TestInMemoryOrderRepositoryQueries(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	r := newTestMemoryRepo(&now)
+
+	for _, o := range []model.Order{
+		{ID: "b", Status: model.StatusPending, Quantity: 1, Price: 100, Currency: "USD"},
+		{ID: "a", Status: model.StatusPending, Quantity: 2, Price: 100, Currency: "USD"},
+		{ID: "c", Status: model.StatusCancelled, Quantity: 3, Price: 100, Currency: "EUR"},
+	} {
+		o := o
+		if o.ID == "c" {
+			now = now.Add(2 * time.Minute)
+		}
+		if err := r.Create(ctx, &o); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	all, _ := r.GetAll(ctx)
+	if len(all) != 3 || all[0].ID != "c" {
+		t.Errorf("expected newest order first, got %+v", all)
+	}
+	if found, _ := r.SearchByProduct(ctx, "", 2); len(found) != 2 || found[0].ID != "c" {
+		t.Errorf("expected search to be limited and newest first, got %+v", found)
+	}
+	if found, _ := r.FindOrders(ctx, OrderFilter{Status: model.StatusPending, CreatedTo: now}); len(found) != 2 {
+		t.Errorf("expected 2 pending orders created before %s, got %+v", now, found)
+	}
+	if found, _ := r.FindOrders(ctx, OrderFilter{CreatedFrom: now}); len(found) != 1 || found[0].ID != "c" {
+		t.Errorf("expected from to be inclusive, got %+v", found)
+	}
+	if found, _ := r.FindOrders(ctx, OrderFilter{Sort: SortQuantity, Ascending: true}); len(found) != 3 || found[0].ID != "b" || found[2].ID != "c" {
+		t.Errorf("expected orders by ascending quantity, got %+v", found)
+	}
+	if n, _ := r.CountOrdersByStatus(ctx, model.StatusPending); n != 2 {
+		t.Errorf("expected 2 pending orders, got %d", n)
+	}
+
+	stats, _ := r.Stats(ctx)
+	if stats.Total != 3 || stats.TotalQuantity != 6 || stats.RevenueByCurrency["USD"] != 300 || stats.RevenueByCurrency["EUR"] != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	var streamed []string
+	err := r.StreamSince(ctx, time.Time{}, "", func(o model.Order) error {
+		streamed = append(streamed, o.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(streamed) != 3 || streamed[0] != "a" || streamed[1] != "b" || streamed[2] != "c" {
+		t.Errorf("expected (created_at, id) order, got %v", streamed)
+	}
+
+	if stale, _ := r.ListInStatusOlderThan(ctx, model.StatusPending, 90*time.Second, 10); len(stale) != 2 {
+		t.Errorf("expected 2 stale pending orders, got %+v", stale)
+	}
+	if stale, _ := r.ListInStatusOlderThan(ctx, model.StatusPending, 90*time.Second, 1); len(stale) != 1 {
+		t.Errorf("expected limit to apply, got %+v", stale)
+	}
+	if stale, _ := r.ListInStatusOlderThan(ctx, model.StatusPending, 3*time.Minute, 10); len(stale) != 0 {
+		t.Errorf("expected no orders older than 3m, got %+v", stale)
+	}
+	if _, err := r.TransitionStatus(ctx, "c", model.StatusPending, model.StatusConfirmed); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for wrong from status, got %v", err)
+	}
+	moved, err := r.TransitionStatus(ctx, "a", model.StatusPending, model.StatusConfirmed)
+	if err != nil || moved.Status != model.StatusConfirmed {
+		t.Errorf("expected transition to confirmed, got %+v (%v)", moved, err)
+	}
+}
+
+func TestInMemoryOrderRepositoryStatsOverflow(t *testing.T) {
+	ctx := context.Background()
+	r := NewInMemoryOrderRepository()
+
+	for _, o := range []model.Order{
+		{ID: "a", Status: model.StatusPending, Quantity: 1, Price: math.MaxInt64, Currency: "USD"},
+		{ID: "b", Status: model.StatusPending, Quantity: 1, Price: 1, Currency: "USD"},
+	} {
+		o := o
+		if err := r.Create(ctx, &o); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := r.Stats(ctx); !errors.Is(err, model.ErrTotalOverflow) {
+		t.Errorf("expected ErrTotalOverflow, got %v", err)
+	}
+}
@@ -1,13 +1,52 @@
 package repo
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strings"
+	"time"
 )
 
-func RunMigrations(db *sql.DB, migrationsPath string) error {
+const noTransactionMarker = "-- migrate:no-transaction"
+
+// migrationLockKey identifies the advisory lock RunMigrations holds so that
+// instances starting together apply each migration once.
+const migrationLockKey = 7_462_781_305
+
+const migrationUnlockTimeout = 5 * time.Second
+
+const createMigrationsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		filename   VARCHAR(255) PRIMARY KEY,
+		checksum   VARCHAR(64) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+
+type ChecksumMismatchError struct {
+	File     string
+	Recorded string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("migration %s was modified after being applied: recorded checksum %s, got %s", e.File, e.Recorded, e.Actual)
+}
+
+// RunMigrations applies the .sql files in migrationsPath that have not been
+// applied yet, in name order. On Postgres it holds an advisory lock on a
+// dedicated connection while it checks and applies them, so concurrent
+// callers wait for each other instead of racing on the same files. SQLite
+// has no such lock and its files are kept in their own directory.
+func RunMigrations(db *sql.DB, migrationsPath string) (err error) {
 	files, err := os.ReadDir(migrationsPath)
 	if err != nil {
 		return err
@@ -21,14 +60,211 @@ func RunMigrations(db *sql.DB, migrationsPath string) error {
 	}
 	sort.Strings(sqlFiles)
 
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := conn.Close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("release migration connection: %w", closeErr))
+		}
+	}()
+
+	driver := driverOf(db)
+	if driver == DriverPostgres {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+			return fmt.Errorf("lock migrations: %w", err)
+		}
+		defer func() {
+			if unlockErr := unlockMigrations(conn); unlockErr != nil {
+				err = errors.Join(err, unlockErr)
+			}
+		}()
+	}
+
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+
 	for _, file := range sqlFiles {
 		content, err := os.ReadFile(filepath.Join(migrationsPath, file))
 		if err != nil {
 			return err
 		}
-		if _, err := db.Exec(string(content)); err != nil {
-			return err
+		sum := sha256.Sum256(content)
+		checksum := hex.EncodeToString(sum[:])
+
+		if recorded, ok := applied[file]; ok {
+			if recorded != checksum {
+				return &ChecksumMismatchError{File: file, Recorded: recorded, Actual: checksum}
+			}
+			continue
+		}
+
+		if err := applyMigration(ctx, conn, driver, file, string(content), checksum); err != nil {
+			return fmt.Errorf("apply migration %s: %w", file, err)
 		}
 	}
 	return nil
 }
+
+// unlockMigrations releases the migration lock. It gets a context of its own
+// so that it runs even if applying the migrations timed out. If the lock
+// cannot be released the connection is discarded rather than returned to the
+// pool still holding it.
+func unlockMigrations(conn *sql.Conn) error {
+	ctx, cancel := context.WithTimeout(context.Background(), migrationUnlockTimeout)
+	defer cancel()
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockKey); err != nil {
+		_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		return fmt.Errorf("unlock migrations: %w", err)
+	}
+	return nil
+}
+
+// LatestMigration returns the most recently applied migration, named after
+// its file without the .sql extension (e.g. "007_order_projection"), or ""
+// if none has been applied yet.
+func LatestMigration(ctx context.Context, db *sql.DB) (string, error) {
+	var file string
+	err := db.QueryRowContext(ctx, `SELECT filename FROM schema_migrations ORDER BY filename DESC LIMIT 1`).Scan(&file)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(file, filepath.Ext(file)), nil
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[string]string, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT filename, checksum FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[string]string)
+	for rows.Next() {
+		var file, checksum string
+		if err := rows.Scan(&file, &checksum); err != nil {
+			return nil, err
+		}
+		applied[file] = checksum
+	}
+	return applied, rows.Err()
+}
+
+// applyMigration runs a migration and records it in a single transaction so a
+// failing file leaves no partial schema behind. Files whose header contains
+// the no-transaction marker (needed for statements such as CREATE INDEX
+// CONCURRENTLY) are executed directly instead. They must hold exactly one
+// statement: several would run as one implicit transaction, which such
+// statements refuse, and a failure midway could not be rolled back.
+func applyMigration(ctx context.Context, conn *sql.Conn, driver, file, content, checksum string) error {
+	record := Rebind(driver, `INSERT INTO schema_migrations (filename, checksum) VALUES ($1, $2)`)
+	if hasNoTransactionMarker(content) {
+		if n := countStatements(content); n != 1 {
+			return fmt.Errorf("%w: found %d", ErrNoTransactionStatements, n)
+		}
+		if _, err := conn.ExecContext(ctx, content); err != nil {
+			return err
+		}
+		_, err := conn.ExecContext(ctx, record, file, checksum)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Rollback after a successful Commit only reports sql.ErrTxDone, and
+	// after a failure the error that caused it is the one to return.
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, content); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, file, checksum); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ErrNoTransactionStatements is returned for a no-transaction migration that
+// does not hold exactly one statement.
+var ErrNoTransactionStatements = errors.New("a no-transaction migration must hold exactly one statement")
+
+// countStatements counts the semicolon-separated statements in content,
+// ignoring comments and semicolons inside quoted strings, quoted identifiers
+// and dollar-quoted bodies.
+func countStatements(content string) int {
+	n := 0
+	pending := false
+	for i := 0; i < len(content); i++ {
+		switch ch := content[i]; {
+		case strings.HasPrefix(content[i:], "--"):
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				end = len(content) - i
+			}
+			i += end
+		case strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				end = len(content) - i - 3
+			}
+			i += end + 3
+		case ch == '\'' || ch == '"':
+			pending = true
+			end := strings.IndexByte(content[i+1:], ch)
+			if end < 0 {
+				end = len(content) - i - 1
+			}
+			i += end + 1
+		case ch == '$' && dollarTag.MatchString(content[i:]):
+			pending = true
+			tag := dollarTag.FindString(content[i:])
+			end := strings.Index(content[i+len(tag):], tag)
+			if end < 0 {
+				end = len(content) - i - 2*len(tag)
+			}
+			i += len(tag) + end + len(tag) - 1
+		case ch == ';':
+			if pending {
+				n++
+			}
+			pending = false
+		case ch != ' ' && ch != '\t' && ch != '\n' && ch != '\r':
+			pending = true
+		}
+	}
+	if pending {
+		n++
+	}
+	return n
+}
+
+var dollarTag = regexp.MustCompile(`^\$(?:[A-Za-z_][A-Za-z_0-9]*)?\$`)
+
+func hasNoTransactionMarker(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			return false
+		}
+		if line == noTransactionMarker {
+			return true
+		}
+	}
+	return false
+}
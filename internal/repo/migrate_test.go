@@ -0,0 +1,305 @@
+package repo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func writeMigrations(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func checksumOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func expectMigrationLock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectMigrationUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestRunMigrationsSkipsApplied(t *testing.T) {
+	first := "CREATE TABLE a (id INT);"
+	second := "CREATE TABLE b (id INT);"
+	dir := writeMigrations(t, map[string]string{"001_a.sql": first, "002_b.sql": second, "README.md": "ignored"})
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	expectMigrationLock(mock)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT filename, checksum FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"filename", "checksum"}).AddRow("001_a.sql", checksumOf(first)))
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs("002_b.sql", checksumOf(second)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectMigrationUnlock(mock)
+
+	if err := RunMigrations(db, dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunMigrationsAppliesNothingWithoutTheLock(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{"001_a.sql": "CREATE TABLE a (id INT);"})
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(migrationLockKey).WillReturnError(errors.New("connection reset"))
+
+	if err := RunMigrations(db, dir); err == nil {
+		t.Fatal("expected error when the migration lock cannot be taken")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunMigrationsReportsUnlockFailure(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{"001_a.sql": "CREATE TABLE a (id INT);"})
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	expectMigrationLock(mock)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT filename, checksum FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"filename", "checksum"}).AddRow("001_a.sql", checksumOf("CREATE TABLE a (id INT);")))
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(migrationLockKey).WillReturnError(errors.New("connection reset"))
+
+	if err := RunMigrations(db, dir); err == nil || !strings.Contains(err.Error(), "unlock migrations") {
+		t.Errorf("expected the unlock failure to be reported, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunMigrationsChecksumMismatch(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{"001_a.sql": "CREATE TABLE a (id INT, name TEXT);"})
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	expectMigrationLock(mock)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT filename, checksum FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"filename", "checksum"}).AddRow("001_a.sql", checksumOf("CREATE TABLE a (id INT);")))
+	expectMigrationUnlock(mock)
+
+	err = RunMigrations(db, dir)
+	var mismatch *ChecksumMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected ChecksumMismatchError, got %v", err)
+	}
+	if mismatch.File != "001_a.sql" {
+		t.Errorf("expected file 001_a.sql, got %s", mismatch.File)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunMigrationsRollsBackOnFailure(t *testing.T) {
+	content := "CREATE TABLE a (id INT);\nCREATE TABLE broken (;"
+	dir := writeMigrations(t, map[string]string{"001_a.sql": content})
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	expectMigrationLock(mock)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT filename, checksum FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"filename", "checksum"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE a").WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+	expectMigrationUnlock(mock)
+
+	if err := RunMigrations(db, dir); err == nil {
+		t.Fatal("expected error from failing migration")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunMigrationsNoTransactionMarker(t *testing.T) {
+	content := "-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY idx_a ON a (id);"
+	dir := writeMigrations(t, map[string]string{"001_a.sql": content})
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	expectMigrationLock(mock)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT filename, checksum FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"filename", "checksum"}))
+	mock.ExpectExec("CREATE INDEX CONCURRENTLY").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs("001_a.sql", checksumOf(content)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectMigrationUnlock(mock)
+
+	if err := RunMigrations(db, dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunMigrationsRejectsMultiStatementNoTransactionFile(t *testing.T) {
+	content := "-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY idx_a ON a (id);\nCREATE INDEX CONCURRENTLY idx_b ON a (name);"
+	dir := writeMigrations(t, map[string]string{"001_a.sql": content})
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	expectMigrationLock(mock)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT filename, checksum FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"filename", "checksum"}))
+	expectMigrationUnlock(mock)
+
+	if err := RunMigrations(db, dir); !errors.Is(err, ErrNoTransactionStatements) {
+		t.Fatalf("expected ErrNoTransactionStatements, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCountStatements(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected int
+	}{
+		{"-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY x ON a (id);\n", 1},
+		{"CREATE INDEX x ON a (id)", 1},
+		{"CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);", 2},
+		{"CREATE TABLE a (id INT);;\n-- trailing; comment\n/* another; */", 1},
+		{"INSERT INTO a VALUES ('x;y', 'it''s');", 1},
+		{`CREATE INDEX "odd;name" ON a (id);`, 1},
+		{"CREATE FUNCTION f() RETURNS void AS $body$ BEGIN; END; $body$ LANGUAGE plpgsql;", 1},
+		{"DO $$ BEGIN; END $$; SELECT 1;", 2},
+		{"-- only a comment\n", 0},
+	}
+
+	for _, tt := range tests {
+		if got := countStatements(tt.content); got != tt.expected {
+			t.Errorf("countStatements(%q) = %d, expected %d", tt.content, got, tt.expected)
+		}
+	}
+}
+
+func TestNoTransactionMigrationsHoldOneStatement(t *testing.T) {
+	for _, dir := range []string{"../../migrations", "../../migrations/sqlite"} {
+		files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hasNoTransactionMarker(string(content)) && countStatements(string(content)) != 1 {
+				t.Errorf("expected %s to hold exactly one statement", file)
+			}
+		}
+	}
+}
+
+func TestHasNoTransactionMarker(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected bool
+	}{
+		{"-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY x ON a (id);", true},
+		{"\n-- adds an index\n-- migrate:no-transaction\nCREATE INDEX x ON a (id);", true},
+		{"CREATE TABLE a (id INT);\n-- migrate:no-transaction", false},
+		{"CREATE TABLE a (id INT);", false},
+	}
+
+	for _, tt := range tests {
+		if got := hasNoTransactionMarker(tt.content); got != tt.expected {
+			t.Errorf("hasNoTransactionMarker(%q) = %v, expected %v", tt.content, got, tt.expected)
+		}
+	}
+}
+
+func TestLatestMigration(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT filename FROM schema_migrations ORDER BY filename DESC LIMIT 1").
+		WillReturnRows(sqlmock.NewRows([]string{"filename"}).AddRow("007_order_projection.sql"))
+	mock.ExpectQuery("SELECT filename FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"filename"}))
+
+	version, err := LatestMigration(t.Context(), db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if version != "007_order_projection" {
+		t.Errorf("expected 007_order_projection, got %q", version)
+	}
+
+	version, err = LatestMigration(t.Context(), db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if version != "" {
+		t.Errorf("expected no version before migrations, got %q", version)
+	}
+}
@@ -2,14 +2,90 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/orders-service/internal/model"
 )
 
+// ErrVersionConflict is returned by Update when the order has been modified
+// since it was read.
+var ErrVersionConflict = errors.New("order version conflict")
+
 type OrderRepository interface {
 	Create(ctx context.Context, order *model.Order) error
 	GetByID(ctx context.Context, id string) (*model.Order, error)
 	GetAll(ctx context.Context) ([]model.Order, error)
+	// Update writes order only if it is still at order.Version, then sets
+	// order.Version to the new version. It returns sql.ErrNoRows if the order
+	// does not exist and ErrVersionConflict if its version has moved on.
 	Update(ctx context.Context, order *model.Order) error
 	Delete(ctx context.Context, id string) error
+	CountOrders(ctx context.Context) (int, error)
+	CountOrdersByStatus(ctx context.Context, status string) (int, error)
+}
+
+// BatchOrderRepository inserts several orders at once.
+type BatchOrderRepository interface {
+	// CreateBatch inserts all of orders or none of them, filling in their
+	// timestamps and versions.
+	CreateBatch(ctx context.Context, orders []*model.Order) error
+}
+
+// OrderStreamer reads orders one row at a time instead of materializing them
+// as a slice.
+type OrderStreamer interface {
+	// StreamSince calls fn for each order after (createdAt, afterID) in
+	// (created_at, id) order, stopping at the first error fn returns.
+	StreamSince(ctx context.Context, createdAt time.Time, afterID string, fn func(model.Order) error) error
+}
+
+// Columns FindOrders can sort by.
+const (
+	SortCreatedAt = "created_at"
+	SortQuantity  = "quantity"
+	SortStatus    = "status"
+	SortProduct   = "product"
+)
+
+// ErrUnknownSortField is returned by FindOrders for a Sort that is not one of
+// the Sort constants.
+var ErrUnknownSortField = errors.New("unknown sort field")
+
+// OrderFilter restricts FindOrders. Zero fields do not filter; CreatedFrom
+// is inclusive and CreatedTo exclusive. Sort defaults to SortCreatedAt, and
+// results are descending unless Ascending is set.
+type OrderFilter struct {
+	CustomerID  string
+	Status      string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Sort        string
+	Ascending   bool
+}
+
+// OrderFinder lists the orders matching a filter in the order it asks for.
+type OrderFinder interface {
+	FindOrders(ctx context.Context, filter OrderFilter) ([]model.Order, error)
+}
+
+// ProductSearchRepository finds orders by product name.
+type ProductSearchRepository interface {
+	// SearchByProduct returns up to limit orders whose product contains q,
+	// ignoring case, newest first. q is matched literally.
+	SearchByProduct(ctx context.Context, q string, limit int) ([]model.Order, error)
+}
+
+type StatsRepository interface {
+	Stats(ctx context.Context) (*model.OrderStats, error)
+}
+
+// AutoTransitionRepository finds orders that have sat in a status for too long
+// and moves them on. Ages are measured against updated_at using the database
+// clock.
+type AutoTransitionRepository interface {
+	ListInStatusOlderThan(ctx context.Context, status string, age time.Duration, limit int) ([]model.Order, error)
+	// TransitionStatus moves an order from one status to another, returning
+	// sql.ErrNoRows if it is no longer in the from status.
+	TransitionStatus(ctx context.Context, id, from, to string) (*model.Order, error)
 }
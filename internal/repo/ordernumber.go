@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+const DefaultOrderNumberPrefix = "ORD-"
+
+// PostgresOrderNumberGenerator issues sequential order ids such as
+// ORD-000123 from the order_number_seq sequence. nextval is atomic and never
+// hands out a number twice, even to transactions that roll back, so ids stay
+// unique across concurrent creates and instances; creates that fail leave
+// gaps in the numbering. Numbers are zero-padded to six digits and simply
+// grow longer past 999999.
+type PostgresOrderNumberGenerator struct {
+	db     *sql.DB
+	prefix string
+}
+
+func NewPostgresOrderNumberGenerator(db *sql.DB, prefix string) *PostgresOrderNumberGenerator {
+	return &PostgresOrderNumberGenerator{db: db, prefix: prefix}
+}
+
+func (g *PostgresOrderNumberGenerator) NewID(ctx context.Context) (string, error) {
+	var n int64
+	if err := g.db.QueryRowContext(ctx, `SELECT nextval('order_number_seq')`).Scan(&n); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%06d", g.prefix, n), nil
+}
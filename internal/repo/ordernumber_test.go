@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPostgresOrderNumberGenerator(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT nextval\\('order_number_seq'\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(123))
+	mock.ExpectQuery("SELECT nextval\\('order_number_seq'\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(1234567))
+	mock.ExpectQuery("SELECT nextval\\('order_number_seq'\\)").
+		WillReturnError(errors.New("connection refused"))
+
+	ids := NewPostgresOrderNumberGenerator(db, DefaultOrderNumberPrefix)
+	for _, expected := range []string{"ORD-000123", "ORD-1234567"} {
+		id, err := ids.NewID(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if id != expected {
+			t.Errorf("expected %s, got %s", expected, id)
+		}
+	}
+	if _, err := ids.NewID(context.Background()); err == nil {
+		t.Error("expected an error when the sequence cannot be read")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
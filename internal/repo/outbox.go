@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// OutboxMessage is a serialized event recorded in the same transaction as the
+// write it describes, so that it survives a failed publish or a crash.
+type OutboxMessage struct {
+	ID          int64
+	EventID     string
+	Channel     string
+	ContentType string
+	Payload     []byte
+	CreatedAt   time.Time
+}
+
+type OutboxRepository interface {
+	// Add records msg and sets its ID. Call it with the context of the
+	// transaction that performs the write.
+	Add(ctx context.Context, msg *OutboxMessage) error
+	// ListUnpublished returns up to limit unpublished messages created more
+	// than minAge ago, oldest first. Inside a transaction the rows are locked
+	// and rows locked by other relays are skipped.
+	ListUnpublished(ctx context.Context, minAge time.Duration, limit int) ([]OutboxMessage, error)
+	MarkPublished(ctx context.Context, ids ...int64) error
+	// DeletePublishedOlderThan removes messages published more than age ago.
+	DeletePublishedOlderThan(ctx context.Context, age time.Duration) (int64, error)
+}
+
+type PostgresOutboxRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresOutboxRepository(db *sql.DB) *PostgresOutboxRepository {
+	return &PostgresOutboxRepository{db: db}
+}
+
+func (r *PostgresOutboxRepository) Add(ctx context.Context, msg *OutboxMessage) error {
+	query := `INSERT INTO outbox (event_id, channel, content_type, payload) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+	return conn(ctx, r.db).QueryRowContext(ctx, query, msg.EventID, msg.Channel, msg.ContentType, msg.Payload).Scan(&msg.ID, &msg.CreatedAt)
+}
+
+func (r *PostgresOutboxRepository) ListUnpublished(ctx context.Context, minAge time.Duration, limit int) ([]OutboxMessage, error) {
+	query := `SELECT id, event_id, channel, content_type, payload, created_at FROM outbox
+		WHERE published_at IS NULL AND created_at < NOW() - make_interval(secs => $1)
+		ORDER BY id LIMIT $2`
+	if txFromContext(ctx) != nil {
+		query += ` FOR UPDATE SKIP LOCKED`
+	}
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, minAge.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []OutboxMessage
+	for rows.Next() {
+		var m OutboxMessage
+		if err := rows.Scan(&m.ID, &m.EventID, &m.Channel, &m.ContentType, &m.Payload, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+func (r *PostgresOutboxRepository) MarkPublished(ctx context.Context, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE outbox SET published_at = NOW() WHERE id = ANY($1)`, pq.Array(ids))
+	return err
+}
+
+func (r *PostgresOutboxRepository) DeletePublishedOlderThan(ctx context.Context, age time.Duration) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM outbox WHERE published_at < NOW() - make_interval(secs => $1)`, age.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPostgresOutboxAddAndMarkPublished(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("INSERT INTO outbox \\(event_id, channel, content_type, payload\\)").
+		WithArgs("event-1", "order.created", "application/json", []byte(`{"id":"order-1"}`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, now))
+	mock.ExpectExec("UPDATE outbox SET published_at = NOW\\(\\) WHERE id = ANY\\(\\$1\\)").
+		WithArgs("{7,8}").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	store := NewPostgresOutboxRepository(db)
+	msg := &OutboxMessage{EventID: "event-1", Channel: "order.created", ContentType: "application/json", Payload: []byte(`{"id":"order-1"}`)}
+	if err := store.Add(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.ID != 7 || !msg.CreatedAt.Equal(now) {
+		t.Errorf("expected id and created_at from database, got %+v", msg)
+	}
+	if err := store.MarkPublished(context.Background(), 7, 8); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.MarkPublished(context.Background()); err != nil {
+		t.Errorf("expected marking nothing to be a no-op, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresOutboxListUnpublishedLocksInTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM outbox WHERE published_at IS NULL (.+) ORDER BY id LIMIT \\$2 FOR UPDATE SKIP LOCKED").
+		WithArgs(float64(5), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_id", "channel", "content_type", "payload", "created_at"}).
+			AddRow(1, "event-1", "order.created", "application/x-protobuf", []byte{0x0a, 0x01}, now))
+	mock.ExpectCommit()
+
+	store := NewPostgresOutboxRepository(db)
+	var messages []OutboxMessage
+	err = NewTxManager(db).WithinTx(context.Background(), func(ctx context.Context) error {
+		var err error
+		messages, err = store.ListUnpublished(ctx, 5*time.Second, 10)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(messages) != 1 || messages[0].EventID != "event-1" || messages[0].ContentType != "application/x-protobuf" || len(messages[0].Payload) != 2 {
+		t.Errorf("unexpected messages %+v", messages)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
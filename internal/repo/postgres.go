@@ -3,39 +3,180 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/orders-service/internal/model"
 )
 
 type PostgresOrderRepository struct {
-	db *sql.DB
+	db           *sql.DB
+	queryTimeout time.Duration
 }
 
-func NewPostgresOrderRepository(db *sql.DB) *PostgresOrderRepository {
-	return &PostgresOrderRepository{db: db}
+// NewPostgresOrderRepository returns a repository whose calls are each
+// bounded by DefaultQueryTimeout, except Stats and StreamSince which may
+// legitimately run for longer.
+func NewPostgresOrderRepository(db *sql.DB, opts ...PostgresOption) *PostgresOrderRepository {
+	r := &PostgresOrderRepository{db: db, queryTimeout: DefaultQueryTimeout}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
-func (r *PostgresOrderRepository) Create(ctx context.Context, order *model.Order) error {
-	query := `INSERT INTO orders (id, product, quantity, status, created_at) VALUES ($1, $2, $3, $4, $5)`
-	_, err := r.db.ExecContext(ctx, query, order.ID, order.Product, order.Quantity, order.Status, order.CreatedAt)
-	return err
-}
+const orderColumns = `id, product, quantity, status, price, currency, created_at, updated_at, version, customer_id, metadata`
 
-func (r *PostgresOrderRepository) GetByID(ctx context.Context, id string) (*model.Order, error) {
-	query := `SELECT id, product, quantity, status, created_at FROM orders WHERE id = $1`
-	row := r.db.QueryRowContext(ctx, query, id)
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
+func scanOrder(row rowScanner) (model.Order, error) {
 	var order model.Order
-	err := row.Scan(&order.ID, &order.Product, &order.Quantity, &order.Status, &order.CreatedAt)
+	err := row.Scan(&order.ID, &order.Product, &order.Quantity, &order.Status, &order.Price, &order.Currency, &order.CreatedAt, &order.UpdatedAt, &order.Version, &order.CustomerID, metadataColumn{&order.Metadata})
+	return order, err
+}
+
+// metadataJSON is the JSONB value of an order's metadata. No metadata is
+// stored as an empty object.
+func metadataJSON(metadata map[string]string) string {
+	if len(metadata) == 0 {
+		return "{}"
+	}
+	b, _ := json.Marshal(metadata)
+	return string(b)
+}
+
+// metadataColumn scans the metadata column, leaving an empty object as nil.
+type metadataColumn struct {
+	metadata *map[string]string
+}
+
+func (c metadataColumn) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("metadata: unsupported type %T", src)
+	}
+	var metadata map[string]string
+	if err := json.Unmarshal(b, &metadata); err != nil {
+		return fmt.Errorf("metadata: %w", err)
+	}
+	if len(metadata) > 0 {
+		*c.metadata = metadata
+	}
+	return nil
+}
+
+// Timestamps are assigned by the database rather than the calling instance so
+// that created_at ordering stays consistent across hosts with skewed clocks.
+func (r *PostgresOrderRepository) Create(ctx context.Context, order *model.Order) (err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer func() { err = done(err) }()
+	query := `INSERT INTO orders (id, product, quantity, status, price, currency, customer_id, metadata) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING created_at, updated_at, version`
+	return conn(ctx, r.db).QueryRowContext(ctx, query, order.ID, order.Product, order.Quantity, order.Status, order.Price, order.Currency, order.CustomerID, metadataJSON(order.Metadata)).Scan(&order.CreatedAt, &order.UpdatedAt, &order.Version)
+}
+
+const insertColumns = 8
+
+// CreateBatch inserts orders with a single multi-row INSERT, so either all of
+// them are written or none are. Batches are limited by Postgres to 65535
+// parameters, i.e. about 10000 orders.
+func (r *PostgresOrderRepository) CreateBatch(ctx context.Context, orders []*model.Order) (err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer func() { err = done(err) }()
+	if len(orders) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(orders))
+	args := make([]interface{}, 0, len(orders)*insertColumns)
+	byID := make(map[string]*model.Order, len(orders))
+	for i, o := range orders {
+		placeholders := make([]string, insertColumns)
+		for j := range placeholders {
+			placeholders[j] = fmt.Sprintf("$%d", i*insertColumns+j+1)
+		}
+		values = append(values, "("+strings.Join(placeholders, ", ")+")")
+		args = append(args, o.ID, o.Product, o.Quantity, o.Status, o.Price, o.Currency, o.CustomerID, metadataJSON(o.Metadata))
+		byID[o.ID] = o
+	}
+
+	query := `INSERT INTO orders (id, product, quantity, status, price, currency, customer_id, metadata)
+		VALUES ` + strings.Join(values, ", ") + `
+		RETURNING id, created_at, updated_at, version`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// RETURNING order is not guaranteed to follow VALUES order, so match
+	// the rows back up by id.
+	for rows.Next() {
+		var id string
+		var createdAt, updatedAt time.Time
+		var version int64
+		if err := rows.Scan(&id, &createdAt, &updatedAt, &version); err != nil {
+			return err
+		}
+		if o, ok := byID[id]; ok {
+			o.CreatedAt, o.UpdatedAt, o.Version = createdAt, updatedAt, version
+		}
+	}
+	return rows.Err()
+}
+
+func (r *PostgresOrderRepository) GetByID(ctx context.Context, id string) (_ *model.Order, err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer func() { err = done(err) }()
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1`
+	order, err := scanOrder(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetByIDForUpdate reads an order with SELECT ... FOR UPDATE inside the
+// transaction carried by ctx (see TxManager.WithinTx), so the row stays locked
+// until that transaction ends. It returns ErrNoTransaction when called outside
+// one, since the lock would be released immediately.
+//
+// To avoid deadlocks, transactions that lock several orders must lock them in
+// the same order everywhere (ascending id), and should take row locks before
+// any other writes and keep the transaction short.
+func (r *PostgresOrderRepository) GetByIDForUpdate(ctx context.Context, id string) (_ *model.Order, err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer func() { err = done(err) }()
+	tx := txFromContext(ctx)
+	if tx == nil {
+		return nil, ErrNoTransaction
+	}
+
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1 FOR UPDATE`
+	order, err := scanOrder(tx.QueryRowContext(ctx, query, id))
 	if err != nil {
 		return nil, err
 	}
 	return &order, nil
 }
 
-func (r *PostgresOrderRepository) GetAll(ctx context.Context) ([]model.Order, error) {
-	query := `SELECT id, product, quantity, status, created_at FROM orders ORDER BY created_at DESC`
-	rows, err := r.db.QueryContext(ctx, query)
+func (r *PostgresOrderRepository) GetAll(ctx context.Context) (_ []model.Order, err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer func() { err = done(err) }()
+	query := `SELECT ` + orderColumns + ` FROM orders ORDER BY created_at DESC`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -43,8 +184,8 @@ func (r *PostgresOrderRepository) GetAll(ctx context.Context) ([]model.Order, er
 
 	var orders []model.Order
 	for rows.Next() {
-		var order model.Order
-		if err := rows.Scan(&order.ID, &order.Product, &order.Quantity, &order.Status, &order.CreatedAt); err != nil {
+		order, err := scanOrder(rows)
+		if err != nil {
 			return nil, err
 		}
 		orders = append(orders, order)
@@ -52,25 +193,129 @@ func (r *PostgresOrderRepository) GetAll(ctx context.Context) ([]model.Order, er
 	return orders, rows.Err()
 }
 
-func (r *PostgresOrderRepository) Update(ctx context.Context, order *model.Order) error {
-	query := `UPDATE orders SET product = $1, quantity = $2, status = $3 WHERE id = $4`
-	result, err := r.db.ExecContext(ctx, query, order.Product, order.Quantity, order.Status, order.ID)
+// orderSortColumns maps the sort fields FindOrders accepts to the column
+// expressions it interpolates; nothing else from the filter reaches the SQL
+// text. id breaks ties so pages of equal keys come back in a stable order.
+var orderSortColumns = map[string]string{
+	SortCreatedAt: "created_at",
+	SortQuantity:  "quantity",
+	SortStatus:    "status",
+	SortProduct:   "product",
+}
+
+func (r *PostgresOrderRepository) FindOrders(ctx context.Context, filter OrderFilter) (_ []model.Order, err error) {
+	sortBy := filter.Sort
+	if sortBy == "" {
+		sortBy = SortCreatedAt
+	}
+	column, ok := orderSortColumns[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSortField, filter.Sort)
+	}
+	direction := "DESC"
+	if filter.Ascending {
+		direction = "ASC"
+	}
+
+	ctx, done := r.withTimeout(ctx)
+	defer func() { err = done(err) }()
+
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.CustomerID != "" {
+		add("customer_id = $%d", filter.CustomerID)
+	}
+	if filter.Status != "" {
+		add("status = $%d", filter.Status)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		add("created_at >= $%d", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		add("created_at < $%d", filter.CreatedTo)
+	}
+
+	query := `SELECT ` + orderColumns + ` FROM orders`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	query += ` ORDER BY ` + column + ` ` + direction + `, id ` + direction
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	rows, err := result.RowsAffected()
+	defer rows.Close()
+
+	var orders []model.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+// likeEscaper escapes the LIKE wildcards and the escape character itself so a
+// search term is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchByProduct matches lower(product) so that the trigram index from
+// migration 013 is used.
+func (r *PostgresOrderRepository) SearchByProduct(ctx context.Context, q string, limit int) (_ []model.Order, err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer func() { err = done(err) }()
+
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE lower(product) ILIKE $1 ORDER BY created_at DESC LIMIT $2`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, "%"+likeEscaper.Replace(strings.ToLower(q))+"%", limit)
 	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []model.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+func (r *PostgresOrderRepository) Update(ctx context.Context, order *model.Order) (err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer func() { err = done(err) }()
+	query := `UPDATE orders SET product = $1, quantity = $2, status = $3, price = $4, currency = $5, metadata = $6, version = version + 1, updated_at = NOW()
+		WHERE id = $7 AND version = $8 RETURNING updated_at, version`
+	q := conn(ctx, r.db)
+	err = q.QueryRowContext(ctx, query, order.Product, order.Quantity, order.Status, order.Price, order.Currency, metadataJSON(order.Metadata), order.ID, order.Version).Scan(&order.UpdatedAt, &order.Version)
+	if !errors.Is(err, sql.ErrNoRows) {
 		return err
 	}
-	if rows == 0 {
-		return sql.ErrNoRows
+
+	// No row matched: either the order is gone or its version has moved on.
+	var exists bool
+	if err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1)`, order.ID).Scan(&exists); err != nil {
+		return err
 	}
-	return nil
+	if exists {
+		return ErrVersionConflict
+	}
+	return sql.ErrNoRows
 }
 
-func (r *PostgresOrderRepository) Delete(ctx context.Context, id string) error {
+func (r *PostgresOrderRepository) Delete(ctx context.Context, id string) (err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer func() { err = done(err) }()
 	query := `DELETE FROM orders WHERE id = $1`
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -83,3 +328,123 @@ func (r *PostgresOrderRepository) Delete(ctx context.Context, id string) error {
 	}
 	return nil
 }
+
+func (r *PostgresOrderRepository) CountOrders(ctx context.Context) (_ int, err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer func() { err = done(err) }()
+	var count int
+	err = conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM orders`).Scan(&count)
+	return count, err
+}
+
+func (r *PostgresOrderRepository) CountOrdersByStatus(ctx context.Context, status string) (_ int, err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer func() { err = done(err) }()
+	var count int
+	err = conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM orders WHERE status = $1`, status).Scan(&count)
+	return count, err
+}
+
+// Stats aggregates orders by status. Revenue is grouped by currency and
+// excludes cancelled orders. It is summed as NUMERIC so that it cannot fail
+// mid-query; a sum outside the int64 range is reported as
+// model.ErrTotalOverflow.
+func (r *PostgresOrderRepository) Stats(ctx context.Context) (*model.OrderStats, error) {
+	query := `SELECT status, currency, COUNT(*), COALESCE(SUM(quantity), 0), COALESCE(SUM(price::NUMERIC * quantity), 0)::TEXT
+		FROM orders GROUP BY status, currency`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := &model.OrderStats{
+		ByStatus:          make(map[string]int),
+		RevenueByCurrency: make(map[string]int64),
+		ComputedAt:        time.Now(),
+	}
+	for rows.Next() {
+		var status, currency, sum string
+		var count int
+		var quantity int64
+		if err := rows.Scan(&status, &currency, &count, &quantity, &sum); err != nil {
+			return nil, err
+		}
+		stats.ByStatus[status] += count
+		stats.Total += count
+		stats.TotalQuantity += quantity
+		if status != model.StatusCancelled {
+			revenue, err := strconv.ParseInt(sum, 10, 64)
+			if err == nil {
+				revenue, err = model.AddTotals(stats.RevenueByCurrency[currency], revenue)
+			} else if errors.Is(err, strconv.ErrRange) {
+				err = model.ErrTotalOverflow
+			}
+			if err != nil {
+				return nil, fmt.Errorf("revenue in %s: %w", currency, err)
+			}
+			stats.RevenueByCurrency[currency] = revenue
+		}
+	}
+	return stats, rows.Err()
+}
+
+func (r *PostgresOrderRepository) ListInStatusOlderThan(ctx context.Context, status string, age time.Duration, limit int) (_ []model.Order, err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer func() { err = done(err) }()
+	query := `SELECT ` + orderColumns + ` FROM orders
+		WHERE status = $1 AND updated_at < NOW() - make_interval(secs => $2)
+		ORDER BY updated_at LIMIT $3`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, status, age.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []model.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+func (r *PostgresOrderRepository) TransitionStatus(ctx context.Context, id, from, to string) (_ *model.Order, err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer func() { err = done(err) }()
+	query := `UPDATE orders SET status = $1, version = version + 1, updated_at = NOW() WHERE id = $2 AND status = $3 RETURNING ` + orderColumns
+	order, err := scanOrder(conn(ctx, r.db).QueryRowContext(ctx, query, to, id, from))
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+const nilUUID = "00000000-0000-0000-0000-000000000000"
+
+func (r *PostgresOrderRepository) StreamSince(ctx context.Context, createdAt time.Time, afterID string, fn func(model.Order) error) error {
+	if afterID == "" {
+		afterID = nilUUID
+	}
+
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE (created_at, id) > ($1, $2) ORDER BY created_at, id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, createdAt, afterID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -31,9 +31,10 @@ func BenchmarkPostgresCreate(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		b.StopTimer()
-		mock.ExpectExec("INSERT INTO orders").
-			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
-			WillReturnResult(sqlmock.NewResult(1, 1))
+		now := time.Now()
+		mock.ExpectQuery("INSERT INTO orders").
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at", "version"}).AddRow(now, now, 1))
 		b.StartTimer()
 
 		err := repo.Create(ctx, order)
@@ -57,8 +58,8 @@ func BenchmarkPostgresGetByID(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		b.StopTimer()
 		// Create new rows for each iteration - rows cannot be reused
-		rows := sqlmock.NewRows([]string{"id", "product", "quantity", "status", "created_at"}).
-			AddRow("test-id", "Test Product", 10, "pending", time.Now())
+		rows := sqlmock.NewRows([]string{"id", "product", "quantity", "status", "price", "currency", "created_at", "updated_at", "version", "customer_id", "metadata"}).
+			AddRow("test-id", "Test Product", 10, "pending", 1999, "USD", time.Now(), time.Now(), 1, "customer-1", []byte("{}"))
 		mock.ExpectQuery("SELECT (.+) FROM orders WHERE id").
 			WithArgs("test-id").
 			WillReturnRows(rows)
@@ -89,14 +90,20 @@ func BenchmarkPostgresGetAll(b *testing.B) {
 			for i := 0; i < b.N; i++ {
 				b.StopTimer()
 				// Create fresh rows for each iteration
-				rows := sqlmock.NewRows([]string{"id", "product", "quantity", "status", "created_at"})
+				rows := sqlmock.NewRows([]string{"id", "product", "quantity", "status", "price", "currency", "created_at", "updated_at", "version", "customer_id", "metadata"})
 				for j := 0; j < size; j++ {
 					rows.AddRow(
 						fmt.Sprintf("id-%d", j),
 						fmt.Sprintf("Product %d", j),
 						j+1,
 						"pending",
+						int64(1999),
+						"USD",
 						time.Now(),
+						time.Now(),
+						1,
+						"customer-1",
+						[]byte("{}"),
 					)
 				}
 				mock.ExpectQuery("SELECT (.+) FROM orders ORDER BY created_at DESC").
@@ -132,9 +139,9 @@ func BenchmarkPostgresUpdate(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		b.StopTimer()
-		mock.ExpectExec("UPDATE orders").
-			WithArgs(order.Product, order.Quantity, order.Status, order.ID).
-			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("UPDATE orders").
+			WithArgs(order.Product, order.Quantity, order.Status, order.Price, order.Currency, "{}", order.ID, order.Version).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at", "version"}).AddRow(time.Now(), order.Version))
 		b.StartTimer()
 
 		err := repo.Update(ctx, order)
@@ -194,8 +201,8 @@ func BenchmarkUpdateWithRowsAffectedCheck(b *testing.B) {
 	b.Run("WithCheck", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
 			b.StopTimer()
-			mock.ExpectExec("UPDATE orders").
-				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery("UPDATE orders").
+				WillReturnRows(sqlmock.NewRows([]string{"updated_at", "version"}).AddRow(time.Now(), order.Version))
 			b.StartTimer()
 
 			err := repo.Update(ctx, order)
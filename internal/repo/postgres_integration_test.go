@@ -0,0 +1,187 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/orders-service/internal/model"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	return db
+}
+
+func TestIntegrationDatabaseTimestamps(t *testing.T) {
+	db := openTestDB(t)
+	db.SetMaxOpenConns(1)
+	repo := NewPostgresOrderRepository(db)
+	ctx := context.Background()
+
+	skewed := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	var previous time.Time
+	for i := 0; i < 5; i++ {
+		order := &model.Order{ID: uuid.NewString(), Product: "Clock", Quantity: 1, Status: "pending", CreatedAt: skewed}
+		if err := repo.Create(ctx, order); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		t.Cleanup(func() { _ = repo.Delete(ctx, order.ID) })
+
+		if order.CreatedAt.Equal(skewed) {
+			t.Fatal("expected created_at to be assigned by the database")
+		}
+		if order.CreatedAt.Before(previous) {
+			t.Errorf("created_at went backwards: %v before %v", order.CreatedAt, previous)
+		}
+		previous = order.CreatedAt
+
+		stored, err := repo.GetByID(ctx, order.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !stored.CreatedAt.Equal(order.CreatedAt) {
+			t.Errorf("expected stored created_at %v, got %v", order.CreatedAt, stored.CreatedAt)
+		}
+
+		order.Status = "confirmed"
+		if err := repo.Update(ctx, order); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if order.UpdatedAt.Before(order.CreatedAt) {
+			t.Errorf("updated_at %v before created_at %v", order.UpdatedAt, order.CreatedAt)
+		}
+	}
+}
+
+func TestIntegrationGetByIDForUpdateSerializesWriters(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPostgresOrderRepository(db)
+	txm := NewTxManager(db)
+	ctx := context.Background()
+
+	order := &model.Order{ID: uuid.NewString(), Product: "Stock", Quantity: 10, Status: "pending"}
+	if err := repo.Create(ctx, order); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = repo.Delete(ctx, order.ID) })
+
+	decrement := func(locked chan<- struct{}, release <-chan struct{}) error {
+		return txm.WithinTx(ctx, func(ctx context.Context) error {
+			o, err := repo.GetByIDForUpdate(ctx, order.ID)
+			if err != nil {
+				return err
+			}
+			if locked != nil {
+				close(locked)
+			}
+			if release != nil {
+				<-release
+			}
+			o.Quantity--
+			return repo.Update(ctx, o)
+		})
+	}
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	first := make(chan error, 1)
+	go func() { first <- decrement(locked, release) }()
+	<-locked
+
+	second := make(chan error, 1)
+	go func() { second <- decrement(nil, nil) }()
+
+	select {
+	case err := <-second:
+		t.Fatalf("expected second transaction to block on the row lock, returned %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := <-second; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stored, err := repo.GetByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored.Quantity != 8 {
+		t.Errorf("expected quantity 8 after two serialized decrements, got %d", stored.Quantity)
+	}
+}
+
+func TestIntegrationOrderNumbersAreUniqueUnderConcurrency(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPostgresOrderRepository(db)
+	ids := NewPostgresOrderNumberGenerator(db, DefaultOrderNumberPrefix)
+	ctx := context.Background()
+
+	const workers, perWorker = 8, 25
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				id, err := ids.NewID(ctx)
+				if err != nil {
+					errs <- err
+					return
+				}
+				order := &model.Order{ID: id, Product: "Counter", Quantity: 1, Status: "pending"}
+				if err := repo.Create(ctx, order); err != nil {
+					errs <- err
+					return
+				}
+				mu.Lock()
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for id := range seen {
+		t.Cleanup(func() { _ = repo.Delete(ctx, id) })
+	}
+	for err := range errs {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(seen) != workers*perWorker {
+		t.Errorf("expected %d distinct ids, got %d", workers*perWorker, len(seen))
+	}
+	for id := range seen {
+		if !strings.HasPrefix(id, DefaultOrderNumberPrefix) || len(id) < len(DefaultOrderNumberPrefix)+6 {
+			t.Errorf("expected an id like ORD-000123, got %q", id)
+		}
+	}
+}
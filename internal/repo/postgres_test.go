@@ -0,0 +1,460 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/orders-service/internal/model"
+)
+
+func TestPostgresUpdateNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("UPDATE orders SET").
+		WithArgs("Test", 1, "confirmed", int64(0), "", "{}", "missing-id", int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at", "version"}))
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs("missing-id").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	repo := NewPostgresOrderRepository(db)
+	err = repo.Update(context.Background(), &model.Order{ID: "missing-id", Product: "Test", Quantity: 1, Status: "confirmed", Version: 1})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresUpdateVersionConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("UPDATE orders SET (.+) WHERE id = \\$7 AND version = \\$8").
+		WithArgs("Test", 1, "confirmed", int64(0), "", "{}", "test-id", int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at", "version"}))
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs("test-id").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	repo := NewPostgresOrderRepository(db)
+	err = repo.Update(context.Background(), &model.Order{ID: "test-id", Product: "Test", Quantity: 1, Status: "confirmed", Version: 2})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresUpdate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	updatedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("UPDATE orders SET (.+) version = version \\+ 1, updated_at = NOW\\(\\)").
+		WithArgs("Test", 1, "confirmed", int64(0), "", "{}", "test-id", int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at", "version"}).AddRow(updatedAt, 2))
+
+	repo := NewPostgresOrderRepository(db)
+	order := &model.Order{ID: "test-id", Product: "Test", Quantity: 1, Status: "confirmed", Version: 1}
+	if err := repo.Update(context.Background(), order); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !order.UpdatedAt.Equal(updatedAt) {
+		t.Errorf("expected updated_at from database, got %v", order.UpdatedAt)
+	}
+	if order.Version != 2 {
+		t.Errorf("expected version 2, got %d", order.Version)
+	}
+}
+
+func TestPostgresCreateBatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("INSERT INTO orders \\(id, product, quantity, status, price, currency, customer_id, metadata\\) VALUES \\(\\$1, (.+)\\), \\(\\$9, (.+), \\$16\\) RETURNING id, created_at, updated_at, version").
+		WithArgs("a", "Widget", 1, "pending", int64(100), "USD", "customer-1", "{}", "b", "Gadget", 2, "pending", int64(200), "EUR", "customer-2", `{"channel":"web"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at", "version"}).
+			AddRow("b", createdAt, createdAt, 1).
+			AddRow("a", createdAt, createdAt, 1))
+
+	orders := []*model.Order{
+		{ID: "a", CustomerID: "customer-1", Product: "Widget", Quantity: 1, Status: "pending", Price: 100, Currency: "USD"},
+		{ID: "b", CustomerID: "customer-2", Product: "Gadget", Quantity: 2, Status: "pending", Price: 200, Currency: "EUR", Metadata: map[string]string{"channel": "web"}},
+	}
+	if err := NewPostgresOrderRepository(db).CreateBatch(context.Background(), orders); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, o := range orders {
+		if !o.CreatedAt.Equal(createdAt) || o.Version != 1 {
+			t.Errorf("expected database fields on %s, got %+v", o.ID, o)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresCreateUsesDatabaseTimestamps(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("INSERT INTO orders \\(id, product, quantity, status, price, currency, customer_id, metadata\\) VALUES (.+) RETURNING created_at, updated_at, version").
+		WithArgs("test-id", "Test", 1, "pending", int64(250), "EUR", "customer-1", "{}").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at", "version"}).AddRow(createdAt, createdAt, 1))
+
+	repo := NewPostgresOrderRepository(db)
+	order := &model.Order{ID: "test-id", CustomerID: "customer-1", Product: "Test", Quantity: 1, Status: "pending", Price: 250, Currency: "EUR", CreatedAt: createdAt.Add(-time.Hour)}
+	if err := repo.Create(context.Background(), order); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !order.CreatedAt.Equal(createdAt) || !order.UpdatedAt.Equal(createdAt) {
+		t.Errorf("expected timestamps from database, got created_at %v updated_at %v", order.CreatedAt, order.UpdatedAt)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresGetByIDScansMetadata(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	columns := []string{"id", "product", "quantity", "status", "price", "currency", "created_at", "updated_at", "version", "customer_id", "metadata"}
+	mock.ExpectQuery("SELECT (.+) FROM orders WHERE id = \\$1").
+		WithArgs("a").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("a", "Widget", 1, "pending", 100, "USD", now, now, 1, "customer-1", []byte(`{"channel":"web","promo":"SPRING"}`)))
+	mock.ExpectQuery("SELECT (.+) FROM orders WHERE id = \\$1").
+		WithArgs("b").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("b", "Widget", 1, "pending", 100, "USD", now, now, 1, "customer-1", []byte("{}")))
+
+	repo := NewPostgresOrderRepository(db)
+	order, err := repo.GetByID(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(order.Metadata) != 2 || order.Metadata["channel"] != "web" || order.Metadata["promo"] != "SPRING" {
+		t.Errorf("expected metadata from the column, got %v", order.Metadata)
+	}
+	if order, err = repo.GetByID(context.Background(), "b"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.Metadata != nil {
+		t.Errorf("expected no metadata for an empty object, got %v", order.Metadata)
+	}
+}
+
+func TestPostgresDeleteNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM orders").
+		WithArgs("missing-id").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := NewPostgresOrderRepository(db)
+	if err := repo.Delete(context.Background(), "missing-id"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestPostgresDeleteRowsAffectedError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM orders").
+		WithArgs("test-id").
+		WillReturnResult(sqlmock.NewErrorResult(errors.New("driver does not support RowsAffected")))
+
+	repo := NewPostgresOrderRepository(db)
+	if err := repo.Delete(context.Background(), "test-id"); err == nil || errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected RowsAffected error to be returned, got %v", err)
+	}
+}
+
+func TestPostgresCountOrders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM orders$`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM orders WHERE status = \$1`).
+		WithArgs("pending").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	repo := NewPostgresOrderRepository(db)
+
+	total, err := repo.CountOrders(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total != 42 {
+		t.Errorf("expected 42 orders, got %d", total)
+	}
+
+	pending, err := repo.CountOrdersByStatus(context.Background(), "pending")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pending != 7 {
+		t.Errorf("expected 7 pending orders, got %d", pending)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresStreamSinceStopsOnCallbackError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "product", "quantity", "status", "price", "currency", "created_at", "updated_at", "version", "customer_id", "metadata"})
+	for _, id := range []string{"a", "b", "c"} {
+		rows.AddRow(id, "Widget", 1, "pending", 100, "USD", now, now, 1, "customer-1", []byte("{}"))
+	}
+	mock.ExpectQuery(`SELECT (.+) FROM orders WHERE \(created_at, id\) > \(\$1, \$2\) ORDER BY created_at, id`).
+		WithArgs(time.Time{}, nilUUID).
+		WillReturnRows(rows)
+
+	stop := errors.New("client went away")
+	var seen []string
+	err = NewPostgresOrderRepository(db).StreamSince(context.Background(), time.Time{}, "", func(o model.Order) error {
+		seen = append(seen, o.ID)
+		if o.ID == "b" {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("expected the callback error, got %v", err)
+	}
+	if len(seen) != 2 {
+		t.Errorf("expected iteration to stop after b, got %v", seen)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresTransitionStatusChangedConcurrently(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("UPDATE orders SET status = (.+) WHERE id = (.+) AND status = ").
+		WithArgs("cancelled", "test-id", "pending").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product", "quantity", "status", "price", "currency", "created_at", "updated_at", "version", "customer_id", "metadata"}))
+
+	repo := NewPostgresOrderRepository(db)
+	_, err = repo.TransitionStatus(context.Background(), "test-id", "pending", "cancelled")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresStatsRevenueByCurrency(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT status, currency, (.+) FROM orders GROUP BY status, currency").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "currency", "count", "quantity", "revenue"}).
+			AddRow("pending", "USD", 2, 3, 3000).
+			AddRow("confirmed", "USD", 1, 1, 500).
+			AddRow("confirmed", "EUR", 1, 2, 800).
+			AddRow("cancelled", "EUR", 1, 1, 999))
+
+	stats, err := NewPostgresOrderRepository(db).Stats(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Total != 5 || stats.ByStatus["confirmed"] != 2 || stats.TotalQuantity != 7 {
+		t.Errorf("unexpected totals %+v", stats)
+	}
+	if stats.RevenueByCurrency["USD"] != 3500 || stats.RevenueByCurrency["EUR"] != 800 {
+		t.Errorf("expected revenue USD 3500 and EUR 800 excluding cancelled, got %v", stats.RevenueByCurrency)
+	}
+}
+
+func TestPostgresStatsRevenueOverflow(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT status, currency, (.+) FROM orders GROUP BY status, currency").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "currency", "count", "quantity", "revenue"}).
+			AddRow("pending", "USD", 2, 2, "9223372036854775808"))
+
+	if _, err := NewPostgresOrderRepository(db).Stats(context.Background()); !errors.Is(err, model.ErrTotalOverflow) {
+		t.Errorf("expected ErrTotalOverflow for a sum above int64, got %v", err)
+	}
+
+	mock.ExpectQuery("SELECT status, currency, (.+) FROM orders GROUP BY status, currency").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "currency", "count", "quantity", "revenue"}).
+			AddRow("pending", "USD", 1, 1, "9223372036854775807").
+			AddRow("confirmed", "USD", 1, 1, "1"))
+
+	if _, err := NewPostgresOrderRepository(db).Stats(context.Background()); !errors.Is(err, model.ErrTotalOverflow) {
+		t.Errorf("expected ErrTotalOverflow when adding statuses overflows, got %v", err)
+	}
+}
+
+func TestPostgresQueryTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// The mock blocks until the query's context is cancelled.
+	mock.ExpectQuery("SELECT (.+) FROM orders WHERE id = \\$1").
+		WithArgs("order-1").
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT (.+) FROM orders WHERE id = \\$1").
+		WithArgs("order-1").
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	repo := NewPostgresOrderRepository(db, WithQueryTimeout(20*time.Millisecond))
+
+	start := time.Now()
+	_, err = repo.GetByID(context.Background(), "order-1")
+	if !errors.Is(err, ErrQueryTimeout) {
+		t.Errorf("expected ErrQueryTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the query to be cut off by the timeout, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err = repo.GetByID(ctx, "order-1")
+	if err == nil || errors.Is(err, ErrQueryTimeout) {
+		t.Errorf("expected the caller's cancellation not to be reported as a timeout, got %v", err)
+	}
+}
+
+func TestPostgresSearchByProductEscapesWildcards(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT (.+) FROM orders WHERE lower\(product\) ILIKE \$1 ORDER BY created_at DESC LIMIT \$2`).
+		WithArgs(`%50\%\_off\_%`, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product", "quantity", "status", "price", "currency", "created_at", "updated_at", "version", "customer_id", "metadata"}).
+			AddRow("a", "Widget 50%_OFF_X", 1, "pending", 100, "USD", now, now, 1, "customer-1", []byte("{}")))
+
+	orders, err := NewPostgresOrderRepository(db).SearchByProduct(context.Background(), "50%_OFF_", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(orders) != 1 || orders[0].ID != "a" {
+		t.Errorf("unexpected orders %+v", orders)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresFindOrdersCombinesFilters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	mock.ExpectQuery(`SELECT (.+) FROM orders WHERE customer_id = \$1 AND status = \$2 AND created_at >= \$3 AND created_at < \$4 ORDER BY created_at DESC, id DESC`).
+		WithArgs("customer-1", "shipped", from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product", "quantity", "status", "price", "currency", "created_at", "updated_at", "version", "customer_id", "metadata"}).
+			AddRow("a", "Widget", 1, "shipped", 100, "USD", from, from, 2, "customer-1", []byte("{}")))
+
+	orders, err := NewPostgresOrderRepository(db).FindOrders(context.Background(), OrderFilter{CustomerID: "customer-1", Status: "shipped", CreatedFrom: from, CreatedTo: to})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(orders) != 1 || orders[0].ID != "a" {
+		t.Errorf("unexpected orders %+v", orders)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresFindOrdersSort(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	repo := NewPostgresOrderRepository(db)
+
+	mock.ExpectQuery(`SELECT (.+) FROM orders ORDER BY quantity ASC, id ASC`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product", "quantity", "status", "price", "currency", "created_at", "updated_at", "version", "customer_id", "metadata"}))
+	if _, err := repo.FindOrders(context.Background(), OrderFilter{Sort: SortQuantity, Ascending: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = repo.FindOrders(context.Background(), OrderFilter{Sort: "price; DROP TABLE orders"})
+	if !errors.Is(err, ErrUnknownSortField) {
+		t.Errorf("expected ErrUnknownSortField, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/orders-service/internal/model"
+)
+
+// ProjectionRepository maintains order_projection, the denormalized read
+// model of orders. It can always be rebuilt from the orders table.
+type ProjectionRepository interface {
+	Upsert(ctx context.Context, orders []model.Order) error
+	Delete(ctx context.Context, id string) error
+	Truncate(ctx context.Context) error
+}
+
+type PostgresProjectionRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresProjectionRepository(db *sql.DB) *PostgresProjectionRepository {
+	return &PostgresProjectionRepository{db: db}
+}
+
+const projectionColumns = 9
+
+func (r *PostgresProjectionRepository) Upsert(ctx context.Context, orders []model.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(orders))
+	args := make([]interface{}, 0, len(orders)*projectionColumns)
+	for i, o := range orders {
+		placeholders := make([]string, projectionColumns)
+		for j := range placeholders {
+			placeholders[j] = fmt.Sprintf("$%d", i*projectionColumns+j+1)
+		}
+		total, err := o.Total()
+		if err != nil {
+			return fmt.Errorf("order %s: %w", o.ID, err)
+		}
+		values = append(values, "("+strings.Join(placeholders, ", ")+")")
+		args = append(args, o.ID, o.Product, o.Quantity, o.Status, o.Price, o.Currency, total, o.CreatedAt, o.UpdatedAt)
+	}
+
+	query := `INSERT INTO order_projection (id, product, quantity, status, price, currency, total, created_at, updated_at)
+		VALUES ` + strings.Join(values, ", ") + `
+		ON CONFLICT (id) DO UPDATE SET
+			product = EXCLUDED.product,
+			quantity = EXCLUDED.quantity,
+			status = EXCLUDED.status,
+			price = EXCLUDED.price,
+			currency = EXCLUDED.currency,
+			total = EXCLUDED.total,
+			updated_at = EXCLUDED.updated_at`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *PostgresProjectionRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM order_projection WHERE id = $1`, id)
+	return err
+}
+
+func (r *PostgresProjectionRepository) Truncate(ctx context.Context) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `TRUNCATE order_projection`)
+	return err
+}
@@ -0,0 +1,361 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/orders-service/internal/model"
+)
+
+// sqliteNow is the current time in the format SQLite timestamps are stored
+// in, with millisecond precision. It matches the column defaults in
+// migrations/sqlite.
+const sqliteNow = `strftime('%Y-%m-%d %H:%M:%f', 'now')`
+
+const sqliteTimeLayout = "2006-01-02 15:04:05.000"
+
+// sqliteTime formats t like sqliteNow, so that comparing the stored text
+// compares the times.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+func rebindSQLite(query string) string {
+	return Rebind(DriverSQLite, query)
+}
+
+// ErrSQLiteUnavailable is returned by OpenSQLite in binaries built without
+// cgo, such as the Docker image.
+var ErrSQLiteUnavailable = errors.New("SQLite is not available: this binary was built with CGO_ENABLED=0")
+
+// OpenSQLite opens the SQLite database file at path, creating it if needed,
+// with foreign keys enforced. SQLite allows one writer at a time, so the pool
+// is limited to a single connection and callers queue in database/sql rather
+// than failing with "database is locked". The driver needs cgo; without it
+// OpenSQLite returns ErrSQLiteUnavailable.
+func OpenSQLite(path string) (*sql.DB, error) {
+	if !SQLiteAvailable {
+		return nil, ErrSQLiteUnavailable
+	}
+	db, err := sql.Open(DriverSQLite, "file:"+path+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// SQLiteOrderRepository stores orders in SQLite for local development and
+// tests. It offers the same queries as PostgresOrderRepository but without
+// row locks, query timeouts or the trigram index; the outbox, change feed
+// and other Postgres-only stores have no SQLite counterpart.
+type SQLiteOrderRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteOrderRepository(db *sql.DB) *SQLiteOrderRepository {
+	return &SQLiteOrderRepository{db: db}
+}
+
+func (r *SQLiteOrderRepository) query(ctx context.Context, query string, args ...interface{}) ([]model.Order, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebindSQLite(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []model.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+func (r *SQLiteOrderRepository) Create(ctx context.Context, order *model.Order) error {
+	query := `INSERT INTO orders (id, product, quantity, status, price, currency, customer_id, metadata) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING created_at, updated_at, version`
+	return conn(ctx, r.db).QueryRowContext(ctx, rebindSQLite(query), order.ID, order.Product, order.Quantity, order.Status, order.Price, order.Currency, order.CustomerID, metadataJSON(order.Metadata)).Scan(&order.CreatedAt, &order.UpdatedAt, &order.Version)
+}
+
+// CreateBatch inserts orders one by one inside a single transaction, so
+// either all of them are written or none are.
+func (r *SQLiteOrderRepository) CreateBatch(ctx context.Context, orders []*model.Order) error {
+	return NewTxManager(r.db).WithinTx(ctx, func(ctx context.Context) error {
+		for _, o := range orders {
+			if err := r.Create(ctx, o); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteOrderRepository) GetByID(ctx context.Context, id string) (*model.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1`
+	order, err := scanOrder(conn(ctx, r.db).QueryRowContext(ctx, rebindSQLite(query), id))
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *SQLiteOrderRepository) GetAll(ctx context.Context) ([]model.Order, error) {
+	return r.query(ctx, `SELECT `+orderColumns+` FROM orders ORDER BY created_at DESC`)
+}
+
+func (r *SQLiteOrderRepository) FindOrders(ctx context.Context, filter OrderFilter) ([]model.Order, error) {
+	sortBy := filter.Sort
+	if sortBy == "" {
+		sortBy = SortCreatedAt
+	}
+	column, ok := orderSortColumns[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSortField, filter.Sort)
+	}
+	direction := "DESC"
+	if filter.Ascending {
+		direction = "ASC"
+	}
+
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.CustomerID != "" {
+		add("customer_id = $%d", filter.CustomerID)
+	}
+	if filter.Status != "" {
+		add("status = $%d", filter.Status)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		add("created_at >= $%d", sqliteTime(filter.CreatedFrom))
+	}
+	if !filter.CreatedTo.IsZero() {
+		add("created_at < $%d", sqliteTime(filter.CreatedTo))
+	}
+
+	query := `SELECT ` + orderColumns + ` FROM orders`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	query += ` ORDER BY ` + column + ` ` + direction + `, id ` + direction
+	return r.query(ctx, query, args...)
+}
+
+func (r *SQLiteOrderRepository) SearchByProduct(ctx context.Context, q string, limit int) ([]model.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE lower(product) LIKE $1 ESCAPE '\' ORDER BY created_at DESC LIMIT $2`
+	return r.query(ctx, query, "%"+likeEscaper.Replace(strings.ToLower(q))+"%", limit)
+}
+
+func (r *SQLiteOrderRepository) Update(ctx context.Context, order *model.Order) error {
+	query := `UPDATE orders SET product = $1, quantity = $2, status = $3, price = $4, currency = $5, metadata = $6, version = version + 1, updated_at = ` + sqliteNow + `
+		WHERE id = $7 AND version = $8 RETURNING updated_at, version`
+	q := conn(ctx, r.db)
+	err := q.QueryRowContext(ctx, rebindSQLite(query), order.Product, order.Quantity, order.Status, order.Price, order.Currency, metadataJSON(order.Metadata), order.ID, order.Version).Scan(&order.UpdatedAt, &order.Version)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	var exists bool
+	if err := q.QueryRowContext(ctx, rebindSQLite(`SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1)`), order.ID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionConflict
+	}
+	return sql.ErrNoRows
+}
+
+func (r *SQLiteOrderRepository) Delete(ctx context.Context, id string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, rebindSQLite(`DELETE FROM orders WHERE id = $1`), id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SQLiteOrderRepository) CountOrders(ctx context.Context) (int, error) {
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM orders`).Scan(&count)
+	return count, err
+}
+
+func (r *SQLiteOrderRepository) CountOrdersByStatus(ctx context.Context, status string) (int, error) {
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(ctx, rebindSQLite(`SELECT COUNT(*) FROM orders WHERE status = $1`), status).Scan(&count)
+	return count, err
+}
+
+// Stats aggregates orders like PostgresOrderRepository.Stats. SQLite sums
+// 64-bit integers and fails on overflow, which is reported as
+// model.ErrTotalOverflow.
+func (r *SQLiteOrderRepository) Stats(ctx context.Context) (*model.OrderStats, error) {
+	query := `SELECT status, currency, COUNT(*), COALESCE(SUM(quantity), 0),
+		COALESCE(SUM(CASE WHEN status = $1 THEN 0 ELSE price * quantity END), 0)
+		FROM orders GROUP BY status, currency`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebindSQLite(query), model.StatusCancelled)
+	if err != nil {
+		return nil, sqliteOverflow(err)
+	}
+	defer rows.Close()
+
+	stats := &model.OrderStats{
+		ByStatus:          make(map[string]int),
+		RevenueByCurrency: make(map[string]int64),
+		ComputedAt:        time.Now(),
+	}
+	for rows.Next() {
+		var status, currency string
+		var count int
+		var quantity, revenue int64
+		if err := rows.Scan(&status, &currency, &count, &quantity, &revenue); err != nil {
+			return nil, err
+		}
+		stats.ByStatus[status] += count
+		stats.Total += count
+		stats.TotalQuantity += quantity
+		if status != model.StatusCancelled {
+			total, err := model.AddTotals(stats.RevenueByCurrency[currency], revenue)
+			if err != nil {
+				return nil, fmt.Errorf("revenue in %s: %w", currency, err)
+			}
+			stats.RevenueByCurrency[currency] = total
+		}
+	}
+	return stats, sqliteOverflow(rows.Err())
+}
+
+func sqliteOverflow(err error) error {
+	if err != nil && strings.Contains(err.Error(), "integer overflow") {
+		return fmt.Errorf("%w: %v", model.ErrTotalOverflow, err)
+	}
+	return err
+}
+
+func (r *SQLiteOrderRepository) ListInStatusOlderThan(ctx context.Context, status string, age time.Duration, limit int) ([]model.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders
+		WHERE status = $1 AND updated_at < strftime('%Y-%m-%d %H:%M:%f', 'now', $2)
+		ORDER BY updated_at LIMIT $3`
+	return r.query(ctx, query, status, fmt.Sprintf("%+.3f seconds", -age.Seconds()), limit)
+}
+
+func (r *SQLiteOrderRepository) TransitionStatus(ctx context.Context, id, from, to string) (*model.Order, error) {
+	query := `UPDATE orders SET status = $1, version = version + 1, updated_at = ` + sqliteNow + ` WHERE id = $2 AND status = $3 RETURNING ` + orderColumns
+	order, err := scanOrder(conn(ctx, r.db).QueryRowContext(ctx, rebindSQLite(query), to, id, from))
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *SQLiteOrderRepository) StreamSince(ctx context.Context, createdAt time.Time, afterID string, fn func(model.Order) error) error {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE (created_at, id) > ($1, $2) ORDER BY created_at, id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebindSQLite(query), sqliteTime(createdAt), afterID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// SQLiteTagRepository is the SQLite TagRepository. SQLite runs one write
+// transaction at a time, so AddTag needs no row lock to keep to the limit.
+type SQLiteTagRepository struct {
+	db *sql.DB
+	tx *TxManager
+}
+
+func NewSQLiteTagRepository(db *sql.DB) *SQLiteTagRepository {
+	return &SQLiteTagRepository{db: db, tx: NewTxManager(db)}
+}
+
+func (r *SQLiteTagRepository) AddTag(ctx context.Context, orderID, tag string, limit int) error {
+	return r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+
+		var id string
+		if err := q.QueryRowContext(ctx, rebindSQLite(`SELECT id FROM orders WHERE id = $1`), orderID).Scan(&id); err != nil {
+			return err
+		}
+
+		var count int
+		var exists bool
+		err := q.QueryRowContext(ctx, rebindSQLite(`SELECT COUNT(*), COALESCE(MAX(tag = $2), 0) FROM order_tags WHERE order_id = $1`), orderID, tag).
+			Scan(&count, &exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return nil
+		}
+		if count >= limit {
+			return ErrTagLimit
+		}
+
+		_, err = q.ExecContext(ctx, rebindSQLite(`INSERT INTO order_tags (order_id, tag) VALUES ($1, $2)`), orderID, tag)
+		return err
+	})
+}
+
+func (r *SQLiteTagRepository) RemoveTag(ctx context.Context, orderID, tag string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, rebindSQLite(`DELETE FROM order_tags WHERE order_id = $1 AND tag = $2`), orderID, tag)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SQLiteTagRepository) ListTags(ctx context.Context, orderID string) ([]string, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebindSQLite(`SELECT tag FROM order_tags WHERE order_id = $1 ORDER BY tag`), orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
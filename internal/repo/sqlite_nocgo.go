@@ -0,0 +1,7 @@
+//go:build !cgo
+
+package repo
+
+// SQLiteAvailable reports whether this binary can open SQLite databases. The
+// driver is written in C, so it needs a build with cgo enabled.
+const SQLiteAvailable = false
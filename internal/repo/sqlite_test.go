@@ -0,0 +1,219 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/model"
+)
+
+func newTestSQLiteDB(t *testing.T) *sql.DB {
+	t.Helper()
+	if !SQLiteAvailable {
+		t.Skip("SQLite needs cgo")
+	}
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "orders.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := RunMigrations(db, "../../migrations/sqlite"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return db
+}
+
+func TestParseDatabaseURL(t *testing.T) {
+	if driver, dsn := ParseDatabaseURL("sqlite://data/orders.db"); driver != DriverSQLite || dsn != "data/orders.db" {
+		t.Errorf("expected SQLite at data/orders.db, got %s %q", driver, dsn)
+	}
+	url := "postgres://orders@localhost/orders?sslmode=disable"
+	if driver, dsn := ParseDatabaseURL(url); driver != DriverPostgres || dsn != url {
+		t.Errorf("expected Postgres at %q, got %s %q", url, driver, dsn)
+	}
+}
+
+func TestOpenSQLiteWithoutCgo(t *testing.T) {
+	if SQLiteAvailable {
+		t.Skip("built with cgo")
+	}
+	if _, err := OpenSQLite(filepath.Join(t.TempDir(), "orders.db")); !errors.Is(err, ErrSQLiteUnavailable) {
+		t.Errorf("expected ErrSQLiteUnavailable, got %v", err)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	query := `UPDATE orders SET status = $1 WHERE id = $2 AND status = $1 LIMIT $10`
+	if got := Rebind(DriverSQLite, query); got != `UPDATE orders SET status = ?1 WHERE id = ?2 AND status = ?1 LIMIT ?10` {
+		t.Errorf("unexpected SQLite query %q", got)
+	}
+	if got := Rebind(DriverPostgres, query); got != query {
+		t.Errorf("expected Postgres query unchanged, got %q", got)
+	}
+}
+
+func TestSQLiteOrderRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	r := NewSQLiteOrderRepository(newTestSQLiteDB(t))
+
+	order := &model.Order{ID: "order-1", Product: "widget", Quantity: 2, Status: model.StatusPending, Price: 500, Currency: "USD", CustomerID: "customer-1", Metadata: map[string]string{"channel": "web"}}
+	if err := r.Create(ctx, order); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.CreatedAt.IsZero() || order.Version != 1 {
+		t.Errorf("expected timestamps and version to be set, got %+v", order)
+	}
+
+	got, err := r.GetByID(ctx, "order-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Product != "widget" || got.CustomerID != "customer-1" || got.Metadata["channel"] != "web" || !got.CreatedAt.Equal(order.CreatedAt) {
+		t.Errorf("unexpected order %+v", got)
+	}
+
+	got.Status = model.StatusConfirmed
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Version != 2 {
+		t.Errorf("expected version 2, got %d", got.Version)
+	}
+	if err := r.Update(ctx, order); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict for stale version, got %v", err)
+	}
+	if err := r.Update(ctx, &model.Order{ID: "missing"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for missing order, got %v", err)
+	}
+
+	if n, err := r.CountOrdersByStatus(ctx, model.StatusConfirmed); err != nil || n != 1 {
+		t.Errorf("expected one confirmed order, got %d, %v", n, err)
+	}
+	if err := r.Delete(ctx, "order-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.GetByID(ctx, "order-1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows after delete, got %v", err)
+	}
+	if err := r.Delete(ctx, "order-1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for second delete, got %v", err)
+	}
+}
+
+func TestSQLiteOrderRepositoryQueries(t *testing.T) {
+	ctx := context.Background()
+	r := NewSQLiteOrderRepository(newTestSQLiteDB(t))
+
+	orders := []*model.Order{
+		{ID: "order-1", Product: "Blue_Widget", Quantity: 1, Status: model.StatusPending, Price: 100, Currency: "USD", CustomerID: "customer-1"},
+		{ID: "order-2", Product: "Gadget", Quantity: 3, Status: model.StatusCancelled, Price: 100, Currency: "USD", CustomerID: "customer-2"},
+		{ID: "order-3", Product: "BlueXWidget", Quantity: 2, Status: model.StatusPending, Price: 250, Currency: "EUR", CustomerID: "customer-1"},
+	}
+	if err := r.CreateBatch(ctx, orders); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.CreateBatch(ctx, []*model.Order{{ID: "order-4", Product: "x", Quantity: 1}, {ID: "order-1", Product: "x", Quantity: 1}}); err == nil {
+		t.Error("expected a batch with a duplicate id to fail")
+	}
+	if n, _ := r.CountOrders(ctx); n != 3 {
+		t.Errorf("expected the failed batch to be rolled back, got %d orders", n)
+	}
+
+	found, err := r.FindOrders(ctx, OrderFilter{CustomerID: "customer-1", Sort: SortQuantity, Ascending: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(found) != 2 || found[0].ID != "order-1" || found[1].ID != "order-3" {
+		t.Errorf("unexpected orders %+v", found)
+	}
+	found, _ = r.FindOrders(ctx, OrderFilter{CreatedTo: orders[0].CreatedAt.Add(-time.Hour)})
+	if len(found) != 0 {
+		t.Errorf("expected no orders created before the batch, got %d", len(found))
+	}
+
+	matches, err := r.SearchByProduct(ctx, "blue_", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(matches) != 1 || matches[0].ID != "order-1" {
+		t.Errorf("expected _ to be matched literally, got %+v", matches)
+	}
+
+	stats, err := r.Stats(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Total != 3 || stats.ByStatus[model.StatusPending] != 2 || stats.RevenueByCurrency["USD"] != 100 || stats.RevenueByCurrency["EUR"] != 500 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	var streamed []string
+	err = r.StreamSince(ctx, time.Time{}, "", func(o model.Order) error {
+		streamed = append(streamed, o.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(streamed) != 3 {
+		t.Errorf("expected every order to be streamed, got %v", streamed)
+	}
+
+	stale, err := r.ListInStatusOlderThan(ctx, model.StatusPending, -time.Hour, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stale) != 2 {
+		t.Errorf("expected both pending orders, got %d", len(stale))
+	}
+	moved, err := r.TransitionStatus(ctx, "order-1", model.StatusPending, model.StatusConfirmed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if moved.Status != model.StatusConfirmed || moved.Version != 2 {
+		t.Errorf("unexpected order after transition %+v", moved)
+	}
+	if _, err := r.TransitionStatus(ctx, "order-1", model.StatusPending, model.StatusConfirmed); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows once the order has moved on, got %v", err)
+	}
+}
+
+func TestSQLiteTagRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLiteDB(t)
+	orders := NewSQLiteOrderRepository(db)
+	tags := NewSQLiteTagRepository(db)
+
+	if err := orders.Create(ctx, &model.Order{ID: "order-1", Product: "widget", Quantity: 1, Status: model.StatusPending}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tag := range []string{"vip", "gift", "vip"} {
+		if err := tags.AddTag(ctx, "order-1", tag, 2); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := tags.AddTag(ctx, "order-1", "rush", 2); !errors.Is(err, ErrTagLimit) {
+		t.Errorf("expected ErrTagLimit, got %v", err)
+	}
+	if err := tags.AddTag(ctx, "missing", "vip", 2); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for missing order, got %v", err)
+	}
+	if got, _ := tags.ListTags(ctx, "order-1"); len(got) != 2 || got[0] != "gift" || got[1] != "vip" {
+		t.Errorf("unexpected tags %v", got)
+	}
+	if err := tags.RemoveTag(ctx, "order-1", "rush"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for absent tag, got %v", err)
+	}
+
+	if err := orders.Delete(ctx, "order-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var left int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM order_tags`).Scan(&left); err != nil || left != 0 {
+		t.Errorf("expected tags to be deleted with their order, got %d, %v", left, err)
+	}
+}
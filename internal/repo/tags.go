@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var ErrTagLimit = errors.New("order tag limit reached")
+
+// TagRepository stores tags on orders.
+type TagRepository interface {
+	// AddTag tags an order unless it already has limit tags, in which case it
+	// returns ErrTagLimit. Adding a tag the order already has is a no-op. It
+	// returns sql.ErrNoRows if the order does not exist.
+	AddTag(ctx context.Context, orderID, tag string, limit int) error
+	// RemoveTag returns sql.ErrNoRows if the order does not have the tag.
+	RemoveTag(ctx context.Context, orderID, tag string) error
+	ListTags(ctx context.Context, orderID string) ([]string, error)
+}
+
+type PostgresTagRepository struct {
+	db *sql.DB
+	tx *TxManager
+}
+
+func NewPostgresTagRepository(db *sql.DB) *PostgresTagRepository {
+	return &PostgresTagRepository{db: db, tx: NewTxManager(db)}
+}
+
+// AddTag locks the order row while counting its tags so concurrent adds
+// cannot overshoot the limit.
+func (r *PostgresTagRepository) AddTag(ctx context.Context, orderID, tag string, limit int) error {
+	return r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+
+		var id string
+		if err := q.QueryRowContext(ctx, `SELECT id FROM orders WHERE id = $1 FOR UPDATE`, orderID).Scan(&id); err != nil {
+			return err
+		}
+
+		var count int
+		var exists bool
+		err := q.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(BOOL_OR(tag = $2), false) FROM order_tags WHERE order_id = $1`, orderID, tag).
+			Scan(&count, &exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return nil
+		}
+		if count >= limit {
+			return ErrTagLimit
+		}
+
+		_, err = q.ExecContext(ctx, `INSERT INTO order_tags (order_id, tag) VALUES ($1, $2)`, orderID, tag)
+		return err
+	})
+}
+
+func (r *PostgresTagRepository) RemoveTag(ctx context.Context, orderID, tag string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM order_tags WHERE order_id = $1 AND tag = $2`, orderID, tag)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PostgresTagRepository) ListTags(ctx context.Context, orderID string) ([]string, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT tag FROM order_tags WHERE order_id = $1 ORDER BY tag`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
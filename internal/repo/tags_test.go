@@ -0,0 +1,61 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPostgresAddTagLocksOrderAndEnforcesLimit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM orders WHERE id = \\$1 FOR UPDATE").
+		WithArgs("order-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("order-1"))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\), COALESCE\\(BOOL_OR\\(tag = \\$2\\), false\\) FROM order_tags").
+		WithArgs("order-1", "urgent").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "exists"}).AddRow(2, false))
+	mock.ExpectRollback()
+
+	err = NewPostgresTagRepository(db).AddTag(context.Background(), "order-1", "urgent", 2)
+	if !errors.Is(err, ErrTagLimit) {
+		t.Errorf("expected ErrTagLimit, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresAddTagInserts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM orders").
+		WithArgs("order-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("order-1"))
+	mock.ExpectQuery("FROM order_tags").
+		WithArgs("order-1", "urgent").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "exists"}).AddRow(1, false))
+	mock.ExpectExec("INSERT INTO order_tags").
+		WithArgs("order-1", "urgent").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := NewPostgresTagRepository(db).AddTag(context.Background(), "order-1", "urgent", 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
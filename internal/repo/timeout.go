@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultQueryTimeout bounds each PostgresOrderRepository call so that a
+// stalled database fails requests instead of holding pool connections.
+const DefaultQueryTimeout = 3 * time.Second
+
+// ErrQueryTimeout is returned when a repository call runs past its query
+// timeout. It wraps the driver's error.
+var ErrQueryTimeout = errors.New("database query timed out")
+
+type PostgresOption func(*PostgresOrderRepository)
+
+// WithQueryTimeout overrides DefaultQueryTimeout. Zero or less disables it.
+func WithQueryTimeout(timeout time.Duration) PostgresOption {
+	return func(r *PostgresOrderRepository) {
+		r.queryTimeout = timeout
+	}
+}
+
+// withTimeout derives the context for one repository call. done releases it
+// and turns an error caused by the timeout into ErrQueryTimeout; errors from
+// the caller's own cancellation or deadline pass through unchanged.
+func (r *PostgresOrderRepository) withTimeout(ctx context.Context) (context.Context, func(error) error) {
+	if r.queryTimeout <= 0 {
+		return ctx, func(err error) error { return err }
+	}
+	queryCtx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	return queryCtx, func(err error) error {
+		cancel()
+		if err != nil && ctx.Err() == nil && errors.Is(queryCtx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w after %s: %w", ErrQueryTimeout, r.queryTimeout, err)
+		}
+		return err
+	}
+}
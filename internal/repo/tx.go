@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var ErrNoTransaction = errors.New("operation requires a transaction")
+
+type txKey struct{}
+
+// querier is the subset of *sql.DB and *sql.Tx used by the repositories.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Transactor runs fn in a transaction that repository calls made with the
+// context passed to fn join.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// TxManager runs functions inside a database transaction. Repository calls
+// made with the context passed to fn join that transaction.
+type TxManager struct {
+	db *sql.DB
+}
+
+func NewTxManager(db *sql.DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+// WithinTx runs fn in a transaction, committing if it returns nil and rolling
+// back otherwise. Nested calls reuse the outer transaction.
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Rollback after a successful Commit only reports sql.ErrTxDone, and
+	// after a failure fn's error is the one to return.
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func txFromContext(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(txKey{}).(*sql.Tx)
+	return tx
+}
+
+// conn returns the transaction carried by ctx, or db when there is none.
+func conn(ctx context.Context, db *sql.DB) querier {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestWithinTxCommitsRepositoryCalls(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM orders WHERE id = \\$1 FOR UPDATE").
+		WithArgs("test-id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product", "quantity", "status", "price", "currency", "created_at", "updated_at", "version", "customer_id", "metadata"}).
+			AddRow("test-id", "Test", 5, "pending", 1999, "USD", now, now, 3, "customer-1", []byte("{}")))
+	mock.ExpectQuery("UPDATE orders SET").
+		WithArgs("Test", 4, "pending", int64(1999), "USD", "{}", "test-id", int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at", "version"}).AddRow(now, 4))
+	mock.ExpectCommit()
+
+	repo := NewPostgresOrderRepository(db)
+	err = NewTxManager(db).WithinTx(context.Background(), func(ctx context.Context) error {
+		order, err := repo.GetByIDForUpdate(ctx, "test-id")
+		if err != nil {
+			return err
+		}
+		order.Quantity--
+		return repo.Update(ctx, order)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWithinTxRollsBackOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	failure := errors.New("insufficient stock")
+	err = NewTxManager(db).WithinTx(context.Background(), func(ctx context.Context) error {
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("expected %v, got %v", failure, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetByIDForUpdateRequiresTransaction(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = NewPostgresOrderRepository(db).GetByIDForUpdate(context.Background(), "test-id")
+	if !errors.Is(err, ErrNoTransaction) {
+		t.Errorf("expected ErrNoTransaction, got %v", err)
+	}
+}
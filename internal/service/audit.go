@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+	"go.uber.org/zap"
+)
+
+const OrderStatusChangedEvent = "order.status_changed"
+
+const (
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 200
+)
+
+var auditEventTypes = map[string]bool{
+	OrderCreatedChannel:     true,
+	OrderUpdatedChannel:     true,
+	OrderDeletedChannel:     true,
+	OrderStatusChangedEvent: true,
+}
+
+type HistoryQuery struct {
+	EventType string
+	From      time.Time
+	To        time.Time
+	Cursor    string
+	Limit     int
+}
+
+type HistoryPage struct {
+	Items      []model.AuditEntry `json:"items"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+func WithAuditLog(audit repo.AuditRepository) Option {
+	return func(s *OrderService) {
+		s.audit = audit
+	}
+}
+
+func (q *HistoryQuery) Validate() error {
+	if q.EventType != "" && !auditEventTypes[q.EventType] {
+		return &ValidationError{Field: "event_type", Message: "must be one of: order.created, order.updated, order.deleted, order.status_changed"}
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return &ValidationError{Field: "to", Message: "must be after from"}
+	}
+	if q.Limit < 0 || q.Limit > MaxHistoryLimit {
+		return &ValidationError{Field: "limit", Message: "must be between 1 and 200"}
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultHistoryLimit
+	}
+	return nil
+}
+
+func (s *OrderService) GetOrderHistory(ctx context.Context, id string, q HistoryQuery) (*HistoryPage, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	if s.audit == nil {
+		return &HistoryPage{Items: []model.AuditEntry{}}, nil
+	}
+	release, err := s.acquireRead(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Warn("shedding history request", zap.Error(err))
+		return nil, err
+	}
+	defer release()
+
+	entries, next, err := s.audit.History(ctx, id, repo.HistoryFilter{
+		EventType: q.EventType,
+		From:      q.From,
+		To:        q.To,
+		Cursor:    q.Cursor,
+		Limit:     q.Limit,
+	})
+	if err != nil {
+		if errors.Is(err, repo.ErrInvalidCursor) {
+			return nil, &ValidationError{Field: "cursor", Message: "is invalid"}
+		}
+		logger.FromContext(ctx).Error("postgres: failed to get order history", zap.String("order_id", id), zap.Error(err))
+		return nil, err
+	}
+	return &HistoryPage{Items: entries, NextCursor: next}, nil
+}
+
+func (s *OrderService) recordAudit(ctx context.Context, eventType string, order *model.Order) {
+	if s.audit == nil {
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	payload, err := json.Marshal(order)
+	if err != nil {
+		log.Error("failed to encode audit payload", zap.String("order_id", order.ID), zap.Error(err))
+		return
+	}
+	if err := s.audit.Record(ctx, &model.AuditEntry{OrderID: order.ID, EventType: eventType, Payload: payload}); err != nil {
+		log.Error("postgres: failed to record audit entry", zap.String("order_id", order.ID), zap.String("event_type", eventType), zap.Error(err))
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+	"go.uber.org/zap"
+)
+
+const autoTransitionBatchSize = 100
+
+// AutoTransition moves orders that have stayed in From for longer than After
+// to To, e.g. cancelling orders left pending for a day.
+type AutoTransition struct {
+	From  string
+	To    string
+	After time.Duration
+}
+
+type autoTransitioner struct {
+	source repo.AutoTransitionRepository
+	rules  []AutoTransition
+}
+
+// ValidateAutoTransitions checks that every rule is a legal status
+// transition and that no status has more than one rule.
+func ValidateAutoTransitions(rules []AutoTransition) error {
+	seen := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		if !CanTransition(r.From, r.To) {
+			return fmt.Errorf("auto-transition %s -> %s is not a legal status transition", r.From, r.To)
+		}
+		if r.After <= 0 {
+			return fmt.Errorf("auto-transition %s -> %s: delay must be positive", r.From, r.To)
+		}
+		if seen[r.From] {
+			return fmt.Errorf("auto-transition from %s is configured more than once", r.From)
+		}
+		seen[r.From] = true
+	}
+	return nil
+}
+
+// ParseAutoTransitions parses a comma-separated list of from:to:after rules,
+// e.g. "pending:cancelled:24h,confirmed:shipped:72h".
+func ParseAutoTransitions(spec string) ([]AutoTransition, error) {
+	var rules []AutoTransition
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid auto-transition %q: expected from:to:after", item)
+		}
+		after, err := time.ParseDuration(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid auto-transition %q: %w", item, err)
+		}
+		rules = append(rules, AutoTransition{From: parts[0], To: parts[1], After: after})
+	}
+	if err := ValidateAutoTransitions(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// WithAutoTransitions enables the auto-transition sweeper. Rules are expected
+// to have passed ValidateAutoTransitions.
+func WithAutoTransitions(source repo.AutoTransitionRepository, rules []AutoTransition) Option {
+	return func(s *OrderService) {
+		s.transitions = &autoTransitioner{source: source, rules: rules}
+	}
+}
+
+// RunAutoTransitions applies the configured auto-transitions every interval
+// until ctx is cancelled.
+func (s *OrderService) RunAutoTransitions(ctx context.Context, interval time.Duration) {
+	if s.transitions == nil || len(s.transitions.rules) == 0 {
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.SweepAutoTransitions(ctx); err != nil && ctx.Err() == nil {
+				log.Error("failed to apply auto-transitions", zap.Error(err))
+			}
+		}
+	}
+}
+
+// SweepAutoTransitions applies every rule once and returns the number of
+// orders transitioned. Each transition is audited and published as an
+// order.status_changed event.
+func (s *OrderService) SweepAutoTransitions(ctx context.Context) (int, error) {
+	if s.transitions == nil {
+		return 0, nil
+	}
+	log := logger.FromContext(ctx)
+
+	transitioned := 0
+	for _, rule := range s.transitions.rules {
+		for {
+			orders, err := s.transitions.source.ListInStatusOlderThan(ctx, rule.From, rule.After, autoTransitionBatchSize)
+			if err != nil {
+				return transitioned, err
+			}
+
+			for _, o := range orders {
+				change := &model.StatusChange{From: rule.From, To: rule.To}
+				changed := &pendingEvent{channel: OrderStatusChangedEvent, orderID: o.ID, message: change}
+				err := s.commit(ctx, func(ctx context.Context) error {
+					var err error
+					change.Order, err = s.transitions.source.TransitionStatus(ctx, o.ID, rule.From, rule.To)
+					return err
+				}, changed)
+				order := change.Order
+				if errors.Is(err, sql.ErrNoRows) {
+					// Changed or deleted since it was listed.
+					continue
+				}
+				if err != nil {
+					return transitioned, err
+				}
+
+				s.evictCachedOrder(ctx, order.ID)
+				s.recordAudit(ctx, OrderStatusChangedEvent, order)
+				s.updateProjection(ctx, OrderStatusChangedEvent, order)
+				s.publishPending(ctx, changed)
+				log.Info("order auto-transitioned", zap.String("order_id", order.ID), zap.String("from", rule.From), zap.String("to", rule.To))
+				transitioned++
+			}
+
+			if len(orders) < autoTransitionBatchSize {
+				break
+			}
+		}
+	}
+	return transitioned, nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/model"
+)
+
+type fakeTransitionRepo struct {
+	mu     sync.Mutex
+	now    time.Time
+	orders map[string]*model.Order
+}
+
+func (f *fakeTransitionRepo) ListInStatusOlderThan(ctx context.Context, status string, age time.Duration, limit int) ([]model.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []model.Order
+	for _, o := range f.orders {
+		if o.Status == status && o.UpdatedAt.Before(f.now.Add(-age)) && len(result) < limit {
+			result = append(result, *o)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeTransitionRepo) TransitionStatus(ctx context.Context, id, from, to string) (*model.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o, ok := f.orders[id]
+	if !ok || o.Status != from {
+		return nil, sql.ErrNoRows
+	}
+	o.Status = to
+	o.UpdatedAt = f.now
+	updated := *o
+	return &updated, nil
+}
+
+func TestAutoTransitionPendingToCancelled(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	source := &fakeTransitionRepo{
+		now: created,
+		orders: map[string]*model.Order{
+			"order-1": {ID: "order-1", Status: model.StatusPending, UpdatedAt: created},
+			"order-2": {ID: "order-2", Status: model.StatusConfirmed, UpdatedAt: created},
+		},
+	}
+	pub := &mockPublisher{}
+	svc := NewOrderService(newMockRepo(), pub, WithAutoTransitions(source, []AutoTransition{
+		{From: model.StatusPending, To: model.StatusCancelled, After: time.Hour},
+	}))
+	ctx := context.Background()
+
+	source.now = created.Add(59 * time.Minute)
+	n, err := svc.SweepAutoTransitions(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 0 || source.orders["order-1"].Status != model.StatusPending {
+		t.Fatalf("expected no transition before the window elapsed, got %d", n)
+	}
+
+	source.now = created.Add(61 * time.Minute)
+	n, err = svc.SweepAutoTransitions(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 transition, got %d", n)
+	}
+	if source.orders["order-1"].Status != model.StatusCancelled {
+		t.Errorf("expected order-1 to be cancelled, got %s", source.orders["order-1"].Status)
+	}
+	if source.orders["order-2"].Status != model.StatusConfirmed {
+		t.Errorf("expected order-2 to be untouched, got %s", source.orders["order-2"].Status)
+	}
+	if len(pub.published) != 1 {
+		t.Fatalf("expected 1 published event, got %d", len(pub.published))
+	}
+	if change, ok := pub.published[0].(*model.StatusChange); !ok || change.From != model.StatusPending || change.To != model.StatusCancelled {
+		t.Errorf("expected pending -> cancelled status change, got %+v", pub.published[0])
+	}
+}
+
+func TestParseAutoTransitions(t *testing.T) {
+	rules, err := ParseAutoTransitions("pending:cancelled:24h, confirmed:shipped:72h")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 2 || rules[1] != (AutoTransition{From: "confirmed", To: "shipped", After: 72 * time.Hour}) {
+		t.Errorf("unexpected rules %+v", rules)
+	}
+
+	for _, spec := range []string{
+		"pending:shipped:1h",
+		"cancelled:pending:1h",
+		"pending:cancelled:0s",
+		"pending:cancelled",
+		"pending:cancelled:1h,pending:confirmed:2h",
+	} {
+		if _, err := ParseAutoTransitions(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+	"go.uber.org/zap"
+)
+
+const MaxBatchSize = 500
+
+var ErrBatchUnavailable = errors.New("batch order creation is not configured")
+
+// BatchItemError is a validation failure of the order at Index in a batch.
+type BatchItemError struct {
+	Index   int    `json:"index"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// BatchValidationError rejects a whole batch, listing every invalid order.
+type BatchValidationError struct {
+	Errors []BatchItemError
+}
+
+func (e *BatchValidationError) Error() string {
+	return fmt.Sprintf("%d of the orders in the batch are invalid", len(e.Errors))
+}
+
+// WithBatchCreate enables CreateOrders, inserting batches through store.
+func WithBatchCreate(store repo.BatchOrderRepository) Option {
+	return func(s *OrderService) {
+		s.batch = store
+	}
+}
+
+// CreateOrders creates up to MaxBatchSize orders atomically: if any request
+// is invalid or denied by policy the whole batch is rejected with a
+// *BatchValidationError, and
+// otherwise all orders are inserted together. One order.created event is
+// published per order. Idempotency keys are not supported for batches.
+func (s *OrderService) CreateOrders(ctx context.Context, reqs []CreateOrderRequest) ([]*OrderResult, error) {
+	if s.batch == nil {
+		return nil, ErrBatchUnavailable
+	}
+	log := logger.FromContext(ctx)
+
+	if len(reqs) == 0 {
+		return nil, &ValidationError{Field: "orders", Message: "must not be empty"}
+	}
+	if len(reqs) > MaxBatchSize {
+		return nil, &ValidationError{Field: "orders", Message: fmt.Sprintf("must contain at most %d orders", MaxBatchSize)}
+	}
+
+	var invalid []BatchItemError
+	for i := range reqs {
+		if err := reqs[i].Validate(); err != nil {
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				return nil, err
+			}
+			invalid = append(invalid, BatchItemError{Index: i, Field: validationErr.Field, Message: validationErr.Message})
+		}
+	}
+	if len(invalid) > 0 {
+		log.Warn("invalid batch create request", zap.Int("orders", len(reqs)), zap.Int("invalid", len(invalid)))
+		return nil, &BatchValidationError{Errors: invalid}
+	}
+
+	orders := make([]*model.Order, len(reqs))
+	results := make([]*OrderResult, len(reqs))
+	created := make([]*pendingEvent, len(reqs))
+	var denied []BatchItemError
+	for i, req := range reqs {
+		if err := s.checkProductLimit(ctx, req.Product); err != nil {
+			return nil, err
+		}
+		order, err := s.newOrder(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkPolicy(ctx, order); err != nil {
+			var deniedErr *PolicyDeniedError
+			if !errors.As(err, &deniedErr) {
+				return nil, err
+			}
+			denied = append(denied, BatchItemError{Index: i, Field: "policy", Message: deniedErr.Error()})
+		}
+		orders[i] = order
+		created[i] = orderEvent(OrderCreatedChannel, order)
+		results[i] = &OrderResult{Order: order, Warnings: s.runSoftChecks(ctx, order)}
+	}
+	if len(denied) > 0 {
+		return nil, &BatchValidationError{Errors: denied}
+	}
+
+	if err := s.commit(ctx, func(ctx context.Context) error { return s.batch.CreateBatch(ctx, orders) }, created...); err != nil {
+		log.Error("postgres: failed to create orders", zap.Int("orders", len(orders)), zap.Error(err))
+		return nil, err
+	}
+
+	for i, result := range results {
+		s.recordAudit(ctx, OrderCreatedChannel, result.Order)
+		s.updateProjection(ctx, OrderCreatedChannel, result.Order)
+		result.StreamPosition = s.publishPending(ctx, created[i])
+	}
+
+	log.Info("orders created in batch", zap.Int("orders", len(orders)))
+	return results, nil
+}
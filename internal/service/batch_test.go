@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/orders-service/internal/repo"
+)
+
+func TestCreateOrdersInsertsBatch(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	pub := &mockPublisher{}
+	svc := NewOrderService(store, pub, WithBatchCreate(store))
+	ctx := context.Background()
+
+	results, err := svc.CreateOrders(ctx, []CreateOrderRequest{
+		{CustomerID: "customer-1", Product: "Widget", Quantity: 1},
+		{CustomerID: "customer-1", Product: "Gadget", Quantity: 2, Price: 500, Currency: "eur"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 || results[0].ID == "" || results[0].ID == results[1].ID {
+		t.Fatalf("expected 2 orders with distinct ids, got %+v", results)
+	}
+	if results[1].Currency != "EUR" || results[0].Currency != DefaultCurrency || results[0].Version != 1 {
+		t.Errorf("unexpected orders %+v, %+v", results[0].Order, results[1].Order)
+	}
+	for _, result := range results {
+		if _, err := store.GetByID(ctx, result.ID); err != nil {
+			t.Errorf("expected order %s to be stored, got %v", result.ID, err)
+		}
+	}
+	if len(pub.channels) != 2 || pub.channels[0] != OrderCreatedChannel || pub.channels[1] != OrderCreatedChannel {
+		t.Errorf("expected one order.created per order, got %v", pub.channels)
+	}
+}
+
+func TestCreateOrdersRejectsWholeBatch(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	pub := &mockPublisher{}
+	svc := NewOrderService(store, pub, WithBatchCreate(store))
+	ctx := context.Background()
+
+	_, err := svc.CreateOrders(ctx, []CreateOrderRequest{
+		{CustomerID: "customer-1", Product: "Widget", Quantity: 1},
+		{CustomerID: "customer-1", Product: " ", Quantity: 1},
+		{CustomerID: "customer-1", Product: "Gadget", Quantity: 1},
+		{CustomerID: "customer-1", Product: "Gizmo", Quantity: 0},
+	})
+	var batchErr *BatchValidationError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected BatchValidationError, got %v", err)
+	}
+	if len(batchErr.Errors) != 2 ||
+		batchErr.Errors[0] != (BatchItemError{Index: 1, Field: "product", Message: "must not be empty"}) ||
+		batchErr.Errors[1].Index != 3 || batchErr.Errors[1].Field != "quantity" {
+		t.Errorf("unexpected item errors %+v", batchErr.Errors)
+	}
+	if n, _ := store.CountOrders(ctx); n != 0 {
+		t.Errorf("expected no orders to be created, got %d", n)
+	}
+	if len(pub.published) != 0 {
+		t.Errorf("expected no events, got %d", len(pub.published))
+	}
+
+	var validationErr *ValidationError
+	if _, err := svc.CreateOrders(ctx, nil); !errors.As(err, &validationErr) {
+		t.Errorf("expected ValidationError for an empty batch, got %v", err)
+	}
+	if _, err := svc.CreateOrders(ctx, make([]CreateOrderRequest, MaxBatchSize+1)); !errors.As(err, &validationErr) {
+		t.Errorf("expected ValidationError for an oversized batch, got %v", err)
+	}
+}
+
+func TestCreateOrdersUnavailable(t *testing.T) {
+	svc := NewOrderService(newMockRepo(), nil)
+	if _, err := svc.CreateOrders(context.Background(), []CreateOrderRequest{{CustomerID: "customer-1", Product: "Widget", Quantity: 1}}); !errors.Is(err, ErrBatchUnavailable) {
+		t.Errorf("expected ErrBatchUnavailable, got %v", err)
+	}
+}
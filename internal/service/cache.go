@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/metrics"
+	"github.com/orders-service/internal/model"
+	"go.uber.org/zap"
+)
+
+// OrderCache holds orders by ID for GetOrder.
+type OrderCache interface {
+	Get(ctx context.Context, id string) (*model.Order, bool, error)
+	Set(ctx context.Context, order *model.Order) error
+	Delete(ctx context.Context, id string) error
+}
+
+// WithOrderCache serves GetOrder from cache, filling it from the repository
+// on a miss. Writes evict the order once committed. Cache failures fall back
+// to the repository. Reads made to update or delete an order always go to
+// the repository so version checks see the latest row.
+func WithOrderCache(cache OrderCache) Option {
+	return func(s *OrderService) {
+		s.cache = cache
+	}
+}
+
+func (s *OrderService) getCachedOrder(ctx context.Context, id string) (*model.Order, error) {
+	log := logger.FromContext(ctx)
+
+	order, ok, err := s.cache.Get(ctx, id)
+	if err != nil {
+		log.Warn("redis: failed to read cached order", zap.String("order_id", id), zap.Error(err))
+	}
+	if ok {
+		metrics.OrderCacheLookups.WithLabelValues(metrics.CacheHit).Inc()
+		return order, nil
+	}
+	metrics.OrderCacheLookups.WithLabelValues(metrics.CacheMiss).Inc()
+
+	order, err = s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, orderNotFound(err)
+	}
+	if err := s.cache.Set(ctx, order); err != nil {
+		log.Warn("redis: failed to cache order", zap.String("order_id", id), zap.Error(err))
+	}
+	return order, nil
+}
+
+func (s *OrderService) evictCachedOrder(ctx context.Context, id string) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.Delete(ctx, id); err != nil {
+		logger.FromContext(ctx).Error("redis: failed to evict cached order", zap.String("order_id", id), zap.Error(err))
+	}
+}
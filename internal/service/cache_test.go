@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/orders-service/internal/model"
+)
+
+type mapCache struct {
+	mu     sync.Mutex
+	orders map[string]model.Order
+}
+
+func (c *mapCache) Get(ctx context.Context, id string) (*model.Order, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	order, ok := c.orders[id]
+	return &order, ok, nil
+}
+
+func (c *mapCache) Set(ctx context.Context, order *model.Order) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.orders[order.ID] = *order
+	return nil
+}
+
+func (c *mapCache) Delete(ctx context.Context, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.orders, id)
+	return nil
+}
+
+type countingRepo struct {
+	*mockRepo
+	gets int
+}
+
+func (r *countingRepo) GetByID(ctx context.Context, id string) (*model.Order, error) {
+	r.gets++
+	return r.mockRepo.GetByID(ctx, id)
+}
+
+func TestGetOrderCacheHitSkipsRepository(t *testing.T) {
+	repo := &countingRepo{mockRepo: newMockRepo()}
+	svc := NewOrderService(repo, nil, WithOrderCache(&mapCache{orders: map[string]model.Order{}}))
+	ctx := context.Background()
+
+	created, err := svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Widget", Quantity: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		order, err := svc.GetOrder(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if order.ID != created.ID || order.Product != "Widget" {
+			t.Errorf("unexpected order %+v", order)
+		}
+	}
+	if repo.gets != 1 {
+		t.Errorf("expected 1 repository read, got %d", repo.gets)
+	}
+}
+
+func TestWritesEvictCachedOrder(t *testing.T) {
+	cache := &mapCache{orders: map[string]model.Order{}}
+	svc := NewOrderService(newMockRepo(), nil, WithOrderCache(cache))
+	ctx := context.Background()
+
+	created, err := svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Widget", Quantity: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.GetOrder(ctx, created.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := svc.UpdateOrder(ctx, created.ID, UpdateOrderRequest{Product: "Gadget", Quantity: 2, Status: model.StatusPending}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	order, err := svc.GetOrder(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.Product != "Gadget" || order.Quantity != 2 {
+		t.Errorf("expected the updated order after an update, got %+v", order)
+	}
+
+	if err := svc.UpdateOrderStatus(ctx, created.ID, model.StatusConfirmed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order, _ := svc.GetOrder(ctx, created.ID); order.Status != model.StatusConfirmed {
+		t.Errorf("expected status confirmed after a status update, got %q", order.Status)
+	}
+
+	if err := svc.DeleteOrder(ctx, created.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.GetOrder(ctx, created.ID); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("expected ErrOrderNotFound after a delete, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultChangeFeedLimit = 100
+	MaxChangeFeedLimit     = 1000
+)
+
+var ErrChangeFeedUnavailable = errors.New("change feed is not configured")
+
+// ChangePage is a batch of the change feed. Next is the sequence number to
+// resume from; it equals the requested one when there were no changes.
+type ChangePage struct {
+	Changes []model.OrderChange `json:"changes"`
+	Next    int64               `json:"next"`
+}
+
+// WithChangeFeed records every event in feed in the same transaction as the
+// write it describes, so the feed holds exactly the committed changes.
+func WithChangeFeed(tx repo.Transactor, feed repo.ChangeFeedRepository) Option {
+	return func(s *OrderService) {
+		s.tx = tx
+		s.changes = feed
+	}
+}
+
+// Changes returns up to limit changes after sequence number from.
+func (s *OrderService) Changes(ctx context.Context, from int64, limit int) (*ChangePage, error) {
+	if s.changes == nil {
+		return nil, ErrChangeFeedUnavailable
+	}
+	if from < 0 {
+		return nil, &ValidationError{Field: "from", Message: "must not be negative"}
+	}
+	if limit < 0 || limit > MaxChangeFeedLimit {
+		return nil, &ValidationError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", MaxChangeFeedLimit)}
+	}
+	if limit == 0 {
+		limit = DefaultChangeFeedLimit
+	}
+
+	changes, err := s.changes.Since(ctx, from, limit)
+	if err != nil {
+		logger.FromContext(ctx).Error("postgres: failed to read change feed", zap.Int64("from", from), zap.Error(err))
+		return nil, err
+	}
+	page := &ChangePage{Changes: changes, Next: from}
+	if len(changes) > 0 {
+		page.Next = changes[len(changes)-1].Seq
+	}
+	return page, nil
+}
+
+// TailChanges calls fn with every batch of changes after from, polling for new
+// ones every poll interval once caught up, until ctx is done or fn fails.
+func (s *OrderService) TailChanges(ctx context.Context, from int64, poll time.Duration, fn func(*ChangePage) error) error {
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		page, err := s.Changes(ctx, from, MaxChangeFeedLimit)
+		if err != nil {
+			return err
+		}
+		if len(page.Changes) > 0 {
+			if err := fn(page); err != nil {
+				return err
+			}
+			from = page.Next
+			if len(page.Changes) == MaxChangeFeedLimit {
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *OrderService) appendChange(ctx context.Context, ev *pendingEvent) error {
+	log := logger.FromContext(ctx)
+
+	payload, err := json.Marshal(ev.message)
+	if err != nil {
+		log.Error("failed to encode change payload", zap.String("order_id", ev.orderID), zap.Error(err))
+		return err
+	}
+	if err := s.changes.Append(ctx, &model.OrderChange{OrderID: ev.orderID, EventType: ev.channel, Payload: payload}); err != nil {
+		log.Error("postgres: failed to append to change feed", zap.String("order_id", ev.orderID), zap.String("event_type", ev.channel), zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+)
+
+type memChangeFeed struct {
+	mu      sync.Mutex
+	changes []model.OrderChange
+}
+
+func (f *memChangeFeed) Append(ctx context.Context, change *model.OrderChange) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	change.Seq = int64(len(f.changes) + 1)
+	change.CreatedAt = time.Now()
+	f.changes = append(f.changes, *change)
+	return nil
+}
+
+func (f *memChangeFeed) Since(ctx context.Context, seq int64, limit int) ([]model.OrderChange, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	changes := []model.OrderChange{}
+	for _, c := range f.changes {
+		if c.Seq > seq && len(changes) < limit {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+func (f *memChangeFeed) ForOrder(ctx context.Context, orderID string, seq int64, limit int) ([]model.OrderChange, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	changes := []model.OrderChange{}
+	for _, c := range f.changes {
+		if c.OrderID == orderID && c.Seq > seq && len(changes) < limit {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+func TestChangesAfterSequence(t *testing.T) {
+	feed := &memChangeFeed{}
+	svc := NewOrderService(repo.NewInMemoryOrderRepository(), &mockPublisher{}, WithChangeFeed(noTx{}, feed))
+	ctx := context.Background()
+
+	order, err := svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Widget", Quantity: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.UpdateOrder(ctx, order.ID, UpdateOrderRequest{Product: "Widget", Quantity: 2, Status: model.StatusConfirmed}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.DeleteOrder(ctx, order.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	page, err := svc.Changes(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{OrderCreatedChannel, OrderUpdatedChannel, OrderStatusChangedEvent, OrderDeletedChannel}
+	if len(page.Changes) != len(want) || page.Next != int64(len(want)) {
+		t.Fatalf("expected %d changes up to seq %d, got %+v", len(want), len(want), page)
+	}
+	for i, c := range page.Changes {
+		if c.Seq != int64(i+1) || c.EventType != want[i] || c.OrderID != order.ID {
+			t.Errorf("change %d: unexpected %+v", i, c)
+		}
+	}
+
+	page, err = svc.Changes(ctx, 2, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Changes) != 1 || page.Changes[0].Seq != 3 || page.Next != 3 {
+		t.Errorf("expected only seq 3, got %+v", page)
+	}
+
+	page, _ = svc.Changes(ctx, 4, 0)
+	if len(page.Changes) != 0 || page.Next != 4 {
+		t.Errorf("expected an empty page resuming from 4, got %+v", page)
+	}
+
+	var validationErr *ValidationError
+	if _, err := svc.Changes(ctx, -1, 0); !errors.As(err, &validationErr) || validationErr.Field != "from" {
+		t.Errorf("expected from validation error, got %v", err)
+	}
+}
+
+func TestTailChangesPicksUpNewChanges(t *testing.T) {
+	feed := &memChangeFeed{}
+	svc := NewOrderService(repo.NewInMemoryOrderRepository(), &mockPublisher{}, WithChangeFeed(noTx{}, feed))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Widget", Quantity: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	received := make(chan model.OrderChange, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- svc.TailChanges(ctx, 0, 5*time.Millisecond, func(page *ChangePage) error {
+			for _, c := range page.Changes {
+				received <- c
+			}
+			return nil
+		})
+	}()
+
+	if c := <-received; c.Seq != 1 {
+		t.Fatalf("expected the existing change first, got %+v", c)
+	}
+	if _, err := svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Gadget", Quantity: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case c := <-received:
+		if c.Seq != 2 {
+			t.Errorf("expected seq 2, got %+v", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the tail to pick up the new change")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestChangesUnavailable(t *testing.T) {
+	svc := NewOrderService(newMockRepo(), &mockPublisher{})
+	if _, err := svc.Changes(context.Background(), 0, 0); !errors.Is(err, ErrChangeFeedUnavailable) {
+		t.Errorf("expected ErrChangeFeedUnavailable, got %v", err)
+	}
+}
+
+func TestOrderEvents(t *testing.T) {
+	feed := &memChangeFeed{}
+	svc := NewOrderService(repo.NewInMemoryOrderRepository(), &mockPublisher{}, WithChangeFeed(noTx{}, feed))
+	ctx := context.Background()
+
+	order, err := svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Widget", Quantity: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, err := svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-2", Product: "Gadget", Quantity: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.UpdateOrder(ctx, order.ID, UpdateOrderRequest{Product: "Widget", Quantity: 2, Status: order.Status}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.UpdateOrderStatus(ctx, order.ID, "confirmed"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.DeleteOrder(ctx, order.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	page, err := svc.OrderEvents(ctx, order.ID, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []model.OrderEvent{
+		{Event: OrderCreatedChannel, Status: "pending", Version: 1},
+		{Event: OrderUpdatedChannel, Status: "pending", Version: 2},
+		{Event: OrderStatusChangedEvent, Status: "confirmed", From: "pending", Version: 3},
+		{Event: OrderDeletedChannel, Status: "confirmed", Version: 3},
+	}
+	if len(page.Events) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), page.Events)
+	}
+	for i, e := range page.Events {
+		if e.Event != expected[i].Event || e.Status != expected[i].Status || e.From != expected[i].From || e.Version != expected[i].Version {
+			t.Errorf("expected event %d to be %+v, got %+v", i, expected[i], e)
+		}
+		if e.OccurredAt.IsZero() {
+			t.Errorf("expected event %d to have a timestamp", i)
+		}
+	}
+	if last := page.Events[len(page.Events)-1]; page.Next != last.Seq {
+		t.Errorf("expected next %d, got %d", last.Seq, page.Next)
+	}
+
+	page, err = svc.OrderEvents(ctx, order.ID, page.Events[1].Seq, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Events) != 1 || page.Events[0].Event != OrderStatusChangedEvent {
+		t.Errorf("expected the status change after the update, got %+v", page.Events)
+	}
+
+	page, err = svc.OrderEvents(ctx, other.ID, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Events) != 1 || page.Events[0].Event != OrderCreatedChannel {
+		t.Errorf("expected only the other order's creation, got %+v", page.Events)
+	}
+
+	if _, err := svc.OrderEvents(ctx, "missing", 0, 0); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("expected ErrOrderNotFound, got %v", err)
+	}
+	var validationErr *ValidationError
+	if _, err := svc.OrderEvents(ctx, order.ID, -1, 0); !errors.As(err, &validationErr) || validationErr.Field != "from" {
+		t.Errorf("expected a validation error for from, got %v", err)
+	}
+	if _, err := NewOrderService(newMockRepo(), &mockPublisher{}).OrderEvents(ctx, order.ID, 0, 0); !errors.Is(err, ErrChangeFeedUnavailable) {
+		t.Errorf("expected ErrChangeFeedUnavailable, got %v", err)
+	}
+}
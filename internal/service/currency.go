@@ -0,0 +1,51 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+const DefaultCurrency = "USD"
+
+// knownCurrencies is the set of ISO 4217 codes orders may be priced in.
+var knownCurrencies = map[string]bool{
+	"AED": true, "ARS": true, "AUD": true, "BRL": true, "CAD": true, "CHF": true,
+	"CLP": true, "CNY": true, "COP": true, "CZK": true, "DKK": true, "EGP": true,
+	"EUR": true, "GBP": true, "HKD": true, "HUF": true, "IDR": true, "ILS": true,
+	"INR": true, "JPY": true, "KRW": true, "MXN": true, "MYR": true, "NGN": true,
+	"NOK": true, "NZD": true, "PHP": true, "PLN": true, "RON": true, "SAR": true,
+	"SEK": true, "SGD": true, "THB": true, "TRY": true, "TWD": true, "UAH": true,
+	"USD": true, "VND": true, "ZAR": true,
+}
+
+// NormalizeCurrency upper-cases code and checks it is a known ISO 4217 code.
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !knownCurrencies[code] {
+		return "", fmt.Errorf("unknown currency %q", code)
+	}
+	return code, nil
+}
+
+// WithDefaultCurrency sets the currency assigned to orders created without
+// one. The code is expected to have passed NormalizeCurrency.
+func WithDefaultCurrency(code string) Option {
+	return func(s *OrderService) {
+		s.defaultCurrency = code
+	}
+}
+
+func validatePrice(price int64, currency *string) error {
+	if price < 0 {
+		return &ValidationError{Field: "price", Message: "must not be negative"}
+	}
+	if *currency == "" {
+		return nil
+	}
+	code, err := NormalizeCurrency(*currency)
+	if err != nil {
+		return &ValidationError{Field: "currency", Message: "must be a supported ISO 4217 code"}
+	}
+	*currency = code
+	return nil
+}
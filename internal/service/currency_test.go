@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestCreateOrderCurrency(t *testing.T) {
+	svc := NewOrderService(newMockRepo(), nil, WithDefaultCurrency("EUR"))
+	ctx := context.Background()
+
+	result, err := svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Book", Quantity: 1, Price: 1250})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Currency != "EUR" || result.Price != 1250 {
+		t.Errorf("expected 1250 EUR, got %d %s", result.Price, result.Currency)
+	}
+
+	result, err = svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Book", Quantity: 1, Price: 1250, Currency: "gbp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Currency != "GBP" {
+		t.Errorf("expected currency to be normalized to GBP, got %s", result.Currency)
+	}
+
+	for _, req := range []CreateOrderRequest{
+		{CustomerID: "customer-1", Product: "Book", Quantity: 1, Currency: "XYZ"},
+		{CustomerID: "customer-1", Product: "Book", Quantity: 1, Currency: "dollars"},
+		{CustomerID: "customer-1", Product: "Book", Quantity: 1, Price: -1},
+	} {
+		_, err := svc.CreateOrder(ctx, req)
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("expected validation error for %+v, got %v", req, err)
+		}
+	}
+}
+
+func TestUpdateOrderKeepsCurrencyWhenOmitted(t *testing.T) {
+	svc := NewOrderService(newMockRepo(), nil)
+	ctx := context.Background()
+
+	created, err := svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Book", Quantity: 1, Price: 500, Currency: "JPY"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	updated, err := svc.UpdateOrder(ctx, created.ID, UpdateOrderRequest{Product: "Book", Quantity: 2, Status: "pending"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Price != 500 || updated.Currency != "JPY" {
+		t.Errorf("expected 500 JPY to be kept, got %d %s", updated.Price, updated.Currency)
+	}
+
+	_, err = svc.UpdateOrder(ctx, created.ID, UpdateOrderRequest{Product: "Book", Quantity: 2, Status: "pending", Currency: "ABC"})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("expected validation error, got %v", err)
+	}
+}
+
+func TestOrderTotalOverflowRejected(t *testing.T) {
+	svc := NewOrderService(newMockRepo(), nil)
+	ctx := context.Background()
+
+	// math.MaxInt64 / 2 = 4611686018427387903
+	if _, err := svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Yacht", Quantity: 2, Price: 4611686018427387903}); err != nil {
+		t.Fatalf("expected a total just below the int64 limit to be accepted, got %v", err)
+	}
+
+	_, err := svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Yacht", Quantity: 2, Price: 4611686018427387904})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Field != "quantity" {
+		t.Fatalf("expected quantity validation error for an overflowing total, got %v", err)
+	}
+
+	created, err := svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Yacht", Quantity: 1, Price: 4611686018427387904})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The kept price overflows together with the new quantity.
+	_, err = svc.UpdateOrder(ctx, created.ID, UpdateOrderRequest{Product: "Yacht", Quantity: 2, Status: "pending"})
+	if !errors.As(err, &validationErr) || validationErr.Field != "quantity" {
+		t.Errorf("expected quantity validation error on update, got %v", err)
+	}
+}
+
+func TestOrderResultJSON(t *testing.T) {
+	svc := NewOrderService(newMockRepo(), nil, WithSoftChecks(LargeQuantityCheck(10)))
+
+	result, err := svc.CreateOrder(context.Background(), CreateOrderRequest{CustomerID: "customer-1", Product: "Book", Quantity: 20, Price: 150})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var body struct {
+		ID       string    `json:"id"`
+		Total    int64     `json:"total"`
+		Warnings []Warning `json:"warnings"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body.ID != result.ID || body.Total != 3000 {
+		t.Errorf("expected order %s with total 3000, got %s", result.ID, data)
+	}
+	if len(body.Warnings) != 1 {
+		t.Errorf("expected the warning to be kept, got %s", data)
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import "errors"
+
+// Error codes tell clients what kind of failure occurred. The transports map
+// each one to an HTTP status and a gRPC code.
+const (
+	CodeValidation         = "validation_failed"
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodeFailedPrecondition = "failed_precondition"
+	CodePolicyDenied       = "policy_denied"
+	CodeRateLimited        = "rate_limited"
+	CodeUnavailable        = "unavailable"
+	CodeInternal           = "internal"
+)
+
+// Error is a failure reported to clients as a machine-readable Code and a
+// Message that is safe to show them. Err, if set, is the underlying cause; it
+// is logged but never sent to clients.
+type Error struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func NewError(code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// InternalError hides err from clients behind a generic message.
+func InternalError(err error) *Error {
+	return &Error{Code: CodeInternal, Message: "internal error", Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns the code of the first *Error in err's chain,
+// CodeValidation for validation errors and CodeInternal for anything else.
+func ErrorCode(err error) string {
+	var e *Error
+	var validationErr *ValidationError
+	var batchErr *BatchValidationError
+	switch {
+	case errors.As(err, &e):
+		return e.Code
+	case errors.As(err, &validationErr), errors.As(err, &batchErr):
+		return CodeValidation
+	default:
+		return CodeInternal
+	}
+}
+
+// ErrorMessage returns the message to show clients for err: the text of the
+// service's own error types, which carry details such as a policy denial
+// reason, the Message of an *Error, or a generic message for anything else.
+func ErrorMessage(err error) string {
+	var validationErr *ValidationError
+	var batchErr *BatchValidationError
+	var policyErr *PolicyDeniedError
+	var rateErr *RateLimitError
+	var tagErr *TagLimitError
+	var transitionErr *TransitionError
+	var partialErr *EventNotPublishedError
+	var e *Error
+	switch {
+	case errors.As(err, &validationErr):
+		return validationErr.Error()
+	case errors.As(err, &batchErr):
+		return batchErr.Error()
+	case errors.As(err, &policyErr):
+		return policyErr.Error()
+	case errors.As(err, &rateErr):
+		return rateErr.Error()
+	case errors.As(err, &tagErr):
+		return tagErr.Error()
+	case errors.As(err, &transitionErr):
+		return transitionErr.Error()
+	case errors.As(err, &partialErr):
+		return partialErr.Error()
+	case errors.As(err, &e):
+		return e.Message
+	default:
+		return "internal error"
+	}
+}
+
+// EventNotPublishedError is returned by CreateOrder, along with the order,
+// when ctx was cancelled after the order was saved but before its
+// order.created event was published. The order exists. With the outbox
+// enabled the relay publishes the event later; otherwise it is never
+// published. Err is ctx.Err().
+type EventNotPublishedError struct {
+	OrderID string
+	Err     error
+}
+
+func (e *EventNotPublishedError) Error() string {
+	return "order " + e.OrderID + " was created but its event was not published: " + e.Err.Error()
+}
+
+func (e *EventNotPublishedError) Unwrap() error {
+	return e.Err
+}
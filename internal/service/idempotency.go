@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+var ErrIdempotencyKeyInProgress = NewError(CodeConflict, "a request with this idempotency key is already in progress")
+
+const (
+	defaultIdempotencyWait         = 5 * time.Second
+	defaultIdempotencyPollInterval = 50 * time.Millisecond
+)
+
+type IdempotencyStore interface {
+	// Reserve claims key for the caller. When the key has already been
+	// completed, the order ID stored for it is returned instead.
+	Reserve(ctx context.Context, key string) (orderID string, reserved bool, err error)
+	Complete(ctx context.Context, key string, orderID string) error
+	Release(ctx context.Context, key string) error
+}
+
+type Option func(*OrderService)
+
+func WithIdempotencyStore(store IdempotencyStore) Option {
+	return func(s *OrderService) {
+		s.idempotency = store
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+type memIdempotencyStore struct {
+	keys map[string]string
+	mu   sync.Mutex
+}
+
+func newMemIdempotencyStore() *memIdempotencyStore {
+	return &memIdempotencyStore{keys: make(map[string]string)}
+}
+
+func (m *memIdempotencyStore) Reserve(ctx context.Context, key string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.keys[key]
+	if !ok {
+		m.keys[key] = ""
+		return "", true, nil
+	}
+	return value, false, nil
+}
+
+func (m *memIdempotencyStore) Complete(ctx context.Context, key string, orderID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys[key] = orderID
+	return nil
+}
+
+func (m *memIdempotencyStore) Release(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.keys, key)
+	return nil
+}
+
+func TestCreateOrderIdempotentReplay(t *testing.T) {
+	repo := newMockRepo()
+	pub := &mockPublisher{}
+	svc := NewOrderService(repo, pub, WithIdempotencyStore(newMemIdempotencyStore()))
+
+	req := CreateOrderRequest{CustomerID: "customer-1", Product: "Test", Quantity: 1, IdempotencyKey: "key-1"}
+
+	first, err := svc.CreateOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := svc.CreateOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first.ID != second.ID {
+		t.Errorf("expected replay to return order %s, got %s", first.ID, second.ID)
+	}
+	if len(repo.orders) != 1 {
+		t.Errorf("expected 1 order persisted, got %d", len(repo.orders))
+	}
+	if len(pub.published) != 1 {
+		t.Errorf("expected 1 event published, got %d", len(pub.published))
+	}
+}
+
+func TestCreateOrderIdempotentConcurrentDuplicates(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewOrderService(repo, nil, WithIdempotencyStore(newMemIdempotencyStore()))
+
+	const callers = 10
+	ids := make([]string, callers)
+	errs := make([]error, callers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			order, err := svc.CreateOrder(context.Background(), CreateOrderRequest{CustomerID: "customer-1", Product: "Test", Quantity: 1, IdempotencyKey: "race"})
+			errs[i] = err
+			if order != nil {
+				ids[i] = order.ID
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < callers; i++ {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: unexpected error: %v", i, errs[i])
+		}
+		if ids[i] != ids[0] {
+			t.Errorf("caller %d got order %s, expected %s", i, ids[i], ids[0])
+		}
+	}
+	if len(repo.orders) != 1 {
+		t.Errorf("expected exactly 1 order persisted, got %d", len(repo.orders))
+	}
+}
+
+func TestCreateOrderDistinctIdempotencyKeys(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewOrderService(repo, nil, WithIdempotencyStore(newMemIdempotencyStore()))
+
+	for _, key := range []string{"a", "b", ""} {
+		if _, err := svc.CreateOrder(context.Background(), CreateOrderRequest{CustomerID: "customer-1", Product: "Test", Quantity: 1, IdempotencyKey: key}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(repo.orders) != 3 {
+		t.Errorf("expected 3 orders persisted, got %d", len(repo.orders))
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrIDGeneration = errors.New("failed to generate order id")
+
+// IDGenerator issues order ids. They must be unique across every instance
+// writing to the same database: orders.id is the primary key, so a repeated
+// id fails the create rather than overwriting an order.
+type IDGenerator interface {
+	NewID(ctx context.Context) (string, error)
+}
+
+type UUIDGenerator struct{}
+
+func (UUIDGenerator) NewID(ctx context.Context) (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// PseudoRandomUUIDGenerator produces v4 UUIDs from a ChaCha8 stream seeded at
+// startup. It does not depend on the OS entropy source, which makes it a
+// suitable fallback when crypto/rand is unavailable.
+type PseudoRandomUUIDGenerator struct {
+	mu  sync.Mutex
+	rng *rand.ChaCha8
+}
+
+func NewPseudoRandomUUIDGenerator() *PseudoRandomUUIDGenerator {
+	var seed [32]byte
+	binary.LittleEndian.PutUint64(seed[0:], uint64(time.Now().UnixNano()))
+	binary.LittleEndian.PutUint64(seed[8:], uint64(os.Getpid()))
+	binary.LittleEndian.PutUint64(seed[16:], rand.Uint64())
+	binary.LittleEndian.PutUint64(seed[24:], rand.Uint64())
+	return &PseudoRandomUUIDGenerator{rng: rand.NewChaCha8(seed)}
+}
+
+func (g *PseudoRandomUUIDGenerator) NewID(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id, err := uuid.NewRandomFromReader(g.rng)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+type FallbackIDGenerator struct {
+	primary  IDGenerator
+	fallback IDGenerator
+}
+
+func NewFallbackIDGenerator(primary, fallback IDGenerator) *FallbackIDGenerator {
+	return &FallbackIDGenerator{primary: primary, fallback: fallback}
+}
+
+func (g *FallbackIDGenerator) NewID(ctx context.Context) (string, error) {
+	id, err := g.primary.NewID(ctx)
+	if err == nil {
+		return id, nil
+	}
+	id, fallbackErr := g.fallback.NewID(ctx)
+	if fallbackErr != nil {
+		return "", fmt.Errorf("primary: %v, fallback: %v", err, fallbackErr)
+	}
+	return id, nil
+}
+
+func WithIDGenerator(ids IDGenerator) Option {
+	return func(s *OrderService) {
+		s.ids = ids
+	}
+}
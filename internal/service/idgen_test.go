@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type failingIDGenerator struct{}
+
+func (failingIDGenerator) NewID(ctx context.Context) (string, error) {
+	return "", errors.New("entropy source unavailable")
+}
+
+type staticIDGenerator struct {
+	id string
+}
+
+func (g staticIDGenerator) NewID(ctx context.Context) (string, error) {
+	return g.id, nil
+}
+
+func TestCreateOrderIDGenerationFailure(t *testing.T) {
+	repo := newMockRepo()
+	pub := &mockPublisher{}
+	svc := NewOrderService(repo, pub, WithIDGenerator(failingIDGenerator{}))
+
+	_, err := svc.CreateOrder(context.Background(), CreateOrderRequest{CustomerID: "customer-1", Product: "Test", Quantity: 1})
+	if !errors.Is(err, ErrIDGeneration) {
+		t.Fatalf("expected ErrIDGeneration, got %v", err)
+	}
+	if len(repo.orders) != 0 {
+		t.Error("expected no order to be persisted")
+	}
+	if len(pub.published) != 0 {
+		t.Error("expected no event to be published")
+	}
+}
+
+func TestFallbackIDGenerator(t *testing.T) {
+	gen := NewFallbackIDGenerator(failingIDGenerator{}, staticIDGenerator{id: "fallback-id"})
+
+	id, err := gen.NewID(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id != "fallback-id" {
+		t.Errorf("expected fallback id, got %s", id)
+	}
+
+	_, err = NewFallbackIDGenerator(failingIDGenerator{}, failingIDGenerator{}).NewID(context.Background())
+	if err == nil {
+		t.Error("expected error when both generators fail")
+	}
+}
+
+func TestPseudoRandomUUIDGenerator(t *testing.T) {
+	gen := NewPseudoRandomUUIDGenerator()
+	seen := make(map[string]bool)
+
+	for i := 0; i < 1000; i++ {
+		id, err := gen.NewID(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			t.Fatalf("invalid uuid %q: %v", id, err)
+		}
+		if parsed.Version() != 4 {
+			t.Errorf("expected v4 uuid, got version %d", parsed.Version())
+		}
+		if seen[id] {
+			t.Fatalf("duplicate id %s", id)
+		}
+		seen[id] = true
+	}
+}
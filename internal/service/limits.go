@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/orders-service/internal/admission"
+)
+
+var ErrRateLimited = NewError(CodeRateLimited, "order rate limit exceeded")
+
+type RateLimitError struct {
+	Product    string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s for product %q, retry after %s", ErrRateLimited, e.Product, e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+type ProductLimiter interface {
+	Allow(ctx context.Context, product string) (bool, time.Duration, error)
+}
+
+func WithProductLimiter(limiter ProductLimiter) Option {
+	return func(s *OrderService) {
+		s.productLimiter = limiter
+	}
+}
+
+func WithAdmission(ctrl *admission.Controller) Option {
+	return func(s *OrderService) {
+		s.admission = ctrl
+	}
+}
+
+// WithReadLimiter caps concurrent list and history reads, leaving capacity
+// for gets and writes.
+func WithReadLimiter(limiter *admission.Limiter) Option {
+	return func(s *OrderService) {
+		s.readLimiter = limiter
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/orders-service/internal/admission"
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+	"go.uber.org/zap"
+)
+
+var ErrListFilterUnavailable = errors.New("filtered listing is not configured")
+
+var orderStatuses = map[string]bool{
+	model.StatusPending:   true,
+	model.StatusConfirmed: true,
+	model.StatusShipped:   true,
+	model.StatusDelivered: true,
+	model.StatusCancelled: true,
+}
+
+var sortFields = map[string]bool{
+	repo.SortCreatedAt: true,
+	repo.SortQuantity:  true,
+	repo.SortStatus:    true,
+	repo.SortProduct:   true,
+}
+
+// ListQuery filters and sorts ListOrders. From is inclusive and To exclusive;
+// zero fields do not filter. Sort defaults to created_at and Order to desc.
+type ListQuery struct {
+	CustomerID string
+	Status     string
+	From       time.Time
+	To         time.Time
+	Sort       string
+	Order      string
+}
+
+func (q ListQuery) Validate() error {
+	if q.Status != "" && !orderStatuses[q.Status] {
+		return &ValidationError{Field: "status", Message: "must be one of: pending, confirmed, shipped, delivered, cancelled"}
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return &ValidationError{Field: "to", Message: "must be after from"}
+	}
+	if q.Sort != "" && !sortFields[q.Sort] {
+		return &ValidationError{Field: "sort", Message: "must be one of: created_at, quantity, status, product"}
+	}
+	if q.Order != "" && q.Order != "asc" && q.Order != "desc" {
+		return &ValidationError{Field: "order", Message: "must be one of: asc, desc"}
+	}
+	return nil
+}
+
+func (q ListQuery) filtered() bool {
+	return q.CustomerID != "" || q.Status != "" || !q.From.IsZero() || !q.To.IsZero() || q.Sort != "" || q.Order != ""
+}
+
+// WithOrderFinder enables filtered ListOrders queries, reading from source.
+func WithOrderFinder(source repo.OrderFinder) Option {
+	return func(s *OrderService) {
+		s.finder = source
+	}
+}
+
+// GetOrdersByCustomer returns the orders owned by customerID, newest first.
+func (s *OrderService) GetOrdersByCustomer(ctx context.Context, customerID string) ([]model.Order, error) {
+	if customerID == "" {
+		return nil, &ValidationError{Field: "customer_id", Message: "must not be empty"}
+	}
+	return s.ListOrders(ctx, ListQuery{CustomerID: customerID})
+}
+
+// ListOrders returns the orders matching q in the order it asks for. An empty
+// query is the same as GetOrders.
+func (s *OrderService) ListOrders(ctx context.Context, q ListQuery) ([]model.Order, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	if !q.filtered() {
+		return s.GetOrders(ctx)
+	}
+	if s.finder == nil {
+		return nil, ErrListFilterUnavailable
+	}
+
+	if err := s.admit(admission.PriorityLow); err != nil {
+		logger.FromContext(ctx).Warn("shedding list request", zap.Error(err))
+		return nil, err
+	}
+	release, err := s.acquireRead(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Warn("shedding list request", zap.Error(err))
+		return nil, err
+	}
+	defer release()
+
+	orders, err := s.finder.FindOrders(ctx, repo.OrderFilter{
+		CustomerID:  q.CustomerID,
+		Status:      q.Status,
+		CreatedFrom: q.From,
+		CreatedTo:   q.To,
+		Sort:        q.Sort,
+		Ascending:   q.Order == "asc",
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("postgres: failed to list orders", zap.Error(err))
+		return nil, err
+	}
+	return orders, nil
+}
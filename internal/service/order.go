@@ -2,9 +2,13 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/orders-service/internal/admission"
 	"github.com/orders-service/internal/events"
 	"github.com/orders-service/internal/logger"
 	"github.com/orders-service/internal/model"
@@ -18,88 +22,422 @@ const (
 	OrderDeletedChannel = "order.deleted"
 )
 
+// ErrConflict is returned when an update is based on an out-of-date version
+// of the order. Clients should re-read the order and retry.
+var ErrConflict = NewError(CodeConflict, "order was modified concurrently")
+
+// ErrOrderNotFound is returned when the order does not exist.
+var ErrOrderNotFound = NewError(CodeNotFound, "order not found")
+
 type OrderService struct {
-	repo      repo.OrderRepository
-	publisher events.Publisher
+	repo           repo.OrderRepository
+	publisher      events.Publisher
+	idempotency    IdempotencyStore
+	ids            IDGenerator
+	audit          repo.AuditRepository
+	softChecks     []SoftCheck
+	productLimiter ProductLimiter
+	admission      *admission.Controller
+	readLimiter    *admission.Limiter
+	stats          *statsCache
+	transitions    *autoTransitioner
+	projection     repo.ProjectionRepository
+	cache          OrderCache
+	statusEvents   StatusEventMode
+	tags           repo.TagRepository
+	maxTags        int
+	batch          repo.BatchOrderRepository
+	streamer       repo.OrderStreamer
+	search         repo.ProductSearchRepository
+	finder         repo.OrderFinder
+	outbox         *outbox
+	changes        repo.ChangeFeedRepository
+	tx             repo.Transactor
+	policy         PolicyEvaluator
+	policyFailOpen bool
+
+	defaultCurrency string
 }
 
-func NewOrderService(repo repo.OrderRepository, publisher events.Publisher) *OrderService {
-	return &OrderService{repo: repo, publisher: publisher}
+func NewOrderService(repo repo.OrderRepository, publisher events.Publisher, opts ...Option) *OrderService {
+	s := &OrderService{
+		repo:      repo,
+		publisher: publisher,
+		ids:       NewFallbackIDGenerator(UUIDGenerator{}, NewPseudoRandomUUIDGenerator()),
+		policy:    AllowAllPolicy{},
+
+		defaultCurrency: DefaultCurrency,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 type CreateOrderRequest struct {
-	Product  string `json:"product"`
-	Quantity int    `json:"quantity"`
+	CustomerID     string            `json:"customer_id" binding:"required"`
+	Product        string            `json:"product" binding:"required"`
+	Quantity       int               `json:"quantity" binding:"gt=0"`
+	Price          int64             `json:"price"`
+	Currency       string            `json:"currency"`
+	Metadata       map[string]string `json:"metadata"`
+	IdempotencyKey string            `json:"-"`
+}
+
+type OrderResult struct {
+	*model.Order
+	Warnings       []Warning `json:"warnings"`
+	StreamPosition string    `json:"-"`
+}
+
+// MarshalJSON writes the order's fields, including its total, next to the
+// warnings. Without it the embedded order's MarshalJSON would be promoted and
+// drop them.
+func (r OrderResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		model.OrderJSON
+		Warnings []Warning `json:"warnings"`
+	}{r.Order.JSON(), r.Warnings})
 }
 
+// UpdateOrderRequest replaces an order's fields. Price, currency and metadata
+// are kept when omitted; an empty metadata object removes the metadata. When
+// Version is set the update fails with ErrConflict unless the order is still
+// at that version.
 type UpdateOrderRequest struct {
-	Product  string `json:"product"`
-	Quantity int    `json:"quantity"`
-	Status   string `json:"status"`
+	Product  string            `json:"product" binding:"required"`
+	Quantity int               `json:"quantity" binding:"gt=0"`
+	Status   string            `json:"status" binding:"required"`
+	Price    *int64            `json:"price"`
+	Currency string            `json:"currency"`
+	Metadata map[string]string `json:"metadata"`
+	Version  int64             `json:"version"`
 }
 
-func (s *OrderService) CreateOrder(ctx context.Context, req CreateOrderRequest) (*model.Order, error) {
+// CreateOrder validates and saves a new order and publishes order.created.
+// If ctx is cancelled after the order was saved, the event is not published
+// and CreateOrder returns the saved order together with an
+// *EventNotPublishedError.
+func (s *OrderService) CreateOrder(ctx context.Context, req CreateOrderRequest) (*OrderResult, error) {
 	log := logger.FromContext(ctx)
 
-	order := &model.Order{
-		ID:        uuid.New().String(),
-		Product:   req.Product,
-		Quantity:  req.Quantity,
-		Status:    "pending",
-		CreatedAt: time.Now(),
+	if err := req.Validate(); err != nil {
+		log.Warn("invalid create order request", zap.Error(err))
+		return nil, err
 	}
 
-	if err := s.repo.Create(ctx, order); err != nil {
-		log.Error("postgres: failed to create order", zap.Error(err))
+	if req.IdempotencyKey != "" && s.idempotency != nil {
+		return s.createOrderIdempotent(ctx, req)
+	}
+	return s.createOrder(ctx, req)
+}
+
+// ValidateOrder runs the checks CreateOrder would, including the policy and
+// soft checks, and returns the order as it would be created but without an
+// id or timestamps. It neither saves the order nor publishes an event, and
+// does not count against the per-product create limit.
+func (s *OrderService) ValidateOrder(ctx context.Context, req CreateOrderRequest) (*OrderResult, error) {
+	if err := req.Validate(); err != nil {
+		logger.FromContext(ctx).Warn("invalid create order request", zap.Error(err))
+		return nil, err
+	}
+
+	order := s.buildOrder(req)
+	if err := s.checkPolicy(ctx, order); err != nil {
 		return nil, err
 	}
+	return &OrderResult{Order: order, Warnings: s.runSoftChecks(ctx, order)}, nil
+}
+
+func (s *OrderService) createOrderIdempotent(ctx context.Context, req CreateOrderRequest) (*OrderResult, error) {
+	log := logger.FromContext(ctx)
+	deadline := time.Now().Add(defaultIdempotencyWait)
+
+	for {
+		orderID, reserved, err := s.idempotency.Reserve(ctx, req.IdempotencyKey)
+		if err != nil {
+			log.Error("failed to reserve idempotency key", zap.Error(err))
+			return nil, err
+		}
 
-	if s.publisher != nil {
-		if err := s.publisher.Publish(ctx, OrderCreatedChannel, order); err != nil {
-			log.Error("failed to publish order.created event", zap.Error(err))
-		} else {
-			log.Info("event published", zap.String("channel", OrderCreatedChannel), zap.String("order_id", order.ID))
+		if reserved {
+			order, err := s.createOrder(ctx, req)
+			var partialErr *EventNotPublishedError
+			if errors.As(err, &partialErr) {
+				// The order exists, so a retry must replay it rather than
+				// create another.
+				if err := s.idempotency.Complete(context.WithoutCancel(ctx), req.IdempotencyKey, order.ID); err != nil {
+					log.Error("failed to record idempotency key", zap.String("order_id", order.ID), zap.Error(err))
+				}
+				return order, partialErr
+			}
+			if err != nil {
+				if releaseErr := s.idempotency.Release(ctx, req.IdempotencyKey); releaseErr != nil {
+					log.Error("failed to release idempotency key", zap.Error(releaseErr))
+				}
+				return nil, err
+			}
+			if err := s.idempotency.Complete(ctx, req.IdempotencyKey, order.ID); err != nil {
+				log.Error("failed to record idempotency key", zap.String("order_id", order.ID), zap.Error(err))
+			}
+			return order, nil
+		}
+
+		if orderID != "" {
+			log.Info("idempotent replay of create order", zap.String("order_id", orderID))
+			order, err := s.repo.GetByID(ctx, orderID)
+			if err != nil {
+				return nil, orderNotFound(err)
+			}
+			return &OrderResult{Order: order, Warnings: []Warning{}}, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, ErrIdempotencyKeyInProgress
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(defaultIdempotencyPollInterval):
 		}
 	}
+}
 
+func (s *OrderService) createOrder(ctx context.Context, req CreateOrderRequest) (*OrderResult, error) {
+	log := logger.FromContext(ctx)
+
+	if err := s.checkProductLimit(ctx, req.Product); err != nil {
+		return nil, err
+	}
+
+	order, err := s.newOrder(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPolicy(ctx, order); err != nil {
+		return nil, err
+	}
+
+	warnings := s.runSoftChecks(ctx, order)
+
+	created := orderEvent(OrderCreatedChannel, order)
+	if err := s.commit(ctx, func(ctx context.Context) error { return s.repo.Create(ctx, order) }, created); err != nil {
+		log.Error("postgres: failed to create order", zap.Error(err))
+		return nil, err
+	}
+
+	s.recordAudit(ctx, OrderCreatedChannel, order)
+	s.updateProjection(ctx, OrderCreatedChannel, order)
+	if err := ctx.Err(); err != nil {
+		log.Warn("context cancelled after order was created, not publishing its event", zap.String("order_id", order.ID), zap.Error(err))
+		return &OrderResult{Order: order, Warnings: warnings}, &EventNotPublishedError{OrderID: order.ID, Err: err}
+	}
+	position := s.publishPending(ctx, created)
+
+	return &OrderResult{Order: order, Warnings: warnings, StreamPosition: position}, nil
+}
+
+func (s *OrderService) checkProductLimit(ctx context.Context, product string) error {
+	if s.productLimiter == nil {
+		return nil
+	}
+	log := logger.FromContext(ctx)
+
+	allowed, retryAfter, err := s.productLimiter.Allow(ctx, product)
+	if err != nil {
+		log.Error("failed to check product rate limit", zap.String("product", product), zap.Error(err))
+	} else if !allowed {
+		log.Warn("product rate limit exceeded", zap.String("product", product), zap.Duration("retry_after", retryAfter))
+		return &RateLimitError{Product: product, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// newOrder builds a pending order with a fresh id from a validated request.
+func (s *OrderService) newOrder(ctx context.Context, req CreateOrderRequest) (*model.Order, error) {
+	id, err := s.ids.NewID(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to generate order id", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrIDGeneration, err)
+	}
+	order := s.buildOrder(req)
+	order.ID = id
 	return order, nil
 }
 
+// buildOrder builds a pending order without an id from a validated request.
+func (s *OrderService) buildOrder(req CreateOrderRequest) *model.Order {
+	order := &model.Order{
+		CustomerID: req.CustomerID,
+		Product:    req.Product,
+		Quantity:   req.Quantity,
+		Status:     model.StatusPending,
+		Price:      req.Price,
+		Currency:   req.Currency,
+		Metadata:   req.Metadata,
+	}
+	if len(order.Metadata) == 0 {
+		order.Metadata = nil
+	}
+	if order.Currency == "" {
+		order.Currency = s.defaultCurrency
+	}
+	return order
+}
+
 func (s *OrderService) GetOrder(ctx context.Context, id string) (*model.Order, error) {
-	return s.repo.GetByID(ctx, id)
+	if s.cache != nil {
+		return s.getCachedOrder(ctx, id)
+	}
+	order, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, orderNotFound(err)
+	}
+	return order, nil
+}
+
+// orderNotFound translates the repositories' sql.ErrNoRows into
+// ErrOrderNotFound so callers do not depend on database/sql.
+func orderNotFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrOrderNotFound
+	}
+	return err
 }
 
 func (s *OrderService) GetOrders(ctx context.Context) ([]model.Order, error) {
+	if err := s.admit(admission.PriorityLow); err != nil {
+		logger.FromContext(ctx).Warn("shedding list request", zap.Error(err))
+		return nil, err
+	}
+	release, err := s.acquireRead(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Warn("shedding list request", zap.Error(err))
+		return nil, err
+	}
+	defer release()
 	return s.repo.GetAll(ctx)
 }
 
-func (s *OrderService) UpdateOrder(ctx context.Context, id string, req UpdateOrderRequest) (*model.Order, error) {
+// WithOrderStream makes StreamOrders read orders from source row by row.
+// Without it StreamOrders loads them all with GetAll first.
+func WithOrderStream(source repo.OrderStreamer) Option {
+	return func(s *OrderService) {
+		s.streamer = source
+	}
+}
+
+// StreamOrders calls fn for every order, oldest first, and stops at the first
+// error fn returns.
+func (s *OrderService) StreamOrders(ctx context.Context, fn func(model.Order) error) error {
+	if err := s.admit(admission.PriorityLow); err != nil {
+		logger.FromContext(ctx).Warn("shedding stream request", zap.Error(err))
+		return err
+	}
+	release, err := s.acquireRead(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Warn("shedding stream request", zap.Error(err))
+		return err
+	}
+	defer release()
+
+	if s.streamer != nil {
+		return s.streamer.StreamSince(ctx, time.Time{}, "", fn)
+	}
+	orders, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	for i := len(orders) - 1; i >= 0; i-- {
+		if err := fn(orders[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountOrders returns the number of orders, restricted to status when it is
+// not empty.
+func (s *OrderService) CountOrders(ctx context.Context, status string) (int, error) {
+	if status == "" {
+		return s.repo.CountOrders(ctx)
+	}
+	return s.repo.CountOrdersByStatus(ctx, status)
+}
+
+func (s *OrderService) UpdateOrder(ctx context.Context, id string, req UpdateOrderRequest) (*OrderResult, error) {
 	log := logger.FromContext(ctx)
 
+	if err := req.Validate(); err != nil {
+		log.Warn("invalid update order request", zap.Error(err))
+		return nil, err
+	}
+
 	order, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		log.Error("postgres: failed to get order", zap.String("order_id", id), zap.Error(err))
-		return nil, err
+		return nil, orderNotFound(err)
+	}
+	if req.Version != 0 && req.Version != order.Version {
+		log.Warn("stale order version", zap.String("order_id", id), zap.Int64("version", req.Version), zap.Int64("current_version", order.Version))
+		return nil, ErrConflict
 	}
 
+	from := order.Status
+	if err := checkTransition(from, req.Status); err != nil {
+		log.Warn("invalid status transition", zap.String("order_id", id), zap.String("from", from), zap.String("to", req.Status))
+		return nil, err
+	}
 	order.Product = req.Product
 	order.Quantity = req.Quantity
 	order.Status = req.Status
-
-	if err := s.repo.Update(ctx, order); err != nil {
-		log.Error("postgres: failed to update order", zap.String("order_id", id), zap.Error(err))
+	if req.Price != nil {
+		order.Price = *req.Price
+	}
+	if req.Currency != "" {
+		order.Currency = req.Currency
+	}
+	if req.Metadata != nil {
+		order.Metadata = req.Metadata
+		if len(order.Metadata) == 0 {
+			order.Metadata = nil
+		}
+	}
+	if err := validateTotal(order.Price, order.Quantity); err != nil {
+		log.Warn("invalid update order request", zap.String("order_id", id), zap.Error(err))
 		return nil, err
 	}
+	if err := s.checkPolicy(ctx, order); err != nil {
+		return nil, err
+	}
+
+	warnings := s.runSoftChecks(ctx, order)
+
+	var evs []*pendingEvent
+	statusChanged := order.Status != from
+	if !statusChanged || s.statusEvents == StatusEventsAlongside {
+		evs = append(evs, orderEvent(OrderUpdatedChannel, order))
+	}
+	if statusChanged {
+		evs = append(evs, statusChangeEvent(from, order))
+	}
 
-	if s.publisher != nil {
-		if err := s.publisher.Publish(ctx, OrderUpdatedChannel, order); err != nil {
-			log.Error("failed to publish order.updated event", zap.Error(err))
-		} else {
-			log.Info("event published", zap.String("channel", OrderUpdatedChannel), zap.String("order_id", order.ID))
+	if err := s.commit(ctx, func(ctx context.Context) error { return s.repo.Update(ctx, order) }, evs...); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			log.Warn("order modified concurrently", zap.String("order_id", id))
+			return nil, ErrConflict
 		}
+		log.Error("postgres: failed to update order", zap.String("order_id", id), zap.Error(err))
+		return nil, orderNotFound(err)
 	}
 
-	return order, nil
+	s.evictCachedOrder(ctx, order.ID)
+	s.recordAudit(ctx, OrderUpdatedChannel, order)
+	s.updateProjection(ctx, OrderUpdatedChannel, order)
+	position := s.publishPending(ctx, evs...)
+
+	return &OrderResult{Order: order, Warnings: warnings, StreamPosition: position}, nil
 }
 
 func (s *OrderService) DeleteOrder(ctx context.Context, id string) error {
@@ -108,21 +446,19 @@ func (s *OrderService) DeleteOrder(ctx context.Context, id string) error {
 	order, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		log.Error("postgres: failed to get order", zap.String("order_id", id), zap.Error(err))
-		return err
+		return orderNotFound(err)
 	}
 
-	if err := s.repo.Delete(ctx, id); err != nil {
+	deleted := orderEvent(OrderDeletedChannel, order)
+	if err := s.commit(ctx, func(ctx context.Context) error { return s.repo.Delete(ctx, id) }, deleted); err != nil {
 		log.Error("postgres: failed to delete order", zap.String("order_id", id), zap.Error(err))
-		return err
+		return orderNotFound(err)
 	}
 
-	if s.publisher != nil {
-		if err := s.publisher.Publish(ctx, OrderDeletedChannel, order); err != nil {
-			log.Error("failed to publish order.deleted event", zap.Error(err))
-		} else {
-			log.Info("event published", zap.String("channel", OrderDeletedChannel), zap.String("order_id", order.ID))
-		}
-	}
+	s.evictCachedOrder(ctx, order.ID)
+	s.recordAudit(ctx, OrderDeletedChannel, order)
+	s.updateProjection(ctx, OrderDeletedChannel, order)
+	s.publishPending(ctx, deleted)
 
 	return nil
 }
@@ -133,15 +469,71 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, id string, status
 	order, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		log.Error("postgres: failed to get order", zap.String("order_id", id), zap.Error(err))
-		return err
+		return orderNotFound(err)
 	}
 
+	from := order.Status
+	if err := checkTransition(from, status); err != nil {
+		log.Warn("invalid status transition", zap.String("order_id", id), zap.String("from", from), zap.String("to", status))
+		return err
+	}
 	order.Status = status
-	if err := s.repo.Update(ctx, order); err != nil {
+	var evs []*pendingEvent
+	if from != status {
+		evs = append(evs, statusChangeEvent(from, order))
+	}
+	if err := s.commit(ctx, func(ctx context.Context) error { return s.repo.Update(ctx, order) }, evs...); err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			log.Warn("order modified concurrently", zap.String("order_id", id))
+			return ErrConflict
+		}
 		log.Error("postgres: failed to update order status", zap.String("order_id", id), zap.Error(err))
-		return err
+		return orderNotFound(err)
 	}
 
+	s.evictCachedOrder(ctx, order.ID)
+	s.recordAudit(ctx, OrderStatusChangedEvent, order)
+	s.updateProjection(ctx, OrderStatusChangedEvent, order)
+	s.publishPending(ctx, evs...)
 	log.Info("order status updated", zap.String("order_id", id), zap.String("status", status))
 	return nil
 }
+
+func (s *OrderService) admit(p admission.Priority) error {
+	if s.admission == nil {
+		return nil
+	}
+	return s.admission.Admit(p)
+}
+
+// acquireRead takes a slot from the read limiter for an expensive read.
+func (s *OrderService) acquireRead(ctx context.Context) (func(), error) {
+	if s.readLimiter == nil {
+		return func() {}, nil
+	}
+	return s.readLimiter.Acquire(ctx)
+}
+
+var errNoPublisher = errors.New("no event publisher configured")
+
+func (s *OrderService) publish(ctx context.Context, channel, orderID string, message interface{}) (string, error) {
+	if s.publisher == nil {
+		return "", errNoPublisher
+	}
+	log := logger.FromContext(ctx)
+
+	var position string
+	var err error
+	if p, ok := s.publisher.(events.PositionPublisher); ok {
+		position, err = p.PublishWithPosition(ctx, channel, message)
+	} else {
+		err = s.publisher.Publish(ctx, channel, message)
+	}
+	if err != nil {
+		log.Error("failed to publish "+channel+" event", zap.Error(err))
+		return "", err
+	}
+
+	log.Info("event published", zap.String("channel", channel), zap.String("order_id", orderID), zap.String("stream_position", position))
+	return position, nil
+}
@@ -18,8 +18,9 @@ func BenchmarkCreateOrder(b *testing.B) {
 	ctx := context.Background()
 
 	req := CreateOrderRequest{
-		Product:  "Benchmark Product",
-		Quantity: 100,
+		CustomerID: "customer-1",
+		Product:    "Benchmark Product",
+		Quantity:   100,
 	}
 
 	b.ResetTimer()
@@ -38,8 +39,9 @@ func BenchmarkCreateOrderParallel(b *testing.B) {
 	ctx := context.Background()
 
 	req := CreateOrderRequest{
-		Product:  "Benchmark Product",
-		Quantity: 100,
+		CustomerID: "customer-1",
+		Product:    "Benchmark Product",
+		Quantity:   100,
 	}
 
 	b.ResetTimer()
@@ -216,8 +218,9 @@ func BenchmarkConcurrentReadWrite(b *testing.B) {
 			switch i % 4 {
 			case 0:
 				req := CreateOrderRequest{
-					Product:  fmt.Sprintf("Product %d", i),
-					Quantity: i,
+					CustomerID: "customer-1",
+					Product:    fmt.Sprintf("Product %d", i),
+					Quantity:   i,
 				}
 				_, _ = svc.CreateOrder(ctx, req) // Errors ignored in mixed benchmark
 			case 1:
@@ -301,6 +304,24 @@ func (m *slowMockRepo) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *slowMockRepo) CountOrders(ctx context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.orders), nil
+}
+
+func (m *slowMockRepo) CountOrdersByStatus(ctx context.Context, status string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	count := 0
+	for _, o := range m.orders {
+		if o.Status == status {
+			count++
+		}
+	}
+	return count, nil
+}
+
 func BenchmarkSlowDB_CreateOrder(b *testing.B) {
 	delays := []time.Duration{1 * time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond}
 
@@ -312,8 +333,9 @@ func BenchmarkSlowDB_CreateOrder(b *testing.B) {
 			ctx := context.Background()
 
 			req := CreateOrderRequest{
-				Product:  "Benchmark Product",
-				Quantity: 100,
+				CustomerID: "customer-1",
+				Product:    "Benchmark Product",
+				Quantity:   100,
 			}
 
 			b.ResetTimer()
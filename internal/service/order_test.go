@@ -3,11 +3,16 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/orders-service/internal/admission"
+	"github.com/orders-service/internal/events"
 	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
 )
 
 type mockRepo struct {
@@ -22,6 +27,8 @@ func newMockRepo() *mockRepo {
 func (m *mockRepo) Create(ctx context.Context, order *model.Order) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
 	m.orders[order.ID] = order
 	return nil
 }
@@ -52,6 +59,7 @@ func (m *mockRepo) Update(ctx context.Context, order *model.Order) error {
 	if _, ok := m.orders[order.ID]; !ok {
 		return sql.ErrNoRows
 	}
+	order.UpdatedAt = time.Now()
 	m.orders[order.ID] = order
 	return nil
 }
@@ -66,8 +74,28 @@ func (m *mockRepo) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *mockRepo) CountOrders(ctx context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.orders), nil
+}
+
+func (m *mockRepo) CountOrdersByStatus(ctx context.Context, status string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	count := 0
+	for _, o := range m.orders {
+		if o.Status == status {
+			count++
+		}
+	}
+	return count, nil
+}
+
 type mockPublisher struct {
 	published []interface{}
+	channels  []string
+	eventIDs  []string
 	mu        sync.Mutex
 }
 
@@ -75,6 +103,8 @@ func (m *mockPublisher) Publish(ctx context.Context, channel string, message int
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.published = append(m.published, message)
+	m.channels = append(m.channels, channel)
+	m.eventIDs = append(m.eventIDs, events.EventIDFromContext(ctx))
 	return nil
 }
 
@@ -84,8 +114,9 @@ func TestCreateOrder(t *testing.T) {
 	svc := NewOrderService(repo, pub)
 
 	req := CreateOrderRequest{
-		Product:  "Test Product",
-		Quantity: 5,
+		CustomerID: "customer-1",
+		Product:    "Test Product",
+		Quantity:   5,
 	}
 
 	order, err := svc.CreateOrder(context.Background(), req)
@@ -158,7 +189,7 @@ func TestUpdateOrder(t *testing.T) {
 	req := UpdateOrderRequest{
 		Product:  "Updated Product",
 		Quantity: 10,
-		Status:   "shipped",
+		Status:   "confirmed",
 	}
 
 	order, err := svc.UpdateOrder(context.Background(), "test-id", req)
@@ -178,8 +209,172 @@ func TestUpdateOrder(t *testing.T) {
 		t.Errorf("expected status %s, got %s", req.Status, order.Status)
 	}
 
+	if len(pub.published) != 2 || pub.channels[0] != OrderUpdatedChannel || pub.channels[1] != OrderStatusChangedEvent {
+		t.Fatalf("expected order.updated and order.status_changed, got %v", pub.channels)
+	}
+	change, ok := pub.published[1].(*model.StatusChange)
+	if !ok || change.From != "pending" || change.To != "confirmed" || change.Order.ID != "test-id" {
+		t.Errorf("unexpected status change payload %+v", pub.published[1])
+	}
+}
+
+func TestOrderMetadata(t *testing.T) {
+	svc := NewOrderService(repo.NewInMemoryOrderRepository(), &mockPublisher{})
+	ctx := context.Background()
+
+	created, err := svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Test", Quantity: 1,
+		Metadata: map[string]string{"channel": "web", "promo": "SPRING"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	id := created.ID
+
+	order, err := svc.UpdateOrder(ctx, id, UpdateOrderRequest{Product: "Test", Quantity: 2, Status: model.StatusPending})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.Metadata["channel"] != "web" || order.Metadata["promo"] != "SPRING" {
+		t.Errorf("expected metadata to be kept when omitted, got %v", order.Metadata)
+	}
+
+	if _, err = svc.UpdateOrder(ctx, id, UpdateOrderRequest{Product: "Test", Quantity: 2, Status: model.StatusPending,
+		Metadata: map[string]string{"channel": "store"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored, _ := svc.GetOrder(ctx, id); len(stored.Metadata) != 1 || stored.Metadata["channel"] != "store" {
+		t.Errorf("expected metadata to be replaced, got %v", stored.Metadata)
+	}
+
+	if _, err = svc.UpdateOrder(ctx, id, UpdateOrderRequest{Product: "Test", Quantity: 2, Status: model.StatusPending,
+		Metadata: map[string]string{}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored, _ := svc.GetOrder(ctx, id); stored.Metadata != nil {
+		t.Errorf("expected an empty object to remove the metadata, got %v", stored.Metadata)
+	}
+}
+
+func TestValidateOrder(t *testing.T) {
+	store := newMockRepo()
+	pub := &mockPublisher{}
+	// An id generator that fails shows that validation does not use one up.
+	svc := NewOrderService(store, pub,
+		WithIDGenerator(failingIDGenerator{}),
+		WithDefaultCurrency("EUR"),
+		WithSoftChecks(LargeQuantityCheck(10)),
+		WithPolicy(productPolicy{denied: "Gadget"}, false),
+	)
+	ctx := context.Background()
+
+	result, err := svc.ValidateOrder(ctx, CreateOrderRequest{CustomerID: " customer-1 ", Product: " Widget ", Quantity: 20, Price: 100})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ID != "" || result.CustomerID != "customer-1" || result.Product != "Widget" || result.Status != model.StatusPending || result.Currency != "EUR" {
+		t.Errorf("expected the normalized order without an id, got %+v", result.Order)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].Field != "quantity" {
+		t.Errorf("expected a quantity warning, got %v", result.Warnings)
+	}
+
+	var validationErr *ValidationError
+	if _, err := svc.ValidateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Widget"}); !errors.As(err, &validationErr) || validationErr.Field != "quantity" {
+		t.Errorf("expected a validation error for quantity, got %v", err)
+	}
+	var deniedErr *PolicyDeniedError
+	if _, err := svc.ValidateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Gadget", Quantity: 1}); !errors.As(err, &deniedErr) {
+		t.Errorf("expected the policy to be checked, got %v", err)
+	}
+
+	if len(store.orders) != 0 {
+		t.Errorf("expected nothing to be saved, got %d orders", len(store.orders))
+	}
+	if len(pub.published) != 0 {
+		t.Errorf("expected nothing to be published, got %d events", len(pub.published))
+	}
+}
+
+func TestUpdateOrderRejectsStaleVersion(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	pub := &mockPublisher{}
+	svc := NewOrderService(store, pub)
+	ctx := context.Background()
+
+	if err := store.Create(ctx, &model.Order{ID: "test-id", Product: "Original", Quantity: 1, Status: model.StatusPending}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	order, err := svc.UpdateOrder(ctx, "test-id", UpdateOrderRequest{Product: "Renamed", Quantity: 1, Status: model.StatusPending, Version: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.Version != 2 {
+		t.Errorf("expected version 2, got %d", order.Version)
+	}
+
+	_, err = svc.UpdateOrder(ctx, "test-id", UpdateOrderRequest{Product: "Stale", Quantity: 1, Status: model.StatusPending, Version: 1})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	if stored, _ := store.GetByID(ctx, "test-id"); stored.Product != "Renamed" {
+		t.Errorf("expected stale update not to be applied, got %q", stored.Product)
+	}
 	if len(pub.published) != 1 {
-		t.Errorf("expected 1 event published, got %d", len(pub.published))
+		t.Errorf("expected 1 event, got %d", len(pub.published))
+	}
+}
+
+// interleavingRepo applies a competing write between the service's read and
+// its update.
+type interleavingRepo struct {
+	*repo.InMemoryOrderRepository
+	once sync.Once
+}
+
+func (r *interleavingRepo) Update(ctx context.Context, order *model.Order) error {
+	r.once.Do(func() {
+		competing, _ := r.InMemoryOrderRepository.GetByID(ctx, order.ID)
+		competing.Quantity++
+		_ = r.InMemoryOrderRepository.Update(ctx, competing)
+	})
+	return r.InMemoryOrderRepository.Update(ctx, order)
+}
+
+func TestUpdateOrderConflictsWithConcurrentWrite(t *testing.T) {
+	store := &interleavingRepo{InMemoryOrderRepository: repo.NewInMemoryOrderRepository()}
+	svc := NewOrderService(store, nil)
+	ctx := context.Background()
+
+	if err := store.Create(ctx, &model.Order{ID: "test-id", Product: "Original", Quantity: 1, Status: model.StatusPending}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err := svc.UpdateOrder(ctx, "test-id", UpdateOrderRequest{Product: "Renamed", Quantity: 1, Status: model.StatusPending})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	stored, _ := store.GetByID(ctx, "test-id")
+	if stored.Product != "Original" || stored.Quantity != 2 || stored.Version != 2 {
+		t.Errorf("expected only the competing write to apply, got %+v", stored)
+	}
+}
+
+func TestUpdateOrderStatusEventsOnly(t *testing.T) {
+	repo := newMockRepo()
+	pub := &mockPublisher{}
+	svc := NewOrderService(repo, pub, WithStatusEventMode(StatusEventsOnly))
+
+	repo.orders["test-id"] = &model.Order{ID: "test-id", Product: "Original", Quantity: 1, Status: "pending"}
+
+	if _, err := svc.UpdateOrder(context.Background(), "test-id", UpdateOrderRequest{Product: "Original", Quantity: 1, Status: "confirmed"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.UpdateOrder(context.Background(), "test-id", UpdateOrderRequest{Product: "Renamed", Quantity: 1, Status: "confirmed"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pub.channels) != 2 || pub.channels[0] != OrderStatusChangedEvent || pub.channels[1] != OrderUpdatedChannel {
+		t.Errorf("expected only status_changed for the status change and updated otherwise, got %v", pub.channels)
 	}
 }
 
@@ -194,8 +389,8 @@ func TestUpdateOrderNotFound(t *testing.T) {
 	}
 
 	_, err := svc.UpdateOrder(context.Background(), "nonexistent", req)
-	if err != sql.ErrNoRows {
-		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	if err != ErrOrderNotFound {
+		t.Errorf("expected ErrOrderNotFound, got %v", err)
 	}
 }
 
@@ -232,14 +427,15 @@ func TestDeleteOrderNotFound(t *testing.T) {
 	svc := NewOrderService(repo, nil)
 
 	err := svc.DeleteOrder(context.Background(), "nonexistent")
-	if err != sql.ErrNoRows {
-		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	if err != ErrOrderNotFound {
+		t.Errorf("expected ErrOrderNotFound, got %v", err)
 	}
 }
 
 func TestUpdateOrderStatus(t *testing.T) {
 	repo := newMockRepo()
-	svc := NewOrderService(repo, nil)
+	pub := &mockPublisher{}
+	svc := NewOrderService(repo, pub)
 
 	existing := &model.Order{
 		ID:        "test-id",
@@ -258,4 +454,261 @@ func TestUpdateOrderStatus(t *testing.T) {
 	if repo.orders["test-id"].Status != "confirmed" {
 		t.Errorf("expected status confirmed, got %s", repo.orders["test-id"].Status)
 	}
+	if len(pub.channels) != 1 || pub.channels[0] != OrderStatusChangedEvent {
+		t.Fatalf("expected an order.status_changed event, got %v", pub.channels)
+	}
+	if change := pub.published[0].(*model.StatusChange); change.From != "pending" || change.To != "confirmed" {
+		t.Errorf("expected pending -> confirmed, got %s -> %s", change.From, change.To)
+	}
+
+	// Redelivered confirmations do not announce a transition again.
+	if err := svc.UpdateOrderStatus(context.Background(), "test-id", "confirmed"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pub.channels) != 1 {
+		t.Errorf("expected no event for an unchanged status, got %v", pub.channels)
+	}
+}
+
+// The consumer acks order.created events that fail with these errors, so
+// confirming a cancelled or deleted order must report them.
+func TestUpdateOrderStatusOfCancelledOrMissingOrder(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewOrderService(repo, nil)
+	repo.orders["test-id"] = &model.Order{ID: "test-id", Product: "Test", Quantity: 1, Status: "cancelled", CreatedAt: time.Now()}
+
+	if err := svc.UpdateOrderStatus(context.Background(), "test-id", "confirmed"); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("expected ErrInvalidTransition for a cancelled order, got %v", err)
+	}
+	if err := svc.UpdateOrderStatus(context.Background(), "nonexistent", "confirmed"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("expected ErrOrderNotFound, got %v", err)
+	}
+}
+
+func TestCreateOrderValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   CreateOrderRequest
+		field string
+	}{
+		{"missing customer", CreateOrderRequest{Product: "Test", Quantity: 1}, "customer_id"},
+		{"oversized customer", CreateOrderRequest{CustomerID: strings.Repeat("c", MaxCustomerIDLength+1), Product: "Test", Quantity: 1}, "customer_id"},
+		{"empty product", CreateOrderRequest{CustomerID: "customer-1", Product: "", Quantity: 1}, "product"},
+		{"whitespace product", CreateOrderRequest{CustomerID: "customer-1", Product: "   ", Quantity: 1}, "product"},
+		{"oversized product", CreateOrderRequest{CustomerID: "customer-1", Product: strings.Repeat("x", MaxProductLength+1), Quantity: 1}, "product"},
+		{"zero quantity", CreateOrderRequest{CustomerID: "customer-1", Product: "Test", Quantity: 0}, "quantity"},
+		{"negative quantity", CreateOrderRequest{CustomerID: "customer-1", Product: "Test", Quantity: -3}, "quantity"},
+		{"empty metadata key", CreateOrderRequest{CustomerID: "customer-1", Product: "Test", Quantity: 1, Metadata: map[string]string{" ": "x"}}, "metadata"},
+		{"oversized metadata", CreateOrderRequest{CustomerID: "customer-1", Product: "Test", Quantity: 1, Metadata: map[string]string{"note": strings.Repeat("x", MaxMetadataSize)}}, "metadata"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockRepo()
+			pub := &mockPublisher{}
+			svc := NewOrderService(repo, pub)
+
+			_, err := svc.CreateOrder(context.Background(), tt.req)
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected ValidationError, got %v", err)
+			}
+			if validationErr.Field != tt.field {
+				t.Errorf("expected field %s, got %s", tt.field, validationErr.Field)
+			}
+			if len(repo.orders) != 0 {
+				t.Error("expected invalid order not to be persisted")
+			}
+			if len(pub.published) != 0 {
+				t.Error("expected no event for invalid order")
+			}
+		})
+	}
+}
+
+func TestCreateOrderTrimsProduct(t *testing.T) {
+	svc := NewOrderService(newMockRepo(), nil)
+
+	order, err := svc.CreateOrder(context.Background(), CreateOrderRequest{CustomerID: "customer-1", Product: "  Widget  ", Quantity: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.Product != "Widget" {
+		t.Errorf("expected trimmed product Widget, got %q", order.Product)
+	}
+}
+
+// cancellingRepo cancels the request context once an order is saved, as if
+// the client went away between the write and the publish.
+type cancellingRepo struct {
+	*mockRepo
+	cancel context.CancelFunc
+}
+
+func (r *cancellingRepo) Create(ctx context.Context, order *model.Order) error {
+	err := r.mockRepo.Create(ctx, order)
+	r.cancel()
+	return err
+}
+
+func TestCreateOrderCancelledBeforePublish(t *testing.T) {
+	for _, key := range []string{"", "key-1"} {
+		ctx, cancel := context.WithCancel(context.Background())
+		repo := &cancellingRepo{mockRepo: newMockRepo(), cancel: cancel}
+		pub := &mockPublisher{}
+		svc := NewOrderService(repo, pub, WithIdempotencyStore(newMemIdempotencyStore()))
+		req := CreateOrderRequest{CustomerID: "customer-1", Product: "Widget", Quantity: 1, IdempotencyKey: key}
+
+		result, err := svc.CreateOrder(ctx, req)
+		var partialErr *EventNotPublishedError
+		if !errors.As(err, &partialErr) || !errors.Is(err, context.Canceled) {
+			t.Fatalf("key %q: expected EventNotPublishedError wrapping context.Canceled, got %v", key, err)
+		}
+		if result == nil || partialErr.OrderID != result.ID {
+			t.Fatalf("key %q: expected the saved order with the error, got %+v", key, result)
+		}
+		if _, ok := repo.orders[result.ID]; !ok {
+			t.Errorf("key %q: expected the order to be saved", key)
+		}
+		if len(pub.published) != 0 {
+			t.Errorf("key %q: expected no event to be published, got %d", key, len(pub.published))
+		}
+
+		if key == "" {
+			continue
+		}
+		replay, err := svc.CreateOrder(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if replay.ID != result.ID || len(repo.orders) != 1 {
+			t.Errorf("expected a retry to replay order %s, got %s with %d orders", result.ID, replay.ID, len(repo.orders))
+		}
+	}
+}
+
+func TestCreateOrderSoftWarnings(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewOrderService(repo, nil, WithSoftChecks(LargeQuantityCheck(100), CatalogCheck([]string{"Widget"})))
+
+	order, err := svc.CreateOrder(context.Background(), CreateOrderRequest{CustomerID: "customer-1", Product: "Widget", Quantity: 101})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := repo.orders[order.ID]; !ok {
+		t.Error("expected borderline order to be persisted")
+	}
+	if len(order.Warnings) != 1 || order.Warnings[0].Field != "quantity" {
+		t.Errorf("expected a single quantity warning, got %+v", order.Warnings)
+	}
+
+	order, err = svc.CreateOrder(context.Background(), CreateOrderRequest{CustomerID: "customer-1", Product: "Widget", Quantity: 100})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(order.Warnings) != 0 {
+		t.Errorf("expected no warnings at the threshold, got %+v", order.Warnings)
+	}
+
+	order, err = svc.CreateOrder(context.Background(), CreateOrderRequest{CustomerID: "customer-1", Product: "Gizmo", Quantity: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(order.Warnings) != 1 || order.Warnings[0].Field != "product" {
+		t.Errorf("expected a single product warning, got %+v", order.Warnings)
+	}
+}
+
+type blockingListRepo struct {
+	*mockRepo
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (r *blockingListRepo) GetAll(ctx context.Context) ([]model.Order, error) {
+	r.started <- struct{}{}
+	<-r.unblock
+	return r.mockRepo.GetAll(ctx)
+}
+
+func TestReadLimiterShedsListsButNotGets(t *testing.T) {
+	repo := &blockingListRepo{mockRepo: newMockRepo(), started: make(chan struct{}, 2), unblock: make(chan struct{})}
+	svc := NewOrderService(repo, nil, WithReadLimiter(admission.NewLimiter(1, 20*time.Millisecond)))
+	ctx := context.Background()
+
+	created, err := svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "widget", Quantity: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.GetOrders(ctx)
+		done <- err
+	}()
+	<-repo.started
+
+	if _, err := svc.GetOrders(ctx); !errors.Is(err, admission.ErrOverloaded) {
+		t.Errorf("expected second list to be shed, got %v", err)
+	}
+	if _, err := svc.GetOrder(ctx, created.Order.ID); err != nil {
+		t.Errorf("expected get to proceed while a list holds the slot, got %v", err)
+	}
+
+	close(repo.unblock)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.GetOrders(ctx); err != nil {
+		t.Errorf("expected list to run once the slot is released, got %v", err)
+	}
+}
+
+func TestStatusTransitions(t *testing.T) {
+	statuses := []string{model.StatusPending, model.StatusConfirmed, model.StatusShipped, model.StatusDelivered, model.StatusCancelled}
+	legal := map[[2]string]bool{
+		{model.StatusPending, model.StatusConfirmed}:   true,
+		{model.StatusPending, model.StatusCancelled}:   true,
+		{model.StatusConfirmed, model.StatusShipped}:   true,
+		{model.StatusConfirmed, model.StatusCancelled}: true,
+		{model.StatusShipped, model.StatusDelivered}:   true,
+	}
+	for _, from := range statuses {
+		for _, to := range statuses {
+			store := repo.NewInMemoryOrderRepository()
+			svc := NewOrderService(store, nil)
+			ctx := context.Background()
+			if err := store.Create(ctx, &model.Order{ID: "order-1", Product: "Widget", Quantity: 1, Status: from}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, updateErr := svc.UpdateOrder(ctx, "order-1", UpdateOrderRequest{Product: "Widget", Quantity: 1, Status: to})
+			statusErr := svc.UpdateOrderStatus(ctx, "order-1", to)
+			for name, err := range map[string]error{"UpdateOrder": updateErr, "UpdateOrderStatus": statusErr} {
+				if from == to || legal[[2]string{from, to}] {
+					if err != nil {
+						t.Errorf("%s %s -> %s: unexpected error: %v", name, from, to, err)
+					}
+					continue
+				}
+				var transitionErr *TransitionError
+				if !errors.As(err, &transitionErr) || ErrorCode(err) != CodeFailedPrecondition {
+					t.Errorf("%s %s -> %s: expected a TransitionError, got %v", name, from, to, err)
+				}
+			}
+
+			order, err := store.GetByID(ctx, "order-1")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := from
+			if legal[[2]string{from, to}] {
+				want = to
+			}
+			if order.Status != want {
+				t.Errorf("%s -> %s: expected status %s, got %s", from, to, want, order.Status)
+			}
+		}
+	}
 }
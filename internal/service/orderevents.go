@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/model"
+	"go.uber.org/zap"
+)
+
+// OrderEventPage is a batch of an order's lifecycle. Next is the sequence
+// number to resume from; it equals the requested one when there were no
+// more events.
+type OrderEventPage struct {
+	Events []model.OrderEvent `json:"events"`
+	Next   int64              `json:"next"`
+}
+
+// OrderEvents returns up to limit events of order id after change feed
+// sequence number from, oldest first. They are read from the change feed,
+// so they include the events of deleted orders but not those written before
+// the feed was enabled.
+func (s *OrderService) OrderEvents(ctx context.Context, id string, from int64, limit int) (*OrderEventPage, error) {
+	log := logger.FromContext(ctx)
+
+	if s.changes == nil {
+		return nil, ErrChangeFeedUnavailable
+	}
+	if from < 0 {
+		return nil, &ValidationError{Field: "from", Message: "must not be negative"}
+	}
+	if limit < 0 || limit > MaxChangeFeedLimit {
+		return nil, &ValidationError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", MaxChangeFeedLimit)}
+	}
+	if limit == 0 {
+		limit = DefaultChangeFeedLimit
+	}
+	release, err := s.acquireRead(ctx)
+	if err != nil {
+		log.Warn("shedding order events request", zap.Error(err))
+		return nil, err
+	}
+	defer release()
+
+	changes, err := s.changes.ForOrder(ctx, id, from, limit)
+	if err != nil {
+		log.Error("postgres: failed to read order events", zap.String("order_id", id), zap.Error(err))
+		return nil, err
+	}
+	if len(changes) == 0 && from == 0 {
+		// Tell an unknown order apart from one without recorded events.
+		if _, err := s.repo.GetByID(ctx, id); err != nil {
+			return nil, orderNotFound(err)
+		}
+	}
+
+	page := &OrderEventPage{Events: make([]model.OrderEvent, 0, len(changes)), Next: from}
+	for _, change := range changes {
+		event, err := decodeOrderEvent(change)
+		if err != nil {
+			log.Error("failed to decode change payload", zap.String("order_id", id), zap.Int64("seq", change.Seq), zap.Error(err))
+			return nil, err
+		}
+		page.Events = append(page.Events, event)
+		page.Next = change.Seq
+	}
+	return page, nil
+}
+
+// decodeOrderEvent reads the order's status and version from the payload of
+// change, which is an order or, for order.status_changed, a status change.
+func decodeOrderEvent(change model.OrderChange) (model.OrderEvent, error) {
+	var payload struct {
+		Status  string `json:"status"`
+		Version int64  `json:"version"`
+		From    string `json:"from"`
+		To      string `json:"to"`
+		Order   *struct {
+			Version int64 `json:"version"`
+		} `json:"order"`
+	}
+	if err := json.Unmarshal(change.Payload, &payload); err != nil {
+		return model.OrderEvent{}, err
+	}
+
+	event := model.OrderEvent{
+		Seq:        change.Seq,
+		Event:      change.EventType,
+		Status:     payload.Status,
+		Version:    payload.Version,
+		OccurredAt: change.CreatedAt,
+	}
+	if change.EventType == OrderStatusChangedEvent {
+		event.Status, event.From = payload.To, payload.From
+		if payload.Order != nil {
+			event.Version = payload.Order.Version
+		}
+	}
+	return event, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+
+	"github.com/orders-service/internal/events"
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+	"go.uber.org/zap"
+)
+
+type outbox struct {
+	store      repo.OutboxRepository
+	serializer events.Serializer
+}
+
+// WithOutbox records every event in store in the same transaction as the
+// write it describes, so no committed write loses its event. Events are still
+// published right after the commit; outbox.Relay publishes those that were
+// not, e.g. because Redis was unavailable or the process crashed.
+func WithOutbox(tx repo.Transactor, store repo.OutboxRepository, serializer events.Serializer) Option {
+	return func(s *OrderService) {
+		s.tx = tx
+		s.outbox = &outbox{store: store, serializer: serializer}
+	}
+}
+
+// pendingEvent is an event to publish once the write it describes has
+// committed. The message is only serialized after the write, so it may point
+// at fields the write fills in such as timestamps.
+type pendingEvent struct {
+	channel  string
+	orderID  string
+	message  interface{}
+	outboxID int64
+}
+
+// eventID is the stable ID of ev. It depends on the order's version, so it is
+// only known once the write has committed.
+func (ev *pendingEvent) eventID() string {
+	var version int64
+	switch m := ev.message.(type) {
+	case *model.Order:
+		version = m.Version
+	case *model.StatusChange:
+		if m.Order != nil {
+			version = m.Order.Version
+		}
+	}
+	return events.EventID(ev.channel, ev.orderID, version)
+}
+
+func orderEvent(channel string, order *model.Order) *pendingEvent {
+	return &pendingEvent{channel: channel, orderID: order.ID, message: order}
+}
+
+func statusChangeEvent(from string, order *model.Order) *pendingEvent {
+	return &pendingEvent{
+		channel: OrderStatusChangedEvent,
+		orderID: order.ID,
+		message: &model.StatusChange{From: from, To: order.Status, Order: order},
+	}
+}
+
+// commit runs write and, when the outbox or the change feed is enabled,
+// records evs in them in the same transaction.
+func (s *OrderService) commit(ctx context.Context, write func(ctx context.Context) error, evs ...*pendingEvent) error {
+	if s.outbox == nil && s.changes == nil {
+		return write(ctx)
+	}
+
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := write(ctx); err != nil {
+			return err
+		}
+		for _, ev := range evs {
+			if s.changes != nil {
+				if err := s.appendChange(ctx, ev); err != nil {
+					return err
+				}
+			}
+			if s.outbox != nil {
+				if err := s.addToOutbox(ctx, ev); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (s *OrderService) addToOutbox(ctx context.Context, ev *pendingEvent) error {
+	payload, err := s.outbox.serializer.Marshal(ev.message)
+	if err != nil {
+		return err
+	}
+	msg := &repo.OutboxMessage{EventID: ev.eventID(), Channel: ev.channel, ContentType: s.outbox.serializer.ContentType(), Payload: payload}
+	if err := s.outbox.store.Add(ctx, msg); err != nil {
+		logger.FromContext(ctx).Error("postgres: failed to record outbox message", zap.String("channel", ev.channel), zap.String("order_id", ev.orderID), zap.Error(err))
+		return err
+	}
+	ev.outboxID = msg.ID
+	return nil
+}
+
+// publishPending publishes evs after their write has committed and marks
+// those recorded in the outbox as published. It returns the stream position
+// of the last event published.
+func (s *OrderService) publishPending(ctx context.Context, evs ...*pendingEvent) string {
+	var position string
+	var published []int64
+	for _, ev := range evs {
+		p, err := s.publish(events.WithEventID(ctx, ev.eventID()), ev.channel, ev.orderID, ev.message)
+		if err != nil {
+			continue
+		}
+		if p != "" {
+			position = p
+		}
+		if ev.outboxID != 0 {
+			published = append(published, ev.outboxID)
+		}
+	}
+
+	if len(published) > 0 {
+		if err := s.outbox.store.MarkPublished(ctx, published...); err != nil {
+			logger.FromContext(ctx).Error("postgres: failed to mark outbox messages published, they will be published again", zap.Int64s("outbox_ids", published), zap.Error(err))
+		}
+	}
+	return position
+}
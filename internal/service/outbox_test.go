@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/events"
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+)
+
+type noTx struct{}
+
+func (noTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type memOutbox struct {
+	messages  []repo.OutboxMessage
+	published map[int64]bool
+	addErr    error
+}
+
+func newMemOutbox() *memOutbox {
+	return &memOutbox{published: map[int64]bool{}}
+}
+
+func (m *memOutbox) Add(ctx context.Context, msg *repo.OutboxMessage) error {
+	if m.addErr != nil {
+		return m.addErr
+	}
+	msg.ID = int64(len(m.messages) + 1)
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+func (m *memOutbox) ListUnpublished(ctx context.Context, minAge time.Duration, limit int) ([]repo.OutboxMessage, error) {
+	return nil, nil
+}
+
+func (m *memOutbox) MarkPublished(ctx context.Context, ids ...int64) error {
+	for _, id := range ids {
+		m.published[id] = true
+	}
+	return nil
+}
+
+func (m *memOutbox) DeletePublishedOlderThan(ctx context.Context, age time.Duration) (int64, error) {
+	return 0, nil
+}
+
+type downPublisher struct{}
+
+func (downPublisher) Publish(ctx context.Context, channel string, message interface{}) error {
+	return errors.New("redis unavailable")
+}
+
+func TestCreateOrderRecordsOutboxMessage(t *testing.T) {
+	store := newMemOutbox()
+	pub := &mockPublisher{}
+	svc := NewOrderService(newMockRepo(), pub, WithOutbox(noTx{}, store, events.JSONSerializer{}))
+
+	order, err := svc.CreateOrder(context.Background(), CreateOrderRequest{CustomerID: "customer-1", Product: "Widget", Quantity: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(store.messages) != 1 || store.messages[0].Channel != OrderCreatedChannel || store.messages[0].ContentType != "application/json" {
+		t.Fatalf("expected one order.created outbox message, got %+v", store.messages)
+	}
+	var recorded model.Order
+	if err := json.Unmarshal(store.messages[0].Payload, &recorded); err != nil {
+		t.Fatal(err)
+	}
+	if recorded.ID != order.ID || recorded.CreatedAt.IsZero() {
+		t.Errorf("expected the outbox payload to be the created order, got %+v", recorded)
+	}
+	if !store.published[1] || len(pub.published) != 1 {
+		t.Errorf("expected the event to be published and marked, got %v", store.published)
+	}
+}
+
+func TestPublishFailureLeavesOutboxMessageForRelay(t *testing.T) {
+	store := newMemOutbox()
+	repo := newMockRepo()
+	svc := NewOrderService(repo, downPublisher{}, WithOutbox(noTx{}, store, events.JSONSerializer{}))
+
+	order, err := svc.CreateOrder(context.Background(), CreateOrderRequest{CustomerID: "customer-1", Product: "Widget", Quantity: 1})
+	if err != nil {
+		t.Fatalf("expected the order to be created despite the publish failure, got %v", err)
+	}
+	if _, err := svc.UpdateOrder(context.Background(), order.ID, UpdateOrderRequest{Product: "Widget", Quantity: 1, Status: model.StatusConfirmed}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(store.messages) != 3 {
+		t.Fatalf("expected created, updated and status_changed outbox messages, got %d", len(store.messages))
+	}
+	if len(store.published) != 0 {
+		t.Errorf("expected no message to be marked published, got %v", store.published)
+	}
+}
+
+func TestOutboxFailureFailsWrite(t *testing.T) {
+	store := newMemOutbox()
+	store.addErr = errors.New("outbox unavailable")
+	pub := &mockPublisher{}
+	svc := NewOrderService(newMockRepo(), pub, WithOutbox(noTx{}, store, events.JSONSerializer{}))
+
+	if _, err := svc.CreateOrder(context.Background(), CreateOrderRequest{CustomerID: "customer-1", Product: "Widget", Quantity: 1}); !errors.Is(err, store.addErr) {
+		t.Fatalf("expected the outbox error, got %v", err)
+	}
+	if len(pub.published) != 0 {
+		t.Errorf("expected nothing to be published, got %d events", len(pub.published))
+	}
+}
+
+func TestEventIDStableAcrossOutboxAndPublish(t *testing.T) {
+	store := newMemOutbox()
+	pub := &mockPublisher{}
+	svc := NewOrderService(repo.NewInMemoryOrderRepository(), pub, WithOutbox(noTx{}, store, events.JSONSerializer{}))
+	ctx := context.Background()
+
+	created, err := svc.CreateOrder(ctx, CreateOrderRequest{CustomerID: "customer-1", Product: "Widget", Quantity: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.UpdateOrder(ctx, created.ID, UpdateOrderRequest{Product: "Widget", Quantity: 2, Status: model.StatusPending}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(store.messages) != 2 || len(pub.eventIDs) != 2 {
+		t.Fatalf("expected 2 recorded and 2 published events, got %d and %d", len(store.messages), len(pub.eventIDs))
+	}
+	if want := events.EventID(OrderCreatedChannel, created.ID, 1); pub.eventIDs[0] != want {
+		t.Errorf("expected created event ID %s, got %s", want, pub.eventIDs[0])
+	}
+	for i, msg := range store.messages {
+		if msg.EventID == "" || msg.EventID != pub.eventIDs[i] {
+			t.Errorf("event %d: expected the relayed copy to carry the published ID %s, got %q", i, pub.eventIDs[i], msg.EventID)
+		}
+	}
+	if pub.eventIDs[0] == pub.eventIDs[1] {
+		t.Errorf("expected the update to get a new event ID, got %s twice", pub.eventIDs[0])
+	}
+}
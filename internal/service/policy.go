@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/model"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrPolicyDenied      = NewError(CodePolicyDenied, "order denied by policy")
+	ErrPolicyUnavailable = NewError(CodeUnavailable, "order policy could not be evaluated")
+)
+
+// PolicyDeniedError is returned when the policy evaluator rejects an order.
+type PolicyDeniedError struct {
+	Reason string
+}
+
+func (e *PolicyDeniedError) Error() string {
+	if e.Reason == "" {
+		return ErrPolicyDenied.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrPolicyDenied, e.Reason)
+}
+
+func (e *PolicyDeniedError) Unwrap() error {
+	return ErrPolicyDenied
+}
+
+// PolicyEvaluator decides whether an order may be created or updated. It sees
+// the order as it would be written; reason explains a denial to the client.
+type PolicyEvaluator interface {
+	Evaluate(ctx context.Context, order *model.Order) (allow bool, reason string, err error)
+}
+
+// AllowAllPolicy is the default PolicyEvaluator and allows every order.
+type AllowAllPolicy struct{}
+
+func (AllowAllPolicy) Evaluate(ctx context.Context, order *model.Order) (bool, string, error) {
+	return true, "", nil
+}
+
+// WithPolicy consults evaluator on every create and update. When evaluation
+// fails the order is allowed if failOpen is set and rejected with
+// ErrPolicyUnavailable otherwise.
+func WithPolicy(evaluator PolicyEvaluator, failOpen bool) Option {
+	return func(s *OrderService) {
+		s.policy = evaluator
+		s.policyFailOpen = failOpen
+	}
+}
+
+func (s *OrderService) checkPolicy(ctx context.Context, order *model.Order) error {
+	log := logger.FromContext(ctx)
+
+	allow, reason, err := s.policy.Evaluate(ctx, order)
+	if err != nil {
+		if s.policyFailOpen {
+			log.Warn("policy evaluation failed, allowing order", zap.String("order_id", order.ID), zap.Error(err))
+			return nil
+		}
+		log.Error("policy evaluation failed", zap.String("order_id", order.ID), zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrPolicyUnavailable, err)
+	}
+	if !allow {
+		log.Warn("order denied by policy", zap.String("order_id", order.ID), zap.String("reason", reason))
+		return &PolicyDeniedError{Reason: reason}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+)
+
+type productPolicy struct {
+	denied string
+	err    error
+}
+
+func (p productPolicy) Evaluate(ctx context.Context, order *model.Order) (bool, string, error) {
+	if p.err != nil {
+		return false, "", p.err
+	}
+	if order.Product == p.denied {
+		return false, "product is embargoed", nil
+	}
+	return true, "", nil
+}
+
+func TestUpdateOrderDeniedByPolicy(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	pub := &mockPublisher{}
+	svc := NewOrderService(store, pub, WithPolicy(productPolicy{denied: "Gadget"}, false))
+	ctx := context.Background()
+
+	if err := store.Create(ctx, &model.Order{ID: "test-id", Product: "Widget", Quantity: 1, Status: model.StatusPending}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err := svc.UpdateOrder(ctx, "test-id", UpdateOrderRequest{Product: "Gadget", Quantity: 1, Status: model.StatusPending})
+	var deniedErr *PolicyDeniedError
+	if !errors.As(err, &deniedErr) || deniedErr.Reason != "product is embargoed" {
+		t.Fatalf("expected PolicyDeniedError, got %v", err)
+	}
+	if stored, _ := store.GetByID(ctx, "test-id"); stored.Product != "Widget" {
+		t.Errorf("expected denied update not to be applied, got %q", stored.Product)
+	}
+	if len(pub.published) != 0 {
+		t.Errorf("expected no events, got %d", len(pub.published))
+	}
+}
+
+func TestPolicyErrorFailOpenAndClosed(t *testing.T) {
+	policyErr := errors.New("policy service down")
+	req := CreateOrderRequest{CustomerID: "customer-1", Product: "Widget", Quantity: 1}
+
+	closed := NewOrderService(newMockRepo(), &mockPublisher{}, WithPolicy(productPolicy{err: policyErr}, false))
+	if _, err := closed.CreateOrder(context.Background(), req); !errors.Is(err, ErrPolicyUnavailable) {
+		t.Errorf("expected ErrPolicyUnavailable when failing closed, got %v", err)
+	}
+
+	open := NewOrderService(newMockRepo(), &mockPublisher{}, WithPolicy(productPolicy{err: policyErr}, true))
+	if _, err := open.CreateOrder(context.Background(), req); err != nil {
+		t.Errorf("expected order to be created when failing open, got %v", err)
+	}
+}
+
+func TestCreateOrdersDeniedByPolicy(t *testing.T) {
+	store := repo.NewInMemoryOrderRepository()
+	svc := NewOrderService(store, &mockPublisher{}, WithBatchCreate(store), WithPolicy(productPolicy{denied: "Gadget"}, false))
+
+	_, err := svc.CreateOrders(context.Background(), []CreateOrderRequest{
+		{CustomerID: "customer-1", Product: "Widget", Quantity: 1},
+		{CustomerID: "customer-1", Product: "Gadget", Quantity: 1},
+	})
+	var batchErr *BatchValidationError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected BatchValidationError, got %v", err)
+	}
+	if len(batchErr.Errors) != 1 || batchErr.Errors[0].Index != 1 || batchErr.Errors[0].Field != "policy" {
+		t.Errorf("expected the second order to be denied, got %+v", batchErr.Errors)
+	}
+	if n, _ := store.CountOrders(context.Background()); n != 0 {
+		t.Errorf("expected no orders to be created, got %d", n)
+	}
+}
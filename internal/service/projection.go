@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+	"go.uber.org/zap"
+)
+
+// WithProjection keeps the order read model in step with every write. Updates
+// are best-effort; a projection that drifts can be rebuilt from the orders
+// table.
+func WithProjection(store repo.ProjectionRepository) Option {
+	return func(s *OrderService) {
+		s.projection = store
+	}
+}
+
+func (s *OrderService) updateProjection(ctx context.Context, eventType string, order *model.Order) {
+	if s.projection == nil {
+		return
+	}
+
+	var err error
+	if eventType == OrderDeletedChannel {
+		err = s.projection.Delete(ctx, order.ID)
+	} else {
+		err = s.projection.Upsert(ctx, []model.Order{*order})
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("postgres: failed to update order projection", zap.String("order_id", order.ID), zap.String("event_type", eventType), zap.Error(err))
+	}
+}
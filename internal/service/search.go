@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/orders-service/internal/admission"
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultSearchLimit     = 50
+	MaxSearchLimit         = 200
+	MaxProductSearchLength = 100
+)
+
+var ErrSearchUnavailable = errors.New("product search is not configured")
+
+// WithProductSearch enables SearchOrdersByProduct, querying source.
+func WithProductSearch(source repo.ProductSearchRepository) Option {
+	return func(s *OrderService) {
+		s.search = source
+	}
+}
+
+// SearchOrdersByProduct returns up to limit orders whose product contains q,
+// ignoring case, newest first. A limit of 0 means DefaultSearchLimit.
+func (s *OrderService) SearchOrdersByProduct(ctx context.Context, q string, limit int) ([]model.Order, error) {
+	if s.search == nil {
+		return nil, ErrSearchUnavailable
+	}
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, &ValidationError{Field: "product", Message: "must not be empty"}
+	}
+	if utf8.RuneCountInString(q) > MaxProductSearchLength {
+		return nil, &ValidationError{Field: "product", Message: fmt.Sprintf("must be at most %d characters", MaxProductSearchLength)}
+	}
+	if limit < 0 || limit > MaxSearchLimit {
+		return nil, &ValidationError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", MaxSearchLimit)}
+	}
+	if limit == 0 {
+		limit = DefaultSearchLimit
+	}
+
+	if err := s.admit(admission.PriorityLow); err != nil {
+		logger.FromContext(ctx).Warn("shedding search request", zap.Error(err))
+		return nil, err
+	}
+	release, err := s.acquireRead(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Warn("shedding search request", zap.Error(err))
+		return nil, err
+	}
+	defer release()
+
+	orders, err := s.search.SearchByProduct(ctx, q, limit)
+	if err != nil {
+		logger.FromContext(ctx).Error("postgres: failed to search orders", zap.String("product", q), zap.Error(err))
+		return nil, err
+	}
+	return orders, nil
+}
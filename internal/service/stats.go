@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+	"go.uber.org/zap"
+)
+
+var ErrStatsUnavailable = errors.New("order statistics are not configured")
+
+const (
+	DefaultStatsSoftTimeout = 2 * time.Second
+	statsQueryTimeout       = 30 * time.Second
+)
+
+type StatsResult struct {
+	*model.OrderStats
+	Stale bool `json:"stale"`
+}
+
+type statsFlight struct {
+	done  chan struct{}
+	stats *model.OrderStats
+	err   error
+}
+
+// statsCache keeps the last successfully computed statistics and at most one
+// refresh in flight, so slow aggregates do not pile up on the database.
+type statsCache struct {
+	source      repo.StatsRepository
+	softTimeout time.Duration
+
+	mu     sync.Mutex
+	last   *model.OrderStats
+	flight *statsFlight
+}
+
+func WithStats(source repo.StatsRepository, softTimeout time.Duration) Option {
+	return func(s *OrderService) {
+		s.stats = &statsCache{source: source, softTimeout: softTimeout}
+	}
+}
+
+func (c *statsCache) refresh() *statsFlight {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.flight != nil {
+		return c.flight
+	}
+
+	f := &statsFlight{done: make(chan struct{})}
+	c.flight = f
+	go func() {
+		// Detached from the request so a slow query can still complete and
+		// refresh the cache after the caller has been served stale data.
+		ctx, cancel := context.WithTimeout(context.Background(), statsQueryTimeout)
+		defer cancel()
+		f.stats, f.err = c.source.Stats(ctx)
+
+		c.mu.Lock()
+		if f.err == nil {
+			c.last = f.stats
+		}
+		c.flight = nil
+		c.mu.Unlock()
+		close(f.done)
+	}()
+	return f
+}
+
+func (c *statsCache) lastKnown() *model.OrderStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// GetOrderStats returns fresh statistics when the aggregate completes within
+// the soft timeout, and otherwise falls back to the last known value marked
+// as stale. Without a previous value it waits for the query to finish.
+func (s *OrderService) GetOrderStats(ctx context.Context) (*StatsResult, error) {
+	if s.stats == nil {
+		return nil, ErrStatsUnavailable
+	}
+	log := logger.FromContext(ctx)
+
+	f := s.stats.refresh()
+	timer := time.NewTimer(s.stats.softTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-f.done:
+	case <-timer.C:
+		if last := s.stats.lastKnown(); last != nil {
+			log.Warn("order stats query exceeded soft timeout, serving stale result", zap.Time("computed_at", last.ComputedAt))
+			return &StatsResult{OrderStats: last, Stale: true}, nil
+		}
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if f.err != nil {
+		if last := s.stats.lastKnown(); last != nil {
+			log.Warn("order stats query failed, serving stale result", zap.Error(f.err))
+			return &StatsResult{OrderStats: last, Stale: true}, nil
+		}
+		log.Error("postgres: failed to compute order stats", zap.Error(f.err))
+		return nil, f.err
+	}
+	return &StatsResult{OrderStats: f.stats}, nil
+}
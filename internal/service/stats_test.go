@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orders-service/internal/model"
+)
+
+type fakeStatsSource struct {
+	calls chan chan *model.OrderStats
+}
+
+func (f *fakeStatsSource) Stats(ctx context.Context) (*model.OrderStats, error) {
+	reply := make(chan *model.OrderStats)
+	f.calls <- reply
+	select {
+	case stats := <-reply:
+		return stats, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestGetOrderStatsFreshThenStale(t *testing.T) {
+	source := &fakeStatsSource{calls: make(chan chan *model.OrderStats, 1)}
+	svc := NewOrderService(newMockRepo(), nil, WithStats(source, 50*time.Millisecond))
+	ctx := context.Background()
+
+	first := &model.OrderStats{Total: 3, ComputedAt: time.Now()}
+	go func() { (<-source.calls) <- first }()
+
+	result, err := svc.GetOrderStats(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Stale || result.Total != 3 {
+		t.Fatalf("expected fresh stats with total 3, got stale=%v total=%d", result.Stale, result.Total)
+	}
+
+	result, err = svc.GetOrderStats(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Stale || result.Total != 3 {
+		t.Errorf("expected stale stats with total 3 after soft timeout, got stale=%v total=%d", result.Stale, result.Total)
+	}
+
+	// Let the slow query finish; its result replaces the cached value.
+	(<-source.calls) <- &model.OrderStats{Total: 5, ComputedAt: time.Now()}
+	deadline := time.Now().Add(time.Second)
+	for svc.stats.lastKnown().Total != 5 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected slow query to refresh the cache")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/orders-service/internal/model"
+)
+
+// statusTransitions is the order lifecycle: each status maps to the statuses
+// an order may move to from it. Delivered and cancelled are terminal.
+var statusTransitions = map[string][]string{
+	model.StatusPending:   {model.StatusConfirmed, model.StatusCancelled},
+	model.StatusConfirmed: {model.StatusShipped, model.StatusCancelled},
+	model.StatusShipped:   {model.StatusDelivered},
+}
+
+// ErrInvalidTransition is returned when an update would move an order to a
+// status that cannot be reached from its current one.
+var ErrInvalidTransition = NewError(CodeFailedPrecondition, "invalid order status transition")
+
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%s: cannot move order from %s to %s", ErrInvalidTransition, e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+// CanTransition reports whether an order may move from one status to another.
+func CanTransition(from, to string) bool {
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTransition allows keeping the current status or moving along the
+// lifecycle, and returns a *TransitionError otherwise.
+func checkTransition(from, to string) error {
+	if from == to || CanTransition(from, to) {
+		return nil
+	}
+	return &TransitionError{From: from, To: to}
+}
+
+// StatusEventMode controls which events UpdateOrder publishes when it changes
+// an order's status.
+type StatusEventMode int
+
+const (
+	// StatusEventsAlongside publishes order.status_changed in addition to
+	// order.updated.
+	StatusEventsAlongside StatusEventMode = iota
+	// StatusEventsOnly publishes only order.status_changed.
+	StatusEventsOnly
+)
+
+// ParseStatusEventMode parses "both" or "only".
+func ParseStatusEventMode(mode string) (StatusEventMode, error) {
+	switch mode {
+	case "both":
+		return StatusEventsAlongside, nil
+	case "only":
+		return StatusEventsOnly, nil
+	default:
+		return 0, fmt.Errorf("unknown status event mode %q, expected both or only", mode)
+	}
+}
+
+func WithStatusEventMode(mode StatusEventMode) Option {
+	return func(s *OrderService) {
+		s.statusEvents = mode
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/orders-service/internal/logger"
+	"github.com/orders-service/internal/repo"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultMaxTags = 20
+	MaxTagLength   = 32
+)
+
+var (
+	ErrTagLimitExceeded = NewError(CodeFailedPrecondition, "order tag limit exceeded")
+	ErrTagsUnavailable  = errors.New("order tags are not configured")
+	ErrTagNotFound      = NewError(CodeNotFound, "tag not found")
+)
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+type TagLimitError struct {
+	OrderID string
+	Max     int
+}
+
+func (e *TagLimitError) Error() string {
+	return fmt.Sprintf("%s: order %s already has %d tags", ErrTagLimitExceeded, e.OrderID, e.Max)
+}
+
+func (e *TagLimitError) Unwrap() error {
+	return ErrTagLimitExceeded
+}
+
+// WithTags enables order tagging, allowing at most max tags per order.
+func WithTags(store repo.TagRepository, max int) Option {
+	return func(s *OrderService) {
+		s.tags = store
+		s.maxTags = max
+	}
+}
+
+// NormalizeTag trims and lowercases tag and checks that it is 1 to
+// MaxTagLength characters of a-z, 0-9, '-' and '_', starting with a letter
+// or digit.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", &ValidationError{Field: "tag", Message: "must not be empty"}
+	}
+	if len(tag) > MaxTagLength {
+		return "", &ValidationError{Field: "tag", Message: fmt.Sprintf("must be at most %d characters", MaxTagLength)}
+	}
+	if !tagPattern.MatchString(tag) {
+		return "", &ValidationError{Field: "tag", Message: "must contain only letters, digits, '-' and '_', starting with a letter or digit"}
+	}
+	return tag, nil
+}
+
+// AddTag tags an order and returns its tags. It fails with a *TagLimitError
+// once the order has the maximum number of tags; re-adding an existing tag
+// succeeds.
+func (s *OrderService) AddTag(ctx context.Context, id, tag string) ([]string, error) {
+	if s.tags == nil {
+		return nil, ErrTagsUnavailable
+	}
+	log := logger.FromContext(ctx)
+
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.tags.AddTag(ctx, id, tag, s.maxTags); err != nil {
+		if errors.Is(err, repo.ErrTagLimit) {
+			log.Warn("order tag limit reached", zap.String("order_id", id), zap.Int("max_tags", s.maxTags))
+			return nil, &TagLimitError{OrderID: id, Max: s.maxTags}
+		}
+		return nil, orderNotFound(err)
+	}
+	return s.tags.ListTags(ctx, id)
+}
+
+// RemoveTag fails with ErrTagNotFound if the order does not have the tag.
+func (s *OrderService) RemoveTag(ctx context.Context, id, tag string) error {
+	if s.tags == nil {
+		return ErrTagsUnavailable
+	}
+	err := s.tags.RemoveTag(ctx, id, strings.ToLower(strings.TrimSpace(tag)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTagNotFound
+	}
+	return err
+}
+
+// ListTags returns an order's tags in alphabetical order, or ErrOrderNotFound
+// if the order does not exist.
+func (s *OrderService) ListTags(ctx context.Context, id string) ([]string, error) {
+	if s.tags == nil {
+		return nil, ErrTagsUnavailable
+	}
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, orderNotFound(err)
+	}
+	return s.tags.ListTags(ctx, id)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/orders-service/internal/model"
+	"github.com/orders-service/internal/repo"
+)
+
+func newTaggedService(t *testing.T, max int) (*OrderService, string) {
+	t.Helper()
+	store := repo.NewInMemoryOrderRepository()
+	svc := NewOrderService(store, nil, WithTags(store, max))
+	if err := store.Create(context.Background(), &model.Order{ID: "order-1", Product: "widget", Quantity: 1, Status: model.StatusPending}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return svc, "order-1"
+}
+
+func TestAddTagEnforcesLimit(t *testing.T) {
+	svc, id := newTaggedService(t, 3)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.AddTag(ctx, id, fmt.Sprintf("tag-%d", i)); err != nil {
+			t.Fatalf("tag %d: unexpected error: %v", i, err)
+		}
+	}
+
+	_, err := svc.AddTag(ctx, id, "one-too-many")
+	var limitErr *TagLimitError
+	if !errors.As(err, &limitErr) || !errors.Is(err, ErrTagLimitExceeded) || limitErr.Max != 3 {
+		t.Fatalf("expected TagLimitError, got %v", err)
+	}
+
+	tags, err := svc.AddTag(ctx, id, "TAG-0")
+	if err != nil {
+		t.Errorf("expected re-adding an existing tag at the limit to succeed, got %v", err)
+	}
+	if len(tags) != 3 {
+		t.Errorf("expected 3 tags, got %v", tags)
+	}
+
+	if _, err := svc.AddTag(ctx, "missing", "tag"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("expected ErrOrderNotFound for a missing order, got %v", err)
+	}
+}
+
+func TestAddTagValidation(t *testing.T) {
+	svc, id := newTaggedService(t, DefaultMaxTags)
+
+	tests := []struct {
+		tag   string
+		valid bool
+	}{
+		{"priority", true},
+		{"  VIP ", true},
+		{"gift_wrap-2", true},
+		{"", false},
+		{"-leading-dash", false},
+		{"has space", false},
+		{"emoji-🎁", false},
+		{"semi;colon", false},
+		{strings.Repeat("a", MaxTagLength), true},
+		{strings.Repeat("a", MaxTagLength+1), false},
+	}
+
+	for _, tt := range tests {
+		_, err := svc.AddTag(context.Background(), id, tt.tag)
+		var validationErr *ValidationError
+		if tt.valid && err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.tag, err)
+		}
+		if !tt.valid && !errors.As(err, &validationErr) {
+			t.Errorf("%q: expected ValidationError, got %v", tt.tag, err)
+		}
+	}
+
+	tags, _ := svc.ListTags(context.Background(), id)
+	if len(tags) != 4 || tags[3] != "vip" {
+		t.Errorf("expected normalized tags, got %v", tags)
+	}
+}
+
+func TestTagNotFoundErrors(t *testing.T) {
+	svc, id := newTaggedService(t, 3)
+	ctx := context.Background()
+
+	if err := svc.RemoveTag(ctx, id, "missing"); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("expected ErrTagNotFound for a missing tag, got %v", err)
+	}
+	if _, err := svc.ListTags(ctx, "missing"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("expected ErrOrderNotFound for a missing order, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/orders-service/internal/model"
+)
+
+const (
+	MaxProductLength    = 255
+	MaxCustomerIDLength = 255
+	// MaxMetadataSize caps the combined length in bytes of an order's
+	// metadata keys and values.
+	MaxMetadataSize = 4096
+)
+
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (r *CreateOrderRequest) Validate() error {
+	r.CustomerID = strings.TrimSpace(r.CustomerID)
+	if r.CustomerID == "" {
+		return &ValidationError{Field: "customer_id", Message: "must not be empty"}
+	}
+	if utf8.RuneCountInString(r.CustomerID) > MaxCustomerIDLength {
+		return &ValidationError{Field: "customer_id", Message: "must be at most 255 characters"}
+	}
+	if err := validateItem(&r.Product, r.Quantity); err != nil {
+		return err
+	}
+	if err := validatePrice(r.Price, &r.Currency); err != nil {
+		return err
+	}
+	if err := validateMetadata(r.Metadata); err != nil {
+		return err
+	}
+	return validateTotal(r.Price, r.Quantity)
+}
+
+func (r *UpdateOrderRequest) Validate() error {
+	if r.Version < 0 {
+		return &ValidationError{Field: "version", Message: "must not be negative"}
+	}
+	if err := validateItem(&r.Product, r.Quantity); err != nil {
+		return err
+	}
+	var price int64
+	if r.Price != nil {
+		price = *r.Price
+	}
+	if err := validatePrice(price, &r.Currency); err != nil {
+		return err
+	}
+	if err := validateMetadata(r.Metadata); err != nil {
+		return err
+	}
+	return validateTotal(price, r.Quantity)
+}
+
+// validateItem trims product and checks it and quantity. Creates and updates
+// share it so that their rules stay the same.
+func validateItem(product *string, quantity int) error {
+	*product = strings.TrimSpace(*product)
+	if *product == "" {
+		return &ValidationError{Field: "product", Message: "must not be empty"}
+	}
+	if utf8.RuneCountInString(*product) > MaxProductLength {
+		return &ValidationError{Field: "product", Message: "must be at most 255 characters"}
+	}
+	if quantity <= 0 {
+		return &ValidationError{Field: "quantity", Message: "must be greater than 0"}
+	}
+	return nil
+}
+
+func validateMetadata(metadata map[string]string) error {
+	size := 0
+	for k, v := range metadata {
+		if strings.TrimSpace(k) == "" {
+			return &ValidationError{Field: "metadata", Message: "keys must not be empty"}
+		}
+		size += len(k) + len(v)
+	}
+	if size > MaxMetadataSize {
+		return &ValidationError{Field: "metadata", Message: fmt.Sprintf("must be at most %d bytes of keys and values", MaxMetadataSize)}
+	}
+	return nil
+}
+
+// validateTotal rejects orders whose total, price × quantity, does not fit in
+// an int64 rather than letting it wrap around.
+func validateTotal(price int64, quantity int) error {
+	if _, err := model.MulTotal(price, int64(quantity)); err != nil {
+		return &ValidationError{Field: "quantity", Message: "times price exceeds the maximum order total"}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/orders-service/internal/model"
+)
+
+type Warning struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// SoftCheck inspects an order that passed validation and returns a non-nil
+// Warning when the input is acceptable but worth flagging to the client.
+type SoftCheck func(ctx context.Context, order *model.Order) *Warning
+
+func WithSoftChecks(checks ...SoftCheck) Option {
+	return func(s *OrderService) {
+		s.softChecks = append(s.softChecks, checks...)
+	}
+}
+
+func LargeQuantityCheck(threshold int) SoftCheck {
+	return func(ctx context.Context, order *model.Order) *Warning {
+		if order.Quantity > threshold {
+			return &Warning{Field: "quantity", Message: fmt.Sprintf("is unusually large (above %d)", threshold)}
+		}
+		return nil
+	}
+}
+
+// CatalogCheck flags products that are not in the given catalog without
+// rejecting the order.
+func CatalogCheck(products []string) SoftCheck {
+	catalog := make(map[string]bool, len(products))
+	for _, p := range products {
+		catalog[p] = true
+	}
+	return func(ctx context.Context, order *model.Order) *Warning {
+		if !catalog[order.Product] {
+			return &Warning{Field: "product", Message: "is not in the product catalog"}
+		}
+		return nil
+	}
+}
+
+func (s *OrderService) runSoftChecks(ctx context.Context, order *model.Order) []Warning {
+	warnings := []Warning{}
+	for _, check := range s.softChecks {
+		if w := check(ctx, order); w != nil {
+			warnings = append(warnings, *w)
+		}
+	}
+	return warnings
+}
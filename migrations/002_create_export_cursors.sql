@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS export_cursors (
+    name VARCHAR(100) PRIMARY KEY,
+    last_created_at TIMESTAMP NOT NULL,
+    last_id UUID NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_created_at_id ON orders (created_at, id);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS order_audit (
+    id BIGSERIAL PRIMARY KEY,
+    order_id UUID NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_audit_order_created ON order_audit (order_id, created_at DESC, id DESC);
@@ -0,0 +1,2 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS price BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
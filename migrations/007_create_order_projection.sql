@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS order_projection (
+    id UUID PRIMARY KEY,
+    product VARCHAR(255) NOT NULL,
+    quantity INT NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    price BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    total BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_projection_status ON order_projection (status);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS order_tags (
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    tag VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (order_id, tag)
+);
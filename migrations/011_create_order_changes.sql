@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS order_changes (
+    seq BIGSERIAL PRIMARY KEY,
+    order_id UUID NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1 @@
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS event_id VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_order_changes_order_id_seq ON order_changes (order_id, seq);
@@ -0,0 +1,10 @@
+ALTER TABLE order_tags DROP CONSTRAINT IF EXISTS order_tags_order_id_fkey;
+ALTER TABLE orders ALTER COLUMN id TYPE TEXT;
+ALTER TABLE order_tags ALTER COLUMN order_id TYPE TEXT;
+ALTER TABLE order_tags ADD CONSTRAINT order_tags_order_id_fkey FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE;
+ALTER TABLE order_audit ALTER COLUMN order_id TYPE TEXT;
+ALTER TABLE order_changes ALTER COLUMN order_id TYPE TEXT;
+ALTER TABLE order_projection ALTER COLUMN id TYPE TEXT;
+ALTER TABLE export_cursors ALTER COLUMN last_id TYPE TEXT;
+
+CREATE SEQUENCE IF NOT EXISTS order_number_seq;
@@ -0,0 +1 @@
+ALTER TABLE export_cursors ADD COLUMN IF NOT EXISTS recent_ids JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+-- migrate:no-transaction
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_orders_product_trgm ON orders USING GIN (lower(product) gin_trgm_ops);
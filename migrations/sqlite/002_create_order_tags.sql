@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS order_tags (
+    order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    PRIMARY KEY (order_id, tag)
+);
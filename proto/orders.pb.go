@@ -28,6 +28,8 @@ const (
 	OrderStatus_ORDER_STATUS_PENDING     OrderStatus = 1
 	OrderStatus_ORDER_STATUS_CONFIRMED   OrderStatus = 2
 	OrderStatus_ORDER_STATUS_CANCELLED   OrderStatus = 3
+	OrderStatus_ORDER_STATUS_SHIPPED     OrderStatus = 4
+	OrderStatus_ORDER_STATUS_DELIVERED   OrderStatus = 5
 )
 
 // Enum value maps for OrderStatus.
@@ -37,12 +39,16 @@ var (
 		1: "ORDER_STATUS_PENDING",
 		2: "ORDER_STATUS_CONFIRMED",
 		3: "ORDER_STATUS_CANCELLED",
+		4: "ORDER_STATUS_SHIPPED",
+		5: "ORDER_STATUS_DELIVERED",
 	}
 	OrderStatus_value = map[string]int32{
 		"ORDER_STATUS_UNSPECIFIED": 0,
 		"ORDER_STATUS_PENDING":     1,
 		"ORDER_STATUS_CONFIRMED":   2,
 		"ORDER_STATUS_CANCELLED":   3,
+		"ORDER_STATUS_SHIPPED":     4,
+		"ORDER_STATUS_DELIVERED":   5,
 	}
 )
 
@@ -74,12 +80,21 @@ func (OrderStatus) EnumDescriptor() ([]byte, []int) {
 }
 
 type Order struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Product       string                 `protobuf:"bytes,2,opt,name=product,proto3" json:"product,omitempty"`
-	Quantity      int64                  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
-	Status        OrderStatus            `protobuf:"varint,4,opt,name=status,proto3,enum=orders.OrderStatus" json:"status,omitempty"`
-	CreatedAt     string                 `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Product   string                 `protobuf:"bytes,2,opt,name=product,proto3" json:"product,omitempty"`
+	Quantity  int64                  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	Status    OrderStatus            `protobuf:"varint,4,opt,name=status,proto3,enum=orders.OrderStatus" json:"status,omitempty"`
+	CreatedAt string                 `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// Unit price in minor units of currency.
+	Price      int64  `protobuf:"varint,6,opt,name=price,proto3" json:"price,omitempty"`
+	Currency   string `protobuf:"bytes,7,opt,name=currency,proto3" json:"currency,omitempty"`
+	UpdatedAt  string `protobuf:"bytes,8,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Version    int64  `protobuf:"varint,9,opt,name=version,proto3" json:"version,omitempty"`
+	CustomerId string `protobuf:"bytes,10,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
+	// Price × quantity. Unset if it does not fit in an int64.
+	Total         *int64            `protobuf:"varint,11,opt,name=total,proto3,oneof" json:"total,omitempty"`
+	Metadata      map[string]string `protobuf:"bytes,12,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -149,10 +164,69 @@ func (x *Order) GetCreatedAt() string {
 	return ""
 }
 
+func (x *Order) GetPrice() int64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Order) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Order) GetUpdatedAt() string {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return ""
+}
+
+func (x *Order) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Order) GetCustomerId() string {
+	if x != nil {
+		return x.CustomerId
+	}
+	return ""
+}
+
+func (x *Order) GetTotal() int64 {
+	if x != nil && x.Total != nil {
+		return *x.Total
+	}
+	return 0
+}
+
+func (x *Order) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
 type CreateOrderRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Product       string                 `protobuf:"bytes,1,opt,name=product,proto3" json:"product,omitempty"`
-	Quantity      int64                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Product  string                 `protobuf:"bytes,1,opt,name=product,proto3" json:"product,omitempty"`
+	Quantity int64                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	Price    int64                  `protobuf:"varint,3,opt,name=price,proto3" json:"price,omitempty"`
+	Currency string                 `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency,omitempty"`
+	// Required.
+	CustomerId string `protobuf:"bytes,5,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
+	// Keys must not be empty; keys and values together are limited to 4096
+	// bytes.
+	Metadata map[string]string `protobuf:"bytes,6,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Only run the checks and return the order as it would be created, without
+	// an id or timestamps; nothing is saved or published.
+	ValidateOnly  bool `protobuf:"varint,7,opt,name=validate_only,json=validateOnly,proto3" json:"validate_only,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -201,16 +275,104 @@ func (x *CreateOrderRequest) GetQuantity() int64 {
 	return 0
 }
 
+func (x *CreateOrderRequest) GetPrice() int64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *CreateOrderRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *CreateOrderRequest) GetCustomerId() string {
+	if x != nil {
+		return x.CustomerId
+	}
+	return ""
+}
+
+func (x *CreateOrderRequest) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *CreateOrderRequest) GetValidateOnly() bool {
+	if x != nil {
+		return x.ValidateOnly
+	}
+	return false
+}
+
+type Warning struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Field         string                 `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Warning) Reset() {
+	*x = Warning{}
+	mi := &file_proto_orders_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Warning) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Warning) ProtoMessage() {}
+
+func (x *Warning) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_orders_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Warning.ProtoReflect.Descriptor instead.
+func (*Warning) Descriptor() ([]byte, []int) {
+	return file_proto_orders_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Warning) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *Warning) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
 type CreateOrderResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Order         *Order                 `protobuf:"bytes,1,opt,name=order,proto3" json:"order,omitempty"`
+	Warnings      []*Warning             `protobuf:"bytes,2,rep,name=warnings,proto3" json:"warnings,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *CreateOrderResponse) Reset() {
 	*x = CreateOrderResponse{}
-	mi := &file_proto_orders_proto_msgTypes[2]
+	mi := &file_proto_orders_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -222,7 +384,7 @@ func (x *CreateOrderResponse) String() string {
 func (*CreateOrderResponse) ProtoMessage() {}
 
 func (x *CreateOrderResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_orders_proto_msgTypes[2]
+	mi := &file_proto_orders_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -235,7 +397,7 @@ func (x *CreateOrderResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateOrderResponse.ProtoReflect.Descriptor instead.
 func (*CreateOrderResponse) Descriptor() ([]byte, []int) {
-	return file_proto_orders_proto_rawDescGZIP(), []int{2}
+	return file_proto_orders_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *CreateOrderResponse) GetOrder() *Order {
@@ -245,6 +407,102 @@ func (x *CreateOrderResponse) GetOrder() *Order {
 	return nil
 }
 
+func (x *CreateOrderResponse) GetWarnings() []*Warning {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+type BatchCreateOrdersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Orders        []*CreateOrderRequest  `protobuf:"bytes,1,rep,name=orders,proto3" json:"orders,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCreateOrdersRequest) Reset() {
+	*x = BatchCreateOrdersRequest{}
+	mi := &file_proto_orders_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCreateOrdersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCreateOrdersRequest) ProtoMessage() {}
+
+func (x *BatchCreateOrdersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_orders_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCreateOrdersRequest.ProtoReflect.Descriptor instead.
+func (*BatchCreateOrdersRequest) Descriptor() ([]byte, []int) {
+	return file_proto_orders_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchCreateOrdersRequest) GetOrders() []*CreateOrderRequest {
+	if x != nil {
+		return x.Orders
+	}
+	return nil
+}
+
+type BatchCreateOrdersResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// In the order of the request.
+	Orders        []*CreateOrderResponse `protobuf:"bytes,1,rep,name=orders,proto3" json:"orders,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCreateOrdersResponse) Reset() {
+	*x = BatchCreateOrdersResponse{}
+	mi := &file_proto_orders_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCreateOrdersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCreateOrdersResponse) ProtoMessage() {}
+
+func (x *BatchCreateOrdersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_orders_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCreateOrdersResponse.ProtoReflect.Descriptor instead.
+func (*BatchCreateOrdersResponse) Descriptor() ([]byte, []int) {
+	return file_proto_orders_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BatchCreateOrdersResponse) GetOrders() []*CreateOrderResponse {
+	if x != nil {
+		return x.Orders
+	}
+	return nil
+}
+
 type GetOrderRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -254,7 +512,7 @@ type GetOrderRequest struct {
 
 func (x *GetOrderRequest) Reset() {
 	*x = GetOrderRequest{}
-	mi := &file_proto_orders_proto_msgTypes[3]
+	mi := &file_proto_orders_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -266,7 +524,7 @@ func (x *GetOrderRequest) String() string {
 func (*GetOrderRequest) ProtoMessage() {}
 
 func (x *GetOrderRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_orders_proto_msgTypes[3]
+	mi := &file_proto_orders_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -279,7 +537,7 @@ func (x *GetOrderRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetOrderRequest.ProtoReflect.Descriptor instead.
 func (*GetOrderRequest) Descriptor() ([]byte, []int) {
-	return file_proto_orders_proto_rawDescGZIP(), []int{3}
+	return file_proto_orders_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *GetOrderRequest) GetId() string {
@@ -298,7 +556,7 @@ type GetOrderResponse struct {
 
 func (x *GetOrderResponse) Reset() {
 	*x = GetOrderResponse{}
-	mi := &file_proto_orders_proto_msgTypes[4]
+	mi := &file_proto_orders_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -310,7 +568,7 @@ func (x *GetOrderResponse) String() string {
 func (*GetOrderResponse) ProtoMessage() {}
 
 func (x *GetOrderResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_orders_proto_msgTypes[4]
+	mi := &file_proto_orders_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -323,7 +581,7 @@ func (x *GetOrderResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetOrderResponse.ProtoReflect.Descriptor instead.
 func (*GetOrderResponse) Descriptor() ([]byte, []int) {
-	return file_proto_orders_proto_rawDescGZIP(), []int{4}
+	return file_proto_orders_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *GetOrderResponse) GetOrder() *Order {
@@ -334,14 +592,16 @@ func (x *GetOrderResponse) GetOrder() *Order {
 }
 
 type ListOrdersRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// When set, only orders owned by this customer are returned.
+	CustomerId    string `protobuf:"bytes,1,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ListOrdersRequest) Reset() {
 	*x = ListOrdersRequest{}
-	mi := &file_proto_orders_proto_msgTypes[5]
+	mi := &file_proto_orders_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -353,7 +613,7 @@ func (x *ListOrdersRequest) String() string {
 func (*ListOrdersRequest) ProtoMessage() {}
 
 func (x *ListOrdersRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_orders_proto_msgTypes[5]
+	mi := &file_proto_orders_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -366,7 +626,14 @@ func (x *ListOrdersRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListOrdersRequest.ProtoReflect.Descriptor instead.
 func (*ListOrdersRequest) Descriptor() ([]byte, []int) {
-	return file_proto_orders_proto_rawDescGZIP(), []int{5}
+	return file_proto_orders_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListOrdersRequest) GetCustomerId() string {
+	if x != nil {
+		return x.CustomerId
+	}
+	return ""
 }
 
 type ListOrdersResponse struct {
@@ -378,7 +645,7 @@ type ListOrdersResponse struct {
 
 func (x *ListOrdersResponse) Reset() {
 	*x = ListOrdersResponse{}
-	mi := &file_proto_orders_proto_msgTypes[6]
+	mi := &file_proto_orders_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -390,7 +657,7 @@ func (x *ListOrdersResponse) String() string {
 func (*ListOrdersResponse) ProtoMessage() {}
 
 func (x *ListOrdersResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_orders_proto_msgTypes[6]
+	mi := &file_proto_orders_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -403,7 +670,7 @@ func (x *ListOrdersResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListOrdersResponse.ProtoReflect.Descriptor instead.
 func (*ListOrdersResponse) Descriptor() ([]byte, []int) {
-	return file_proto_orders_proto_rawDescGZIP(), []int{6}
+	return file_proto_orders_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *ListOrdersResponse) GetOrders() []*Order {
@@ -413,19 +680,117 @@ func (x *ListOrdersResponse) GetOrders() []*Order {
 	return nil
 }
 
-type UpdateOrderRequest struct {
+type CountOrdersRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Product       string                 `protobuf:"bytes,2,opt,name=product,proto3" json:"product,omitempty"`
-	Quantity      int64                  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
-	Status        OrderStatus            `protobuf:"varint,4,opt,name=status,proto3,enum=orders.OrderStatus" json:"status,omitempty"`
+	Status        OrderStatus            `protobuf:"varint,1,opt,name=status,proto3,enum=orders.OrderStatus" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountOrdersRequest) Reset() {
+	*x = CountOrdersRequest{}
+	mi := &file_proto_orders_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountOrdersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountOrdersRequest) ProtoMessage() {}
+
+func (x *CountOrdersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_orders_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountOrdersRequest.ProtoReflect.Descriptor instead.
+func (*CountOrdersRequest) Descriptor() ([]byte, []int) {
+	return file_proto_orders_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *CountOrdersRequest) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+type CountOrdersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int64                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountOrdersResponse) Reset() {
+	*x = CountOrdersResponse{}
+	mi := &file_proto_orders_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountOrdersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountOrdersResponse) ProtoMessage() {}
+
+func (x *CountOrdersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_orders_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountOrdersResponse.ProtoReflect.Descriptor instead.
+func (*CountOrdersResponse) Descriptor() ([]byte, []int) {
+	return file_proto_orders_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CountOrdersResponse) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type UpdateOrderRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Id       string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Product  string                 `protobuf:"bytes,2,opt,name=product,proto3" json:"product,omitempty"`
+	Quantity int64                  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	Status   OrderStatus            `protobuf:"varint,4,opt,name=status,proto3,enum=orders.OrderStatus" json:"status,omitempty"`
+	// Price and currency are kept when unset.
+	Price    *int64 `protobuf:"varint,5,opt,name=price,proto3,oneof" json:"price,omitempty"`
+	Currency string `protobuf:"bytes,6,opt,name=currency,proto3" json:"currency,omitempty"`
+	// When set, the update is rejected with ABORTED unless the order is still
+	// at this version.
+	Version int64 `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+	// Replaces the order's metadata when not empty. It is kept otherwise,
+	// unless clear_metadata is set.
+	Metadata      map[string]string `protobuf:"bytes,8,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	ClearMetadata bool              `protobuf:"varint,9,opt,name=clear_metadata,json=clearMetadata,proto3" json:"clear_metadata,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *UpdateOrderRequest) Reset() {
 	*x = UpdateOrderRequest{}
-	mi := &file_proto_orders_proto_msgTypes[7]
+	mi := &file_proto_orders_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -437,7 +802,7 @@ func (x *UpdateOrderRequest) String() string {
 func (*UpdateOrderRequest) ProtoMessage() {}
 
 func (x *UpdateOrderRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_orders_proto_msgTypes[7]
+	mi := &file_proto_orders_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -450,7 +815,7 @@ func (x *UpdateOrderRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateOrderRequest.ProtoReflect.Descriptor instead.
 func (*UpdateOrderRequest) Descriptor() ([]byte, []int) {
-	return file_proto_orders_proto_rawDescGZIP(), []int{7}
+	return file_proto_orders_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *UpdateOrderRequest) GetId() string {
@@ -481,16 +846,52 @@ func (x *UpdateOrderRequest) GetStatus() OrderStatus {
 	return OrderStatus_ORDER_STATUS_UNSPECIFIED
 }
 
+func (x *UpdateOrderRequest) GetPrice() int64 {
+	if x != nil && x.Price != nil {
+		return *x.Price
+	}
+	return 0
+}
+
+func (x *UpdateOrderRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *UpdateOrderRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *UpdateOrderRequest) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *UpdateOrderRequest) GetClearMetadata() bool {
+	if x != nil {
+		return x.ClearMetadata
+	}
+	return false
+}
+
 type UpdateOrderResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Order         *Order                 `protobuf:"bytes,1,opt,name=order,proto3" json:"order,omitempty"`
+	Warnings      []*Warning             `protobuf:"bytes,2,rep,name=warnings,proto3" json:"warnings,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *UpdateOrderResponse) Reset() {
 	*x = UpdateOrderResponse{}
-	mi := &file_proto_orders_proto_msgTypes[8]
+	mi := &file_proto_orders_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -502,7 +903,7 @@ func (x *UpdateOrderResponse) String() string {
 func (*UpdateOrderResponse) ProtoMessage() {}
 
 func (x *UpdateOrderResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_orders_proto_msgTypes[8]
+	mi := &file_proto_orders_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -515,7 +916,7 @@ func (x *UpdateOrderResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateOrderResponse.ProtoReflect.Descriptor instead.
 func (*UpdateOrderResponse) Descriptor() ([]byte, []int) {
-	return file_proto_orders_proto_rawDescGZIP(), []int{8}
+	return file_proto_orders_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *UpdateOrderResponse) GetOrder() *Order {
@@ -525,6 +926,110 @@ func (x *UpdateOrderResponse) GetOrder() *Order {
 	return nil
 }
 
+func (x *UpdateOrderResponse) GetWarnings() []*Warning {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+type UpdateOrderStatusRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Must be set; ORDER_STATUS_UNSPECIFIED is rejected with INVALID_ARGUMENT.
+	Status        OrderStatus `protobuf:"varint,2,opt,name=status,proto3,enum=orders.OrderStatus" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateOrderStatusRequest) Reset() {
+	*x = UpdateOrderStatusRequest{}
+	mi := &file_proto_orders_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateOrderStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateOrderStatusRequest) ProtoMessage() {}
+
+func (x *UpdateOrderStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_orders_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateOrderStatusRequest.ProtoReflect.Descriptor instead.
+func (*UpdateOrderStatusRequest) Descriptor() ([]byte, []int) {
+	return file_proto_orders_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *UpdateOrderStatusRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdateOrderStatusRequest) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+type UpdateOrderStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Order         *Order                 `protobuf:"bytes,1,opt,name=order,proto3" json:"order,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateOrderStatusResponse) Reset() {
+	*x = UpdateOrderStatusResponse{}
+	mi := &file_proto_orders_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateOrderStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateOrderStatusResponse) ProtoMessage() {}
+
+func (x *UpdateOrderStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_orders_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateOrderStatusResponse.ProtoReflect.Descriptor instead.
+func (*UpdateOrderStatusResponse) Descriptor() ([]byte, []int) {
+	return file_proto_orders_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *UpdateOrderStatusResponse) GetOrder() *Order {
+	if x != nil {
+		return x.Order
+	}
+	return nil
+}
+
 type DeleteOrderRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -534,7 +1039,7 @@ type DeleteOrderRequest struct {
 
 func (x *DeleteOrderRequest) Reset() {
 	*x = DeleteOrderRequest{}
-	mi := &file_proto_orders_proto_msgTypes[9]
+	mi := &file_proto_orders_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -546,7 +1051,7 @@ func (x *DeleteOrderRequest) String() string {
 func (*DeleteOrderRequest) ProtoMessage() {}
 
 func (x *DeleteOrderRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_orders_proto_msgTypes[9]
+	mi := &file_proto_orders_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -559,7 +1064,7 @@ func (x *DeleteOrderRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteOrderRequest.ProtoReflect.Descriptor instead.
 func (*DeleteOrderRequest) Descriptor() ([]byte, []int) {
-	return file_proto_orders_proto_rawDescGZIP(), []int{9}
+	return file_proto_orders_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *DeleteOrderRequest) GetId() string {
@@ -577,7 +1082,7 @@ type DeleteOrderResponse struct {
 
 func (x *DeleteOrderResponse) Reset() {
 	*x = DeleteOrderResponse{}
-	mi := &file_proto_orders_proto_msgTypes[10]
+	mi := &file_proto_orders_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -589,7 +1094,7 @@ func (x *DeleteOrderResponse) String() string {
 func (*DeleteOrderResponse) ProtoMessage() {}
 
 func (x *DeleteOrderResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_orders_proto_msgTypes[10]
+	mi := &file_proto_orders_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -602,55 +1107,281 @@ func (x *DeleteOrderResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteOrderResponse.ProtoReflect.Descriptor instead.
 func (*DeleteOrderResponse) Descriptor() ([]byte, []int) {
-	return file_proto_orders_proto_rawDescGZIP(), []int{10}
+	return file_proto_orders_proto_rawDescGZIP(), []int{17}
+}
+
+type AddOrderTagRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Tag           string                 `protobuf:"bytes,2,opt,name=tag,proto3" json:"tag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddOrderTagRequest) Reset() {
+	*x = AddOrderTagRequest{}
+	mi := &file_proto_orders_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddOrderTagRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddOrderTagRequest) ProtoMessage() {}
+
+func (x *AddOrderTagRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_orders_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddOrderTagRequest.ProtoReflect.Descriptor instead.
+func (*AddOrderTagRequest) Descriptor() ([]byte, []int) {
+	return file_proto_orders_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *AddOrderTagRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *AddOrderTagRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+type AddOrderTagResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tags          []string               `protobuf:"bytes,1,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddOrderTagResponse) Reset() {
+	*x = AddOrderTagResponse{}
+	mi := &file_proto_orders_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddOrderTagResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddOrderTagResponse) ProtoMessage() {}
+
+func (x *AddOrderTagResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_orders_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddOrderTagResponse.ProtoReflect.Descriptor instead.
+func (*AddOrderTagResponse) Descriptor() ([]byte, []int) {
+	return file_proto_orders_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *AddOrderTagResponse) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+// OrderStatusChanged is the payload of order.status_changed events in the
+// protobuf event format.
+type OrderStatusChanged struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          OrderStatus            `protobuf:"varint,1,opt,name=from,proto3,enum=orders.OrderStatus" json:"from,omitempty"`
+	To            OrderStatus            `protobuf:"varint,2,opt,name=to,proto3,enum=orders.OrderStatus" json:"to,omitempty"`
+	Order         *Order                 `protobuf:"bytes,3,opt,name=order,proto3" json:"order,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderStatusChanged) Reset() {
+	*x = OrderStatusChanged{}
+	mi := &file_proto_orders_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderStatusChanged) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderStatusChanged) ProtoMessage() {}
+
+func (x *OrderStatusChanged) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_orders_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderStatusChanged.ProtoReflect.Descriptor instead.
+func (*OrderStatusChanged) Descriptor() ([]byte, []int) {
+	return file_proto_orders_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *OrderStatusChanged) GetFrom() OrderStatus {
+	if x != nil {
+		return x.From
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (x *OrderStatusChanged) GetTo() OrderStatus {
+	if x != nil {
+		return x.To
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (x *OrderStatusChanged) GetOrder() *Order {
+	if x != nil {
+		return x.Order
+	}
+	return nil
 }
 
 var File_proto_orders_proto protoreflect.FileDescriptor
 
 const file_proto_orders_proto_rawDesc = "" +
 	"\n" +
-	"\x12proto/orders.proto\x12\x06orders\"\x99\x01\n" +
+	"\x12proto/orders.proto\x12\x06orders\"\xc0\x03\n" +
 	"\x05Order\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
 	"\aproduct\x18\x02 \x01(\tR\aproduct\x12\x1a\n" +
 	"\bquantity\x18\x03 \x01(\x03R\bquantity\x12+\n" +
 	"\x06status\x18\x04 \x01(\x0e2\x13.orders.OrderStatusR\x06status\x12\x1d\n" +
 	"\n" +
-	"created_at\x18\x05 \x01(\tR\tcreatedAt\"J\n" +
+	"created_at\x18\x05 \x01(\tR\tcreatedAt\x12\x14\n" +
+	"\x05price\x18\x06 \x01(\x03R\x05price\x12\x1a\n" +
+	"\bcurrency\x18\a \x01(\tR\bcurrency\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\b \x01(\tR\tupdatedAt\x12\x18\n" +
+	"\aversion\x18\t \x01(\x03R\aversion\x12\x1f\n" +
+	"\vcustomer_id\x18\n" +
+	" \x01(\tR\n" +
+	"customerId\x12\x19\n" +
+	"\x05total\x18\v \x01(\x03H\x00R\x05total\x88\x01\x01\x127\n" +
+	"\bmetadata\x18\f \x03(\v2\x1b.orders.Order.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\b\n" +
+	"\x06_total\"\xc5\x02\n" +
 	"\x12CreateOrderRequest\x12\x18\n" +
 	"\aproduct\x18\x01 \x01(\tR\aproduct\x12\x1a\n" +
-	"\bquantity\x18\x02 \x01(\x03R\bquantity\":\n" +
+	"\bquantity\x18\x02 \x01(\x03R\bquantity\x12\x14\n" +
+	"\x05price\x18\x03 \x01(\x03R\x05price\x12\x1a\n" +
+	"\bcurrency\x18\x04 \x01(\tR\bcurrency\x12\x1f\n" +
+	"\vcustomer_id\x18\x05 \x01(\tR\n" +
+	"customerId\x12D\n" +
+	"\bmetadata\x18\x06 \x03(\v2(.orders.CreateOrderRequest.MetadataEntryR\bmetadata\x12#\n" +
+	"\rvalidate_only\x18\a \x01(\bR\fvalidateOnly\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"9\n" +
+	"\aWarning\x12\x14\n" +
+	"\x05field\x18\x01 \x01(\tR\x05field\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"g\n" +
 	"\x13CreateOrderResponse\x12#\n" +
-	"\x05order\x18\x01 \x01(\v2\r.orders.OrderR\x05order\"!\n" +
+	"\x05order\x18\x01 \x01(\v2\r.orders.OrderR\x05order\x12+\n" +
+	"\bwarnings\x18\x02 \x03(\v2\x0f.orders.WarningR\bwarnings\"N\n" +
+	"\x18BatchCreateOrdersRequest\x122\n" +
+	"\x06orders\x18\x01 \x03(\v2\x1a.orders.CreateOrderRequestR\x06orders\"P\n" +
+	"\x19BatchCreateOrdersResponse\x123\n" +
+	"\x06orders\x18\x01 \x03(\v2\x1b.orders.CreateOrderResponseR\x06orders\"!\n" +
 	"\x0fGetOrderRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\"7\n" +
 	"\x10GetOrderResponse\x12#\n" +
-	"\x05order\x18\x01 \x01(\v2\r.orders.OrderR\x05order\"\x13\n" +
-	"\x11ListOrdersRequest\";\n" +
+	"\x05order\x18\x01 \x01(\v2\r.orders.OrderR\x05order\"4\n" +
+	"\x11ListOrdersRequest\x12\x1f\n" +
+	"\vcustomer_id\x18\x01 \x01(\tR\n" +
+	"customerId\";\n" +
 	"\x12ListOrdersResponse\x12%\n" +
-	"\x06orders\x18\x01 \x03(\v2\r.orders.OrderR\x06orders\"\x87\x01\n" +
+	"\x06orders\x18\x01 \x03(\v2\r.orders.OrderR\x06orders\"A\n" +
+	"\x12CountOrdersRequest\x12+\n" +
+	"\x06status\x18\x01 \x01(\x0e2\x13.orders.OrderStatusR\x06status\"+\n" +
+	"\x13CountOrdersResponse\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x03R\x05count\"\x8c\x03\n" +
 	"\x12UpdateOrderRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
 	"\aproduct\x18\x02 \x01(\tR\aproduct\x12\x1a\n" +
 	"\bquantity\x18\x03 \x01(\x03R\bquantity\x12+\n" +
-	"\x06status\x18\x04 \x01(\x0e2\x13.orders.OrderStatusR\x06status\":\n" +
+	"\x06status\x18\x04 \x01(\x0e2\x13.orders.OrderStatusR\x06status\x12\x19\n" +
+	"\x05price\x18\x05 \x01(\x03H\x00R\x05price\x88\x01\x01\x12\x1a\n" +
+	"\bcurrency\x18\x06 \x01(\tR\bcurrency\x12\x18\n" +
+	"\aversion\x18\a \x01(\x03R\aversion\x12D\n" +
+	"\bmetadata\x18\b \x03(\v2(.orders.UpdateOrderRequest.MetadataEntryR\bmetadata\x12%\n" +
+	"\x0eclear_metadata\x18\t \x01(\bR\rclearMetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\b\n" +
+	"\x06_price\"g\n" +
 	"\x13UpdateOrderResponse\x12#\n" +
+	"\x05order\x18\x01 \x01(\v2\r.orders.OrderR\x05order\x12+\n" +
+	"\bwarnings\x18\x02 \x03(\v2\x0f.orders.WarningR\bwarnings\"W\n" +
+	"\x18UpdateOrderStatusRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12+\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x13.orders.OrderStatusR\x06status\"@\n" +
+	"\x19UpdateOrderStatusResponse\x12#\n" +
 	"\x05order\x18\x01 \x01(\v2\r.orders.OrderR\x05order\"$\n" +
 	"\x12DeleteOrderRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\"\x15\n" +
-	"\x13DeleteOrderResponse*}\n" +
+	"\x13DeleteOrderResponse\"6\n" +
+	"\x12AddOrderTagRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x10\n" +
+	"\x03tag\x18\x02 \x01(\tR\x03tag\")\n" +
+	"\x13AddOrderTagResponse\x12\x12\n" +
+	"\x04tags\x18\x01 \x03(\tR\x04tags\"\x87\x01\n" +
+	"\x12OrderStatusChanged\x12'\n" +
+	"\x04from\x18\x01 \x01(\x0e2\x13.orders.OrderStatusR\x04from\x12#\n" +
+	"\x02to\x18\x02 \x01(\x0e2\x13.orders.OrderStatusR\x02to\x12#\n" +
+	"\x05order\x18\x03 \x01(\v2\r.orders.OrderR\x05order*\xb3\x01\n" +
 	"\vOrderStatus\x12\x1c\n" +
 	"\x18ORDER_STATUS_UNSPECIFIED\x10\x00\x12\x18\n" +
 	"\x14ORDER_STATUS_PENDING\x10\x01\x12\x1a\n" +
 	"\x16ORDER_STATUS_CONFIRMED\x10\x02\x12\x1a\n" +
-	"\x16ORDER_STATUS_CANCELLED\x10\x032\xea\x02\n" +
+	"\x16ORDER_STATUS_CANCELLED\x10\x03\x12\x18\n" +
+	"\x14ORDER_STATUS_SHIPPED\x10\x04\x12\x1a\n" +
+	"\x16ORDER_STATUS_DELIVERED\x10\x052\xea\x05\n" +
 	"\fOrderService\x12F\n" +
-	"\vCreateOrder\x12\x1a.orders.CreateOrderRequest\x1a\x1b.orders.CreateOrderResponse\x12=\n" +
+	"\vCreateOrder\x12\x1a.orders.CreateOrderRequest\x1a\x1b.orders.CreateOrderResponse\x12X\n" +
+	"\x11BatchCreateOrders\x12 .orders.BatchCreateOrdersRequest\x1a!.orders.BatchCreateOrdersResponse\x12=\n" +
 	"\bGetOrder\x12\x17.orders.GetOrderRequest\x1a\x18.orders.GetOrderResponse\x12C\n" +
 	"\n" +
-	"ListOrders\x12\x19.orders.ListOrdersRequest\x1a\x1a.orders.ListOrdersResponse\x12F\n" +
-	"\vUpdateOrder\x12\x1a.orders.UpdateOrderRequest\x1a\x1b.orders.UpdateOrderResponse\x12F\n" +
-	"\vDeleteOrder\x12\x1a.orders.DeleteOrderRequest\x1a\x1b.orders.DeleteOrderResponseB!Z\x1fgithub.com/orders-service/protob\x06proto3"
+	"ListOrders\x12\x19.orders.ListOrdersRequest\x1a\x1a.orders.ListOrdersResponse\x12:\n" +
+	"\fStreamOrders\x12\x19.orders.ListOrdersRequest\x1a\r.orders.Order0\x01\x12F\n" +
+	"\vCountOrders\x12\x1a.orders.CountOrdersRequest\x1a\x1b.orders.CountOrdersResponse\x12F\n" +
+	"\vUpdateOrder\x12\x1a.orders.UpdateOrderRequest\x1a\x1b.orders.UpdateOrderResponse\x12X\n" +
+	"\x11UpdateOrderStatus\x12 .orders.UpdateOrderStatusRequest\x1a!.orders.UpdateOrderStatusResponse\x12F\n" +
+	"\vDeleteOrder\x12\x1a.orders.DeleteOrderRequest\x1a\x1b.orders.DeleteOrderResponse\x12F\n" +
+	"\vAddOrderTag\x12\x1a.orders.AddOrderTagRequest\x1a\x1b.orders.AddOrderTagResponseB!Z\x1fgithub.com/orders-service/protob\x06proto3"
 
 var (
 	file_proto_orders_proto_rawDescOnce sync.Once
@@ -665,43 +1396,79 @@ func file_proto_orders_proto_rawDescGZIP() []byte {
 }
 
 var file_proto_orders_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_proto_orders_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_proto_orders_proto_msgTypes = make([]protoimpl.MessageInfo, 24)
 var file_proto_orders_proto_goTypes = []any{
-	(OrderStatus)(0),            // 0: orders.OrderStatus
-	(*Order)(nil),               // 1: orders.Order
-	(*CreateOrderRequest)(nil),  // 2: orders.CreateOrderRequest
-	(*CreateOrderResponse)(nil), // 3: orders.CreateOrderResponse
-	(*GetOrderRequest)(nil),     // 4: orders.GetOrderRequest
-	(*GetOrderResponse)(nil),    // 5: orders.GetOrderResponse
-	(*ListOrdersRequest)(nil),   // 6: orders.ListOrdersRequest
-	(*ListOrdersResponse)(nil),  // 7: orders.ListOrdersResponse
-	(*UpdateOrderRequest)(nil),  // 8: orders.UpdateOrderRequest
-	(*UpdateOrderResponse)(nil), // 9: orders.UpdateOrderResponse
-	(*DeleteOrderRequest)(nil),  // 10: orders.DeleteOrderRequest
-	(*DeleteOrderResponse)(nil), // 11: orders.DeleteOrderResponse
+	(OrderStatus)(0),                  // 0: orders.OrderStatus
+	(*Order)(nil),                     // 1: orders.Order
+	(*CreateOrderRequest)(nil),        // 2: orders.CreateOrderRequest
+	(*Warning)(nil),                   // 3: orders.Warning
+	(*CreateOrderResponse)(nil),       // 4: orders.CreateOrderResponse
+	(*BatchCreateOrdersRequest)(nil),  // 5: orders.BatchCreateOrdersRequest
+	(*BatchCreateOrdersResponse)(nil), // 6: orders.BatchCreateOrdersResponse
+	(*GetOrderRequest)(nil),           // 7: orders.GetOrderRequest
+	(*GetOrderResponse)(nil),          // 8: orders.GetOrderResponse
+	(*ListOrdersRequest)(nil),         // 9: orders.ListOrdersRequest
+	(*ListOrdersResponse)(nil),        // 10: orders.ListOrdersResponse
+	(*CountOrdersRequest)(nil),        // 11: orders.CountOrdersRequest
+	(*CountOrdersResponse)(nil),       // 12: orders.CountOrdersResponse
+	(*UpdateOrderRequest)(nil),        // 13: orders.UpdateOrderRequest
+	(*UpdateOrderResponse)(nil),       // 14: orders.UpdateOrderResponse
+	(*UpdateOrderStatusRequest)(nil),  // 15: orders.UpdateOrderStatusRequest
+	(*UpdateOrderStatusResponse)(nil), // 16: orders.UpdateOrderStatusResponse
+	(*DeleteOrderRequest)(nil),        // 17: orders.DeleteOrderRequest
+	(*DeleteOrderResponse)(nil),       // 18: orders.DeleteOrderResponse
+	(*AddOrderTagRequest)(nil),        // 19: orders.AddOrderTagRequest
+	(*AddOrderTagResponse)(nil),       // 20: orders.AddOrderTagResponse
+	(*OrderStatusChanged)(nil),        // 21: orders.OrderStatusChanged
+	nil,                               // 22: orders.Order.MetadataEntry
+	nil,                               // 23: orders.CreateOrderRequest.MetadataEntry
+	nil,                               // 24: orders.UpdateOrderRequest.MetadataEntry
 }
 var file_proto_orders_proto_depIdxs = []int32{
 	0,  // 0: orders.Order.status:type_name -> orders.OrderStatus
-	1,  // 1: orders.CreateOrderResponse.order:type_name -> orders.Order
-	1,  // 2: orders.GetOrderResponse.order:type_name -> orders.Order
-	1,  // 3: orders.ListOrdersResponse.orders:type_name -> orders.Order
-	0,  // 4: orders.UpdateOrderRequest.status:type_name -> orders.OrderStatus
-	1,  // 5: orders.UpdateOrderResponse.order:type_name -> orders.Order
-	2,  // 6: orders.OrderService.CreateOrder:input_type -> orders.CreateOrderRequest
-	4,  // 7: orders.OrderService.GetOrder:input_type -> orders.GetOrderRequest
-	6,  // 8: orders.OrderService.ListOrders:input_type -> orders.ListOrdersRequest
-	8,  // 9: orders.OrderService.UpdateOrder:input_type -> orders.UpdateOrderRequest
-	10, // 10: orders.OrderService.DeleteOrder:input_type -> orders.DeleteOrderRequest
-	3,  // 11: orders.OrderService.CreateOrder:output_type -> orders.CreateOrderResponse
-	5,  // 12: orders.OrderService.GetOrder:output_type -> orders.GetOrderResponse
-	7,  // 13: orders.OrderService.ListOrders:output_type -> orders.ListOrdersResponse
-	9,  // 14: orders.OrderService.UpdateOrder:output_type -> orders.UpdateOrderResponse
-	11, // 15: orders.OrderService.DeleteOrder:output_type -> orders.DeleteOrderResponse
-	11, // [11:16] is the sub-list for method output_type
-	6,  // [6:11] is the sub-list for method input_type
-	6,  // [6:6] is the sub-list for extension type_name
-	6,  // [6:6] is the sub-list for extension extendee
-	0,  // [0:6] is the sub-list for field type_name
+	22, // 1: orders.Order.metadata:type_name -> orders.Order.MetadataEntry
+	23, // 2: orders.CreateOrderRequest.metadata:type_name -> orders.CreateOrderRequest.MetadataEntry
+	1,  // 3: orders.CreateOrderResponse.order:type_name -> orders.Order
+	3,  // 4: orders.CreateOrderResponse.warnings:type_name -> orders.Warning
+	2,  // 5: orders.BatchCreateOrdersRequest.orders:type_name -> orders.CreateOrderRequest
+	4,  // 6: orders.BatchCreateOrdersResponse.orders:type_name -> orders.CreateOrderResponse
+	1,  // 7: orders.GetOrderResponse.order:type_name -> orders.Order
+	1,  // 8: orders.ListOrdersResponse.orders:type_name -> orders.Order
+	0,  // 9: orders.CountOrdersRequest.status:type_name -> orders.OrderStatus
+	0,  // 10: orders.UpdateOrderRequest.status:type_name -> orders.OrderStatus
+	24, // 11: orders.UpdateOrderRequest.metadata:type_name -> orders.UpdateOrderRequest.MetadataEntry
+	1,  // 12: orders.UpdateOrderResponse.order:type_name -> orders.Order
+	3,  // 13: orders.UpdateOrderResponse.warnings:type_name -> orders.Warning
+	0,  // 14: orders.UpdateOrderStatusRequest.status:type_name -> orders.OrderStatus
+	1,  // 15: orders.UpdateOrderStatusResponse.order:type_name -> orders.Order
+	0,  // 16: orders.OrderStatusChanged.from:type_name -> orders.OrderStatus
+	0,  // 17: orders.OrderStatusChanged.to:type_name -> orders.OrderStatus
+	1,  // 18: orders.OrderStatusChanged.order:type_name -> orders.Order
+	2,  // 19: orders.OrderService.CreateOrder:input_type -> orders.CreateOrderRequest
+	5,  // 20: orders.OrderService.BatchCreateOrders:input_type -> orders.BatchCreateOrdersRequest
+	7,  // 21: orders.OrderService.GetOrder:input_type -> orders.GetOrderRequest
+	9,  // 22: orders.OrderService.ListOrders:input_type -> orders.ListOrdersRequest
+	9,  // 23: orders.OrderService.StreamOrders:input_type -> orders.ListOrdersRequest
+	11, // 24: orders.OrderService.CountOrders:input_type -> orders.CountOrdersRequest
+	13, // 25: orders.OrderService.UpdateOrder:input_type -> orders.UpdateOrderRequest
+	15, // 26: orders.OrderService.UpdateOrderStatus:input_type -> orders.UpdateOrderStatusRequest
+	17, // 27: orders.OrderService.DeleteOrder:input_type -> orders.DeleteOrderRequest
+	19, // 28: orders.OrderService.AddOrderTag:input_type -> orders.AddOrderTagRequest
+	4,  // 29: orders.OrderService.CreateOrder:output_type -> orders.CreateOrderResponse
+	6,  // 30: orders.OrderService.BatchCreateOrders:output_type -> orders.BatchCreateOrdersResponse
+	8,  // 31: orders.OrderService.GetOrder:output_type -> orders.GetOrderResponse
+	10, // 32: orders.OrderService.ListOrders:output_type -> orders.ListOrdersResponse
+	1,  // 33: orders.OrderService.StreamOrders:output_type -> orders.Order
+	12, // 34: orders.OrderService.CountOrders:output_type -> orders.CountOrdersResponse
+	14, // 35: orders.OrderService.UpdateOrder:output_type -> orders.UpdateOrderResponse
+	16, // 36: orders.OrderService.UpdateOrderStatus:output_type -> orders.UpdateOrderStatusResponse
+	18, // 37: orders.OrderService.DeleteOrder:output_type -> orders.DeleteOrderResponse
+	20, // 38: orders.OrderService.AddOrderTag:output_type -> orders.AddOrderTagResponse
+	29, // [29:39] is the sub-list for method output_type
+	19, // [19:29] is the sub-list for method input_type
+	19, // [19:19] is the sub-list for extension type_name
+	19, // [19:19] is the sub-list for extension extendee
+	0,  // [0:19] is the sub-list for field type_name
 }
 
 func init() { file_proto_orders_proto_init() }
@@ -709,13 +1476,15 @@ func file_proto_orders_proto_init() {
 	if File_proto_orders_proto != nil {
 		return
 	}
+	file_proto_orders_proto_msgTypes[0].OneofWrappers = []any{}
+	file_proto_orders_proto_msgTypes[12].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_orders_proto_rawDesc), len(file_proto_orders_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   11,
+			NumMessages:   24,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -26,7 +27,10 @@ type Stats struct {
 	TotalLatency    int64
 	MinLatency      int64
 	MaxLatency      int64
-	Errors          sync.Map
+	// LatencySamples counts requests that got a response and so recorded a
+	// latency; requests failing before that only count towards the totals.
+	LatencySamples int64
+	Errors         sync.Map
 }
 
 func main() {
@@ -223,8 +227,9 @@ func runMixedTest(config LoadTestConfig, stats *Stats) {
 
 func createOrder(baseURL string, stats *Stats) string {
 	payload := map[string]interface{}{
-		"product":  fmt.Sprintf("Product-%d", time.Now().UnixNano()),
-		"quantity": 10,
+		"customer_id": "loadtest",
+		"product":     fmt.Sprintf("Product-%d", time.Now().UnixNano()),
+		"quantity":    10,
 	}
 
 	return makeRequest("POST", baseURL+"/orders", payload, stats)
@@ -232,8 +237,9 @@ func createOrder(baseURL string, stats *Stats) string {
 
 func createOrderAndGetID(baseURL string) string {
 	payload := map[string]interface{}{
-		"product":  fmt.Sprintf("Product-%d", time.Now().UnixNano()),
-		"quantity": 10,
+		"customer_id": "loadtest",
+		"product":     fmt.Sprintf("Product-%d", time.Now().UnixNano()),
+		"quantity":    10,
 	}
 
 	body := makeRequestRaw("POST", baseURL+"/orders", payload)
@@ -342,6 +348,7 @@ func makeRequestRaw(method, url string, payload interface{}) string {
 
 func recordLatency(stats *Stats, latency int64) {
 	atomic.AddInt64(&stats.TotalLatency, latency)
+	atomic.AddInt64(&stats.LatencySamples, 1)
 
 	for {
 		old := atomic.LoadInt64(&stats.MinLatency)
@@ -372,36 +379,60 @@ func recordError(stats *Stats, err error) {
 }
 
 func printResults(stats *Stats, elapsed time.Duration) {
+	writeResults(os.Stdout, stats, elapsed)
+}
+
+// writeResults reports N/A for ratios that have no requests or latency
+// samples to divide by, e.g. when every request failed to connect.
+func writeResults(w io.Writer, stats *Stats, elapsed time.Duration) {
 	total := atomic.LoadInt64(&stats.TotalRequests)
 	success := atomic.LoadInt64(&stats.SuccessRequests)
 	failed := atomic.LoadInt64(&stats.FailedRequests)
 	totalLatency := atomic.LoadInt64(&stats.TotalLatency)
 	minLatency := atomic.LoadInt64(&stats.MinLatency)
 	maxLatency := atomic.LoadInt64(&stats.MaxLatency)
+	samples := atomic.LoadInt64(&stats.LatencySamples)
 
-	fmt.Printf("\n📊 Load Test Results\n")
-	fmt.Printf("═══════════════════════════════════════════════════\n")
-	fmt.Printf("Total time:           %v\n", elapsed)
-	fmt.Printf("Total requests:       %d\n", total)
-	fmt.Printf("Successful:           %d (%.2f%%)\n", success, float64(success)/float64(total)*100)
-	fmt.Printf("Failed:               %d (%.2f%%)\n", failed, float64(failed)/float64(total)*100)
-	fmt.Printf("\n")
-	fmt.Printf("Throughput:           %.2f req/sec\n", float64(total)/elapsed.Seconds())
-	fmt.Printf("\n")
-	fmt.Printf("Latency:\n")
-	fmt.Printf("  Average:            %d ms\n", totalLatency/total)
-	fmt.Printf("  Minimum:            %d ms\n", minLatency)
-	fmt.Printf("  Maximum:            %d ms\n", maxLatency)
+	percent := func(n int64) string {
+		if total == 0 {
+			return "N/A"
+		}
+		return fmt.Sprintf("%.2f%%", float64(n)/float64(total)*100)
+	}
+
+	fmt.Fprintf(w, "\n📊 Load Test Results\n")
+	fmt.Fprintf(w, "═══════════════════════════════════════════════════\n")
+	fmt.Fprintf(w, "Total time:           %v\n", elapsed)
+	fmt.Fprintf(w, "Total requests:       %d\n", total)
+	fmt.Fprintf(w, "Successful:           %d (%s)\n", success, percent(success))
+	fmt.Fprintf(w, "Failed:               %d (%s)\n", failed, percent(failed))
+	fmt.Fprintf(w, "\n")
+	if elapsed > 0 {
+		fmt.Fprintf(w, "Throughput:           %.2f req/sec\n", float64(total)/elapsed.Seconds())
+	} else {
+		fmt.Fprintf(w, "Throughput:           N/A\n")
+	}
+	fmt.Fprintf(w, "\n")
+	fmt.Fprintf(w, "Latency:\n")
+	if samples > 0 {
+		fmt.Fprintf(w, "  Average:            %d ms\n", totalLatency/samples)
+		fmt.Fprintf(w, "  Minimum:            %d ms\n", minLatency)
+		fmt.Fprintf(w, "  Maximum:            %d ms\n", maxLatency)
+	} else {
+		fmt.Fprintf(w, "  Average:            N/A\n")
+		fmt.Fprintf(w, "  Minimum:            N/A\n")
+		fmt.Fprintf(w, "  Maximum:            N/A\n")
+	}
 
 	if failed > 0 {
-		fmt.Printf("\n❌ Errors:\n")
+		fmt.Fprintf(w, "\n❌ Errors:\n")
 		stats.Errors.Range(func(key, value interface{}) bool {
 			count := atomic.LoadInt64(value.(*int64))
-			fmt.Printf("  [%d] %s\n", count, key.(string))
+			fmt.Fprintf(w, "  [%d] %s\n", count, key.(string))
 			return true
 		})
 	}
-	fmt.Printf("═══════════════════════════════════════════════════\n")
+	fmt.Fprintf(w, "═══════════════════════════════════════════════════\n")
 }
 
 func runUpdateTest(config LoadTestConfig, stats *Stats) {
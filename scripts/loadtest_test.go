@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteResultsAllFailed(t *testing.T) {
+	stats := &Stats{MinLatency: int64(^uint64(0) >> 1)}
+	for i := 0; i < 3; i++ {
+		stats.TotalRequests++
+		recordError(stats, errors.New("connection refused"))
+	}
+
+	var out bytes.Buffer
+	writeResults(&out, stats, time.Second)
+
+	for _, want := range []string{"Successful:           0 (0.00%)", "Average:            N/A", "Minimum:            N/A", "[3] connection refused"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestWriteResultsNoRequests(t *testing.T) {
+	var out bytes.Buffer
+	writeResults(&out, &Stats{}, 0)
+
+	for _, want := range []string{"Successful:           0 (N/A)", "Throughput:           N/A", "Average:            N/A"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestWriteResultsAveragesRecordedLatencies(t *testing.T) {
+	stats := &Stats{MinLatency: int64(^uint64(0) >> 1), TotalRequests: 3, SuccessRequests: 2}
+	recordLatency(stats, 10)
+	recordLatency(stats, 30)
+	recordError(stats, errors.New("timeout"))
+
+	var out bytes.Buffer
+	writeResults(&out, stats, time.Second)
+
+	for _, want := range []string{"Average:            20 ms", "Minimum:            10 ms", "Maximum:            30 ms"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}